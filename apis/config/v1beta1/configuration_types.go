@@ -19,6 +19,7 @@ package v1beta1
 import (
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	configv1alpha1 "k8s.io/component-base/config/v1alpha1"
 )
@@ -70,6 +71,20 @@ type Configuration struct {
 
 	// MultiKueue controls the behaviour of the MultiKueue AdmissionCheck Controller.
 	MultiKueue *MultiKueue `json:"multiKueue,omitempty"`
+
+	// Resources provides additional configuration options for handling the
+	// resources.
+	Resources *Resources `json:"resources,omitempty"`
+
+	// PauseBorrowing prevents all the ClusterQueues from borrowing unused
+	// quota from their cohorts, so they only admit workloads within their
+	// nominal quota, for example to restore a predictable isolation between
+	// them during an incident. The workloads already borrowing are not
+	// evicted. Borrowing can also be paused and resumed at runtime with the
+	// "paused" key of the kueue-borrowing ConfigMap in the namespace of Kueue,
+	// which overrides this field while it exists.
+	// Defaults to false.
+	PauseBorrowing bool `json:"pauseBorrowing,omitempty"`
 }
 
 type ControllerManager struct {
@@ -330,3 +345,18 @@ type ClusterQueueVisibility struct {
 	// Defaults to 10.
 	MaxCount int32 `json:"maxCount,omitempty"`
 }
+
+type Resources struct {
+	// Substitutions lists the resources whose quota can be replaced by the
+	// quota of other resources when it's exhausted.
+	Substitutions []ResourceSubstitution `json:"substitutions,omitempty"`
+}
+
+type ResourceSubstitution struct {
+	// Resource is the requested resource.
+	Resource corev1.ResourceName `json:"resource"`
+
+	// Substitutes are the resources whose quota can be consumed, in order,
+	// when the quota of Resource is exhausted.
+	Substitutes []corev1.ResourceName `json:"substitutes"`
+}
@@ -214,6 +214,17 @@ type ResourceQuota struct {
 	// enable the feature gate LendingLimit, which is disabled by default.
 	// +optional
 	LendingLimit *resource.Quantity `json:"lendingLimit,omitempty"`
+
+	// overcommitRatio is the ratio by which Workloads admitted by this
+	// ClusterQueue can overcommit the nominalQuota for the [flavor, resource]
+	// combination. In total, at a given time, Workloads in the ClusterQueue
+	// can consume a quantity of quota equal to nominalQuota*overcommitRatio
+	// without borrowing. The overcommitted quota is exclusive to the
+	// ClusterQueue; it's neither lent to nor accounted in the cohort.
+	// If null, it means that the nominalQuota is not overcommitted.
+	// If not null, it must be greater than or equal to 1.
+	// +optional
+	OvercommitRatio *float64 `json:"overcommitRatio,omitempty"`
 }
 
 // ResourceFlavorReference is the name of the ResourceFlavor.
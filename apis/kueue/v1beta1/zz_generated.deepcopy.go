@@ -979,6 +979,11 @@ func (in *ResourceQuota) DeepCopyInto(out *ResourceQuota) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
+	if in.OvercommitRatio != nil {
+		in, out := &in.OvercommitRatio, &out.OvercommitRatio
+		*out = new(float64)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuota.
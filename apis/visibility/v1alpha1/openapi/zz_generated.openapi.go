@@ -2735,12 +2735,18 @@ func schema_kueue_apis_visibility_v1alpha1_PendingWorkload(ref common.ReferenceC
 							Format:      "int32",
 						},
 					},
+					"queuedAt": {
+						SchemaProps: spec.SchemaProps{
+							Description: "QueuedAt indicates the time used to order the workload in the queue, which is either its creation time or the time it was last evicted",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
 				},
 				Required: []string{"priority", "localQueueName", "positionInClusterQueue", "positionInLocalQueue"},
 			},
 		},
 		Dependencies: []string{
-			"k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+			"k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta", "k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
 	}
 }
 
@@ -75,6 +75,10 @@ type PendingWorkload struct {
 
 	// PositionInLocalQueue indicates the workload's position in the LocalQueue, starting from 0
 	PositionInLocalQueue int32 `json:"positionInLocalQueue"`
+
+	// QueuedAt indicates the time used to order the workload in the queue,
+	// which is either its creation time or the time it was last evicted
+	QueuedAt metav1.Time `json:"queuedAt,omitempty"`
 }
 
 // +k8s:openapi-gen=true
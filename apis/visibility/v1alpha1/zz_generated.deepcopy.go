@@ -144,6 +144,7 @@ func (in *LocalQueueList) DeepCopyObject() runtime.Object {
 func (in *PendingWorkload) DeepCopyInto(out *PendingWorkload) {
 	*out = *in
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.QueuedAt.DeepCopyInto(&out.QueuedAt)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PendingWorkload.
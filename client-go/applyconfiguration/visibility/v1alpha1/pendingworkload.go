@@ -27,10 +27,11 @@ import (
 // with apply.
 type PendingWorkloadApplyConfiguration struct {
 	*v1.ObjectMetaApplyConfiguration `json:"metadata,omitempty"`
-	Priority                         *int32  `json:"priority,omitempty"`
-	LocalQueueName                   *string `json:"localQueueName,omitempty"`
-	PositionInClusterQueue           *int32  `json:"positionInClusterQueue,omitempty"`
-	PositionInLocalQueue             *int32  `json:"positionInLocalQueue,omitempty"`
+	Priority                         *int32       `json:"priority,omitempty"`
+	LocalQueueName                   *string      `json:"localQueueName,omitempty"`
+	PositionInClusterQueue           *int32       `json:"positionInClusterQueue,omitempty"`
+	PositionInLocalQueue             *int32       `json:"positionInLocalQueue,omitempty"`
+	QueuedAt                         *metav1.Time `json:"queuedAt,omitempty"`
 }
 
 // PendingWorkloadApplyConfiguration constructs an declarative configuration of the PendingWorkload type for use with
@@ -212,3 +213,11 @@ func (b *PendingWorkloadApplyConfiguration) WithPositionInLocalQueue(value int32
 	b.PositionInLocalQueue = &value
 	return b
 }
+
+// WithQueuedAt sets the QueuedAt field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the QueuedAt field is set to the value of the last call.
+func (b *PendingWorkloadApplyConfiguration) WithQueuedAt(value metav1.Time) *PendingWorkloadApplyConfiguration {
+	b.QueuedAt = &value
+	return b
+}
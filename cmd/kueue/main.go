@@ -22,6 +22,7 @@ import (
 	"flag"
 	"net/http"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -72,6 +73,18 @@ import (
 	// +kubebuilder:scaffold:imports
 )
 
+// scheduledHoldsSweepPeriod is how often the scheduled reservations in the
+// cache are activated or released.
+const scheduledHoldsSweepPeriod = 10 * time.Second
+
+// borrowBoostsSweepPeriod is how often the expired borrow boosts of the
+// ClusterQueues in the cache are cleared.
+const borrowBoostsSweepPeriod = 30 * time.Second
+
+// cohortUsageSamplePeriod is how often the usage of the cohorts is sampled
+// into their usage history, which holds the last hour of samples.
+const cohortUsageSamplePeriod = time.Minute
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -137,6 +150,13 @@ func main() {
 	kubeConfig.QPS = *cfg.ClientConnection.QPS
 	kubeConfig.Burst = int(*cfg.ClientConnection.Burst)
 	setupLog.V(2).Info("K8S Client", "qps", kubeConfig.QPS, "burst", kubeConfig.Burst)
+	cacheHandler := &debugger.CacheHandler{}
+	if features.Enabled(features.CacheDebugHandler) {
+		if options.Metrics.ExtraHandlers == nil {
+			options.Metrics.ExtraHandlers = make(map[string]http.Handler)
+		}
+		options.Metrics.ExtraHandlers[debugger.CacheDumpPath] = cacheHandler
+	}
 	mgr, err := ctrl.NewManager(kubeConfig, options)
 	if err != nil {
 		setupLog.Error(err, "Unable to start manager")
@@ -154,8 +174,16 @@ func main() {
 		close(certsReady)
 	}
 
-	cCache := cache.New(mgr.GetClient(), cache.WithPodsReadyTracking(blockForPodsReady(&cfg)))
-	queues := queue.NewManager(mgr.GetClient(), cCache, queue.WithPodsReadyRequeuingTimestamp(podsReadyRequeuingTimestamp(&cfg)))
+	cCache := cache.New(mgr.GetClient(),
+		cache.WithPodsReadyTracking(blockForPodsReady(&cfg)),
+		cache.WithResourceSubstitutions(resourceSubstitutions(&cfg)),
+		cache.WithBorrowingPaused(cfg.PauseBorrowing),
+	)
+	cacheHandler.Cache = cCache
+	queues := queue.NewManager(mgr.GetClient(), cCache,
+		queue.WithPodsReadyRequeuingTimestamp(podsReadyRequeuingTimestamp(&cfg)),
+		queue.WithPendingDemandRecorder(cCache),
+	)
 
 	ctx := ctrl.SetupSignalHandler()
 	if err := setupIndexes(ctx, mgr, &cfg); err != nil {
@@ -178,6 +206,15 @@ func main() {
 	go func() {
 		cCache.CleanUpOnContext(ctx)
 	}()
+	go func() {
+		cCache.RunScheduledHoldsSweeper(ctx, scheduledHoldsSweepPeriod)
+	}()
+	go func() {
+		cCache.RunBorrowBoostsSweeper(ctx, borrowBoostsSweepPeriod)
+	}()
+	go func() {
+		cCache.RunCohortUsageSampler(ctx, cohortUsageSamplePeriod)
+	}()
 
 	if features.Enabled(features.VisibilityOnDemand) {
 		go visibility.CreateAndStartVisibilityServer(queues, ctx)
@@ -354,6 +391,17 @@ func podsReadyRequeuingTimestamp(cfg *configapi.Configuration) configapi.Requeui
 	return configapi.EvictionTimestamp
 }
 
+func resourceSubstitutions(cfg *configapi.Configuration) map[corev1.ResourceName][]corev1.ResourceName {
+	if cfg.Resources == nil || len(cfg.Resources.Substitutions) == 0 {
+		return nil
+	}
+	substitutes := make(map[corev1.ResourceName][]corev1.ResourceName, len(cfg.Resources.Substitutions))
+	for _, substitution := range cfg.Resources.Substitutions {
+		substitutes[substitution.Resource] = substitution.Substitutes
+	}
+	return substitutes
+}
+
 func apply(configFile string) (ctrl.Options, configapi.Configuration, error) {
 	options, cfg, err := config.Load(scheme, configFile)
 	if err != nil {
@@ -16,8 +16,234 @@ limitations under the License.
 
 package cache
 
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
 type AdmissionCheck struct {
 	Active                       bool
 	Controller                   string
 	SingleInstanceInClusterQueue bool
 }
+
+// allChecksReady returns whether the workload has all the admission checks
+// required by its ClusterQueue in the Ready state. A workload holding a quota
+// reservation is not considered admitted until all its checks are ready.
+// If the ClusterQueue of the workload is not known, only the checks present in
+// the workload status are considered.
+func (c *Cache) allChecksReady(w *kueue.Workload) bool {
+	c.RLock()
+	defer c.RUnlock()
+	if cq := c.clusterQueueForWorkload(w); cq != nil && !workload.HasAllChecks(w, cq.AdmissionChecks) {
+		return false
+	}
+	return workload.HasAllChecksReady(w)
+}
+
+// workloadsBlockedByChecks returns the workloads in the ClusterQueue that hold
+// a quota reservation, so they fit in its capacity, but are not admitted yet
+// because some of the admission checks required by the ClusterQueue are not
+// ready. The workloads are sorted by key.
+// Returns nil if the ClusterQueue doesn't exist.
+func (c *Cache) workloadsBlockedByChecks(cqName string) []*workload.Info {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	var blocked []*workload.Info
+	for _, wi := range cq.Workloads {
+		if cq.blockedByChecks(wi) {
+			blocked = append(blocked, wi)
+		}
+	}
+	sort.Slice(blocked, func(i, j int) bool {
+		return workload.Key(blocked[i].Obj) < workload.Key(blocked[j].Obj)
+	})
+	return blocked
+}
+
+// UnreadyWorkload is a workload holding a quota reservation that is not
+// admitted because some of its admission checks are not ready.
+type UnreadyWorkload struct {
+	Info *workload.Info
+	// Waiting is how long the workload has held its quota reservation.
+	Waiting time.Duration
+}
+
+// assumedButUnready returns the workloads in all the ClusterQueues that hold
+// a quota reservation, whether assumed by the scheduler or already recorded
+// in their status, but are not admitted because some of their admission
+// checks are not ready, as in workloadsBlockedByChecks. Such workloads lock
+// capacity, so the ones waiting for too long, for example because the
+// provisioning of their capacity is stuck, are candidates to be released.
+// The workloads are sorted by longest wait first, then by key.
+func (c *Cache) assumedButUnready() []UnreadyWorkload {
+	c.RLock()
+	defer c.RUnlock()
+	now := c.clock.Now()
+	var unready []UnreadyWorkload
+	for _, cq := range c.clusterQueues {
+		for _, wi := range cq.Workloads {
+			if !cq.blockedByChecks(wi) {
+				continue
+			}
+			var waiting time.Duration
+			if cond := apimeta.FindStatusCondition(wi.Obj.Status.Conditions, kueue.WorkloadQuotaReserved); cond != nil {
+				waiting = max(now.Sub(cond.LastTransitionTime.Time), 0)
+			}
+			unready = append(unready, UnreadyWorkload{Info: wi, Waiting: waiting})
+		}
+	}
+	sort.Slice(unready, func(i, j int) bool {
+		if unready[i].Waiting != unready[j].Waiting {
+			return unready[i].Waiting > unready[j].Waiting
+		}
+		return workload.Key(unready[i].Info.Obj) < workload.Key(unready[j].Info.Obj)
+	})
+	return unready
+}
+
+// blockedByChecks returns whether the workload of the ClusterQueue is not
+// admitted because some of the admission checks required by the
+// ClusterQueue are not ready.
+func (c *ClusterQueue) blockedByChecks(wi *workload.Info) bool {
+	if workload.IsAdmitted(wi.Obj) {
+		return false
+	}
+	return !workload.HasAllChecks(wi.Obj, c.AdmissionChecks) || !workload.HasAllChecksReady(wi.Obj)
+}
+
+// SetAdmissionCheckState sets the state and message of the admission check in
+// the status of the workload, and in the copy of the workload held by the
+// cache. When the check transitions to Retry, for example because the
+// capacity couldn't be provisioned, the workload goes back to pending: its
+// quota reservation is unset in its status and the cache releases its quota,
+// whether it was assumed or admitted. The caller persists the status of the
+// workload, which requeues it.
+func (c *Cache) SetAdmissionCheckState(w *kueue.Workload, checkName string, state kueue.CheckState, message string) {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
+	c.Lock()
+	defer c.Unlock()
+
+	prev := workload.FindAdmissionCheck(w.Status.AdmissionChecks, checkName)
+	transitioned := prev == nil || prev.State != state
+	workload.SetAdmissionCheckState(&w.Status.AdmissionChecks, kueue.AdmissionCheckState{
+		Name:               checkName,
+		State:              state,
+		Message:            message,
+		LastTransitionTime: metav1.NewTime(c.clock.Now()),
+	})
+	cq := c.clusterQueueForWorkload(w)
+	k := workload.Key(w)
+	if !transitioned || state != kueue.CheckStateRetry || !workload.HasQuotaReservation(w) {
+		if cq == nil {
+			return
+		}
+		if wi, found := cq.Workloads[k]; found {
+			// The info is shared with the snapshots, so it's replaced.
+			updated := *wi
+			updated.Obj = wi.Obj.DeepCopy()
+			updated.Obj.Status.AdmissionChecks = w.Status.DeepCopy().AdmissionChecks
+			cq.Workloads[k] = &updated
+		}
+		return
+	}
+
+	c.cleanupAssumedState(w)
+	if cq != nil {
+		if _, found := cq.Workloads[k]; found {
+			cq.deleteWorkload(w)
+			changed = append(changed, cq.Name)
+		}
+	}
+	workload.UnsetQuotaReservationWithCondition(w, "Pending", fmt.Sprintf("The admission check %s is in the Retry state: %s", checkName, message))
+	c.recordTransition(w, WorkloadStatePending, "AdmissionCheckRetry")
+	if c.podsReadyTracking {
+		c.podsReadyCond.Broadcast()
+	}
+}
+
+// flavorProvisioningClass returns the provisioning class of the ResourceFlavor,
+// or an empty string if its capacity is available without provisioning.
+func (c *Cache) flavorProvisioningClass(flavorName string) string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.provisioningClasses[kueue.ResourceFlavorReference(flavorName)]
+}
+
+// RequiresProvisioning returns whether the capacity of the ResourceFlavor
+// needs to be provisioned before the workloads assigned to it can run.
+func (c *Cache) RequiresProvisioning(flavorName string) bool {
+	return c.flavorProvisioningClass(flavorName) != ""
+}
+
+// ProvisioningChecks returns the active admission checks of the ClusterQueue
+// that are managed by the provisioning request controller.
+// Returns nil if the ClusterQueue doesn't exist.
+func (c *Cache) ProvisioningChecks(cqName string) sets.Set[string] {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	checks := sets.New[string]()
+	for name := range cq.AdmissionChecks {
+		if ac, ok := c.admissionChecks[name]; ok && ac.Active && ac.Controller == constants.ProvisioningRequestControllerName {
+			checks.Insert(name)
+		}
+	}
+	return checks
+}
+
+// pendingChecksForAssignment returns the admission checks of the ClusterQueue
+// in the admission that the workload has to pass with the flavors assigned in
+// it, sorted by name. The checks managed by the provisioning request
+// controller only apply when the admission assigns a flavor that requires
+// provisioning; the other checks always apply. If the admission is nil, the
+// admission in the workload status is used.
+// Returns nil if there is no admission or its ClusterQueue doesn't exist.
+func (c *Cache) pendingChecksForAssignment(w *kueue.Workload, admission *kueue.Admission) []string {
+	if admission == nil {
+		admission = w.Status.Admission
+	}
+	if admission == nil {
+		return nil
+	}
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[string(admission.ClusterQueue)]
+	if !ok {
+		return nil
+	}
+	provisioned := false
+	for _, psa := range admission.PodSetAssignments {
+		for _, fName := range psa.Flavors {
+			if c.provisioningClasses[fName] != "" {
+				provisioned = true
+			}
+		}
+	}
+	checks := make([]string, 0, len(cq.AdmissionChecks))
+	for name := range cq.AdmissionChecks {
+		if ac, ok := c.admissionChecks[name]; ok && ac.Controller == constants.ProvisioningRequestControllerName && !provisioned {
+			continue
+		}
+		checks = append(checks, name)
+	}
+	sort.Strings(checks)
+	return checks
+}
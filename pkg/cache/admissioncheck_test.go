@@ -0,0 +1,392 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	testingclock "k8s.io/utils/clock/testing"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/constants"
+	controllerconsts "sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+func TestAllChecksReady(t *testing.T) {
+	admission := utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "1").Obj()
+	cases := map[string]struct {
+		workload *kueue.Workload
+		want     bool
+	}{
+		"no checks, unknown ClusterQueue": {
+			workload: utiltesting.MakeWorkload("wl", "ns").Obj(),
+			want:     true,
+		},
+		"missing check required by the ClusterQueue": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				ReserveQuota(admission).
+				AdmissionCheck(kueue.AdmissionCheckState{Name: "check1", State: kueue.CheckStateReady}).
+				Obj(),
+		},
+		"pending check": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				ReserveQuota(admission).
+				AdmissionCheck(kueue.AdmissionCheckState{Name: "check1", State: kueue.CheckStateReady}).
+				AdmissionCheck(kueue.AdmissionCheckState{Name: "check2", State: kueue.CheckStatePending}).
+				Obj(),
+		},
+		"retry check": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				ReserveQuota(admission).
+				AdmissionCheck(kueue.AdmissionCheckState{Name: "check1", State: kueue.CheckStateReady}).
+				AdmissionCheck(kueue.AdmissionCheckState{Name: "check2", State: kueue.CheckStateRetry}).
+				Obj(),
+		},
+		"all checks ready": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				ReserveQuota(admission).
+				AdmissionCheck(kueue.AdmissionCheckState{Name: "check1", State: kueue.CheckStateReady}).
+				AdmissionCheck(kueue.AdmissionCheckState{Name: "check2", State: kueue.CheckStateReady}).
+				Obj(),
+			want: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cq := utiltesting.MakeClusterQueue("cq").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+				AdmissionChecks("check1", "check2").
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			if got := cache.allChecksReady(tc.workload); got != tc.want {
+				t.Errorf("Unexpected allChecksReady, got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWorkloadsBlockedByChecks(t *testing.T) {
+	admission := utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "1").Obj()
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		AdmissionChecks("check1", "check2").
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("pending-check", "ns").
+			ReserveQuota(admission).
+			AdmissionCheck(kueue.AdmissionCheckState{Name: "check1", State: kueue.CheckStateReady}).
+			AdmissionCheck(kueue.AdmissionCheckState{Name: "check2", State: kueue.CheckStatePending}).
+			Obj(),
+		utiltesting.MakeWorkload("missing-check", "ns").
+			ReserveQuota(admission).
+			AdmissionCheck(kueue.AdmissionCheckState{Name: "check1", State: kueue.CheckStateReady}).
+			Obj(),
+		utiltesting.MakeWorkload("checks-ready", "ns").
+			ReserveQuota(admission).
+			AdmissionCheck(kueue.AdmissionCheckState{Name: "check1", State: kueue.CheckStateReady}).
+			AdmissionCheck(kueue.AdmissionCheckState{Name: "check2", State: kueue.CheckStateReady}).
+			Obj(),
+		utiltesting.MakeWorkload("admitted", "ns").
+			ReserveQuota(admission).
+			AdmissionCheck(kueue.AdmissionCheckState{Name: "check1", State: kueue.CheckStateReady}).
+			AdmissionCheck(kueue.AdmissionCheckState{Name: "check2", State: kueue.CheckStateReady}).
+			Admitted(true).
+			Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %q", wl.Name)
+		}
+	}
+
+	var got []string
+	for _, wi := range cache.workloadsBlockedByChecks("cq") {
+		got = append(got, workload.Key(wi.Obj))
+	}
+	want := []string{"ns/missing-check", "ns/pending-check"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected blocked workloads (-want,+got):\n%s", diff)
+	}
+	if got := cache.workloadsBlockedByChecks("missing"); got != nil {
+		t.Errorf("Unexpected blocked workloads for a missing ClusterQueue: %v", got)
+	}
+}
+
+func TestAssumedButUnready(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	fakeClock := testingclock.NewFakeClock(now)
+	admission := utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "1").Obj()
+	cache := New(utiltesting.NewFakeClient(), WithClock(fakeClock))
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		AdmissionChecks("check").
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	assumed := []*kueue.Workload{
+		utiltesting.MakeWorkload("old-pending", "ns").
+			ReserveQuotaAt(admission, now.Add(-time.Minute)).
+			AdmissionCheck(kueue.AdmissionCheckState{Name: "check", State: kueue.CheckStatePending}).
+			Obj(),
+		utiltesting.MakeWorkload("new-pending", "ns").
+			ReserveQuotaAt(admission, now).
+			AdmissionCheck(kueue.AdmissionCheckState{Name: "check", State: kueue.CheckStatePending}).
+			Obj(),
+		utiltesting.MakeWorkload("missing-check", "ns").
+			ReserveQuotaAt(admission, now).
+			Obj(),
+		utiltesting.MakeWorkload("ready", "ns").
+			ReserveQuotaAt(admission, now.Add(-time.Hour)).
+			AdmissionCheck(kueue.AdmissionCheckState{Name: "check", State: kueue.CheckStateReady}).
+			Obj(),
+	}
+	for _, wl := range assumed {
+		if err := cache.AssumeWorkload(wl); err != nil {
+			t.Fatalf("Failed assuming workload %q: %v", wl.Name, err)
+		}
+	}
+	reserved := utiltesting.MakeWorkload("reserved", "ns").
+		ReserveQuotaAt(admission, now.Add(-time.Minute)).
+		AdmissionCheck(kueue.AdmissionCheckState{Name: "check", State: kueue.CheckStateRetry}).
+		Obj()
+	if !cache.AddOrUpdateWorkload(reserved) {
+		t.Fatalf("Failed adding workload %q", reserved.Name)
+	}
+	fakeClock.Step(30 * time.Second)
+
+	type unready struct {
+		Key     string
+		Waiting time.Duration
+	}
+	var got []unready
+	for _, uw := range cache.assumedButUnready() {
+		got = append(got, unready{Key: workload.Key(uw.Info.Obj), Waiting: uw.Waiting})
+	}
+	want := []unready{
+		{Key: "ns/old-pending", Waiting: 90 * time.Second},
+		{Key: "ns/reserved", Waiting: 90 * time.Second},
+		{Key: "ns/missing-check", Waiting: 30 * time.Second},
+		{Key: "ns/new-pending", Waiting: 30 * time.Second},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected unready workloads (-want,+got):\n%s", diff)
+	}
+
+	if err := cache.ForgetWorkload(assumed[0]); err != nil {
+		t.Fatalf("Failed forgetting workload: %v", err)
+	}
+	got = nil
+	for _, uw := range cache.assumedButUnready() {
+		got = append(got, unready{Key: workload.Key(uw.Info.Obj), Waiting: uw.Waiting})
+	}
+	if diff := cmp.Diff(want[1:], got); diff != "" {
+		t.Errorf("Unexpected unready workloads after forgetting one (-want,+got):\n%s", diff)
+	}
+}
+
+func TestSetAdmissionCheckState(t *testing.T) {
+	admission := utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "4").Obj()
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+		AdmissionChecks("prov").
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		Request(corev1.ResourceCPU, "4").
+		ReserveQuota(admission).
+		AdmissionCheck(kueue.AdmissionCheckState{Name: "prov", State: kueue.CheckStatePending}).
+		Obj()
+	if err := cache.AssumeWorkload(wl); err != nil {
+		t.Fatalf("Failed assuming the workload: %v", err)
+	}
+
+	cache.SetAdmissionCheckState(wl, "prov", kueue.CheckStateReady, "provisioned")
+	if got := cache.workloadsBlockedByChecks("cq"); len(got) != 0 {
+		t.Errorf("Unexpected blocked workloads after the check is ready: %v", got)
+	}
+	if got := cache.clusterQueues["cq"].Usage["default"][corev1.ResourceCPU]; got != 4_000 {
+		t.Errorf("Unexpected usage after the check is ready: %d", got)
+	}
+
+	cache.SetAdmissionCheckState(wl, "prov", kueue.CheckStateRetry, "provisioning failed")
+	if got := cache.clusterQueues["cq"].Usage["default"][corev1.ResourceCPU]; got != 0 {
+		t.Errorf("Unexpected usage after the check is retried: %d", got)
+	}
+	if workload.HasQuotaReservation(wl) {
+		t.Error("The workload still has a quota reservation after the check is retried")
+	}
+	wantChecks := []kueue.AdmissionCheckState{{Name: "prov", State: kueue.CheckStateRetry, Message: "provisioning failed"}}
+	if diff := cmp.Diff(wantChecks, wl.Status.AdmissionChecks, cmpopts.IgnoreFields(kueue.AdmissionCheckState{}, "LastTransitionTime")); diff != "" {
+		t.Errorf("Unexpected admission checks (-want,+got):\n%s", diff)
+	}
+	if cache.IsAssumedOrAdmittedWorkload(*workload.NewInfo(wl)) {
+		t.Error("The workload is still assumed after the check is retried")
+	}
+
+	history := cache.workloadTransitions(wl)
+	if got := history[len(history)-1]; got.To != WorkloadStatePending || got.Reason != "AdmissionCheckRetry" {
+		t.Errorf("Unexpected last transition after the check is retried: %+v", got)
+	}
+}
+
+func TestRequiresProvisioning(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	instant := utiltesting.MakeResourceFlavor("on-demand").Obj()
+	provisioned := utiltesting.MakeResourceFlavor("burst").Obj()
+	provisioned.Annotations = map[string]string{controllerconsts.ResourceFlavorProvisioningClassAnnotation: "queued-provisioning"}
+	cache.AddOrUpdateResourceFlavor(instant)
+	cache.AddOrUpdateResourceFlavor(provisioned)
+
+	cases := map[string]struct {
+		flavor    string
+		wantClass string
+	}{
+		"instant flavor": {
+			flavor: "on-demand",
+		},
+		"provisioned flavor": {
+			flavor:    "burst",
+			wantClass: "queued-provisioning",
+		},
+		"unknown flavor": {
+			flavor: "unknown",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := cache.flavorProvisioningClass(tc.flavor); got != tc.wantClass {
+				t.Errorf("Unexpected provisioning class, got %q, want %q", got, tc.wantClass)
+			}
+			if got, want := cache.RequiresProvisioning(tc.flavor), tc.wantClass != ""; got != want {
+				t.Errorf("Unexpected RequiresProvisioning, got %t, want %t", got, want)
+			}
+		})
+	}
+
+	provisioned = provisioned.DeepCopy()
+	provisioned.Annotations = nil
+	cache.AddOrUpdateResourceFlavor(provisioned)
+	if cache.RequiresProvisioning("burst") {
+		t.Error("The flavor requires provisioning after removing its provisioning class")
+	}
+}
+
+func TestProvisioningChecks(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("prov").
+		ControllerName(constants.ProvisioningRequestControllerName).
+		Active(metav1.ConditionTrue).
+		Obj())
+	cache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("prov-inactive").
+		ControllerName(constants.ProvisioningRequestControllerName).
+		Active(metav1.ConditionFalse).
+		Obj())
+	cache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("other").
+		ControllerName("example.com/other").
+		Active(metav1.ConditionTrue).
+		Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		AdmissionChecks("prov", "prov-inactive", "other").
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+
+	if diff := cmp.Diff(sets.New("prov"), cache.ProvisioningChecks("cq")); diff != "" {
+		t.Errorf("Unexpected provisioning checks (-want,+got):\n%s", diff)
+	}
+	if got := cache.ProvisioningChecks("unknown"); got != nil {
+		t.Errorf("Unexpected provisioning checks for an unknown ClusterQueue: %v", got)
+	}
+}
+
+func TestPendingChecksForAssignment(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	spot := utiltesting.MakeResourceFlavor("spot").Obj()
+	spot.Annotations = map[string]string{controllerconsts.ResourceFlavorProvisioningClassAnnotation: "queued-provisioning"}
+	cache.AddOrUpdateResourceFlavor(spot)
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+	cache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("prov").
+		ControllerName(constants.ProvisioningRequestControllerName).
+		Active(metav1.ConditionTrue).
+		Obj())
+	cache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("other").
+		ControllerName("example.com/other").
+		Active(metav1.ConditionTrue).
+		Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(
+			*utiltesting.MakeFlavorQuotas("on-demand").Resource(corev1.ResourceCPU, "10").Obj(),
+			*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "10").Obj(),
+		).
+		AdmissionChecks("prov", "other").
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+
+	cases := map[string]struct {
+		admission *kueue.Admission
+		want      []string
+	}{
+		"assigned to the spot flavor": {
+			admission: utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "spot", "1").Obj(),
+			want:      []string{"other", "prov"},
+		},
+		"assigned to the on-demand flavor": {
+			admission: utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "on-demand", "1").Obj(),
+			want:      []string{"other"},
+		},
+		"unknown ClusterQueue": {
+			admission: utiltesting.MakeAdmission("unknown").Assignment(corev1.ResourceCPU, "spot", "1").Obj(),
+		},
+		"no admission": {},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			wl := utiltesting.MakeWorkload("wl", "ns").Obj()
+			got := cache.pendingChecksForAssignment(wl, tc.admission)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected checks (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
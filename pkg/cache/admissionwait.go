@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// markQueued stores the time at which the workload was queued, the first
+// time the cache sees it pending admission.
+func (c *Cache) markQueued(w *kueue.Workload) {
+	k := workload.Key(w)
+	if _, found := c.queuedAt[k]; !found {
+		c.queuedAt[k] = queuedTime(w)
+	}
+}
+
+// recordAdmissionWait reports the admission wait duration when the workload
+// transitions from pending to admitted. Workloads that were never seen
+// pending by the cache, for example after a restart, are not reported.
+func (c *Cache) recordAdmissionWait(w *kueue.Workload) {
+	if !workload.IsAdmitted(w) {
+		c.markQueued(w)
+		return
+	}
+	k := workload.Key(w)
+	queuedAt, found := c.queuedAt[k]
+	if !found {
+		return
+	}
+	delete(c.queuedAt, k)
+	c.metrics.RecordAdmissionWait(string(w.Status.Admission.ClusterQueue), c.clock.Since(queuedAt))
+}
+
+func (c *Cache) forgetQueued(w *kueue.Workload) {
+	delete(c.queuedAt, workload.Key(w))
+}
+
+// queuedTime returns the time at which the workload was last queued, which
+// is the time of its last eviction or its creation time.
+func queuedTime(w *kueue.Workload) time.Time {
+	if c := apimeta.FindStatusCondition(w.Status.Conditions, kueue.WorkloadEvicted); c != nil {
+		return c.LastTransitionTime.Time
+	}
+	return w.CreationTimestamp.Time
+}
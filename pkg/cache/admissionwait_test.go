@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+// admissionWaits returns the RecordAdmissionWait calls of the recorder.
+func admissionWaits(recorder *fakeMetricsRecorder) []string {
+	var waits []string
+	for _, call := range recorder.takeCalls() {
+		if strings.HasPrefix(call, "RecordAdmissionWait(") {
+			waits = append(waits, call)
+		}
+	}
+	return waits
+}
+
+func TestAdmissionWaitDuration(t *testing.T) {
+	now := time.Now()
+	fakeClock := testingclock.NewFakeClock(now)
+	recorder := &fakeMetricsRecorder{}
+	cache := New(utiltesting.NewFakeClient(), WithClock(fakeClock), WithMetricsRecorder(recorder))
+	cq := utiltesting.MakeClusterQueue("wait-cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	admission := utiltesting.MakeAdmission("wait-cq").Obj()
+
+	// Admitted by the scheduler without admission checks.
+	admitted := utiltesting.MakeWorkload("a", "ns").Creation(now).ReserveQuota(admission).Admitted(true).Obj()
+	if err := cache.AssumeWorkload(admitted); err != nil {
+		t.Fatalf("Failed assuming workload: %v", err)
+	}
+	fakeClock.Step(time.Minute)
+	for i := 0; i < 2; i++ {
+		if !cache.AddOrUpdateWorkload(admitted) {
+			t.Fatalf("Failed adding workload")
+		}
+	}
+	if diff := cmp.Diff([]string{"RecordAdmissionWait(wait-cq, 1m0s)"}, admissionWaits(recorder)); diff != "" {
+		t.Errorf("Unexpected admission waits after the first admission (-want,+got):\n%s", diff)
+	}
+
+	// Quota reserved, then admitted once the admission checks are ready.
+	reserved := utiltesting.MakeWorkload("b", "ns").Creation(now).ReserveQuota(admission).Obj()
+	if !cache.AddOrUpdateWorkload(reserved) {
+		t.Fatalf("Failed adding workload")
+	}
+	fakeClock.Step(time.Minute)
+	admitted = reserved.DeepCopy()
+	apimeta.SetStatusCondition(&admitted.Status.Conditions, metav1.Condition{
+		Type:   kueue.WorkloadAdmitted,
+		Status: metav1.ConditionTrue,
+		Reason: "ByTest",
+	})
+	for _, oldWl := range []*kueue.Workload{reserved, admitted} {
+		if err := cache.UpdateWorkload(oldWl, admitted); err != nil {
+			t.Fatalf("Failed updating workload: %v", err)
+		}
+	}
+	if diff := cmp.Diff([]string{"RecordAdmissionWait(wait-cq, 2m0s)"}, admissionWaits(recorder)); diff != "" {
+		t.Errorf("Unexpected admission waits after the second admission (-want,+got):\n%s", diff)
+	}
+
+	// Seen admitted for the first time, as after a restart.
+	restarted := utiltesting.MakeWorkload("c", "ns").Creation(now).ReserveQuota(admission).Admitted(true).Obj()
+	if !cache.AddOrUpdateWorkload(restarted) {
+		t.Fatalf("Failed adding workload")
+	}
+	if waits := admissionWaits(recorder); len(waits) != 0 {
+		t.Errorf("Unexpected admission waits for a workload never seen pending: %v", waits)
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+// maxAdmittableSize returns, per resource covered by the ClusterQueue, the
+// largest amount that a single workload could be admitted with right now. As
+// a workload gets a single flavor per resource, it's the most that the
+// ClusterQueue can still use in any of its flavors, borrowing from its cohort
+// within its borrowing limit. Flavors that don't exist or are disabled are
+// skipped. Returns nil if the ClusterQueue doesn't exist or isn't active.
+func (c *Cache) maxAdmittableSize(cqName string) Resources {
+	snap := c.Snapshot()
+	cq, ok := snap.ClusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	size := make(Resources)
+	for _, rg := range cq.ResourceGroups {
+		for rName := range rg.CoveredResources {
+			size[rName] = 0
+		}
+		for _, flvQuotas := range rg.Flavors {
+			if _, found := snap.ResourceFlavors[flvQuotas.Name]; !found || snap.DisabledFlavors.Has(flvQuotas.Name) {
+				continue
+			}
+			for rName, rQuota := range flvQuotas.Resources {
+				size[rName] = max(size[rName], availableQuota(cq, flvQuotas.Name, rName, rQuota))
+			}
+		}
+	}
+	return size
+}
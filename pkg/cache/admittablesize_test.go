@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestMaxAdmittableSize(t *testing.T) {
+	cases := map[string]struct {
+		cqName         string
+		borrowingLimit string
+		cohort         string
+		spotQuota      string
+		disableSpot    bool
+		want           Resources
+	}{
+		"free quota plus borrowable quota": {
+			cqName: "cq",
+			cohort: "one",
+			want:   Resources{corev1.ResourceCPU: 7_000},
+		},
+		"borrowing limit": {
+			cqName:         "cq",
+			cohort:         "one",
+			borrowingLimit: "1",
+			want:           Resources{corev1.ResourceCPU: 5_000},
+		},
+		"without cohort": {
+			cqName: "cq",
+			want:   Resources{corev1.ResourceCPU: 4_000},
+		},
+		"larger flavor": {
+			cqName:    "cq",
+			cohort:    "one",
+			spotQuota: "9",
+			want:      Resources{corev1.ResourceCPU: 9_000},
+		},
+		"disabled flavor": {
+			cqName:      "cq",
+			cohort:      "one",
+			spotQuota:   "9",
+			disableSpot: true,
+			want:        Resources{corev1.ResourceCPU: 7_000},
+		},
+		"missing ClusterQueue": {
+			cqName: "missing",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			spot := utiltesting.MakeResourceFlavor("spot").Obj()
+			if tc.disableSpot {
+				spot.Annotations = map[string]string{constants.ResourceFlavorDisabledAnnotation: "true"}
+			}
+			cache.AddOrUpdateResourceFlavor(spot)
+			spotQuota := tc.spotQuota
+			if spotQuota == "" {
+				spotQuota = "0"
+			}
+			clusterQueues := []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("cq").
+					ResourceGroup(
+						*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10", tc.borrowingLimit).Obj(),
+						*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, spotQuota).Obj(),
+					).
+					Cohort(tc.cohort).
+					Obj(),
+				utiltesting.MakeClusterQueue("peer").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "3").Obj()).
+					Cohort(tc.cohort).
+					Obj(),
+			}
+			for _, cq := range clusterQueues {
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			w := utiltesting.MakeWorkload("used", "ns").
+				Request(corev1.ResourceCPU, "6").
+				ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "6").Obj()).
+				Obj()
+			if !cache.AddOrUpdateWorkload(w) {
+				t.Fatal("Failed adding workload")
+			}
+			if diff := cmp.Diff(tc.want, cache.maxAdmittableSize(tc.cqName)); diff != "" {
+				t.Errorf("Unexpected maximum admittable size (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+var (
+	errNoFlavorFits         = errors.New("the workload doesn't fit in the flavors of the ClusterQueue")
+	errClusterQueueInactive = errors.New("cluster queue is inactive")
+)
+
+// assignFlavors computes the flavors of all the PodSets of the workload in the
+// ClusterQueue, returning an Admission ready to be set in the status of the
+// workload. For each PodSet and resource group, the first flavor, in the order
+// of the ClusterQueue, that explainUnschedulable wouldn't reject is assigned,
+// or the flavor pinned by the workload. Unlike explainUnschedulable, each
+// PodSet only gets the quota left by the PodSets before it. A workload that
+// already holds quota in the ClusterQueue is assigned as if it didn't.
+// Preemption is not considered. Returns an error wrapping errNoFlavorFits,
+// explaining why, if some PodSet doesn't fit.
+func (c *Cache) assignFlavors(w *kueue.Workload, cqName string) (*kueue.Admission, error) {
+	snap := c.Snapshot()
+	return assignFlavorsInSnapshot(&snap, w, cqName)
+}
+
+// assignFlavorsInSnapshot computes the flavors of the workload in the
+// ClusterQueue of the snapshot, as in assignFlavors. The snapshot is left as
+// it was.
+func assignFlavorsInSnapshot(snap *Snapshot, w *kueue.Workload, cqName string) (*kueue.Admission, error) {
+	cq, ok := snap.ClusterQueues[cqName]
+	if !ok {
+		if snap.InactiveClusterQueueSets.Has(cqName) {
+			return nil, errClusterQueueInactive
+		}
+		return nil, errCqNotFound
+	}
+	pinned, err := workload.PinnedFlavors(w)
+	if err != nil {
+		return nil, err
+	}
+	if wi, found := cq.Workloads[workload.Key(w)]; found {
+		snap.RemoveWorkload(wi)
+		defer snap.AddWorkload(wi)
+	}
+	excluded := workload.ExcludedFlavors(w)
+	admission := &kueue.Admission{ClusterQueue: kueue.ClusterQueueReference(cqName)}
+	assigned := &workload.Info{Obj: w, ClusterQueue: cqName}
+	defer func() {
+		if len(assigned.TotalRequests) > 0 {
+			snap.RemoveWorkload(assigned)
+		}
+	}()
+	for _, psr := range workload.NewInfo(w).TotalRequests {
+		ps := podSetByName(w, psr.Name)
+		if ps == nil {
+			continue
+		}
+		flavors, reason := assignPodSetFlavors(snap, cq, ps, psr.Requests, pinned, excluded)
+		if reason != "" {
+			return nil, fmt.Errorf("%w: PodSet %s: %s", errNoFlavorFits, psr.Name, reason)
+		}
+		// Account for the PodSet so that the following ones only get the
+		// quota it leaves.
+		if len(assigned.TotalRequests) > 0 {
+			snap.RemoveWorkload(assigned)
+		}
+		psr.Flavors = flavors
+		assigned.TotalRequests = append(assigned.TotalRequests, psr)
+		snap.AddWorkload(assigned)
+		admission.PodSetAssignments = append(admission.PodSetAssignments, kueue.PodSetAssignment{
+			Name:          psr.Name,
+			Flavors:       flavors,
+			ResourceUsage: psr.Requests.ToResourceList(),
+			Count:         ptr.To(psr.Count),
+		})
+	}
+	return admission, nil
+}
+
+// assignPodSetFlavors returns the flavor assigned to each requested resource
+// of the PodSet, or why the PodSet doesn't fit.
+func assignPodSetFlavors(snap *Snapshot, cq *ClusterQueue, ps *kueue.PodSet, requests workload.Requests, pinned map[corev1.ResourceName]kueue.ResourceFlavorReference, excluded sets.Set[kueue.ResourceFlavorReference]) (map[corev1.ResourceName]kueue.ResourceFlavorReference, string) {
+	var uncovered []string
+	var rgs []*ResourceGroup
+	for rName, val := range requests {
+		rg := cq.RGByResource[rName]
+		if rg == nil {
+			if val > 0 {
+				uncovered = append(uncovered, string(rName))
+			}
+			continue
+		}
+		if !slices.Contains(rgs, rg) {
+			rgs = append(rgs, rg)
+		}
+	}
+	if len(uncovered) > 0 {
+		sort.Strings(uncovered)
+		return nil, fmt.Sprintf("resources %s are not covered by the ClusterQueue", strings.Join(uncovered, ", "))
+	}
+	sort.Slice(rgs, func(i, j int) bool {
+		return firstResource(rgs[i]) < firstResource(rgs[j])
+	})
+	flavors := make(map[corev1.ResourceName]kueue.ResourceFlavorReference, len(requests))
+	for _, rg := range rgs {
+		var pinnedFlavor kueue.ResourceFlavorReference
+		for rName := range rg.CoveredResources {
+			if _, requested := requests[rName]; requested && pinned[rName] != "" {
+				pinnedFlavor = pinned[rName]
+				break
+			}
+		}
+		var chosen *FlavorQuotas
+		for i := range rg.Flavors {
+			if pinnedFlavor != "" && rg.Flavors[i].Name != pinnedFlavor {
+				continue
+			}
+			reason := rejectFlavor(snap, cq, rg.Flavors[i], ps, requests, excluded)
+			if reason == "" {
+				chosen = &rg.Flavors[i]
+				break
+			}
+			if pinnedFlavor != "" {
+				return nil, fmt.Sprintf("pinned flavor %s %s", pinnedFlavor, reason)
+			}
+		}
+		if chosen == nil {
+			if pinnedFlavor != "" {
+				return nil, fmt.Sprintf("pinned flavor %s is not defined in the ClusterQueue", pinnedFlavor)
+			}
+			return nil, explainResourceGroup(snap, cq, rg, ps, requests, excluded)
+		}
+		for rName := range rg.CoveredResources {
+			if _, requested := requests[rName]; requested {
+				flavors[rName] = chosen.Name
+			}
+		}
+	}
+	return flavors, ""
+}
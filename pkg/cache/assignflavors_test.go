@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestAssignFlavors(t *testing.T) {
+	driver := func() *utiltesting.PodSetWrapper {
+		return utiltesting.MakePodSet("driver", 1).
+			Request(corev1.ResourceCPU, "2").
+			Request(corev1.ResourceMemory, "2Gi")
+	}
+	workers := func(count int, gpus string) *utiltesting.PodSetWrapper {
+		return utiltesting.MakePodSet("workers", count).
+			Request(corev1.ResourceCPU, "2").
+			Request(resourceGPU, gpus)
+	}
+	cases := map[string]struct {
+		workload      *kueue.Workload
+		cqName        string
+		want          *kueue.Admission
+		wantErr       error
+		wantErrString string
+	}{
+		"multiple PodSets and resources": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				PodSets(*driver().Obj(), *workers(2, "1").Obj()).
+				Obj(),
+			cqName: "cq",
+			want: utiltesting.MakeAdmission("cq").PodSets(
+				kueue.PodSetAssignment{
+					Name: "driver",
+					Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{
+						corev1.ResourceCPU:    "on-demand",
+						corev1.ResourceMemory: "on-demand",
+					},
+					ResourceUsage: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("2"),
+						corev1.ResourceMemory: resource.MustParse("2Gi"),
+					},
+					Count: ptr.To[int32](1),
+				},
+				kueue.PodSetAssignment{
+					Name: "workers",
+					Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{
+						corev1.ResourceCPU: "spot",
+						resourceGPU:        "a100",
+					},
+					ResourceUsage: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("4"),
+						resourceGPU:        resource.MustParse("2"),
+					},
+					Count: ptr.To[int32](2),
+				},
+			).Obj(),
+		},
+		"pinned flavor": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				Annotations(map[string]string{constants.PinnedFlavorsAnnotation: string(resourceGPU) + "=t4"}).
+				PodSets(*workers(1, "1").Obj()).
+				Obj(),
+			cqName: "cq",
+			want: utiltesting.MakeAdmission("cq").PodSets(
+				kueue.PodSetAssignment{
+					Name: "workers",
+					Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{
+						corev1.ResourceCPU: "on-demand",
+						resourceGPU:        "t4",
+					},
+					ResourceUsage: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("2"),
+						resourceGPU:        resource.MustParse("1"),
+					},
+					Count: ptr.To[int32](1),
+				},
+			).Obj(),
+		},
+		"PodSets don't fit together": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				PodSets(
+					*workers(3, "1").NodeSelector(map[string]string{"gpu": "t4"}).Obj(),
+					*utiltesting.MakePodSet("more-workers", 2).
+						Request(resourceGPU, "1").
+						NodeSelector(map[string]string{"gpu": "t4"}).
+						Obj(),
+				).
+				Obj(),
+			cqName:  "cq",
+			wantErr: errNoFlavorFits,
+			wantErrString: "the workload doesn't fit in the flavors of the ClusterQueue: PodSet more-workers: " +
+				"0/2 flavors are available for nvidia.com/gpu: " +
+				"flavor a100 doesn't match the node selector, " +
+				"flavor t4 has insufficient quota for nvidia.com/gpu (requested 2, available 1)",
+		},
+		"resource not covered": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				PodSets(*driver().Request("example.com/fpga", "1").Obj()).
+				Obj(),
+			cqName:        "cq",
+			wantErr:       errNoFlavorFits,
+			wantErrString: "the workload doesn't fit in the flavors of the ClusterQueue: PodSet driver: resources example.com/fpga are not covered by the ClusterQueue",
+		},
+		"unknown ClusterQueue": {
+			workload: utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, "1").Obj(),
+			cqName:   "unknown",
+			wantErr:  errCqNotFound,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("tainted").
+				Taint(corev1.Taint{Key: "spot", Value: "true", Effect: corev1.TaintEffectNoSchedule}).
+				Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("a100").Label("gpu", "a100").Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("t4").Label("gpu", "t4").Obj())
+			cq := utiltesting.MakeClusterQueue("cq").
+				ResourceGroup(
+					*utiltesting.MakeFlavorQuotas("tainted").
+						Resource(corev1.ResourceCPU, "100").
+						Resource(corev1.ResourceMemory, "100Gi").
+						Obj(),
+					*utiltesting.MakeFlavorQuotas("on-demand").
+						Resource(corev1.ResourceCPU, "4").
+						Resource(corev1.ResourceMemory, "8Gi").
+						Obj(),
+					*utiltesting.MakeFlavorQuotas("spot").
+						Resource(corev1.ResourceCPU, "10").
+						Resource(corev1.ResourceMemory, "20Gi").
+						Obj(),
+				).
+				ResourceGroup(
+					*utiltesting.MakeFlavorQuotas("a100").Resource(resourceGPU, "4").Obj(),
+					*utiltesting.MakeFlavorQuotas("t4").Resource(resourceGPU, "4").Obj(),
+				).
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			got, err := cache.assignFlavors(tc.workload, tc.cqName)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Unexpected error: %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErrString != "" && (err == nil || err.Error() != tc.wantErrString) {
+				t.Errorf("Unexpected error message\ngot:  %v\nwant: %s", err, tc.wantErrString)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected admission (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
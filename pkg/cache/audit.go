@@ -0,0 +1,164 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"maps"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+const defaultAuditLogSize = 1000
+
+// AuditAction is a decision recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditActionAdmit AuditAction = "Admit"
+	AuditActionEvict AuditAction = "Evict"
+)
+
+// AuditEntry is an admission or eviction of a workload recorded in the audit
+// log of the cache.
+type AuditEntry struct {
+	Time         time.Time   `json:"time"`
+	Action       AuditAction `json:"action"`
+	Workload     string      `json:"workload"`
+	ClusterQueue string      `json:"clusterQueue,omitempty"`
+	// Flavors are the flavors assigned to each resource, keyed by PodSet name.
+	Flavors map[string]map[corev1.ResourceName]kueue.ResourceFlavorReference `json:"flavors,omitempty"`
+	// Borrowing is whether the ClusterQueue borrows from its cohort in any of
+	// the flavors assigned to the workload, with the usage of the workload.
+	Borrowing bool   `json:"borrowing,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// auditLog is a ring buffer holding the last entries of the audit log.
+type auditLog struct {
+	entries []AuditEntry
+	// start is the index of the oldest entry.
+	start int
+	count int
+}
+
+func (l *auditLog) add(e AuditEntry) {
+	if l.count < len(l.entries) {
+		l.entries[(l.start+l.count)%len(l.entries)] = e
+		l.count++
+		return
+	}
+	l.entries[l.start] = e
+	l.start = (l.start + 1) % len(l.entries)
+}
+
+// list returns up to the last limit entries, from the oldest to the newest. A
+// limit of 0 or less returns all the entries kept.
+// The entries are derived from the state transitions of the workloads, so the
+// audit log is empty if the workload history is disabled.
+func (l *auditLog) list(limit int) []AuditEntry {
+	n := l.count
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	result := make([]AuditEntry, n)
+	first := l.start + l.count - n
+	for i := range result {
+		entry := l.entries[(first+i)%len(l.entries)]
+		if entry.Flavors != nil {
+			flavors := make(map[string]map[corev1.ResourceName]kueue.ResourceFlavorReference, len(entry.Flavors))
+			for psName, psFlavors := range entry.Flavors {
+				flavors[psName] = maps.Clone(psFlavors)
+			}
+			entry.Flavors = flavors
+		}
+		result[i] = entry
+	}
+	return result
+}
+
+// recordAudit records the transition in the audit log if it's an admission
+// decision, that is, the scheduler assuming the workload or an admission
+// observed without it, or an eviction.
+func (c *Cache) recordAudit(w *kueue.Workload, t Transition) {
+	if len(c.auditLog.entries) == 0 {
+		return
+	}
+	var action AuditAction
+	switch {
+	case t.To == WorkloadStateAssumed, t.To == WorkloadStateAdmitted && t.From != WorkloadStateAssumed:
+		action = AuditActionAdmit
+	case t.To == WorkloadStateEvicted:
+		action = AuditActionEvict
+	default:
+		return
+	}
+	entry := AuditEntry{
+		Time:     t.Time,
+		Action:   action,
+		Workload: workload.Key(w),
+		Reason:   t.Reason,
+	}
+	if w.Status.Admission != nil {
+		entry.ClusterQueue = string(w.Status.Admission.ClusterQueue)
+		entry.Flavors = make(map[string]map[corev1.ResourceName]kueue.ResourceFlavorReference, len(w.Status.Admission.PodSetAssignments))
+		for _, psa := range w.Status.Admission.PodSetAssignments {
+			entry.Flavors[psa.Name] = maps.Clone(psa.Flavors)
+		}
+		if cq, ok := c.clusterQueues[entry.ClusterQueue]; ok {
+			entry.Borrowing = cq.borrowsWith(w)
+		}
+	}
+	c.auditLog.add(entry)
+}
+
+// borrowsWith returns whether the usage of the ClusterQueue, including the
+// workload if it's not accounted yet, exceeds the nominal quota in any of the
+// flavors assigned to the workload.
+func (c *ClusterQueue) borrowsWith(w *kueue.Workload) bool {
+	if c.Cohort == nil {
+		return false
+	}
+	_, accounted := c.Workloads[workload.Key(w)]
+	wlUsage := make(FlavorResourceQuantities)
+	for _, psr := range workload.NewInfo(w).TotalRequests {
+		for rName, fName := range psr.Flavors {
+			if wlUsage[fName] == nil {
+				wlUsage[fName] = make(map[corev1.ResourceName]int64)
+			}
+			var extra int64
+			if !accounted {
+				extra = psr.Requests[rName]
+			}
+			wlUsage[fName][rName] += extra
+		}
+	}
+	for _, rg := range c.ResourceGroups {
+		for _, flvQuotas := range rg.Flavors {
+			for rName, rQuota := range flvQuotas.Resources {
+				extra, assigned := wlUsage[flvQuotas.Name][rName]
+				if assigned && c.Usage[flvQuotas.Name][rName]+extra > rQuota.Nominal {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
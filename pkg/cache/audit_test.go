@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	admitted := func(name, cpu string) *kueue.Workload {
+		return utiltesting.MakeWorkload(name, "ns").
+			Request(corev1.ResourceCPU, cpu).
+			ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", cpu).Obj()).
+			Obj()
+	}
+	small := admitted("small", "1")
+	large := admitted("large", "2")
+	evicted := large.DeepCopy()
+	evicted.Status.Conditions = append(evicted.Status.Conditions, metav1.Condition{
+		Type:   kueue.WorkloadEvicted,
+		Status: metav1.ConditionTrue,
+		Reason: kueue.WorkloadEvictedByPreemption,
+	})
+	observed := admitted("observed", "1")
+	defaultFlavors := map[string]map[corev1.ResourceName]kueue.ResourceFlavorReference{
+		kueue.DefaultPodSetName: {corev1.ResourceCPU: "default"},
+	}
+	allEntries := []AuditEntry{
+		{
+			Time:         now,
+			Action:       AuditActionAdmit,
+			Workload:     "ns/small",
+			ClusterQueue: "cq",
+			Flavors:      defaultFlavors,
+			Reason:       "Assumed",
+		},
+		{
+			Time:         now.Add(time.Second),
+			Action:       AuditActionAdmit,
+			Workload:     "ns/large",
+			ClusterQueue: "cq",
+			Flavors:      defaultFlavors,
+			Borrowing:    true,
+			Reason:       "Assumed",
+		},
+		{
+			Time:         now.Add(3 * time.Second),
+			Action:       AuditActionEvict,
+			Workload:     "ns/large",
+			ClusterQueue: "cq",
+			Flavors:      defaultFlavors,
+			Borrowing:    true,
+			Reason:       kueue.WorkloadEvictedByPreemption,
+		},
+		{
+			Time:         now.Add(4 * time.Second),
+			Action:       AuditActionAdmit,
+			Workload:     "ns/observed",
+			ClusterQueue: "cq",
+			Flavors:      defaultFlavors,
+			Reason:       kueue.WorkloadQuotaReserved,
+		},
+	}
+
+	cases := map[string]struct {
+		size  int
+		limit int
+		want  []AuditEntry
+	}{
+		"all entries": {
+			size: 10,
+			want: allEntries,
+		},
+		"limited": {
+			size:  10,
+			limit: 2,
+			want:  allEntries[2:],
+		},
+		"bounded": {
+			size: 3,
+			want: allEntries[1:],
+		},
+		"disabled": {
+			want: []AuditEntry{},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeClock := testingclock.NewFakeClock(now)
+			cache := New(utiltesting.NewFakeClient(), WithClock(fakeClock), WithAuditLogSize(tc.size))
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			clusterQueues := []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("cq").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "2").Obj()).
+					Cohort("one").
+					Obj(),
+				utiltesting.MakeClusterQueue("lender").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+					Cohort("one").
+					Obj(),
+			}
+			for _, cq := range clusterQueues {
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+
+			if err := cache.AssumeWorkload(small); err != nil {
+				t.Fatalf("Failed assuming the workload: %v", err)
+			}
+			fakeClock.Step(time.Second)
+			// Borrows from the cohort, with the usage of the small workload.
+			if err := cache.AssumeWorkload(large); err != nil {
+				t.Fatalf("Failed assuming the workload: %v", err)
+			}
+			fakeClock.Step(time.Second)
+			// Observing the admission of an assumed workload isn't a decision.
+			if !cache.AddOrUpdateWorkload(large) {
+				t.Fatal("Failed adding the workload")
+			}
+			fakeClock.Step(time.Second)
+			if err := cache.UpdateWorkload(large, evicted); err != nil {
+				t.Fatalf("Failed evicting the workload: %v", err)
+			}
+			fakeClock.Step(time.Second)
+			if err := cache.DeleteWorkload(evicted); err != nil {
+				t.Fatalf("Failed deleting the workload: %v", err)
+			}
+			if !cache.AddOrUpdateWorkload(observed) {
+				t.Fatal("Failed adding the workload")
+			}
+
+			if diff := cmp.Diff(tc.want, cache.auditLog.list(tc.limit)); diff != "" {
+				t.Errorf("Unexpected audit log (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
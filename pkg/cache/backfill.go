@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// backfillCandidates returns the pending workloads, given in queue order, that
+// can run in the capacity of the ClusterQueue that is free for
+// availableWindow, for example until a large reserved workload starts.
+// A workload is a candidate if its estimated duration is known and doesn't
+// exceed the window, and if it fits in the available quota left by the
+// candidates before it.
+// The pending workloads are owned by the queue manager, so the caller passes
+// them in.
+func (c *Cache) backfillCandidates(cqName string, availableWindow time.Duration, pending []*workload.Info) []*workload.Info {
+	snap := c.Snapshot()
+	cq, ok := snap.ClusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	var candidates []*workload.Info
+	for _, wi := range pending {
+		if wi.EstimatedDuration <= 0 || wi.EstimatedDuration > availableWindow {
+			continue
+		}
+		assigned, fits := backfillAssignment(&snap, cq, wi)
+		if !fits {
+			continue
+		}
+		// Account for the candidate so that the following ones only get the
+		// quota it leaves.
+		snap.AddWorkload(assigned)
+		candidates = append(candidates, wi)
+	}
+	return candidates
+}
+
+// backfillAssignment returns a copy of the workload assigned to the first
+// flavor of each resource group where its PodSets fit, and whether it fits.
+func backfillAssignment(snap *Snapshot, cq *ClusterQueue, wi *workload.Info) (*workload.Info, bool) {
+	assigned := &workload.Info{
+		Obj:           wi.Obj,
+		ClusterQueue:  cq.Name,
+		TotalRequests: make([]workload.PodSetResources, len(wi.TotalRequests)),
+	}
+	excluded := workload.ExcludedFlavors(wi.Obj)
+	for i, psr := range wi.TotalRequests {
+		ps := podSetByName(wi.Obj, psr.Name)
+		if ps == nil {
+			return nil, false
+		}
+		flavors := make(map[corev1.ResourceName]kueue.ResourceFlavorReference, len(psr.Requests))
+		for rName, val := range psr.Requests {
+			if _, done := flavors[rName]; done {
+				continue
+			}
+			rg := cq.RGByResource[rName]
+			if rg == nil {
+				if val > 0 {
+					return nil, false
+				}
+				continue
+			}
+			var flvQuotas *FlavorQuotas
+			for j := range rg.Flavors {
+				if rejectFlavor(snap, cq, rg.Flavors[j], ps, psr.Requests, excluded) == "" {
+					flvQuotas = &rg.Flavors[j]
+					break
+				}
+			}
+			if flvQuotas == nil {
+				return nil, false
+			}
+			for covered := range rg.CoveredResources {
+				if _, requested := psr.Requests[covered]; requested {
+					flavors[covered] = flvQuotas.Name
+				}
+			}
+		}
+		assigned.TotalRequests[i] = workload.PodSetResources{
+			Name:     psr.Name,
+			Requests: psr.Requests,
+			Count:    psr.Count,
+			Flavors:  flavors,
+		}
+	}
+	return assigned, true
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+func TestBackfillCandidates(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	running := utiltesting.MakeWorkload("running", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "6").Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(running) {
+		t.Fatalf("Failed adding workload %q", running.Name)
+	}
+
+	makeInfo := func(name, cpu, duration string) *workload.Info {
+		wl := utiltesting.MakeWorkload(name, "ns").Request(corev1.ResourceCPU, cpu)
+		if duration != "" {
+			wl.Annotations(map[string]string{constants.EstimatedDurationAnnotation: duration})
+		}
+		return workload.NewInfo(wl.Obj())
+	}
+	pending := []*workload.Info{
+		makeInfo("long", "1", "2h"),
+		makeInfo("unknown-duration", "1", ""),
+		makeInfo("short", "2", "10m"),
+		makeInfo("short-too-big", "3", "10m"),
+		makeInfo("shorter", "2", "5m"),
+		makeInfo("no-room-left", "1", "1m"),
+	}
+
+	cases := map[string]struct {
+		cqName string
+		window time.Duration
+		want   []string
+	}{
+		"short workloads that fit the window": {
+			cqName: "cq",
+			window: time.Hour,
+			want:   []string{"short", "shorter"},
+		},
+		"window shorter than some workloads": {
+			cqName: "cq",
+			window: 7 * time.Minute,
+			want:   []string{"shorter", "no-room-left"},
+		},
+		"no workload fits the window": {
+			cqName: "cq",
+			window: 30 * time.Second,
+		},
+		"unknown ClusterQueue": {
+			cqName: "unknown",
+			window: time.Hour,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			for _, wi := range cache.backfillCandidates(tc.cqName, tc.window, pending) {
+				got = append(got, wi.Obj.Name)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected candidates (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
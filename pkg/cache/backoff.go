@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+const (
+	admissionBackoffBaseDelay = time.Second
+	admissionBackoffMaxDelay  = 10 * time.Minute
+)
+
+// admissionBackoff tracks the failed admission attempts of a workload.
+type admissionBackoff struct {
+	attempts     int32
+	nextEligible time.Time
+}
+
+// RecordAdmissionFailure records a failed admission attempt for the workload
+// and pushes back the time at which the workload is eligible for admission
+// again. The delay doubles with every consecutive failure, starting at
+// admissionBackoffBaseDelay and capped at admissionBackoffMaxDelay.
+func (c *Cache) RecordAdmissionFailure(w *kueue.Workload) {
+	c.Lock()
+	defer c.Unlock()
+	k := workload.Key(w)
+	b, found := c.admissionBackoffs[k]
+	if !found {
+		b = &admissionBackoff{}
+		c.admissionBackoffs[k] = b
+	}
+	b.attempts++
+	delay := admissionBackoffMaxDelay
+	if b.attempts <= 20 {
+		delay = min(admissionBackoffBaseDelay<<(b.attempts-1), admissionBackoffMaxDelay)
+	}
+	b.nextEligible = c.clock.Now().Add(delay)
+}
+
+// RequeueAfter returns how long the workload has to wait before it's
+// eligible for admission again. Returns zero if the workload never failed
+// admission or the backoff already elapsed.
+func (c *Cache) RequeueAfter(w *kueue.Workload) time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	b, found := c.admissionBackoffs[workload.Key(w)]
+	if !found {
+		return 0
+	}
+	return max(b.nextEligible.Sub(c.clock.Now()), 0)
+}
+
+func (c *Cache) resetAdmissionBackoff(w *kueue.Workload) {
+	delete(c.admissionBackoffs, workload.Key(w))
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestAdmissionBackoff(t *testing.T) {
+	now := time.Now()
+	fakeClock := testingclock.NewFakeClock(now)
+	cache := New(utiltesting.NewFakeClient(), WithClock(fakeClock))
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	wl := utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, "1").Obj()
+
+	if got := cache.RequeueAfter(wl); got != 0 {
+		t.Errorf("Unexpected backoff before any failure: %v", got)
+	}
+
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		cache.RecordAdmissionFailure(wl)
+		if got := cache.RequeueAfter(wl); got != want {
+			t.Errorf("Unexpected backoff after %d failures, got %v, want %v", i+1, got, want)
+		}
+	}
+
+	fakeClock.Step(3 * time.Second)
+	if got := cache.RequeueAfter(wl); got != time.Second {
+		t.Errorf("Unexpected backoff after the clock advanced, got %v, want %v", got, time.Second)
+	}
+	fakeClock.Step(time.Second)
+	if got := cache.RequeueAfter(wl); got != 0 {
+		t.Errorf("Unexpected backoff after it elapsed: %v", got)
+	}
+
+	for i := 0; i < 30; i++ {
+		cache.RecordAdmissionFailure(wl)
+	}
+	if got := cache.RequeueAfter(wl); got != admissionBackoffMaxDelay {
+		t.Errorf("Unexpected backoff after many failures, got %v, want %v", got, admissionBackoffMaxDelay)
+	}
+
+	admitted := utiltesting.MakeWorkload("wl", "ns").
+		Request(corev1.ResourceCPU, "1").
+		ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(admitted) {
+		t.Fatal("Failed adding the admitted workload")
+	}
+	if got := cache.RequeueAfter(wl); got != 0 {
+		t.Errorf("Unexpected backoff after a successful admission: %v", got)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+)
+
+var errInvalidBorrowBoost = errors.New("invalid borrow boost")
+
+// parseBorrowBoost returns the amount, per resource, that the ClusterQueue
+// can borrow beyond the borrowing limit of its flavors and its deadline, as
+// declared in the BorrowBoostAnnotation. Flavors without a borrowing limit
+// are not affected.
+func parseBorrowBoost(cq *kueue.ClusterQueue) (Resources, time.Time, error) {
+	value, found := cq.Annotations[constants.BorrowBoostAnnotation]
+	if !found {
+		return nil, time.Time{}, nil
+	}
+	until, resources, found := strings.Cut(value, ";")
+	if !found {
+		return nil, time.Time{}, fmt.Errorf("%w: %q in annotation %s, expected <until>;<resources>", errInvalidBorrowBoost, value, constants.BorrowBoostAnnotation)
+	}
+	deadline, err := time.Parse(time.RFC3339, strings.TrimSpace(until))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: %v", errInvalidBorrowBoost, err)
+	}
+	extra, err := parseResourceQuantities(resources)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: %v", errInvalidBorrowBoost, err)
+	}
+	return extra, deadline, nil
+}
+
+// SweepBorrowBoosts clears the borrow boosts whose deadline passed, according
+// to the clock of the cache. The snapshots already ignore them.
+func (c *Cache) SweepBorrowBoosts() {
+	c.Lock()
+	defer c.Unlock()
+	now := c.clock.Now()
+	for _, cq := range c.clusterQueues {
+		if cq.BorrowBoost != nil && !now.Before(cq.borrowBoostUntil) {
+			cq.BorrowBoost = nil
+			cq.borrowBoostUntil = time.Time{}
+			cq.AllocatableResourceGeneration++
+		}
+	}
+}
+
+// RunBorrowBoostsSweeper calls SweepBorrowBoosts every period until the
+// context is done.
+func (c *Cache) RunBorrowBoostsSweeper(ctx context.Context, period time.Duration) {
+	wait.UntilWithContext(ctx, func(context.Context) {
+		c.SweepBorrowBoosts()
+	}, period)
+}
+
+// BoostedBorrowingLimit returns the borrowing limit of a flavor for the
+// resource plus the borrow boost of the ClusterQueue, if any. A nil limit,
+// meaning that borrowing is not limited, is returned as is. The limit is 0
+// while borrowing is paused in all the ClusterQueues, and for the resources
+// that the ClusterQueue is forbidden to borrow.
+func (c *ClusterQueue) BoostedBorrowingLimit(rName corev1.ResourceName, limit *int64) *int64 {
+	if c.BorrowingDisabled || c.BorrowingForbidden.Has(rName) {
+		return ptr.To[int64](0)
+	}
+	extra, found := c.BorrowBoost[rName]
+	if limit == nil || !found {
+		return limit
+	}
+	return ptr.To(*limit + extra)
+}
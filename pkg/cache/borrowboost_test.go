@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestTemporaryBorrowBoost(t *testing.T) {
+	now := time.Date(2024, time.May, 1, 22, 0, 0, 0, time.UTC)
+	fakeClock := testingclock.NewFakeClock(now)
+	cache := New(utiltesting.NewFakeClient(), WithClock(fakeClock))
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+			Resource(corev1.ResourceCPU, "10", "2").
+			Resource(corev1.ResourceMemory, "10").
+			Obj()).
+		Cohort("one").
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	cpuLimit := func() *int64 {
+		t.Helper()
+		snapCQ := cache.Snapshot().ClusterQueues["cq"]
+		rQuota := snapCQ.ResourceGroups[0].Flavors[0].Resources[corev1.ResourceCPU]
+		return snapCQ.BoostedBorrowingLimit(corev1.ResourceCPU, rQuota.BorrowingLimit)
+	}
+
+	if got := cpuLimit(); ptr.Deref(got, 0) != 2_000 {
+		t.Errorf("Unexpected borrowing limit without boost: %v", ptr.Deref(got, 0))
+	}
+
+	invalid := cq.DeepCopy()
+	invalid.Annotations = map[string]string{constants.BorrowBoostAnnotation: "cpu=3"}
+	if err := cache.UpdateClusterQueue(invalid); !errors.Is(err, errInvalidBorrowBoost) {
+		t.Errorf("Unexpected error for an invalid boost, got %v, want %v", err, errInvalidBorrowBoost)
+	}
+
+	cq = cq.DeepCopy()
+	cq.Annotations = map[string]string{constants.BorrowBoostAnnotation: "2024-05-01T23:00:00Z;cpu=3,memory=1"}
+	if err := cache.UpdateClusterQueue(cq); err != nil {
+		t.Fatalf("Failed boosting the ClusterQueue: %v", err)
+	}
+	if got := cpuLimit(); ptr.Deref(got, 0) != 5_000 {
+		t.Errorf("Unexpected borrowing limit before the deadline: %v", ptr.Deref(got, 0))
+	}
+	snapCQ := cache.Snapshot().ClusterQueues["cq"]
+	if got := snapCQ.BoostedBorrowingLimit(corev1.ResourceMemory, nil); got != nil {
+		t.Errorf("Unexpected borrowing limit for a resource without limit: %v", *got)
+	}
+
+	fakeClock.Step(time.Hour)
+	if got := cpuLimit(); ptr.Deref(got, 0) != 2_000 {
+		t.Errorf("Unexpected borrowing limit after the deadline: %v", ptr.Deref(got, 0))
+	}
+	cache.SweepBorrowBoosts()
+	cache.RLock()
+	boost := cache.clusterQueues["cq"].BorrowBoost
+	cache.RUnlock()
+	if boost != nil {
+		t.Errorf("The boost wasn't cleared by the sweeper: %v", boost)
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+)
+
+// borrowingForbiddenResources returns the resources that the ClusterQueue
+// can't borrow, declared in the BorrowingForbiddenResourcesAnnotation.
+func borrowingForbiddenResources(cq *kueue.ClusterQueue) sets.Set[corev1.ResourceName] {
+	value, found := cq.Annotations[constants.BorrowingForbiddenResourcesAnnotation]
+	if !found {
+		return nil
+	}
+	var forbidden sets.Set[corev1.ResourceName]
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			if forbidden == nil {
+				forbidden = sets.New[corev1.ResourceName]()
+			}
+			forbidden.Insert(corev1.ResourceName(name))
+		}
+	}
+	return forbidden
+}
+
+// SetBorrowingPaused pauses or resumes borrowing in all the ClusterQueues,
+// overriding WithBorrowingPaused. The next snapshots take the change into
+// account. Returns whether borrowing was paused before.
+func (c *Cache) SetBorrowingPaused(paused bool) bool {
+	c.Lock()
+	defer c.Unlock()
+	wasPaused := c.borrowingDisabled
+	if paused == wasPaused {
+		return wasPaused
+	}
+	c.borrowingDisabled = paused
+	for _, cq := range c.clusterQueues {
+		cq.BorrowingDisabled = paused
+		cq.AllocatableResourceGeneration++
+	}
+	return wasPaused
+}
+
+// canBorrowAll returns whether the ClusterQueue can use the additional
+// quantities of all the resources at the same time, borrowing from its cohort
+// if needed. The resources covered by the same resource group must fit
+// together in one of its flavors, as a workload gets a single flavor per
+// resource group; a flavor with room for some of them but not for the others
+// doesn't count. Flavors that don't exist or are disabled are skipped.
+// Returns false if the ClusterQueue doesn't exist, it's inactive or it
+// doesn't cover some of the resources.
+func (c *Cache) canBorrowAll(cqName string, needs Resources) bool {
+	snap := c.Snapshot()
+	cq, ok := snap.ClusterQueues[cqName]
+	if !ok {
+		return false
+	}
+	rgNeeds := make(map[*ResourceGroup]Resources)
+	for rName, val := range needs {
+		if val <= 0 {
+			continue
+		}
+		rg := cq.RGByResource[rName]
+		if rg == nil {
+			return false
+		}
+		if rgNeeds[rg] == nil {
+			rgNeeds[rg] = make(Resources)
+		}
+		rgNeeds[rg][rName] = val
+	}
+	for rg, needs := range rgNeeds {
+		if !slices.ContainsFunc(rg.Flavors, func(flvQuotas FlavorQuotas) bool {
+			return fitsInFlavor(&snap, cq, flvQuotas, needs)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// fitsInFlavor returns whether the ClusterQueue of the snapshot can use the
+// additional quantities of all the resources in the flavor.
+func fitsInFlavor(snap *Snapshot, cq *ClusterQueue, flvQuotas FlavorQuotas, needs Resources) bool {
+	if _, found := snap.ResourceFlavors[flvQuotas.Name]; !found || snap.DisabledFlavors.Has(flvQuotas.Name) {
+		return false
+	}
+	for rName, val := range needs {
+		rQuota := flvQuotas.Resources[rName]
+		if rQuota == nil || availableQuota(cq, flvQuotas.Name, rName, rQuota) < val {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestBorrowingPaused(t *testing.T) {
+	cases := map[string]struct {
+		paused           bool
+		wantNeedsBorrow  error
+		wantNewCQ        error
+		wantNominalLimit *int64
+	}{
+		"borrowing allowed": {},
+		"borrowing paused": {
+			paused:           true,
+			wantNeedsBorrow:  errNoFlavorFits,
+			wantNewCQ:        errNoFlavorFits,
+			wantNominalLimit: ptr.To[int64](0),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient(), WithBorrowingPaused(tc.paused))
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			for _, name := range []string{"a", "b"} {
+				cq := utiltesting.MakeClusterQueue(name).
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+					Cohort("one").
+					Obj()
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			// Workloads already borrowing are kept.
+			borrowing := utiltesting.MakeWorkload("borrowing", "ns").
+				ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "5").Obj()).
+				Obj()
+			if !cache.AddOrUpdateWorkload(borrowing) {
+				t.Fatal("Failed adding the borrowing workload")
+			}
+			if usage := cache.clusterQueues["a"].Usage["default"][corev1.ResourceCPU]; usage != 5_000 {
+				t.Errorf("Unexpected usage of the borrowing workload %d", usage)
+			}
+			needsBorrowing := utiltesting.MakeWorkload("needs-borrowing", "ns").Request(corev1.ResourceCPU, "2").Obj()
+			withinNominal := utiltesting.MakeWorkload("within-nominal", "ns").Request(corev1.ResourceCPU, "2").Obj()
+			if _, err := cache.assignFlavors(needsBorrowing, "a"); !errors.Is(err, tc.wantNeedsBorrow) {
+				t.Errorf("Unexpected error assigning flavors to a workload that needs borrowing: %v, want %v", err, tc.wantNeedsBorrow)
+			}
+			if _, err := cache.assignFlavors(withinNominal, "b"); err != nil {
+				t.Errorf("Unexpected error assigning flavors to a workload within the nominal quota: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantNominalLimit, cache.Snapshot().ClusterQueues["b"].BoostedBorrowingLimit(corev1.ResourceCPU, nil)); diff != "" {
+				t.Errorf("Unexpected borrowing limit in the snapshot (-want,+got):\n%s", diff)
+			}
+
+			// ClusterQueues added later are affected too.
+			cq := utiltesting.MakeClusterQueue("c").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "1").Obj()).
+				Cohort("one").
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			if _, err := cache.assignFlavors(withinNominal, "c"); !errors.Is(err, tc.wantNewCQ) {
+				t.Errorf("Unexpected error assigning flavors in a new ClusterQueue: %v, want %v", err, tc.wantNewCQ)
+			}
+		})
+	}
+}
+
+func TestSetBorrowingPaused(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	for _, name := range []string{"a", "b"} {
+		cq := utiltesting.MakeClusterQueue(name).
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+			Cohort("one").
+			Obj()
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	generation := cache.clusterQueues["a"].AllocatableResourceGeneration
+	needsBorrowing := utiltesting.MakeWorkload("needs-borrowing", "ns").Request(corev1.ResourceCPU, "6").Obj()
+
+	steps := []struct {
+		paused         bool
+		wantWasPaused  bool
+		wantErr        error
+		wantGeneration int64
+	}{
+		{paused: true, wantErr: errNoFlavorFits, wantGeneration: generation + 1},
+		{paused: true, wantWasPaused: true, wantErr: errNoFlavorFits, wantGeneration: generation + 1},
+		{paused: false, wantWasPaused: true, wantGeneration: generation + 2},
+	}
+	for i, step := range steps {
+		if got := cache.SetBorrowingPaused(step.paused); got != step.wantWasPaused {
+			t.Errorf("Step %d: unexpected previous state %t, want %t", i, got, step.wantWasPaused)
+		}
+		if _, err := cache.assignFlavors(needsBorrowing, "a"); !errors.Is(err, step.wantErr) {
+			t.Errorf("Step %d: unexpected error assigning flavors: %v, want %v", i, err, step.wantErr)
+		}
+		if got := cache.Snapshot().ClusterQueues["a"].AllocatableResourceGeneration; got != step.wantGeneration {
+			t.Errorf("Step %d: unexpected allocatable resource generation %d, want %d", i, got, step.wantGeneration)
+		}
+	}
+}
+
+func TestCanBorrowAll(t *testing.T) {
+	cases := map[string]struct {
+		clusterQueues []*kueue.ClusterQueue
+		needs         Resources
+		want          bool
+	}{
+		"cpu and gpu can borrow": {
+			clusterQueues: []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("a").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Resource(resourceGPU, "2").Obj()).
+					Cohort("one").
+					Obj(),
+				utiltesting.MakeClusterQueue("b").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Resource(resourceGPU, "2").Obj()).
+					Cohort("one").
+					Obj(),
+			},
+			needs: Resources{corev1.ResourceCPU: 6_000, resourceGPU: 3},
+			want:  true,
+		},
+		"cpu can borrow but gpu can't": {
+			clusterQueues: []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("a").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Resource(resourceGPU, "2").Obj()).
+					Cohort("one").
+					Obj(),
+				utiltesting.MakeClusterQueue("b").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Resource(resourceGPU, "0").Obj()).
+					Cohort("one").
+					Obj(),
+			},
+			needs: Resources{corev1.ResourceCPU: 6_000, resourceGPU: 3},
+		},
+		"cpu alone can borrow": {
+			clusterQueues: []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("a").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Resource(resourceGPU, "2").Obj()).
+					Cohort("one").
+					Obj(),
+				utiltesting.MakeClusterQueue("b").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Resource(resourceGPU, "0").Obj()).
+					Cohort("one").
+					Obj(),
+			},
+			needs: Resources{corev1.ResourceCPU: 6_000},
+			want:  true,
+		},
+		"cpu and gpu fit in different flavors of the same resource group": {
+			clusterQueues: []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("a").
+					ResourceGroup(
+						*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "8").Resource(resourceGPU, "0").Obj(),
+						*utiltesting.MakeFlavorQuotas("other").Resource(corev1.ResourceCPU, "0").Resource(resourceGPU, "4").Obj(),
+					).
+					Obj(),
+			},
+			needs: Resources{corev1.ResourceCPU: 6_000, resourceGPU: 3},
+		},
+		"gpu can't borrow in its own resource group": {
+			clusterQueues: []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("a").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("other").Resource(resourceGPU, "2").Obj()).
+					Cohort("one").
+					Obj(),
+				utiltesting.MakeClusterQueue("b").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("other").Resource(resourceGPU, "0").Obj()).
+					Cohort("one").
+					Obj(),
+			},
+			needs: Resources{corev1.ResourceCPU: 6_000, resourceGPU: 3},
+		},
+		"resource not covered": {
+			clusterQueues: []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("a").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+					Obj(),
+			},
+			needs: Resources{corev1.ResourceCPU: 1_000, resourceGPU: 1},
+		},
+		"missing ClusterQueue": {
+			needs: Resources{corev1.ResourceCPU: 1_000},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("other").Obj())
+			for _, cq := range tc.clusterQueues {
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			if got := cache.canBorrowAll("a", tc.needs); got != tc.want {
+				t.Errorf("Unexpected result %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBorrowingForbiddenResources(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	clusterQueue := func(name string, annotations map[string]string) *kueue.ClusterQueue {
+		cq := utiltesting.MakeClusterQueue(name).
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Resource(resourceGPU, "2").Obj()).
+			Cohort("one").
+			Obj()
+		cq.Annotations = annotations
+		return cq
+	}
+	forbidGPU := map[string]string{constants.BorrowingForbiddenResourcesAnnotation: " nvidia.com/gpu,"}
+	if err := cache.AddClusterQueue(context.Background(), clusterQueue("a", forbidGPU)); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	if err := cache.AddClusterQueue(context.Background(), clusterQueue("b", nil)); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	borrowsCPU := utiltesting.MakeWorkload("borrows-cpu", "ns").Request(corev1.ResourceCPU, "6").Obj()
+	borrowsGPU := utiltesting.MakeWorkload("borrows-gpu", "ns").Request(resourceGPU, "3").Obj()
+
+	check := func(desc string, wl *kueue.Workload, cqName string, wantErr error) {
+		t.Helper()
+		if _, err := cache.assignFlavors(wl, cqName); !errors.Is(err, wantErr) {
+			t.Errorf("%s: unexpected error assigning flavors to %s in %s: %v, want %v", desc, wl.Name, cqName, err, wantErr)
+		}
+	}
+	check("gpu forbidden", borrowsCPU, "a", nil)
+	check("gpu forbidden", borrowsGPU, "a", errNoFlavorFits)
+	check("gpu forbidden", borrowsGPU, "b", nil)
+	if !cache.canBorrowAll("a", Resources{corev1.ResourceCPU: 6_000}) {
+		t.Error("ClusterQueue a can't borrow cpu")
+	}
+	if cache.canBorrowAll("a", Resources{corev1.ResourceCPU: 6_000, resourceGPU: 3}) {
+		t.Error("ClusterQueue a can borrow gpu")
+	}
+
+	if err := cache.UpdateClusterQueue(clusterQueue("a", nil)); err != nil {
+		t.Fatalf("Failed updating ClusterQueue: %v", err)
+	}
+	check("gpu allowed", borrowsGPU, "a", nil)
+}
@@ -20,28 +20,45 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
 	utilindexer "sigs.k8s.io/kueue/pkg/controller/core/indexer"
 	"sigs.k8s.io/kueue/pkg/metrics"
+	"sigs.k8s.io/kueue/pkg/util/limitrange"
+	utilmaps "sigs.k8s.io/kueue/pkg/util/maps"
+	"sigs.k8s.io/kueue/pkg/util/priority"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
 
 var (
-	errCqNotFound          = errors.New("cluster queue not found")
-	errQNotFound           = errors.New("queue not found")
-	errWorkloadNotAdmitted = errors.New("workload not admitted by a ClusterQueue")
+	errCqNotFound            = errors.New("cluster queue not found")
+	errQNotFound             = errors.New("queue not found")
+	errWorkloadNotAdmitted   = errors.New("workload not admitted by a ClusterQueue")
+	errCohortNotFound        = errors.New("cohort not found")
+	errFlavorNotFound        = errors.New("flavor not found")
+	errInvalidPriorityOffset = errors.New("invalid priority offset")
 )
 
 const (
@@ -51,7 +68,14 @@ const (
 )
 
 type options struct {
-	podsReadyTracking bool
+	podsReadyTracking   bool
+	clock               clock.Clock
+	resourceSubstitutes map[corev1.ResourceName][]corev1.ResourceName
+	workloadHistorySize int
+	auditLogSize        int
+	metricsRecorder     MetricsRecorder
+	maxInflightAssumed  int
+	borrowingPaused     bool
 }
 
 // Option configures the reconciler.
@@ -66,7 +90,68 @@ func WithPodsReadyTracking(f bool) Option {
 	}
 }
 
-var defaultOptions = options{}
+// WithClock sets the clock used by the cache for time based bookkeeping.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithResourceSubstitutions sets, per resource, the resources whose quota can
+// be consumed, in order, when the quota of the resource is exhausted.
+func WithResourceSubstitutions(substitutes map[corev1.ResourceName][]corev1.ResourceName) Option {
+	return func(o *options) {
+		o.resourceSubstitutes = substitutes
+	}
+}
+
+// WithWorkloadHistorySize sets the number of state transitions kept for each
+// workload. A size of 0 disables the history.
+func WithWorkloadHistorySize(n int) Option {
+	return func(o *options) {
+		o.workloadHistorySize = n
+	}
+}
+
+// WithAuditLogSize sets the number of admissions and evictions kept in the
+// audit log. A size of 0 disables the audit log.
+func WithAuditLogSize(n int) Option {
+	return func(o *options) {
+		o.auditLogSize = n
+	}
+}
+
+// WithMetricsRecorder sets the recorder of the metrics of the cache, instead
+// of the Prometheus collectors of the metrics package.
+func WithMetricsRecorder(r MetricsRecorder) Option {
+	return func(o *options) {
+		o.metricsRecorder = r
+	}
+}
+
+// WithMaxInflightAssumedWorkloads sets the maximum number of workloads that
+// can be assumed in each ClusterQueue before their admission is committed. A
+// maximum of 0 doesn't limit them.
+func WithMaxInflightAssumedWorkloads(n int) Option {
+	return func(o *options) {
+		o.maxInflightAssumed = n
+	}
+}
+
+// WithBorrowingPaused prevents all the ClusterQueues from borrowing from
+// their cohorts, as if all their borrowing limits were 0.
+func WithBorrowingPaused(f bool) Option {
+	return func(o *options) {
+		o.borrowingPaused = f
+	}
+}
+
+var defaultOptions = options{
+	clock:               clock.RealClock{},
+	workloadHistorySize: defaultWorkloadHistorySize,
+	auditLogSize:        defaultAuditLogSize,
+	metricsRecorder:     prometheusRecorder{},
+}
 
 // Cache keeps track of the Workloads that got admitted through ClusterQueues.
 type Cache struct {
@@ -80,6 +165,61 @@ type Cache struct {
 	resourceFlavors   map[kueue.ResourceFlavorReference]*kueue.ResourceFlavor
 	podsReadyTracking bool
 	admissionChecks   map[string]AdmissionCheck
+	clock             clock.Clock
+	metrics           MetricsRecorder
+	admissionBackoffs map[string]*admissionBackoff
+	// maxInflightAssumed is the maximum number of workloads assumed and not
+	// committed yet in each ClusterQueue, or 0 if there is no maximum.
+	maxInflightAssumed int
+	// flavorCapacity is the physical capacity observed for each
+	// ResourceFlavor, as reported by SetFlavorObservedCapacity.
+	flavorCapacity map[kueue.ResourceFlavorReference]Resources
+	// flavorTopologyKeys is the node label key that identifies the topology
+	// domains, such as zones, of each ResourceFlavor that set one.
+	flavorTopologyKeys map[kueue.ResourceFlavorReference]string
+	// flavorTopologyDomains is the number of topology domains observed for
+	// each ResourceFlavor, as reported by SetFlavorTopologyDomains.
+	flavorTopologyDomains map[kueue.ResourceFlavorReference]int
+	// flavorShareWeights is the weight of the usage of each ResourceFlavor
+	// that set one in the fair sharing computations.
+	flavorShareWeights map[kueue.ResourceFlavorReference]float64
+	// disabledFlavors holds the ResourceFlavors that can't be assigned to new
+	// workloads.
+	disabledFlavors sets.Set[kueue.ResourceFlavorReference]
+	// provisioningClasses is the provisioning class of each
+	// ResourceFlavor whose capacity is provisioned on demand.
+	provisioningClasses map[kueue.ResourceFlavorReference]string
+	resourceSubstitutes map[corev1.ResourceName][]corev1.ResourceName
+	// queuedAt holds the time at which the workloads pending admission were
+	// queued, keyed by workload key.
+	queuedAt map[string]time.Time
+	// pendingDemand holds the total requests of the workloads pending
+	// admission in each ClusterQueue, as reported by SetPendingDemand.
+	pendingDemand map[string]Resources
+	// workloadHistories holds the last state transitions of each workload,
+	// keyed by workload key.
+	workloadHistories   map[string]*workloadHistory
+	workloadHistorySize int
+	// auditLog holds the last admissions and evictions.
+	auditLog auditLog
+	// scheduledHolds holds the reservations declared in the
+	// ScheduledReservationAnnotation of the ClusterQueues, keyed by holder.
+	scheduledHolds map[string]*scheduledHold
+	// borrowingDisabled is whether borrowing is paused with
+	// WithBorrowingPaused or SetBorrowingPaused.
+	borrowingDisabled bool
+	// admissionTimes holds the times of the last admissions of each
+	// ClusterQueue, from which estimatedWaitTime computes its admission rate.
+	admissionTimes map[string][]time.Time
+	// cohortUsageHistories holds the last usage samples of each cohort, taken
+	// with SampleCohortUsage, keyed by cohort name.
+	cohortUsageHistories map[string]*usageHistory
+
+	// OnClusterQueueUsageChanged, if set, is called with the name of a
+	// ClusterQueue after its usage changes. It's called without holding the
+	// lock of the cache, so it can read the cache. It must be set before the
+	// cache is used.
+	OnClusterQueueUsageChanged func(cqName string)
 }
 
 func New(client client.Client, opts ...Option) *Cache {
@@ -88,25 +228,74 @@ func New(client client.Client, opts ...Option) *Cache {
 		opt(&options)
 	}
 	c := &Cache{
-		client:            client,
-		clusterQueues:     make(map[string]*ClusterQueue),
-		cohorts:           make(map[string]*Cohort),
-		assumedWorkloads:  make(map[string]string),
-		resourceFlavors:   make(map[kueue.ResourceFlavorReference]*kueue.ResourceFlavor),
-		admissionChecks:   make(map[string]AdmissionCheck),
-		podsReadyTracking: options.podsReadyTracking,
-	}
+		client:              client,
+		podsReadyTracking:   options.podsReadyTracking,
+		clock:               options.clock,
+		resourceSubstitutes: options.resourceSubstitutes,
+		workloadHistorySize: options.workloadHistorySize,
+		auditLog:            auditLog{entries: make([]AuditEntry, max(0, options.auditLogSize))},
+		metrics:             options.metricsRecorder,
+		maxInflightAssumed:  options.maxInflightAssumed,
+		borrowingDisabled:   options.borrowingPaused,
+	}
+	c.reset()
 	c.podsReadyCond.L = &c.RWMutex
 	return c
 }
 
+// reset drops the state of the cache, keeping its configuration.
+func (c *Cache) reset() {
+	c.clusterQueues = make(map[string]*ClusterQueue)
+	c.cohorts = make(map[string]*Cohort)
+	c.assumedWorkloads = make(map[string]string)
+	c.resourceFlavors = make(map[kueue.ResourceFlavorReference]*kueue.ResourceFlavor)
+	c.admissionChecks = make(map[string]AdmissionCheck)
+	c.admissionBackoffs = make(map[string]*admissionBackoff)
+	c.flavorCapacity = make(map[kueue.ResourceFlavorReference]Resources)
+	c.flavorTopologyKeys = make(map[kueue.ResourceFlavorReference]string)
+	c.flavorTopologyDomains = make(map[kueue.ResourceFlavorReference]int)
+	c.flavorShareWeights = make(map[kueue.ResourceFlavorReference]float64)
+	c.disabledFlavors = sets.New[kueue.ResourceFlavorReference]()
+	c.provisioningClasses = make(map[kueue.ResourceFlavorReference]string)
+	c.queuedAt = make(map[string]time.Time)
+	c.pendingDemand = make(map[string]Resources)
+	c.workloadHistories = make(map[string]*workloadHistory)
+	c.auditLog = auditLog{entries: make([]AuditEntry, len(c.auditLog.entries))}
+	c.scheduledHolds = make(map[string]*scheduledHold)
+	c.admissionTimes = make(map[string][]time.Time)
+	c.cohortUsageHistories = make(map[string]*usageHistory)
+}
+
+// clearAll drops all the ClusterQueues, cohorts, ResourceFlavors,
+// AdmissionChecks and workloads of the cache, along with the state recorded
+// for them, such as the workload histories and the audit log, for a full
+// resync. Afterwards, the cache behaves as if it was freshly constructed with
+// the same options and OnClusterQueueUsageChanged.
+func (c *Cache) clearAll() {
+	c.Lock()
+	defer c.Unlock()
+	for name := range c.clusterQueues {
+		c.metrics.ClearClusterQueue(name)
+	}
+	for name := range c.cohorts {
+		c.metrics.ClearCohortBorrowedResources(name)
+	}
+	c.reset()
+	// Wake up the routines waiting for the admitted workloads to be ready, as
+	// there are none left.
+	c.podsReadyCond.Broadcast()
+}
+
 func (c *Cache) newClusterQueue(cq *kueue.ClusterQueue) (*ClusterQueue, error) {
 	cqImpl := &ClusterQueue{
-		Name:              cq.Name,
-		Workloads:         make(map[string]*workload.Info),
-		WorkloadsNotReady: sets.New[string](),
-		localQueues:       make(map[string]*queue),
-		podsReadyTracking: c.podsReadyTracking,
+		Name:                cq.Name,
+		Workloads:           make(map[string]*workload.Info),
+		WorkloadsNotReady:   sets.New[string](),
+		localQueues:         make(map[string]*queue),
+		podsReadyTracking:   c.podsReadyTracking,
+		ResourceSubstitutes: c.resourceSubstitutes,
+		BorrowingDisabled:   c.borrowingDisabled,
+		metrics:             c.metrics,
 	}
 	if err := cqImpl.update(cq, c.resourceFlavors, c.admissionChecks); err != nil {
 		return nil, err
@@ -193,7 +382,35 @@ func (c *Cache) updateClusterQueues() sets.Set[string] {
 func (c *Cache) AddOrUpdateResourceFlavor(rf *kueue.ResourceFlavor) sets.Set[string] {
 	c.Lock()
 	defer c.Unlock()
-	c.resourceFlavors[kueue.ResourceFlavorReference(rf.Name)] = rf
+	fName := kueue.ResourceFlavorReference(rf.Name)
+	c.resourceFlavors[fName] = rf
+	if class := rf.Annotations[constants.ResourceFlavorProvisioningClassAnnotation]; class != "" {
+		c.provisioningClasses[fName] = class
+	} else {
+		delete(c.provisioningClasses, fName)
+	}
+	if weight := flavorShareWeightFromAnnotation(rf); weight != defaultFlavorShareWeight {
+		c.flavorShareWeights[fName] = weight
+	} else {
+		delete(c.flavorShareWeights, fName)
+	}
+	if key := flavorTopologyKeyFromAnnotation(rf); key != "" {
+		c.flavorTopologyKeys[fName] = key
+	} else {
+		delete(c.flavorTopologyKeys, fName)
+	}
+	if disabled := rf.Annotations[constants.ResourceFlavorDisabledAnnotation] == "true"; disabled != c.disabledFlavors.Has(fName) {
+		if disabled {
+			c.disabledFlavors.Insert(fName)
+		} else {
+			c.disabledFlavors.Delete(fName)
+		}
+		for _, cq := range c.clusterQueues {
+			if cq.flavorInUse(rf.Name) {
+				cq.AllocatableResourceGeneration++
+			}
+		}
+	}
 	return c.updateClusterQueues()
 }
 
@@ -201,9 +418,38 @@ func (c *Cache) DeleteResourceFlavor(rf *kueue.ResourceFlavor) sets.Set[string]
 	c.Lock()
 	defer c.Unlock()
 	delete(c.resourceFlavors, kueue.ResourceFlavorReference(rf.Name))
+	c.disabledFlavors.Delete(kueue.ResourceFlavorReference(rf.Name))
+	delete(c.provisioningClasses, kueue.ResourceFlavorReference(rf.Name))
+	delete(c.flavorShareWeights, kueue.ResourceFlavorReference(rf.Name))
+	delete(c.flavorTopologyKeys, kueue.ResourceFlavorReference(rf.Name))
 	return c.updateClusterQueues()
 }
 
+// substitutableFlavors returns the resources whose quota can be consumed, in
+// order, when the quota of the resource is exhausted.
+func (c *Cache) substitutableFlavors(resource corev1.ResourceName) []corev1.ResourceName {
+	return slices.Clone(c.resourceSubstitutes[resource])
+}
+
+// SetFlavorObservedCapacity records the capacity that the nodes matching the
+// ResourceFlavor can physically provide. Borrowing in the flavor is clamped to
+// this capacity. An empty capacity removes the clamp.
+func (c *Cache) SetFlavorObservedCapacity(flavorName string, capacity Resources) {
+	c.Lock()
+	defer c.Unlock()
+	fName := kueue.ResourceFlavorReference(flavorName)
+	if len(capacity) == 0 {
+		delete(c.flavorCapacity, fName)
+	} else {
+		c.flavorCapacity[fName] = maps.Clone(capacity)
+	}
+	for _, cq := range c.clusterQueues {
+		if cq.flavorInUse(flavorName) {
+			cq.AllocatableResourceGeneration++
+		}
+	}
+}
+
 func (c *Cache) AddOrUpdateAdmissionCheck(ac *kueue.AdmissionCheck) sets.Set[string] {
 	c.Lock()
 	defer c.Unlock()
@@ -236,13 +482,69 @@ func (c *Cache) ClusterQueueReadiness(name string) (metav1.ConditionStatus, stri
 	defer c.RUnlock()
 	cq := c.clusterQueues[name]
 	if cq == nil {
-		return metav1.ConditionFalse, "NotFound", "ClusterQueue not found"
+		return metav1.ConditionFalse, string(ReasonNotFound), "ClusterQueue not found"
+	}
+	return cq.readiness()
+}
+
+// clusterQueueStatusReason returns the status of the ClusterQueue, the reason
+// for it and a message detailing why the ClusterQueue can't admit workloads,
+// such as the names of the ResourceFlavors that are not found.
+func (c *Cache) clusterQueueStatusReason(cqName string) (status, reason, message string) {
+	c.RLock()
+	defer c.RUnlock()
+	cq := c.clusterQueues[cqName]
+	if cq == nil {
+		return "", string(ReasonNotFound), "ClusterQueue not found"
 	}
 	if cq.Status == active {
-		return metav1.ConditionTrue, "Ready", "Can admit new workloads"
+		return string(cq.Status), string(ReasonReady), "Can admit new workloads"
+	}
+	reasons := cq.pendingReasons()
+	if cq.Status != pending || len(reasons) == 0 {
+		r, msg := cq.inactiveReason()
+		return string(cq.Status), string(r), msg
+	}
+	details := make([]string, 0, len(reasons))
+	for _, r := range reasons {
+		switch r {
+		case ReasonStopped:
+			details = append(details, "the ClusterQueue is stopped")
+		case ReasonFlavorNotFound:
+			details = append(details, fmt.Sprintf("ResourceFlavors not found: %s", strings.Join(c.missingFlavors(cq), ", ")))
+		case ReasonCheckNotFoundOrInactive:
+			details = append(details, fmt.Sprintf("AdmissionChecks not found or inactive: %s", strings.Join(c.missingOrInactiveChecks(cq), ", ")))
+		case ReasonMultipleSingleInstanceControllerChecks:
+			details = append(details, "multiple AdmissionChecks of a controller that allows a single instance")
+		}
+	}
+	return string(cq.Status), string(reasons[0]), "Can't admit new workloads: " + strings.Join(details, "; ")
+}
+
+// missingFlavors returns the sorted names of the flavors referenced by the
+// ClusterQueue that are not found.
+func (c *Cache) missingFlavors(cq *ClusterQueue) []string {
+	missing := sets.New[string]()
+	for _, rg := range cq.ResourceGroups {
+		for _, flvQuotas := range rg.Flavors {
+			if _, found := c.resourceFlavors[flvQuotas.Name]; !found {
+				missing.Insert(string(flvQuotas.Name))
+			}
+		}
+	}
+	return sets.List(missing)
+}
+
+// missingOrInactiveChecks returns the sorted names of the admission checks
+// required by the ClusterQueue that are not found or are inactive.
+func (c *Cache) missingOrInactiveChecks(cq *ClusterQueue) []string {
+	var checks []string
+	for _, name := range sets.List(cq.AdmissionChecks) {
+		if ac, found := c.admissionChecks[name]; !found || !ac.Active {
+			checks = append(checks, name)
+		}
 	}
-	reason, msg := cq.inactiveReason()
-	return metav1.ConditionFalse, reason, msg
+	return checks
 }
 
 func (c *Cache) clusterQueueInStatus(name string, status metrics.ClusterQueueStatus) bool {
@@ -261,7 +563,8 @@ func (c *Cache) TerminateClusterQueue(name string) {
 	defer c.Unlock()
 	if cq, exists := c.clusterQueues[name]; exists {
 		cq.Status = terminating
-		metrics.ReportClusterQueueStatus(cq.Name, cq.Status)
+		c.metrics.SetClusterQueueStatus(cq.Name, cq.Status)
+		cq.syncCohortCapacity()
 	}
 }
 
@@ -279,6 +582,8 @@ func (c *Cache) ClusterQueueEmpty(name string) bool {
 }
 
 func (c *Cache) AddClusterQueue(ctx context.Context, cq *kueue.ClusterQueue) error {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
 	c.Lock()
 	defer c.Unlock()
 
@@ -289,8 +594,11 @@ func (c *Cache) AddClusterQueue(ctx context.Context, cq *kueue.ClusterQueue) err
 	if err != nil {
 		return err
 	}
-	c.addClusterQueueToCohort(cqImpl, cq.Spec.Cohort)
+	c.addClusterQueueToCohorts(cqImpl, cohortNames(cq))
 	c.clusterQueues[cq.Name] = cqImpl
+	for _, cohort := range cqImpl.Cohorts() {
+		c.recomputePercentageQuotas(cohort)
+	}
 
 	// On controller restart, an add ClusterQueue event may come after
 	// add queue and workload, so here we explicitly list and add existing queues
@@ -324,11 +632,18 @@ func (c *Cache) AddClusterQueue(ctx context.Context, cq *kueue.ClusterQueue) err
 		}
 		c.addOrUpdateWorkload(&workloads.Items[i])
 	}
+	for _, cohort := range cqImpl.Cohorts() {
+		cohort.reportBorrowedResources(c.metrics)
+	}
+	cqImpl.reportSoftQuotas()
+	changed = c.syncScheduledHold(cqImpl)
 
 	return nil
 }
 
 func (c *Cache) UpdateClusterQueue(cq *kueue.ClusterQueue) error {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
 	c.Lock()
 	defer c.Unlock()
 	cqImpl, ok := c.clusterQueues[cq.Name]
@@ -347,28 +662,94 @@ func (c *Cache) UpdateClusterQueue(cq *kueue.ClusterQueue) error {
 		}
 	}
 
-	if cqImpl.Cohort == nil {
-		c.addClusterQueueToCohort(cqImpl, cq.Spec.Cohort)
-		return nil
+	oldCohorts := cqImpl.Cohorts()
+	if names := cohortNames(cq); !slices.Equal(cqImpl.cohortNames(), names) {
+		c.deleteClusterQueueFromCohorts(cqImpl)
+		c.addClusterQueueToCohorts(cqImpl, names)
 	}
-
-	if cqImpl.Cohort.Name != cq.Spec.Cohort {
-		c.deleteClusterQueueFromCohort(cqImpl)
-		c.addClusterQueueToCohort(cqImpl, cq.Spec.Cohort)
+	// The quotas of the ClusterQueue might have changed, as well as its
+	// cohorts, so the percentage quotas of the old and new cohorts are
+	// recomputed.
+	for _, cohort := range append(oldCohorts, cqImpl.Cohorts()...) {
+		c.recomputePercentageQuotas(cohort)
+	}
+	// The quotas or the flavors might have changed, so the series of the
+	// cohorts are reported from scratch.
+	for _, cohort := range cqImpl.Cohorts() {
+		c.metrics.ClearCohortBorrowedResources(cohort.Name)
+		cohort.reportBorrowedResources(c.metrics)
 	}
+	cqImpl.reportSoftQuotas()
+	changed = c.syncScheduledHold(cqImpl)
 	return nil
 }
 
-func (c *Cache) DeleteClusterQueue(cq *kueue.ClusterQueue) {
+// DeleteClusterQueue removes the ClusterQueue from the cache and from its
+// cohort. It returns the sorted keys of the workloads that were still holding
+// quota in the ClusterQueue, whose usage is no longer accounted.
+func (c *Cache) DeleteClusterQueue(cq *kueue.ClusterQueue) []string {
 	c.Lock()
 	defer c.Unlock()
 	cqImpl, ok := c.clusterQueues[cq.Name]
 	if !ok {
-		return
+		return nil
+	}
+	var wlKeys []string
+	for k := range cqImpl.Workloads {
+		wlKeys = append(wlKeys, k)
+	}
+	sort.Strings(wlKeys)
+	cohorts := cqImpl.Cohorts()
+	c.deleteClusterQueueFromCohorts(cqImpl)
+	for _, cohort := range cohorts {
+		c.recomputePercentageQuotas(cohort)
 	}
-	c.deleteClusterQueueFromCohort(cqImpl)
 	delete(c.clusterQueues, cq.Name)
-	metrics.ClearCacheMetrics(cq.Name)
+	delete(c.pendingDemand, cq.Name)
+	delete(c.admissionTimes, cq.Name)
+	delete(c.scheduledHolds, scheduledHolder(cq.Name))
+	c.metrics.ClearClusterQueue(cq.Name)
+	return wlKeys
+}
+
+// removeFlavorFromClusterQueue removes the flavor from the resource group of
+// the ClusterQueue that covers the resource, without a full update of the
+// ClusterQueue. It fails if the flavor is still in use.
+func (c *Cache) removeFlavorFromClusterQueue(cqName string, rName corev1.ResourceName, flavor string) error {
+	c.Lock()
+	defer c.Unlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return errCqNotFound
+	}
+	return cq.removeFlavor(rName, kueue.ResourceFlavorReference(flavor), c.resourceFlavors)
+}
+
+// priorityOffset returns the offset added to the priority of the workloads of
+// the ClusterQueue, declared in the PriorityOffsetAnnotation.
+func priorityOffset(cq *kueue.ClusterQueue) (int32, error) {
+	value, found := cq.Annotations[constants.PriorityOffsetAnnotation]
+	if !found {
+		return 0, nil
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q in annotation %s: %v", errInvalidPriorityOffset, value, constants.PriorityOffsetAnnotation, err)
+	}
+	return int32(offset), nil
+}
+
+// EffectivePriority returns the priority of the workload combined with the
+// priority offset of the ClusterQueue. If the ClusterQueue is not found, the
+// priority of the workload is returned.
+func (c *Cache) EffectivePriority(wl *kueue.Workload, cqName string) int32 {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return priority.Priority(wl)
+	}
+	return cq.EffectivePriority(wl)
 }
 
 func (c *Cache) AddLocalQueue(q *kueue.LocalQueue) error {
@@ -409,13 +790,46 @@ func (c *Cache) UpdateLocalQueue(oldQ, newQ *kueue.LocalQueue) error {
 }
 
 func (c *Cache) AddOrUpdateWorkload(w *kueue.Workload) bool {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
 	c.Lock()
 	defer c.Unlock()
-	return c.addOrUpdateWorkload(w)
+	if workload.IsFinished(w) {
+		if cq := c.releaseFinishedWorkload(w); cq != nil {
+			changed = append(changed, cq.Name)
+		}
+		return false
+	}
+	if !c.addOrUpdateWorkload(w) {
+		return false
+	}
+	changed = append(changed, string(w.Status.Admission.ClusterQueue))
+	return true
+}
+
+// releaseFinishedWorkload removes the finished workload from the cache, so
+// that it doesn't hold quota even if its object lingers. Returns the
+// ClusterQueue whose usage was released, if any.
+func (c *Cache) releaseFinishedWorkload(w *kueue.Workload) *ClusterQueue {
+	c.cleanupAssumedState(w)
+	c.resetAdmissionBackoff(w)
+	c.forgetPreemptions(w)
+	cq := c.clusterQueueForWorkload(w)
+	if cq == nil {
+		return nil
+	}
+	if _, found := cq.Workloads[workload.Key(w)]; !found {
+		return nil
+	}
+	cq.deleteWorkload(w)
+	if c.podsReadyTracking {
+		c.podsReadyCond.Broadcast()
+	}
+	return cq
 }
 
 func (c *Cache) addOrUpdateWorkload(w *kueue.Workload) bool {
-	if !workload.HasQuotaReservation(w) {
+	if !workload.HasQuotaReservation(w) || workload.IsFinished(w) {
 		return false
 	}
 
@@ -425,6 +839,9 @@ func (c *Cache) addOrUpdateWorkload(w *kueue.Workload) bool {
 	}
 
 	c.cleanupAssumedState(w)
+	c.resetAdmissionBackoff(w)
+	c.recordAdmissionWait(w)
+	c.recordObservedState(w)
 
 	if _, exist := clusterQueue.Workloads[workload.Key(w)]; exist {
 		clusterQueue.deleteWorkload(w)
@@ -437,6 +854,8 @@ func (c *Cache) addOrUpdateWorkload(w *kueue.Workload) bool {
 }
 
 func (c *Cache) UpdateWorkload(oldWl, newWl *kueue.Workload) error {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
 	c.Lock()
 	defer c.Unlock()
 	if workload.HasQuotaReservation(oldWl) {
@@ -445,33 +864,52 @@ func (c *Cache) UpdateWorkload(oldWl, newWl *kueue.Workload) error {
 			return fmt.Errorf("old ClusterQueue doesn't exist")
 		}
 		cq.deleteWorkload(oldWl)
+		changed = append(changed, cq.Name)
 	}
 	c.cleanupAssumedState(oldWl)
 
-	if !workload.HasQuotaReservation(newWl) {
+	// Finished workloads don't hold quota.
+	if !workload.HasQuotaReservation(newWl) || workload.IsFinished(newWl) {
+		c.forgetQueued(newWl)
+		c.recordObservedState(newWl)
 		return nil
 	}
 	cq, ok := c.clusterQueues[string(newWl.Status.Admission.ClusterQueue)]
 	if !ok {
 		return fmt.Errorf("new ClusterQueue doesn't exist")
 	}
+	c.resetAdmissionBackoff(newWl)
+	c.recordAdmissionWait(newWl)
+	c.recordObservedState(newWl)
 	if c.podsReadyTracking {
 		c.podsReadyCond.Broadcast()
 	}
+	changed = append(changed, cq.Name)
 	return cq.addWorkload(newWl)
 }
 
 func (c *Cache) DeleteWorkload(w *kueue.Workload) error {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
 	c.Lock()
 	defer c.Unlock()
 
 	cq := c.clusterQueueForWorkload(w)
 	if cq == nil {
+		if workload.IsFinished(w) {
+			// The finished workload was already released.
+			return nil
+		}
 		return errCqNotFound
 	}
 
 	c.cleanupAssumedState(w)
+	c.resetAdmissionBackoff(w)
+	c.forgetQueued(w)
 
+	if _, found := cq.Workloads[workload.Key(w)]; found {
+		changed = append(changed, cq.Name)
+	}
 	cq.deleteWorkload(w)
 	if c.podsReadyTracking {
 		c.podsReadyCond.Broadcast()
@@ -479,6 +917,24 @@ func (c *Cache) DeleteWorkload(w *kueue.Workload) error {
 	return nil
 }
 
+// ClearWorkloadState drops the state that the cache keeps for the workload
+// besides its quota: its admission backoff, preemption count, queue time and
+// history. It must be called when the workload is deleted, whether it holds
+// quota or not, so that a new workload with the same key, such as the one of
+// a recreated Job, starts afresh.
+func (c *Cache) ClearWorkloadState(w *kueue.Workload) {
+	c.Lock()
+	defer c.Unlock()
+	c.resetAdmissionBackoff(w)
+	c.forgetQueued(w)
+	c.forgetHistory(w)
+	// The ClusterQueue of the workload might be gone or have changed.
+	k := workload.Key(w)
+	for _, cq := range c.clusterQueues {
+		delete(cq.PreemptionCounts, k)
+	}
+}
+
 func (c *Cache) IsAssumedOrAdmittedWorkload(w workload.Info) bool {
 	c.RLock()
 	defer c.RUnlock()
@@ -496,32 +952,58 @@ func (c *Cache) IsAssumedOrAdmittedWorkload(w workload.Info) bool {
 }
 
 func (c *Cache) AssumeWorkload(w *kueue.Workload) error {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
 	c.Lock()
 	defer c.Unlock()
 
-	if !workload.HasQuotaReservation(w) {
-		return errWorkloadNotAdmitted
+	cq, err := c.assumableClusterQueue(w)
+	if err != nil {
+		return err
 	}
-
-	k := workload.Key(w)
-	assumedCq, assumed := c.assumedWorkloads[k]
-	if assumed {
-		return fmt.Errorf("the workload is already assumed to ClusterQueue %q", assumedCq)
+	if err := c.assumeWorkload(cq, w); err != nil {
+		return err
 	}
+	changed = append(changed, cq.Name)
+	return nil
+}
 
+// assumableClusterQueue returns the ClusterQueue where the workload can be
+// assumed, or the reason why it can't.
+func (c *Cache) assumableClusterQueue(w *kueue.Workload) (*ClusterQueue, error) {
+	if !workload.HasQuotaReservation(w) {
+		return nil, errWorkloadNotAdmitted
+	}
+	if !isWorkloadActive(w) {
+		return nil, errWorkloadInactive
+	}
+	if assumedCq, assumed := c.assumedWorkloads[workload.Key(w)]; assumed {
+		return nil, fmt.Errorf("the workload is already assumed to ClusterQueue %q", assumedCq)
+	}
 	cq, ok := c.clusterQueues[string(w.Status.Admission.ClusterQueue)]
 	if !ok {
-		return errCqNotFound
+		return nil, errCqNotFound
 	}
+	if c.maxInflightAssumed > 0 && c.inflightAssumedCount(cq.Name) >= c.maxInflightAssumed {
+		return nil, fmt.Errorf("%w: %d workloads assumed in ClusterQueue %q", errTooManyInflightAssumed, c.maxInflightAssumed, cq.Name)
+	}
+	return cq, nil
+}
 
+func (c *Cache) assumeWorkload(cq *ClusterQueue, w *kueue.Workload) error {
 	if err := cq.addWorkload(w); err != nil {
 		return err
 	}
-	c.assumedWorkloads[k] = string(w.Status.Admission.ClusterQueue)
+	c.assumedWorkloads[workload.Key(w)] = cq.Name
+	c.markQueued(w)
+	c.recordAdmissionTime(cq.Name)
+	c.recordTransition(w, WorkloadStateAssumed, "Assumed")
 	return nil
 }
 
 func (c *Cache) ForgetWorkload(w *kueue.Workload) error {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
 	c.Lock()
 	defer c.Unlock()
 
@@ -529,6 +1011,7 @@ func (c *Cache) ForgetWorkload(w *kueue.Workload) error {
 		return fmt.Errorf("the workload is not assumed")
 	}
 	c.cleanupAssumedState(w)
+	c.recordTransition(w, WorkloadStatePending, "Forgotten")
 
 	if !workload.HasQuotaReservation(w) {
 		return errWorkloadNotAdmitted
@@ -539,12 +1022,49 @@ func (c *Cache) ForgetWorkload(w *kueue.Workload) error {
 		return errCqNotFound
 	}
 	cq.deleteWorkload(w)
+	changed = append(changed, cq.Name)
 	if c.podsReadyTracking {
 		c.podsReadyCond.Broadcast()
 	}
 	return nil
 }
 
+// notifyUsageChanged reports the borrowed resources in the cohorts of the
+// ClusterQueues and calls OnClusterQueueUsageChanged once for each of them.
+// It must be called without holding the lock.
+func (c *Cache) notifyUsageChanged(cqNames ...string) {
+	if len(cqNames) == 0 {
+		return
+	}
+	names := sets.List(sets.New(cqNames...))
+	c.reportCohortsBorrowedResources(names...)
+	c.reportSoftQuotas(names...)
+	if c.OnClusterQueueUsageChanged == nil {
+		return
+	}
+	for _, name := range names {
+		c.OnClusterQueueUsageChanged(name)
+	}
+}
+
+func (c *Cache) reportCohortsBorrowedResources(cqNames ...string) {
+	c.RLock()
+	defer c.RUnlock()
+	reported := sets.New[string]()
+	for _, name := range cqNames {
+		cq, ok := c.clusterQueues[name]
+		if !ok {
+			continue
+		}
+		for _, cohort := range cq.Cohorts() {
+			if !reported.Has(cohort.Name) {
+				reported.Insert(cohort.Name)
+				cohort.reportBorrowedResources(c.metrics)
+			}
+		}
+	}
+}
+
 type ClusterQueueUsageStats struct {
 	ReservedResources  []kueue.FlavorUsage
 	ReservingWorkloads int
@@ -570,11 +1090,57 @@ func (c *Cache) Usage(cqObj *kueue.ClusterQueue) (*ClusterQueueUsageStats, error
 	}, nil
 }
 
+// usageIncludingAssumed reports the reserved resources in the ClusterQueue
+// split between the workloads whose admission is committed and the assumed
+// workloads, whose admission is pending to be committed by the scheduler.
+// The borrowed resources of the assumed workloads are the ones borrowed on top
+// of the committed usage.
+func (c *Cache) usageIncludingAssumed(cqName string) (committed, assumed []kueue.FlavorUsage, err error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	cq := c.clusterQueues[cqName]
+	if cq == nil {
+		return nil, nil, errCqNotFound
+	}
+
+	assumedUsage := make(FlavorResourceQuantities, len(cq.Usage))
+	for flv, resUsage := range cq.Usage {
+		assumedUsage[flv] = make(map[corev1.ResourceName]int64, len(resUsage))
+		for rName := range resUsage {
+			assumedUsage[flv][rName] = 0
+		}
+	}
+	for k, assumedCQ := range c.assumedWorkloads {
+		if assumedCQ != cqName {
+			continue
+		}
+		if wi, ok := cq.Workloads[k]; ok {
+			updateUsage(wi, assumedUsage, 1)
+		}
+	}
+	committedUsage := make(FlavorResourceQuantities, len(cq.Usage))
+	for flv, resUsage := range cq.Usage {
+		committedUsage[flv] = make(map[corev1.ResourceName]int64, len(resUsage))
+		for rName, v := range resUsage {
+			committedUsage[flv][rName] = v - assumedUsage[flv][rName]
+		}
+	}
+	return getUsage(committedUsage, cq.ResourceGroups, cq.Cohort),
+		getUsageOver(cq.Usage, committedUsage, cq.ResourceGroups, cq.Cohort), nil
+}
+
 func getUsage(frq FlavorResourceQuantities, rgs []ResourceGroup, cohort *Cohort) []kueue.FlavorUsage {
+	return getUsageOver(frq, nil, rgs, cohort)
+}
+
+// getUsageOver reports the usage in frq that exceeds the base usage.
+func getUsageOver(frq, base FlavorResourceQuantities, rgs []ResourceGroup, cohort *Cohort) []kueue.FlavorUsage {
 	usage := make([]kueue.FlavorUsage, 0, len(frq))
 	for _, rg := range rgs {
 		for _, flvQuotas := range rg.Flavors {
 			flvUsage := frq[flvQuotas.Name]
+			flvBase := base[flvQuotas.Name]
 			outFlvUsage := kueue.FlavorUsage{
 				Name:      flvQuotas.Name,
 				Resources: make([]kueue.ResourceUsage, 0, len(flvQuotas.Resources)),
@@ -583,11 +1149,11 @@ func getUsage(frq FlavorResourceQuantities, rgs []ResourceGroup, cohort *Cohort)
 				used := flvUsage[rName]
 				rUsage := kueue.ResourceUsage{
 					Name:  rName,
-					Total: workload.ResourceQuantity(rName, used),
+					Total: workload.ResourceQuantity(rName, used-flvBase[rName]),
 				}
 				// Enforce `borrowed=0` if the clusterQueue doesn't belong to a cohort.
 				if cohort != nil {
-					borrowed := used - rQuota.Nominal
+					borrowed := used - max(rQuota.Nominal, flvBase[rName])
 					if borrowed > 0 {
 						rUsage.Borrowed = workload.ResourceQuantity(rName, borrowed)
 					}
@@ -604,6 +1170,74 @@ func getUsage(frq FlavorResourceQuantities, rgs []ResourceGroup, cohort *Cohort)
 	return usage
 }
 
+// ClusterQueueStatus is a consistent view of the cached state of a ClusterQueue,
+// taken under a single lock.
+type ClusterQueueStatus struct {
+	// Status is the Active/Pending/Terminating status of the ClusterQueue.
+	Status metrics.ClusterQueueStatus
+	// Stopped indicates whether the ClusterQueue has a StopPolicy set.
+	Stopped bool
+	// ConditionStatus, Reason and Message describe the Active condition of
+	// the ClusterQueue, as returned by ClusterQueueReadiness.
+	ConditionStatus metav1.ConditionStatus
+	Reason          string
+	Message         string
+
+	ReservedResources  []kueue.FlavorUsage
+	ReservingWorkloads int
+	AdmittedResources  []kueue.FlavorUsage
+	AdmittedWorkloads  int
+	// ReservedNotAdmittedWorkloads is the number of workloads holding a quota
+	// reservation which are not admitted yet, for example, because they are
+	// waiting for admission checks. The pending workloads, without a quota
+	// reservation, are not in the cache; they are counted by the queue
+	// manager.
+	ReservedNotAdmittedWorkloads int
+	// BorrowedResources is the quota reserved above the nominal quota, per
+	// flavor and resource. It's empty if the ClusterQueue doesn't belong to
+	// a cohort.
+	BorrowedResources FlavorResourceQuantities
+}
+
+// ClusterQueueStatus returns the status of the ClusterQueue, including its
+// usage, number of workloads and borrowed resources.
+func (c *Cache) ClusterQueueStatus(cqName string) (ClusterQueueStatus, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	cq := c.clusterQueues[cqName]
+	if cq == nil {
+		return ClusterQueueStatus{}, errCqNotFound
+	}
+
+	status := ClusterQueueStatus{
+		Status:                       cq.Status,
+		Stopped:                      cq.isStopped,
+		ReservedResources:            getUsage(cq.Usage, cq.ResourceGroups, cq.Cohort),
+		ReservingWorkloads:           len(cq.Workloads),
+		AdmittedResources:            getUsage(cq.AdmittedUsage, cq.ResourceGroups, cq.Cohort),
+		AdmittedWorkloads:            cq.admittedWorkloadsCount,
+		ReservedNotAdmittedWorkloads: len(cq.Workloads) - cq.admittedWorkloadsCount,
+		BorrowedResources:            make(FlavorResourceQuantities),
+	}
+	status.ConditionStatus, status.Reason, status.Message = cq.readiness()
+	if cq.Cohort != nil {
+		for _, rg := range cq.ResourceGroups {
+			for _, flvQuotas := range rg.Flavors {
+				for rName, rQuota := range flvQuotas.Resources {
+					if borrowed := cq.Usage[flvQuotas.Name][rName] - rQuota.Nominal; borrowed > 0 {
+						if status.BorrowedResources[flvQuotas.Name] == nil {
+							status.BorrowedResources[flvQuotas.Name] = make(map[corev1.ResourceName]int64)
+						}
+						status.BorrowedResources[flvQuotas.Name][rName] = borrowed
+					}
+				}
+			}
+		}
+	}
+	return status, nil
+}
+
 type LocalQueueUsageStats struct {
 	ReservedResources  []kueue.LocalQueueFlavorUsage
 	ReservingWorkloads int
@@ -632,6 +1266,190 @@ func (c *Cache) LocalQueueUsage(qObj *kueue.LocalQueue) (*LocalQueueUsageStats,
 	}, nil
 }
 
+// allAdmittedWorkloads returns the admitted workloads of all the
+// ClusterQueues, sorted by ClusterQueue and then by workload key. The returned
+// infos are copies that the caller can modify.
+func (c *Cache) allAdmittedWorkloads() []*workload.Info {
+	c.RLock()
+	defer c.RUnlock()
+	var infos []*workload.Info
+	for _, cq := range c.clusterQueues {
+		for _, wi := range cq.Workloads {
+			if workload.IsAdmitted(wi.Obj) {
+				infos = append(infos, cloneInfo(wi))
+			}
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].ClusterQueue != infos[j].ClusterQueue {
+			return infos[i].ClusterQueue < infos[j].ClusterQueue
+		}
+		return workload.Key(infos[i].Obj) < workload.Key(infos[j].Obj)
+	})
+	return infos
+}
+
+// workloadsForOwner returns the workloads in the cache, holding quota in any
+// ClusterQueue, that have an owner reference to the object with the UID,
+// sorted by workload key. The returned infos are copies that the caller can
+// modify.
+func (c *Cache) workloadsForOwner(ownerUID types.UID) []*workload.Info {
+	c.RLock()
+	defer c.RUnlock()
+	var infos []*workload.Info
+	for _, cq := range c.clusterQueues {
+		for _, wi := range cq.Workloads {
+			if slices.ContainsFunc(wi.Obj.OwnerReferences, func(ref metav1.OwnerReference) bool {
+				return ref.UID == ownerUID
+			}) {
+				infos = append(infos, cloneInfo(wi))
+			}
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return workload.Key(infos[i].Obj) < workload.Key(infos[j].Obj)
+	})
+	return infos
+}
+
+// cloneInfo returns a deep copy of the workload info.
+func cloneInfo(wi *workload.Info) *workload.Info {
+	clone := *wi
+	clone.Obj = wi.Obj.DeepCopy()
+	clone.TotalRequests = make([]workload.PodSetResources, len(wi.TotalRequests))
+	for i, psr := range wi.TotalRequests {
+		clone.TotalRequests[i] = workload.PodSetResources{
+			Name:     psr.Name,
+			Requests: maps.Clone(psr.Requests),
+			Count:    psr.Count,
+			Flavors:  maps.Clone(psr.Flavors),
+		}
+	}
+	if wi.LastAssignment != nil {
+		clone.LastAssignment = wi.LastAssignment.Clone()
+	}
+	return &clone
+}
+
+// getClusterQueue returns a deep copy of the cached state of the ClusterQueue
+// and whether it exists. The copy can be modified without affecting the cache.
+// Its cohorts only hold their names; they don't list their members.
+func (c *Cache) getClusterQueue(name string) (*ClusterQueue, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[name]
+	if !ok {
+		return nil, false
+	}
+	return cq.deepCopy(), true
+}
+
+// deepCopy returns a copy of the exported fields of the ClusterQueue that
+// doesn't share any state with it.
+func (c *ClusterQueue) deepCopy() *ClusterQueue {
+	cc := &ClusterQueue{
+		Name:                          c.Name,
+		ResourceGroups:                make([]ResourceGroup, len(c.ResourceGroups)),
+		Usage:                         cloneFlavorResourceQuantities(c.Usage),
+		AdmittedUsage:                 cloneFlavorResourceQuantities(c.AdmittedUsage),
+		Workloads:                     make(map[string]*workload.Info, len(c.Workloads)),
+		WorkloadsNotReady:             c.WorkloadsNotReady.Clone(),
+		Preemption:                    *c.Preemption.DeepCopy(),
+		FlavorFungibility:             c.FlavorFungibility,
+		AdmissionChecks:               c.AdmissionChecks.Clone(),
+		Status:                        c.Status,
+		GuaranteedQuota:               cloneFlavorResourceQuantities(c.GuaranteedQuota),
+		AllocatableResourceGeneration: c.AllocatableResourceGeneration,
+		OvercommitQuota:               cloneFlavorResourceQuantities(c.OvercommitQuota),
+		ResourceSubstitutes:           make(map[corev1.ResourceName][]corev1.ResourceName, len(c.ResourceSubstitutes)),
+		PriorityOffset:                c.PriorityOffset,
+		PreemptionCounts:              maps.Clone(c.PreemptionCounts),
+		BorrowBoost:                   maps.Clone(c.BorrowBoost),
+		BorrowingDisabled:             c.BorrowingDisabled,
+		BorrowingForbidden:            c.BorrowingForbidden.Clone(),
+		Headroom:                      maps.Clone(c.Headroom),
+	}
+	if c.Cohort != nil {
+		cc.Cohort = &Cohort{Name: c.Cohort.Name}
+	}
+	for _, cohort := range c.SecondaryCohorts {
+		cc.SecondaryCohorts = append(cc.SecondaryCohorts, &Cohort{Name: cohort.Name})
+	}
+	for i, rg := range c.ResourceGroups {
+		ccRG := ResourceGroup{
+			CoveredResources: rg.CoveredResources.Clone(),
+			Flavors:          make([]FlavorQuotas, len(rg.Flavors)),
+			LabelKeys:        rg.LabelKeys.Clone(),
+		}
+		for j, flvQuotas := range rg.Flavors {
+			resources := make(map[corev1.ResourceName]*ResourceQuota, len(flvQuotas.Resources))
+			for rName, rQuota := range flvQuotas.Resources {
+				ccQuota := *rQuota
+				if rQuota.BorrowingLimit != nil {
+					ccQuota.BorrowingLimit = ptr.To(*rQuota.BorrowingLimit)
+				}
+				if rQuota.LendingLimit != nil {
+					ccQuota.LendingLimit = ptr.To(*rQuota.LendingLimit)
+				}
+				resources[rName] = &ccQuota
+			}
+			ccRG.Flavors[j] = FlavorQuotas{Name: flvQuotas.Name, Resources: resources}
+		}
+		cc.ResourceGroups[i] = ccRG
+	}
+	cc.UpdateRGByResource()
+	for key, wi := range c.Workloads {
+		cc.Workloads[key] = cloneInfo(wi)
+	}
+	if c.NamespaceSelector != nil {
+		cc.NamespaceSelector = c.NamespaceSelector.DeepCopySelector()
+	}
+	for rName, substitutes := range c.ResourceSubstitutes {
+		cc.ResourceSubstitutes[rName] = slices.Clone(substitutes)
+	}
+	return cc
+}
+
+func cloneFlavorResourceQuantities(q FlavorResourceQuantities) FlavorResourceQuantities {
+	if q == nil {
+		return nil
+	}
+	clone := make(FlavorResourceQuantities, len(q))
+	for fName, rQuantities := range q {
+		clone[fName] = maps.Clone(rQuantities)
+	}
+	return clone
+}
+
+// workloadsSince returns the admitted workloads in the ClusterQueue that
+// changed since the last sync of the caller, sorted by key. seen holds the
+// resourceVersion of each workload, by key, as of the last sync; a workload is
+// returned if it's not in seen or if its resourceVersion is different.
+// Resource versions are opaque, so they are only compared for equality. The
+// returned infos are copies that the caller can modify.
+func (c *Cache) workloadsSince(cqName string, seen map[string]string) []*workload.Info {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	var result []*workload.Info
+	for k, wi := range cq.Workloads {
+		if !workload.IsAdmitted(wi.Obj) {
+			continue
+		}
+		if rv, found := seen[k]; found && rv == wi.Obj.ResourceVersion {
+			continue
+		}
+		result = append(result, cloneInfo(wi))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return workload.Key(result[i].Obj) < workload.Key(result[j].Obj)
+	})
+	return result
+}
+
 func filterLocalQueueUsage(orig FlavorResourceQuantities, resourceGroups []ResourceGroup) []kueue.LocalQueueFlavorUsage {
 	qFlvUsages := make([]kueue.LocalQueueFlavorUsage, 0, len(orig))
 	for _, rg := range resourceGroups {
@@ -685,28 +1503,369 @@ func (c *Cache) clusterQueueForWorkload(w *kueue.Workload) *ClusterQueue {
 	return nil
 }
 
-func (c *Cache) addClusterQueueToCohort(cq *ClusterQueue, cohortName string) {
-	if cohortName == "" {
-		return
+// addClusterQueueToCohorts registers the ClusterQueue as a member of the
+// cohorts. The first cohort becomes the primary cohort of the ClusterQueue and
+// the rest, its secondary cohorts.
+func (c *Cache) addClusterQueueToCohorts(cq *ClusterQueue, cohortNames []string) {
+	for i, cohortName := range cohortNames {
+		cohort, ok := c.cohorts[cohortName]
+		if !ok {
+			cohort = newCohort(cohortName, 1)
+			c.cohorts[cohortName] = cohort
+		}
+		cohort.Members.Insert(cq)
+		cohort.addCapacity(cq.cohortNominal, cq.cohortUsage, 1)
+		if i == 0 {
+			cq.Cohort = cohort
+		} else {
+			cq.SecondaryCohorts = append(cq.SecondaryCohorts, cohort)
+		}
+	}
+}
+
+func (c *Cache) deleteClusterQueueFromCohorts(cq *ClusterQueue) {
+	for _, cohort := range cq.Cohorts() {
+		cohort.Members.Delete(cq)
+		cohort.addCapacity(cq.cohortNominal, cq.cohortUsage, -1)
+		c.metrics.ClearCohortBorrowedResources(cohort.Name)
+		if cohort.Members.Len() == 0 {
+			delete(c.cohorts, cohort.Name)
+			delete(c.cohortUsageHistories, cohort.Name)
+		} else {
+			cohort.reportBorrowedResources(c.metrics)
+		}
+	}
+	cq.Cohort = nil
+	cq.SecondaryCohorts = nil
+}
+
+// cohortNames returns the cohorts of the ClusterQueue in order: the
+// cohort in its spec followed by the ones in the SecondaryCohortsAnnotation,
+// without duplicates. If the spec doesn't set a cohort, the first secondary
+// cohort becomes the primary one.
+func cohortNames(cq *kueue.ClusterQueue) []string {
+	var names []string
+	seen := sets.New[string]()
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen.Has(name) {
+			seen.Insert(name)
+			names = append(names, name)
+		}
+	}
+	add(cq.Spec.Cohort)
+	if value, found := cq.Annotations[constants.SecondaryCohortsAnnotation]; found {
+		for _, name := range strings.Split(value, ",") {
+			add(name)
+		}
+	}
+	return names
+}
+
+// cohortMembers returns the names of the ClusterQueues in the cohort, sorted
+// by name. Returns nil if the cohort doesn't exist.
+func (c *Cache) cohortMembers(cohortName string) []string {
+	c.RLock()
+	defer c.RUnlock()
+	cohort, ok := c.cohorts[cohortName]
+	if !ok {
+		return nil
+	}
+	members := cohort.SortedMembers()
+	names := make([]string, len(members))
+	for i, cq := range members {
+		names[i] = cq.Name
+	}
+	return names
+}
+
+// cohortFreeCapacity returns, per resource, the nominal quota of the active
+// ClusterQueues in the cohort that is not used, regardless of their borrowing
+// limits. The free capacity is floored at zero in each flavor before adding up
+// the flavors. It's computed from the capacity aggregated by the cohort, so
+// its cost doesn't depend on the number of members.
+func (c *Cache) cohortFreeCapacity(cohortName string) (Resources, error) {
+	c.RLock()
+	defer c.RUnlock()
+	cohort, ok := c.cohorts[cohortName]
+	if !ok {
+		return nil, errCohortNotFound
 	}
+	free := make(Resources)
+	for fName, resources := range cohort.activeNominal {
+		for rName, val := range resources {
+			free[rName] += max(0, val-cohort.activeUsage[fName][rName])
+		}
+	}
+	return free, nil
+}
+
+// cohortResources returns the names of the resources covered by the resource
+// groups of the members of the cohort, or nil if the cohort doesn't exist.
+func (c *Cache) cohortResources(cohortName string) sets.Set[corev1.ResourceName] {
+	c.RLock()
+	defer c.RUnlock()
 	cohort, ok := c.cohorts[cohortName]
 	if !ok {
-		cohort = newCohort(cohortName, 1)
-		c.cohorts[cohortName] = cohort
+		return nil
+	}
+	resources := sets.New[corev1.ResourceName]()
+	for cq := range cohort.Members {
+		for _, rg := range cq.ResourceGroups {
+			resources.Insert(rg.CoveredResources.UnsortedList()...)
+		}
+	}
+	return resources
+}
+
+// cohortsBorrowing returns, keyed by cohort name, how much of the resource the
+// members of each cohort borrow: the sum, across the members and flavors, of
+// their usage above their nominal quota. Only the cohorts whose members have
+// quota for the resource are included.
+func (c *Cache) cohortsBorrowing(rName corev1.ResourceName) map[string]int64 {
+	c.RLock()
+	defer c.RUnlock()
+	result := make(map[string]int64)
+	for name, cohort := range c.cohorts {
+		for _, resources := range cohort.borrowedResources() {
+			if v, found := resources[rName]; found {
+				result[name] += v
+			}
+		}
+	}
+	return result
+}
+
+// cohortOversubscribed returns, for each resource with observed capacity in
+// the flavors of the cohort, whether the maximum quotas of the members exceed
+// it in any flavor. The maximum quota of a member is its nominal quota plus
+// its borrowing limit or, without a borrowing limit, the total nominal quota
+// of the cohort. Flavors without observed capacity for a resource are not
+// evaluated. Returns nil if the cohort doesn't exist.
+func (c *Cache) cohortOversubscribed(cohortName string) map[corev1.ResourceName]bool {
+	c.RLock()
+	defer c.RUnlock()
+	cohort, ok := c.cohorts[cohortName]
+	if !ok {
+		return nil
+	}
+	nominal := make(FlavorResourceQuantities)
+	for cq := range cohort.Members {
+		for _, rg := range cq.ResourceGroups {
+			for _, flvQuotas := range rg.Flavors {
+				if nominal[flvQuotas.Name] == nil {
+					nominal[flvQuotas.Name] = make(map[corev1.ResourceName]int64)
+				}
+				for rName, rQuota := range flvQuotas.Resources {
+					nominal[flvQuotas.Name][rName] += rQuota.Nominal
+				}
+			}
+		}
+	}
+	maxQuota := make(FlavorResourceQuantities)
+	for cq := range cohort.Members {
+		for _, rg := range cq.ResourceGroups {
+			for _, flvQuotas := range rg.Flavors {
+				if maxQuota[flvQuotas.Name] == nil {
+					maxQuota[flvQuotas.Name] = make(map[corev1.ResourceName]int64)
+				}
+				for rName, rQuota := range flvQuotas.Resources {
+					if rQuota.BorrowingLimit != nil {
+						maxQuota[flvQuotas.Name][rName] += rQuota.Nominal + *rQuota.BorrowingLimit
+					} else {
+						maxQuota[flvQuotas.Name][rName] += nominal[flvQuotas.Name][rName]
+					}
+				}
+			}
+		}
+	}
+	oversubscribed := make(map[corev1.ResourceName]bool)
+	for fName, resources := range maxQuota {
+		for rName, val := range resources {
+			capacity, found := c.flavorCapacity[fName][rName]
+			if !found {
+				continue
+			}
+			oversubscribed[rName] = oversubscribed[rName] || val > capacity
+		}
+	}
+	return oversubscribed
+}
+
+// unmanagedResources returns the resources requested by the workload that are
+// not covered by any resource group of the ClusterQueue, sorted by name. The
+// workload can't be admitted in the ClusterQueue if the list isn't empty.
+// Returns nil if the ClusterQueue doesn't exist.
+func (c *Cache) unmanagedResources(w *kueue.Workload, cqName string) []corev1.ResourceName {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
 	}
-	cohort.Members.Insert(cq)
-	cq.Cohort = cohort
+	unmanaged := sets.New[corev1.ResourceName]()
+	for _, ps := range workload.NewInfo(w).TotalRequests {
+		for rName, val := range ps.Requests {
+			if _, found := cq.RGByResource[rName]; !found && val > 0 {
+				unmanaged.Insert(rName)
+			}
+		}
+	}
+	return sets.List(unmanaged)
 }
 
-func (c *Cache) deleteClusterQueueFromCohort(cq *ClusterQueue) {
-	if cq.Cohort == nil {
+// SetPendingDemand records the total requests of the workloads pending
+// admission in the ClusterQueue, as reported by the queue manager whenever they
+// change. An empty demand clears it.
+func (c *Cache) SetPendingDemand(cqName string, demand workload.Requests) {
+	c.Lock()
+	defer c.Unlock()
+	if len(demand) == 0 {
+		delete(c.pendingDemand, cqName)
 		return
 	}
-	cq.Cohort.Members.Delete(cq)
-	if cq.Cohort.Members.Len() == 0 {
-		delete(c.cohorts, cq.Cohort.Name)
+	c.pendingDemand[cqName] = Resources(maps.Clone(demand))
+}
+
+// safeBorrowableCapacity returns, per resource covered by the ClusterQueue,
+// the unused nominal quota of the other active ClusterQueues in its cohort
+// that is not needed by their own pending workloads, as reported by
+// SetPendingDemand. Borrowing within this capacity doesn't take quota that a
+// peer could use right away. Returns nil if the ClusterQueue doesn't exist or
+// doesn't belong to a cohort.
+func (c *Cache) safeBorrowableCapacity(cqName string) Resources {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok || cq.Cohort == nil {
+		return nil
+	}
+	safe := make(Resources)
+	for _, rg := range cq.ResourceGroups {
+		for rName := range rg.CoveredResources {
+			safe[rName] = 0
+		}
 	}
-	cq.Cohort = nil
+	for peer := range cq.Cohort.Members {
+		if peer == cq || !peer.Active() {
+			continue
+		}
+		for rName, val := range peer.idleNominal() {
+			if _, covered := safe[rName]; covered {
+				safe[rName] += max(0, val-c.pendingDemand[peer.Name][rName])
+			}
+		}
+	}
+	return safe
+}
+
+// orphanedFlavorUsage returns, per resource and flavor, the usage of the
+// ClusterQueue recorded against flavors that don't exist as ResourceFlavors.
+// Such usage can't be backed by real capacity, so it's worth alerting on.
+// Returns nil if the ClusterQueue doesn't exist or has no orphaned usage.
+func (c *Cache) orphanedFlavorUsage(cqName string) map[corev1.ResourceName]map[string]int64 {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	var orphaned map[corev1.ResourceName]map[string]int64
+	for fName, rUsage := range cq.Usage {
+		if _, found := c.resourceFlavors[fName]; found {
+			continue
+		}
+		for rName, val := range rUsage {
+			if val <= 0 {
+				continue
+			}
+			if orphaned == nil {
+				orphaned = make(map[corev1.ResourceName]map[string]int64)
+			}
+			if orphaned[rName] == nil {
+				orphaned[rName] = make(map[string]int64)
+			}
+			orphaned[rName][string(fName)] = val
+		}
+	}
+	return orphaned
+}
+
+// requiredQuotaIncrease returns, per flavor and resource, the nominal quota
+// that the ClusterQueue lacks for the workload to fit without borrowing, given
+// its current usage. In each resource group, the flavor that needs the
+// smallest increase, relative to the requests, is picked; ties go to the
+// flavor tried first. Disabled flavors are not considered.
+// Returns an empty result if the workload already fits, or nil if the
+// ClusterQueue doesn't exist.
+func (c *Cache) requiredQuotaIncrease(w *kueue.Workload, cqName string) FlavorResourceQuantities {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	requests := make(Resources)
+	for _, ps := range workload.NewInfo(w).TotalRequests {
+		for rName, val := range ps.Requests {
+			requests[rName] += val
+		}
+	}
+	increase := make(FlavorResourceQuantities)
+	for _, rg := range cq.ResourceGroups {
+		var best map[corev1.ResourceName]int64
+		var bestFlavor kueue.ResourceFlavorReference
+		bestCost := math.Inf(1)
+		for _, flvQuotas := range rg.Flavors {
+			if c.disabledFlavors.Has(flvQuotas.Name) {
+				continue
+			}
+			flvIncrease := make(map[corev1.ResourceName]int64)
+			var cost float64
+			for rName := range rg.CoveredResources {
+				val := requests[rName]
+				if val == 0 {
+					continue
+				}
+				required := cq.Usage[flvQuotas.Name][rName] + val
+				var nominal int64
+				if rQuota := flvQuotas.Resources[rName]; rQuota != nil {
+					nominal = rQuota.Nominal
+					required = rQuota.requiredNominal(required)
+				}
+				if inc := required - nominal; inc > 0 {
+					flvIncrease[rName] = inc
+					cost += float64(inc) / float64(val)
+				}
+			}
+			if cost < bestCost {
+				best, bestFlavor, bestCost = flvIncrease, flvQuotas.Name, cost
+			}
+		}
+		if len(best) > 0 {
+			increase[bestFlavor] = best
+		}
+	}
+	return increase
+}
+
+// WorkloadFootprint returns the total requests of the workload when all its
+// PodSets run with their minimum number of pods, as set in MinCount, and with
+// their desired number of pods, Count. Workloads that can be partially
+// admitted reserve quota for any count in between.
+func WorkloadFootprint(w *kueue.Workload) (minimum, desired Resources) {
+	minimum = make(Resources)
+	desired = make(Resources)
+	for i := range w.Spec.PodSets {
+		ps := &w.Spec.PodSets[i]
+		minCount := ptr.Deref(ps.MinCount, ps.Count)
+		for rName, q := range limitrange.TotalRequests(&ps.Template.Spec) {
+			v := workload.ResourceValue(rName, q)
+			minimum[rName] += v * int64(minCount)
+			desired[rName] += v * int64(ps.Count)
+		}
+	}
+	return minimum, desired
 }
 
 func (c *Cache) ClusterQueuesUsingFlavor(flavor string) []string {
@@ -735,6 +1894,150 @@ func (c *Cache) ClusterQueuesUsingAdmissionCheck(ac string) []string {
 	return cqs
 }
 
+// validatePodSetFlavorConsistency checks that the flavors assigned to the
+// different PodSets of an admitted workload don't require conflicting values
+// for the same node label. All the PodSets of the workload are considered to
+// be co-located.
+// Returns an error listing the conflicting PodSets and flavors, if any.
+func (c *Cache) validatePodSetFlavorConsistency(w *kueue.Workload) error {
+	if w.Status.Admission == nil {
+		return nil
+	}
+	c.RLock()
+	defer c.RUnlock()
+
+	type labelSource struct {
+		podSet string
+		flavor kueue.ResourceFlavorReference
+		value  string
+	}
+	seen := make(map[string]labelSource)
+	var conflicts []string
+	for _, psa := range w.Status.Admission.PodSetAssignments {
+		resources := make([]corev1.ResourceName, 0, len(psa.Flavors))
+		for rName := range psa.Flavors {
+			resources = append(resources, rName)
+		}
+		sort.Slice(resources, func(i, j int) bool { return resources[i] < resources[j] })
+		for _, rName := range resources {
+			fName := psa.Flavors[rName]
+			rf, found := c.resourceFlavors[fName]
+			if !found {
+				continue
+			}
+			keys := make([]string, 0, len(rf.Spec.NodeLabels))
+			for k := range rf.Spec.NodeLabels {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				v := rf.Spec.NodeLabels[k]
+				prev, found := seen[k]
+				if !found {
+					seen[k] = labelSource{podSet: psa.Name, flavor: fName, value: v}
+					continue
+				}
+				if prev.podSet != psa.Name && prev.value != v {
+					conflicts = append(conflicts, fmt.Sprintf("podSet %q (flavor %q, %s=%s) and podSet %q (flavor %q, %s=%s)",
+						prev.podSet, prev.flavor, k, prev.value, psa.Name, fName, k, v))
+				}
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("conflicting flavor labels between podSets: %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// detectLabelConflicts returns the node label keys, sorted, for which the
+// flavors chosen for the resources of the PodSet in different resource groups
+// of the ClusterQueue require different values. A pod of the PodSet can't
+// satisfy them all, so it can't be admitted with that flavor combination.
+// In each resource group covering a resource requested by the PodSet, the
+// chosen flavor is the first one that isn't disabled and whose node labels
+// don't contradict the node selector of the PodSet.
+// Returns nil if the ClusterQueue doesn't exist.
+func (c *Cache) detectLabelConflicts(cqName string, podSet kueue.PodSet) []string {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	requests := limitrange.TotalRequests(&podSet.Template.Spec)
+	nodeSelector := podSet.Template.Spec.NodeSelector
+	values := make(map[string]string)
+	conflicts := sets.New[string]()
+	for _, rg := range cq.ResourceGroups {
+		requested := false
+		for rName := range requests {
+			if rg.CoveredResources.Has(rName) {
+				requested = true
+				break
+			}
+		}
+		if !requested {
+			continue
+		}
+		for _, flvQuotas := range rg.Flavors {
+			rf, found := c.resourceFlavors[flvQuotas.Name]
+			if !found || c.disabledFlavors.Has(flvQuotas.Name) || !flavorMatchesNodeSelector(rf, nodeSelector) {
+				continue
+			}
+			for k, v := range rf.Spec.NodeLabels {
+				if prev, found := values[k]; found && prev != v {
+					conflicts.Insert(k)
+				} else {
+					values[k] = v
+				}
+			}
+			break
+		}
+	}
+	if conflicts.Len() == 0 {
+		return nil
+	}
+	return sets.List(conflicts)
+}
+
+// flavorMatchesNodeSelector returns whether the node labels of the flavor
+// don't contradict the node selector.
+func flavorMatchesNodeSelector(rf *kueue.ResourceFlavor, nodeSelector map[string]string) bool {
+	for k, v := range rf.Spec.NodeLabels {
+		if want, found := nodeSelector[k]; found && want != v {
+			return false
+		}
+	}
+	return true
+}
+
+// podSetNodeSelectors returns, keyed by PodSet name, the node selector that
+// the pods of each PodSet of the admitted workload should carry: the node
+// labels of all the flavors assigned to the PodSet. If two flavors set the same
+// label, the flavor of the resource that sorts first wins.
+func (c *Cache) podSetNodeSelectors(w *kueue.Workload) (map[string]map[string]string, error) {
+	if w.Status.Admission == nil {
+		return nil, errWorkloadNotAdmitted
+	}
+	c.RLock()
+	defer c.RUnlock()
+	selectors := make(map[string]map[string]string, len(w.Status.Admission.PodSetAssignments))
+	for _, psa := range w.Status.Admission.PodSetAssignments {
+		selector := make(map[string]string)
+		for _, rName := range sets.List(sets.KeySet(psa.Flavors)) {
+			fName := psa.Flavors[rName]
+			rf, found := c.resourceFlavors[fName]
+			if !found {
+				return nil, fmt.Errorf("%w: %q assigned to PodSet %q", errFlavorNotFound, fName, psa.Name)
+			}
+			selector = utilmaps.MergeKeepFirst(selector, rf.Spec.NodeLabels)
+		}
+		selectors[psa.Name] = selector
+	}
+	return selectors, nil
+}
+
 func (c *Cache) MatchingClusterQueues(nsLabels map[string]string) sets.Set[string] {
 	c.RLock()
 	defer c.RUnlock()
@@ -752,3 +2055,12 @@ func (c *Cache) MatchingClusterQueues(nsLabels map[string]string) sets.Set[strin
 func queueKey(q *kueue.LocalQueue) string {
 	return fmt.Sprintf("%s/%s", q.Namespace, q.Name)
 }
+
+func podSetByName(w *kueue.Workload, name string) *kueue.PodSet {
+	for i := range w.Spec.PodSets {
+		if w.Spec.PodSets[i].Name == name {
+			return &w.Spec.PodSets[i]
+		}
+	}
+	return nil
+}
@@ -18,24 +18,33 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
 	"sigs.k8s.io/kueue/pkg/features"
+	"sigs.k8s.io/kueue/pkg/metrics"
 	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	testingmetrics "sigs.k8s.io/kueue/pkg/util/testing/metrics"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
 
@@ -1014,7 +1023,8 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					AdmittedUsage:                 FlavorResourceQuantities{"f1": {corev1.ResourceCPU: 1000}},
 					Workloads: map[string]*workload.Info{
 						"ns/reserving": {
-							ClusterQueue: "cq1",
+							ClusterQueue:           "cq1",
+							TerminationGracePeriod: 30 * time.Second,
 							TotalRequests: []workload.PodSetResources{
 								{
 									Name:     "main",
@@ -1027,7 +1037,8 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 							},
 						},
 						"ns/admitted": {
-							ClusterQueue: "cq1",
+							ClusterQueue:           "cq1",
+							TerminationGracePeriod: 30 * time.Second,
 							TotalRequests: []workload.PodSetResources{
 								{
 									Name:     "main",
@@ -1056,7 +1067,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 			if diff := cmp.Diff(tc.wantClusterQueues, cache.clusterQueues,
 				cmpopts.IgnoreFields(ClusterQueue{}, "Cohort", "RGByResource", "ResourceGroups"),
 				cmpopts.IgnoreFields(workload.Info{}, "Obj", "LastAssignment"),
-				cmpopts.IgnoreUnexported(ClusterQueue{}),
+				cmpopts.IgnoreUnexported(ClusterQueue{}, Cohort{}),
 				cmpopts.EquateEmpty()); diff != "" {
 				t.Errorf("Unexpected clusterQueues (-want,+got):\n%s", diff)
 			}
@@ -1992,6 +2003,99 @@ func TestClusterQueueUsage(t *testing.T) {
 	}
 }
 
+func TestUsageIncludingAssumed(t *testing.T) {
+	cq := utiltesting.MakeClusterQueue("foo").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		Cohort("one").Obj()
+	cqWithOutCohort := cq.DeepCopy()
+	cqWithOutCohort.Spec.Cohort = ""
+	committedWl := utiltesting.MakeWorkload("committed", "").
+		Request(corev1.ResourceCPU, "8").
+		ReserveQuota(utiltesting.MakeAdmission("foo").Assignment(corev1.ResourceCPU, "default", "8").Obj()).
+		Obj()
+	assumedWl := utiltesting.MakeWorkload("assumed", "").
+		Request(corev1.ResourceCPU, "4").
+		ReserveQuota(utiltesting.MakeAdmission("foo").Assignment(corev1.ResourceCPU, "default", "4").Obj()).
+		Obj()
+	cpuUsage := func(total, borrowed string) []kueue.FlavorUsage {
+		usage := kueue.ResourceUsage{Name: corev1.ResourceCPU}
+		if total != "" {
+			usage.Total = resource.MustParse(total)
+		}
+		if borrowed != "" {
+			usage.Borrowed = resource.MustParse(borrowed)
+		}
+		return []kueue.FlavorUsage{{Name: "default", Resources: []kueue.ResourceUsage{usage}}}
+	}
+	cases := map[string]struct {
+		clusterQueue  *kueue.ClusterQueue
+		committed     []*kueue.Workload
+		assumed       []*kueue.Workload
+		wantCommitted []kueue.FlavorUsage
+		wantAssumed   []kueue.FlavorUsage
+	}{
+		"only committed workloads": {
+			clusterQueue:  cq,
+			committed:     []*kueue.Workload{committedWl},
+			wantCommitted: cpuUsage("8", ""),
+			wantAssumed:   cpuUsage("", ""),
+		},
+		"only assumed workloads": {
+			clusterQueue:  cq,
+			assumed:       []*kueue.Workload{assumedWl},
+			wantCommitted: cpuUsage("", ""),
+			wantAssumed:   cpuUsage("4", ""),
+		},
+		"assumed workloads borrowing on top of the committed ones": {
+			clusterQueue:  cq,
+			committed:     []*kueue.Workload{committedWl},
+			assumed:       []*kueue.Workload{assumedWl},
+			wantCommitted: cpuUsage("8", ""),
+			wantAssumed:   cpuUsage("4", "2"),
+		},
+		"clusterQueue without cohort": {
+			clusterQueue:  cqWithOutCohort,
+			committed:     []*kueue.Workload{committedWl},
+			assumed:       []*kueue.Workload{assumedWl},
+			wantCommitted: cpuUsage("8", ""),
+			wantAssumed:   cpuUsage("4", ""),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			if err := cache.AddClusterQueue(context.Background(), tc.clusterQueue); err != nil {
+				t.Fatalf("Adding ClusterQueue: %v", err)
+			}
+			for _, w := range tc.committed {
+				if added := cache.AddOrUpdateWorkload(w.DeepCopy()); !added {
+					t.Fatalf("Workload %s was not added", workload.Key(w))
+				}
+			}
+			for _, w := range tc.assumed {
+				if err := cache.AssumeWorkload(w.DeepCopy()); err != nil {
+					t.Fatalf("Assuming workload %s: %v", workload.Key(w), err)
+				}
+			}
+
+			committed, assumed, err := cache.usageIncludingAssumed(tc.clusterQueue.Name)
+			if err != nil {
+				t.Fatalf("Couldn't get usage: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantCommitted, committed); diff != "" {
+				t.Errorf("Unexpected committed resources (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantAssumed, assumed); diff != "" {
+				t.Errorf("Unexpected assumed resources (-want,+got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, _, err := New(utiltesting.NewFakeClient()).usageIncludingAssumed("foo"); !errors.Is(err, errCqNotFound) {
+		t.Errorf("Unexpected error for an unknown ClusterQueue: %v", err)
+	}
+}
+
 func TestLocalQueueUsage(t *testing.T) {
 	cq := *utiltesting.MakeClusterQueue("foo").
 		ResourceGroup(
@@ -3303,3 +3407,1900 @@ func TestClusterQueueReadiness(t *testing.T) {
 		})
 	}
 }
+
+func TestClusterQueueStatusReason(t *testing.T) {
+	baseFlavor := utiltesting.MakeResourceFlavor("flavor1").Obj()
+	baseCheck := utiltesting.MakeAdmissionCheck("check1").Active(metav1.ConditionTrue).Obj()
+	baseQueue := utiltesting.MakeClusterQueue("queue1").
+		ResourceGroup(
+			*utiltesting.MakeFlavorQuotas(baseFlavor.Name).Resource(corev1.ResourceCPU, "10").Obj(),
+			*utiltesting.MakeFlavorQuotas("flavor2").Resource(corev1.ResourceCPU, "10").Obj(),
+		).
+		AdmissionChecks(baseCheck.Name).
+		Obj()
+
+	cases := map[string]struct {
+		clusterQueues    []*kueue.ClusterQueue
+		resourceFlavors  []*kueue.ResourceFlavor
+		admissionChecks  []*kueue.AdmissionCheck
+		clusterQueueName string
+		terminate        bool
+		wantStatus       string
+		wantReason       string
+		wantMessage      string
+	}{
+		"queue not found": {
+			clusterQueueName: "queue1",
+			wantReason:       "NotFound",
+			wantMessage:      "ClusterQueue not found",
+		},
+		"flavor not found": {
+			clusterQueues:    []*kueue.ClusterQueue{baseQueue},
+			resourceFlavors:  []*kueue.ResourceFlavor{baseFlavor},
+			admissionChecks:  []*kueue.AdmissionCheck{baseCheck},
+			clusterQueueName: "queue1",
+			wantStatus:       "pending",
+			wantReason:       "FlavorNotFound",
+			wantMessage:      "Can't admit new workloads: ResourceFlavors not found: flavor2",
+		},
+		"flavor and check not found": {
+			clusterQueues:    []*kueue.ClusterQueue{baseQueue},
+			clusterQueueName: "queue1",
+			wantStatus:       "pending",
+			wantReason:       "FlavorNotFound",
+			wantMessage:      "Can't admit new workloads: ResourceFlavors not found: flavor1, flavor2; AdmissionChecks not found or inactive: check1",
+		},
+		"terminating": {
+			clusterQueues:    []*kueue.ClusterQueue{baseQueue},
+			clusterQueueName: "queue1",
+			terminate:        true,
+			wantStatus:       "terminating",
+			wantReason:       "Terminating",
+			wantMessage:      "Can't admit new workloads; clusterQueue is terminating",
+		},
+		"ready": {
+			clusterQueues: []*kueue.ClusterQueue{baseQueue},
+			resourceFlavors: []*kueue.ResourceFlavor{
+				baseFlavor,
+				utiltesting.MakeResourceFlavor("flavor2").Obj(),
+			},
+			admissionChecks:  []*kueue.AdmissionCheck{baseCheck},
+			clusterQueueName: "queue1",
+			wantStatus:       "active",
+			wantReason:       "Ready",
+			wantMessage:      "Can admit new workloads",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			for _, rf := range tc.resourceFlavors {
+				cache.AddOrUpdateResourceFlavor(rf)
+			}
+			for _, ac := range tc.admissionChecks {
+				cache.AddOrUpdateAdmissionCheck(ac)
+			}
+			for _, cq := range tc.clusterQueues {
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Errorf("failed to add clusterQueue %q: %v", cq.Name, err)
+				}
+			}
+			if tc.terminate {
+				cache.TerminateClusterQueue(tc.clusterQueueName)
+			}
+
+			gotStatus, gotReason, gotMessage := cache.clusterQueueStatusReason(tc.clusterQueueName)
+			if gotStatus != tc.wantStatus {
+				t.Errorf("Unexpected status, got %q, want %q", gotStatus, tc.wantStatus)
+			}
+			if gotReason != tc.wantReason {
+				t.Errorf("Unexpected reason, got %q, want %q", gotReason, tc.wantReason)
+			}
+			if gotMessage != tc.wantMessage {
+				t.Errorf("Unexpected message, got %q, want %q", gotMessage, tc.wantMessage)
+			}
+		})
+	}
+}
+
+func TestValidatePodSetFlavorConsistency(t *testing.T) {
+	flavors := []*kueue.ResourceFlavor{
+		utiltesting.MakeResourceFlavor("east").Label("region", "east").Obj(),
+		utiltesting.MakeResourceFlavor("west").Label("region", "west").Obj(),
+		utiltesting.MakeResourceFlavor("gpu-east").Label("region", "east").Label("gpu", "a100").Obj(),
+	}
+	cases := map[string]struct {
+		admission *kueue.Admission
+		wantErr   string
+	}{
+		"not admitted": {},
+		"same flavor in all podSets": {
+			admission: utiltesting.MakeAdmission("cq").PodSets(
+				kueue.PodSetAssignment{Name: "driver", Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{corev1.ResourceCPU: "east"}},
+				kueue.PodSetAssignment{Name: "workers", Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{corev1.ResourceCPU: "east"}},
+			).Obj(),
+		},
+		"compatible flavors": {
+			admission: utiltesting.MakeAdmission("cq").PodSets(
+				kueue.PodSetAssignment{Name: "driver", Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{corev1.ResourceCPU: "east"}},
+				kueue.PodSetAssignment{Name: "workers", Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{"example.com/gpu": "gpu-east"}},
+			).Obj(),
+		},
+		"unknown flavor is ignored": {
+			admission: utiltesting.MakeAdmission("cq").PodSets(
+				kueue.PodSetAssignment{Name: "driver", Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{corev1.ResourceCPU: "east"}},
+				kueue.PodSetAssignment{Name: "workers", Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{corev1.ResourceCPU: "nonexistent-flavor"}},
+			).Obj(),
+		},
+		"conflicting flavors": {
+			admission: utiltesting.MakeAdmission("cq").PodSets(
+				kueue.PodSetAssignment{Name: "driver", Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{corev1.ResourceCPU: "east"}},
+				kueue.PodSetAssignment{Name: "workers", Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{corev1.ResourceCPU: "west"}},
+			).Obj(),
+			wantErr: `conflicting flavor labels between podSets: podSet "driver" (flavor "east", region=east) and podSet "workers" (flavor "west", region=west)`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			for _, rf := range flavors {
+				cache.AddOrUpdateResourceFlavor(rf)
+			}
+			wl := utiltesting.MakeWorkload("wl", "ns").Obj()
+			if tc.admission != nil {
+				wl.Status.Admission = tc.admission
+			}
+			gotErr := cache.validatePodSetFlavorConsistency(wl)
+			if diff := cmp.Diff(tc.wantErr, messageOrEmpty(gotErr)); diff != "" {
+				t.Errorf("Unexpected error (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDetectLabelConflicts(t *testing.T) {
+	const gpu corev1.ResourceName = "example.com/gpu"
+	cases := map[string]struct {
+		podSet *kueue.PodSet
+		want   []string
+	}{
+		"cpu and gpu flavors in different regions": {
+			podSet: utiltesting.MakePodSet("main", 1).
+				Request(corev1.ResourceCPU, "1").
+				Request(gpu, "1").
+				Obj(),
+			want: []string{"region"},
+		},
+		"only cpu requested": {
+			podSet: utiltesting.MakePodSet("main", 1).
+				Request(corev1.ResourceCPU, "1").
+				Obj(),
+		},
+		"node selector skips the conflicting cpu flavor": {
+			podSet: utiltesting.MakePodSet("main", 1).
+				Request(corev1.ResourceCPU, "1").
+				Request(gpu, "1").
+				NodeSelector(map[string]string{"region": "west"}).
+				Obj(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("cpu-east").Label("region", "east").Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("cpu-west").Label("region", "west").Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("gpu-west").Label("region", "west").Label("gpu", "a100").Obj())
+			cq := utiltesting.MakeClusterQueue("cq").
+				ResourceGroup(
+					*utiltesting.MakeFlavorQuotas("cpu-east").Resource(corev1.ResourceCPU, "10").Obj(),
+					*utiltesting.MakeFlavorQuotas("cpu-west").Resource(corev1.ResourceCPU, "10").Obj(),
+				).
+				ResourceGroup(
+					*utiltesting.MakeFlavorQuotas("gpu-west").Resource(gpu, "4").Obj(),
+				).
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.want, cache.detectLabelConflicts("cq", *tc.podSet)); diff != "" {
+				t.Errorf("Unexpected conflicts (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCohortMembers(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	for _, name := range []string{"e", "b", "d", "a", "c"} {
+		cq := utiltesting.MakeClusterQueue(name).Cohort("one").Obj()
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue %q: %v", name, err)
+		}
+	}
+	if err := cache.AddClusterQueue(context.Background(), utiltesting.MakeClusterQueue("f").Cohort("two").Obj()); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+
+	want := []string{"a", "b", "c", "d", "e"}
+	for i := 0; i < 10; i++ {
+		if diff := cmp.Diff(want, cache.cohortMembers("one")); diff != "" {
+			t.Fatalf("Unexpected cohort members on call %d (-want,+got):\n%s", i, diff)
+		}
+	}
+	if got := cache.cohortMembers("nonexistent"); got != nil {
+		t.Errorf("Unexpected members for nonexistent cohort: %v", got)
+	}
+}
+
+func TestSecondaryCohorts(t *testing.T) {
+	ctx := context.Background()
+	cache := New(utiltesting.NewFakeClient())
+	if err := cache.AddClusterQueue(ctx, utiltesting.MakeClusterQueue("b").Cohort("secondary").Obj()); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	cq := utiltesting.MakeClusterQueue("a").Cohort("primary").Obj()
+	cq.Annotations = map[string]string{constants.SecondaryCohortsAnnotation: "secondary, primary,other"}
+	if err := cache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"primary", "secondary", "other"}, cache.clusterQueues["a"].cohortNames()); diff != "" {
+		t.Errorf("Unexpected cohorts (-want,+got):\n%s", diff)
+	}
+	wantMembers := map[string][]string{
+		"primary":   {"a"},
+		"secondary": {"a", "b"},
+		"other":     {"a"},
+	}
+	for cohort, want := range wantMembers {
+		if diff := cmp.Diff(want, cache.cohortMembers(cohort)); diff != "" {
+			t.Errorf("Unexpected members of cohort %q (-want,+got):\n%s", cohort, diff)
+		}
+	}
+	snapshot := cache.Snapshot()
+	snapCQ := snapshot.ClusterQueues["a"]
+	if snapCQ.Cohort.Name != "primary" || len(snapCQ.SecondaryCohorts) != 2 || snapCQ.SecondaryCohorts[0] != snapshot.ClusterQueues["b"].Cohort {
+		t.Errorf("Unexpected cohorts in the snapshot: %v, %v", snapCQ.Cohort, snapCQ.SecondaryCohorts)
+	}
+
+	cq.Annotations = nil
+	if err := cache.UpdateClusterQueue(cq); err != nil {
+		t.Fatalf("Failed updating ClusterQueue: %v", err)
+	}
+	if diff := cmp.Diff([]string{"primary"}, cache.clusterQueues["a"].cohortNames()); diff != "" {
+		t.Errorf("Unexpected cohorts after the update (-want,+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"b"}, cache.cohortMembers("secondary")); diff != "" {
+		t.Errorf("Unexpected members of the secondary cohort after the update (-want,+got):\n%s", diff)
+	}
+	if got := cache.cohortMembers("other"); got != nil {
+		t.Errorf("Unexpected members of an abandoned cohort: %v", got)
+	}
+
+	cache.DeleteClusterQueue(cq)
+	if got := cache.cohortMembers("primary"); got != nil {
+		t.Errorf("Unexpected members of the primary cohort after deleting the ClusterQueue: %v", got)
+	}
+}
+
+func TestCohortFreeCapacity(t *testing.T) {
+	ctx := context.Background()
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+	clusterQueues := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("a").
+			ResourceGroup(
+				*utiltesting.MakeFlavorQuotas("on-demand").Resource(corev1.ResourceCPU, "4", "2").Obj(),
+				*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "5").Obj(),
+			).
+			Cohort("one").
+			Obj(),
+		utiltesting.MakeClusterQueue("b").
+			ResourceGroup(
+				*utiltesting.MakeFlavorQuotas("on-demand").
+					Resource(corev1.ResourceCPU, "2").
+					Resource(corev1.ResourceMemory, "2Gi").
+					Obj(),
+				*utiltesting.MakeFlavorQuotas("spot").
+					Resource(corev1.ResourceCPU, "5").
+					Resource(corev1.ResourceMemory, "4Gi").
+					Obj(),
+			).
+			Cohort("one").
+			Obj(),
+		utiltesting.MakeClusterQueue("c").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "10").Obj()).
+			Cohort("two").
+			Obj(),
+	}
+	for _, cq := range clusterQueues {
+		if err := cache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("borrowing", "ns").
+			Request(corev1.ResourceCPU, "6").
+			ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "on-demand", "6").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("spot", "ns").
+			Request(corev1.ResourceCPU, "3").
+			Request(corev1.ResourceMemory, "1Gi").
+			ReserveQuota(utiltesting.MakeAdmission("b").
+				Assignment(corev1.ResourceCPU, "spot", "3").
+				Assignment(corev1.ResourceMemory, "spot", "1Gi").
+				Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("other-cohort", "ns").
+			Request(corev1.ResourceCPU, "1").
+			ReserveQuota(utiltesting.MakeAdmission("c").Assignment(corev1.ResourceCPU, "spot", "1").Obj()).
+			Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %q", wl.Name)
+		}
+	}
+
+	got, err := cache.cohortFreeCapacity("one")
+	if err != nil {
+		t.Fatalf("Failed getting the free capacity: %v", err)
+	}
+	// The cpu in the on-demand flavor is saturated, its free capacity is all in spot.
+	want := Resources{
+		corev1.ResourceCPU:    7_000,
+		corev1.ResourceMemory: 5 * utiltesting.Gi,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected free capacity (-want,+got):\n%s", diff)
+	}
+
+	if _, err := cache.cohortFreeCapacity("nonexistent"); !errors.Is(err, errCohortNotFound) {
+		t.Errorf("Unexpected error for nonexistent cohort, got %v, want %v", err, errCohortNotFound)
+	}
+}
+
+func TestCohortResources(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("gpu").Obj())
+	a := utiltesting.MakeClusterQueue("a").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+			Resource(corev1.ResourceCPU, "4").
+			Resource(corev1.ResourceMemory, "4Gi").
+			Obj()).
+		Cohort("one").
+		Obj()
+	b := utiltesting.MakeClusterQueue("b").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+		Cohort("one").
+		Obj()
+	for _, cq := range []*kueue.ClusterQueue{a, b} {
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	if diff := cmp.Diff(sets.New(corev1.ResourceCPU, corev1.ResourceMemory), cache.cohortResources("one")); diff != "" {
+		t.Errorf("Unexpected resources (-want,+got):\n%s", diff)
+	}
+
+	b = utiltesting.MakeClusterQueue("b").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("gpu").Resource("example.com/gpu", "2").Obj()).
+		Cohort("one").
+		Obj()
+	if err := cache.UpdateClusterQueue(b); err != nil {
+		t.Fatalf("Failed updating ClusterQueue: %v", err)
+	}
+	if diff := cmp.Diff(sets.New[corev1.ResourceName](corev1.ResourceCPU, corev1.ResourceMemory, "example.com/gpu"), cache.cohortResources("one")); diff != "" {
+		t.Errorf("Unexpected resources after adding a gpu resource (-want,+got):\n%s", diff)
+	}
+
+	if got := cache.cohortResources("unknown"); got != nil {
+		t.Errorf("Unexpected resources for an unknown cohort: %v", got)
+	}
+}
+
+func TestCohortsBorrowing(t *testing.T) {
+	const gpu corev1.ResourceName = "example.com/gpu"
+	cache := New(utiltesting.NewFakeClient())
+	for _, name := range []string{"a100", "h100", "default"} {
+		cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor(name).Obj())
+	}
+	makeGPUQueue := func(name, cohort string) *kueue.ClusterQueue {
+		return utiltesting.MakeClusterQueue(name).
+			ResourceGroup(
+				*utiltesting.MakeFlavorQuotas("a100").Resource(gpu, "2").Obj(),
+				*utiltesting.MakeFlavorQuotas("h100").Resource(gpu, "2").Obj(),
+			).
+			Cohort(cohort).
+			Obj()
+	}
+	clusterQueues := []*kueue.ClusterQueue{
+		makeGPUQueue("a", "one"),
+		makeGPUQueue("b", "one"),
+		makeGPUQueue("c", "two"),
+		makeGPUQueue("d", "two"),
+		utiltesting.MakeClusterQueue("e").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+			Cohort("cpu-only").
+			Obj(),
+	}
+	for _, cq := range clusterQueues {
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("a-a100", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("a").Assignment(gpu, "a100", "4").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("a-h100", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("a").Assignment(gpu, "h100", "3").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("c-a100", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("c").Assignment(gpu, "a100", "2").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("e-cpu", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("e").Assignment(corev1.ResourceCPU, "default", "4").Obj()).
+			Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %q", wl.Name)
+		}
+	}
+
+	cases := map[string]struct {
+		resource corev1.ResourceName
+		want     map[string]int64
+	}{
+		"gpu": {
+			resource: gpu,
+			// The cohort one borrows 2 a100 and 1 h100, the cohort two
+			// doesn't borrow.
+			want: map[string]int64{"one": 3, "two": 0},
+		},
+		"cpu": {
+			resource: corev1.ResourceCPU,
+			want:     map[string]int64{"cpu-only": 0},
+		},
+		"resource without quota": {
+			resource: corev1.ResourceMemory,
+			want:     map[string]int64{},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, cache.cohortsBorrowing(tc.resource)); diff != "" {
+				t.Errorf("Unexpected borrowing (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCohortOversubscribed(t *testing.T) {
+	ctx := context.Background()
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("unobserved").Obj())
+	clusterQueues := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("a").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+				Resource(corev1.ResourceCPU, "4", "4").
+				Resource(corev1.ResourceMemory, "4Gi", "1Gi").
+				Obj()).
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("unobserved").Resource("example.com/gpu", "8").Obj()).
+			Cohort("one").
+			Obj(),
+		utiltesting.MakeClusterQueue("b").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+				Resource(corev1.ResourceCPU, "4", "4").
+				Resource(corev1.ResourceMemory, "4Gi", "1Gi").
+				Obj()).
+			Cohort("one").
+			Obj(),
+	}
+	for _, cq := range clusterQueues {
+		if err := cache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	// The maximum cpu quotas add up to 16, the maximum memory quotas to 10Gi.
+	cache.SetFlavorObservedCapacity("default", Resources{
+		corev1.ResourceCPU:    12_000,
+		corev1.ResourceMemory: 12 * utiltesting.Gi,
+	})
+
+	want := map[corev1.ResourceName]bool{
+		corev1.ResourceCPU:    true,
+		corev1.ResourceMemory: false,
+	}
+	if diff := cmp.Diff(want, cache.cohortOversubscribed("one")); diff != "" {
+		t.Errorf("Unexpected oversubscribed resources (-want,+got):\n%s", diff)
+	}
+	if got := cache.cohortOversubscribed("nonexistent"); got != nil {
+		t.Errorf("Unexpected oversubscribed resources for nonexistent cohort: %v", got)
+	}
+}
+
+func TestSafeBorrowableCapacity(t *testing.T) {
+	ctx := context.Background()
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	clusterQueues := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("borrower").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "2").Obj()).
+			Cohort("one").
+			Obj(),
+		utiltesting.MakeClusterQueue("idle").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+			Cohort("one").
+			Obj(),
+		utiltesting.MakeClusterQueue("busy").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+				Resource(corev1.ResourceCPU, "6").
+				Resource(corev1.ResourceMemory, "4Gi").
+				Obj()).
+			Cohort("one").
+			Obj(),
+		utiltesting.MakeClusterQueue("alone").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "2").Obj()).
+			Obj(),
+	}
+	for _, cq := range clusterQueues {
+		if err := cache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		Request(corev1.ResourceCPU, "1").
+		ReserveQuota(utiltesting.MakeAdmission("busy").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(wl) {
+		t.Fatalf("Failed adding workload")
+	}
+
+	// Memory is not covered by the borrower, so it's not reported.
+	if diff := cmp.Diff(Resources{corev1.ResourceCPU: 9_000}, cache.safeBorrowableCapacity("borrower")); diff != "" {
+		t.Errorf("Unexpected capacity without pending demand (-want,+got):\n%s", diff)
+	}
+
+	cache.SetPendingDemand("busy", workload.Requests{corev1.ResourceCPU: 3_000})
+	if diff := cmp.Diff(Resources{corev1.ResourceCPU: 6_000}, cache.safeBorrowableCapacity("borrower")); diff != "" {
+		t.Errorf("Unexpected capacity with pending demand in a peer (-want,+got):\n%s", diff)
+	}
+
+	cache.SetPendingDemand("busy", workload.Requests{corev1.ResourceCPU: 8_000})
+	if diff := cmp.Diff(Resources{corev1.ResourceCPU: 4_000}, cache.safeBorrowableCapacity("borrower")); diff != "" {
+		t.Errorf("Unexpected capacity with pending demand exceeding the peer quota (-want,+got):\n%s", diff)
+	}
+
+	cache.SetPendingDemand("busy", nil)
+	if diff := cmp.Diff(Resources{corev1.ResourceCPU: 9_000}, cache.safeBorrowableCapacity("borrower")); diff != "" {
+		t.Errorf("Unexpected capacity after clearing the pending demand (-want,+got):\n%s", diff)
+	}
+
+	if got := cache.safeBorrowableCapacity("alone"); got != nil {
+		t.Errorf("Unexpected capacity for a ClusterQueue without cohort: %v", got)
+	}
+	if got := cache.safeBorrowableCapacity("nonexistent"); got != nil {
+		t.Errorf("Unexpected capacity for a nonexistent ClusterQueue: %v", got)
+	}
+}
+
+func TestOrphanedFlavorUsage(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("e").
+		ResourceGroup(
+			*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj(),
+			*utiltesting.MakeFlavorQuotas("nonexistent-flavor").Resource(corev1.ResourceCPU, "10").Obj(),
+		).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+
+	if got := cache.orphanedFlavorUsage("e"); got != nil {
+		t.Errorf("Unexpected orphaned usage without workloads: %v", got)
+	}
+
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("on-default", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("e").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("on-nonexistent", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("e").Assignment(corev1.ResourceCPU, "nonexistent-flavor", "3").Obj()).
+			Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %q", wl.Name)
+		}
+	}
+	want := map[corev1.ResourceName]map[string]int64{
+		corev1.ResourceCPU: {"nonexistent-flavor": 3_000},
+	}
+	if diff := cmp.Diff(want, cache.orphanedFlavorUsage("e")); diff != "" {
+		t.Errorf("Unexpected orphaned usage (-want,+got):\n%s", diff)
+	}
+
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("nonexistent-flavor").Obj())
+	if got := cache.orphanedFlavorUsage("e"); got != nil {
+		t.Errorf("Unexpected orphaned usage after the flavor was created: %v", got)
+	}
+	if got := cache.orphanedFlavorUsage("nonexistent"); got != nil {
+		t.Errorf("Unexpected orphaned usage for a nonexistent ClusterQueue: %v", got)
+	}
+}
+
+func TestRequiredQuotaIncrease(t *testing.T) {
+	cases := map[string]struct {
+		flavors  []kueue.FlavorQuotas
+		disabled []string
+		wl       *kueue.Workload
+		want     FlavorResourceQuantities
+	}{
+		"fits": {
+			flavors: []kueue.FlavorQuotas{
+				*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj(),
+			},
+			wl:   utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, "4").Obj(),
+			want: FlavorResourceQuantities{},
+		},
+		"needs 4 more cpu": {
+			flavors: []kueue.FlavorQuotas{
+				*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj(),
+			},
+			wl: utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, "8").Obj(),
+			want: FlavorResourceQuantities{
+				"default": {corev1.ResourceCPU: 4_000},
+			},
+		},
+		"counts all the podSets": {
+			flavors: []kueue.FlavorQuotas{
+				*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj(),
+			},
+			wl: utiltesting.MakeWorkload("wl", "ns").
+				PodSets(
+					*utiltesting.MakePodSet("driver", 1).Request(corev1.ResourceCPU, "2").Obj(),
+					*utiltesting.MakePodSet("workers", 3).Request(corev1.ResourceCPU, "2").Obj(),
+				).
+				Obj(),
+			want: FlavorResourceQuantities{
+				"default": {corev1.ResourceCPU: 4_000},
+			},
+		},
+		"picks the flavor needing the smallest increase": {
+			flavors: []kueue.FlavorQuotas{
+				*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj(),
+				*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "7").Obj(),
+			},
+			wl: utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, "8").Obj(),
+			want: FlavorResourceQuantities{
+				"spot": {corev1.ResourceCPU: 1_000},
+			},
+		},
+		"fits in the second flavor": {
+			flavors: []kueue.FlavorQuotas{
+				*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj(),
+				*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "8").Obj(),
+			},
+			wl:   utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, "8").Obj(),
+			want: FlavorResourceQuantities{},
+		},
+		"skips disabled flavors": {
+			flavors: []kueue.FlavorQuotas{
+				*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj(),
+				*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "8").Obj(),
+			},
+			disabled: []string{"spot"},
+			wl:       utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, "8").Obj(),
+			want: FlavorResourceQuantities{
+				"default": {corev1.ResourceCPU: 4_000},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			for _, flvQuotas := range tc.flavors {
+				rf := utiltesting.MakeResourceFlavor(string(flvQuotas.Name)).Obj()
+				if slices.Contains(tc.disabled, rf.Name) {
+					rf.Annotations = map[string]string{constants.ResourceFlavorDisabledAnnotation: "true"}
+				}
+				cache.AddOrUpdateResourceFlavor(rf)
+			}
+			cq := utiltesting.MakeClusterQueue("cq").ResourceGroup(tc.flavors...).Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			running := utiltesting.MakeWorkload("running", "ns").
+				ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "6").Obj()).
+				Obj()
+			if !cache.AddOrUpdateWorkload(running) {
+				t.Fatal("Failed adding workload")
+			}
+
+			if diff := cmp.Diff(tc.want, cache.requiredQuotaIncrease(tc.wl, "cq")); diff != "" {
+				t.Errorf("Unexpected quota increase (-want,+got):\n%s", diff)
+			}
+		})
+	}
+	if got := New(utiltesting.NewFakeClient()).requiredQuotaIncrease(utiltesting.MakeWorkload("wl", "ns").Obj(), "nonexistent"); got != nil {
+		t.Errorf("Unexpected quota increase for a nonexistent ClusterQueue: %v", got)
+	}
+}
+
+func TestUnmanagedResources(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+			Resource(corev1.ResourceCPU, "10").
+			Resource(corev1.ResourceMemory, "10Gi").
+			Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+
+	cases := map[string]struct {
+		workload *kueue.Workload
+		cqName   string
+		want     []corev1.ResourceName
+	}{
+		"declared resources only": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				Request(corev1.ResourceCPU, "1").
+				Request(corev1.ResourceMemory, "1Gi").
+				Obj(),
+			cqName: "cq",
+		},
+		"undeclared resources": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				PodSets(
+					*utiltesting.MakePodSet("main", 1).
+						Request(corev1.ResourceCPU, "1").
+						Request("example.com/gpu", "1").
+						Obj(),
+					*utiltesting.MakePodSet("workers", 2).
+						Request(corev1.ResourceEphemeralStorage, "1Gi").
+						Request("example.com/gpu", "2").
+						Obj(),
+				).
+				Obj(),
+			cqName: "cq",
+			want:   []corev1.ResourceName{corev1.ResourceEphemeralStorage, "example.com/gpu"},
+		},
+		"nonexistent ClusterQueue": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				Request("example.com/gpu", "1").
+				Obj(),
+			cqName: "nonexistent",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := cache.unmanagedResources(tc.workload, tc.cqName)
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Unexpected unmanaged resources (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClusterQueueStatus(t *testing.T) {
+	ctx := context.Background()
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	clusterQueues := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("foo").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "5").Obj()).
+			Cohort("one").
+			Obj(),
+		utiltesting.MakeClusterQueue("bar").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "5").Obj()).
+			Cohort("one").
+			StopPolicy(kueue.Hold).
+			Obj(),
+	}
+	for _, cq := range clusterQueues {
+		if err := cache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("one", "ns").
+			Request(corev1.ResourceCPU, "4").
+			ReserveQuota(utiltesting.MakeAdmission("foo").Assignment(corev1.ResourceCPU, "default", "4").Obj()).
+			Admitted(true).
+			Obj(),
+		utiltesting.MakeWorkload("two", "ns").
+			Request(corev1.ResourceCPU, "3").
+			ReserveQuota(utiltesting.MakeAdmission("foo").Assignment(corev1.ResourceCPU, "default", "3").Obj()).
+			Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %q", wl.Name)
+		}
+	}
+
+	cases := map[string]struct {
+		cqName     string
+		wantStatus ClusterQueueStatus
+		wantErr    error
+	}{
+		"borrowing queue": {
+			cqName: "foo",
+			wantStatus: ClusterQueueStatus{
+				Status:          active,
+				ConditionStatus: metav1.ConditionTrue,
+				Reason:          "Ready",
+				Message:         "Can admit new workloads",
+				ReservedResources: []kueue.FlavorUsage{{
+					Name: "default",
+					Resources: []kueue.ResourceUsage{{
+						Name:     corev1.ResourceCPU,
+						Total:    resource.MustParse("7"),
+						Borrowed: resource.MustParse("2"),
+					}},
+				}},
+				ReservingWorkloads: 2,
+				AdmittedResources: []kueue.FlavorUsage{{
+					Name: "default",
+					Resources: []kueue.ResourceUsage{{
+						Name:  corev1.ResourceCPU,
+						Total: resource.MustParse("4"),
+					}},
+				}},
+				AdmittedWorkloads:            1,
+				ReservedNotAdmittedWorkloads: 1,
+				BorrowedResources: FlavorResourceQuantities{
+					"default": {corev1.ResourceCPU: 2_000},
+				},
+			},
+		},
+		"stopped queue": {
+			cqName: "bar",
+			wantStatus: ClusterQueueStatus{
+				Status:          pending,
+				Stopped:         true,
+				ConditionStatus: metav1.ConditionFalse,
+				Reason:          "Stopped",
+				Message:         "Can't admit new workloads: Stopped",
+				ReservedResources: []kueue.FlavorUsage{{
+					Name:      "default",
+					Resources: []kueue.ResourceUsage{{Name: corev1.ResourceCPU}},
+				}},
+				AdmittedResources: []kueue.FlavorUsage{{
+					Name:      "default",
+					Resources: []kueue.ResourceUsage{{Name: corev1.ResourceCPU}},
+				}},
+				BorrowedResources: FlavorResourceQuantities{},
+			},
+		},
+		"unknown queue": {
+			cqName:  "baz",
+			wantErr: errCqNotFound,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := cache.ClusterQueueStatus(tc.cqName)
+			if diff := cmp.Diff(tc.wantErr, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Unexpected error (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantStatus, got); diff != "" {
+				t.Errorf("Unexpected status (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeleteClusterQueueWithWorkloads(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cqs := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("a").
+			Cohort("cohort").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("b").
+			Cohort("cohort").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+			Obj(),
+	}
+	for _, cq := range cqs {
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue %q: %v", cq.Name, err)
+		}
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("two", "ns").ReserveQuota(utiltesting.MakeAdmission("a").Obj()).Obj(),
+		utiltesting.MakeWorkload("one", "ns").ReserveQuota(utiltesting.MakeAdmission("a").Obj()).Admitted(true).Obj(),
+		utiltesting.MakeWorkload("other", "ns").ReserveQuota(utiltesting.MakeAdmission("b").Obj()).Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %s", workload.Key(wl))
+		}
+	}
+
+	gotKeys := cache.DeleteClusterQueue(cqs[0])
+	if diff := cmp.Diff([]string{"ns/one", "ns/two"}, gotKeys); diff != "" {
+		t.Errorf("Unexpected workload keys (-want,+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"b"}, cache.cohortMembers("cohort")); diff != "" {
+		t.Errorf("Unexpected cohort members (-want,+got):\n%s", diff)
+	}
+	if gotKeys := cache.DeleteClusterQueue(cqs[0]); gotKeys != nil {
+		t.Errorf("Unexpected workload keys deleting an unknown ClusterQueue: %v", gotKeys)
+	}
+}
+
+func TestClearWorkloadState(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	if err := cache.AddLocalQueue(utiltesting.MakeLocalQueue("lq", "ns").ClusterQueue("cq").Obj()); err != nil {
+		t.Fatalf("Failed adding LocalQueue: %v", err)
+	}
+	admitted := utiltesting.MakeWorkload("wl", "ns").Queue("lq").ReserveQuota(utiltesting.MakeAdmission("cq").Obj()).Obj()
+	if err := cache.AssumeWorkload(admitted); err != nil {
+		t.Fatalf("Failed assuming the workload: %v", err)
+	}
+	// The workload is preempted and fails to be admitted again.
+	if err := cache.DeleteWorkload(admitted); err != nil {
+		t.Fatalf("Failed evicting the workload: %v", err)
+	}
+	pending := utiltesting.MakeWorkload("wl", "ns").Queue("lq").Obj()
+	cache.RequeuePreempted(pending)
+	cache.RecordAdmissionFailure(pending)
+	if cache.preemptionCount(pending) == 0 || cache.RequeueAfter(pending) == 0 || cache.workloadTransitions(pending) == nil {
+		t.Fatal("The cache didn't record the state of the pending workload")
+	}
+
+	// The pending workload is deleted, not holding quota, and recreated.
+	if err := cache.DeleteWorkload(pending); err == nil {
+		t.Error("Expected an error deleting a workload without quota reservation")
+	}
+	cache.ClearWorkloadState(pending)
+	recreated := utiltesting.MakeWorkload("wl", "ns").Queue("lq").Obj()
+	if got := cache.preemptionCount(recreated); got != 0 {
+		t.Errorf("Unexpected preemption count of the recreated workload: %d", got)
+	}
+	if got := cache.RequeueAfter(recreated); got != 0 {
+		t.Errorf("Unexpected admission backoff of the recreated workload: %v", got)
+	}
+	if got := cache.workloadTransitions(recreated); got != nil {
+		t.Errorf("Unexpected history of the recreated workload: %v", got)
+	}
+	if len(cache.queuedAt) != 0 {
+		t.Errorf("Unexpected queue times after the workload was deleted: %v", cache.queuedAt)
+	}
+}
+
+func TestResourceSubstitution(t *testing.T) {
+	fastCPU := corev1.ResourceName("example.com/fast-cpu")
+	cache := New(utiltesting.NewFakeClient(), WithResourceSubstitutions(map[corev1.ResourceName][]corev1.ResourceName{
+		corev1.ResourceCPU: {fastCPU},
+	}))
+	if diff := cmp.Diff([]corev1.ResourceName{fastCPU}, cache.substitutableFlavors(corev1.ResourceCPU)); diff != "" {
+		t.Errorf("Unexpected substitutes for cpu (-want,+got):\n%s", diff)
+	}
+	if got := cache.substitutableFlavors(corev1.ResourceMemory); got != nil {
+		t.Errorf("Unexpected substitutes for memory: %v", got)
+	}
+
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("fast").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(
+			*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "2").Resource(fastCPU, "0").Obj(),
+			*utiltesting.MakeFlavorQuotas("fast").Resource(corev1.ResourceCPU, "2").Resource(fastCPU, "4").Obj(),
+		).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	// The flavor covers both cpu and its substitute; the usage is charged to
+	// the resource recorded in the admission.
+	substituted := utiltesting.MakeWorkload("substituted", "ns").
+		Request(corev1.ResourceCPU, "3").
+		ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(fastCPU, "fast", "3").Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(substituted) {
+		t.Fatalf("Failed adding workload")
+	}
+	regular := utiltesting.MakeWorkload("regular", "ns").
+		Request(corev1.ResourceCPU, "1").
+		ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "fast", "1").Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(regular) {
+		t.Fatalf("Failed adding workload")
+	}
+	wantUsage := FlavorResourceQuantities{
+		"default": {corev1.ResourceCPU: 0, fastCPU: 0},
+		"fast":    {corev1.ResourceCPU: 1_000, fastCPU: 3},
+	}
+	if diff := cmp.Diff(wantUsage, cache.clusterQueues["cq"].Usage); diff != "" {
+		t.Errorf("Unexpected usage (-want,+got):\n%s", diff)
+	}
+	snapshot := cache.Snapshot()
+	if diff := cmp.Diff(wantUsage, snapshot.ClusterQueues["cq"].Usage); diff != "" {
+		t.Errorf("Unexpected snapshot usage (-want,+got):\n%s", diff)
+	}
+}
+
+func TestFlavorTopologyKey(t *testing.T) {
+	cases := map[string]struct {
+		annotations []map[string]string
+		deleted     bool
+		wantKey     string
+	}{
+		"unset": {
+			annotations: []map[string]string{nil},
+			wantKey:     "",
+		},
+		"set": {
+			annotations: []map[string]string{
+				{constants.ResourceFlavorTopologyKeyAnnotation: "topology.kubernetes.io/zone"},
+			},
+			wantKey: "topology.kubernetes.io/zone",
+		},
+		"updated": {
+			annotations: []map[string]string{
+				{constants.ResourceFlavorTopologyKeyAnnotation: "topology.kubernetes.io/zone"},
+				{constants.ResourceFlavorTopologyKeyAnnotation: "topology.kubernetes.io/region"},
+			},
+			wantKey: "topology.kubernetes.io/region",
+		},
+		"removed": {
+			annotations: []map[string]string{
+				{constants.ResourceFlavorTopologyKeyAnnotation: "topology.kubernetes.io/zone"},
+				nil,
+			},
+			wantKey: "",
+		},
+		"invalid key": {
+			annotations: []map[string]string{
+				{constants.ResourceFlavorTopologyKeyAnnotation: "not a/valid/key"},
+			},
+			wantKey: "",
+		},
+		"flavor deleted": {
+			annotations: []map[string]string{
+				{constants.ResourceFlavorTopologyKeyAnnotation: "topology.kubernetes.io/zone"},
+			},
+			deleted: true,
+			wantKey: "",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			var rf *kueue.ResourceFlavor
+			for _, annotations := range tc.annotations {
+				rf = utiltesting.MakeResourceFlavor("default").Obj()
+				rf.Annotations = annotations
+				cache.AddOrUpdateResourceFlavor(rf)
+			}
+			if tc.deleted {
+				cache.DeleteResourceFlavor(rf)
+			}
+			if got := cache.flavorTopologyKey("default"); got != tc.wantKey {
+				t.Errorf("Unexpected topology key, got %q, want %q", got, tc.wantKey)
+			}
+			if got := cache.flavorTopologyKey("other"); got != "" {
+				t.Errorf("Unexpected topology key for another flavor: %q", got)
+			}
+		})
+	}
+}
+
+func TestWorkloadsSince(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	admission := utiltesting.MakeAdmission("cq").Obj()
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("a", "ns").ResourceVersion("12").ReserveQuota(admission).Admitted(true).Obj(),
+		utiltesting.MakeWorkload("b", "ns").ResourceVersion("5").ReserveQuota(admission).Admitted(true).Obj(),
+		utiltesting.MakeWorkload("c", "ns").ResourceVersion("abc").ReserveQuota(admission).Admitted(true).Obj(),
+		utiltesting.MakeWorkload("reserved", "ns").ResourceVersion("30").ReserveQuota(admission).Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %s", workload.Key(wl))
+		}
+	}
+
+	cases := map[string]struct {
+		cqName   string
+		seen     map[string]string
+		wantKeys []string
+	}{
+		"first sync": {
+			cqName:   "cq",
+			wantKeys: []string{"ns/a", "ns/b", "ns/c"},
+		},
+		"some workloads seen": {
+			cqName:   "cq",
+			seen:     map[string]string{"ns/b": "5"},
+			wantKeys: []string{"ns/a", "ns/c"},
+		},
+		"workloads updated since seen": {
+			cqName:   "cq",
+			seen:     map[string]string{"ns/a": "12", "ns/b": "7", "ns/c": "abd"},
+			wantKeys: []string{"ns/b", "ns/c"},
+		},
+		"resource versions are not ordered": {
+			cqName:   "cq",
+			seen:     map[string]string{"ns/a": "100", "ns/b": "5", "ns/c": "abc"},
+			wantKeys: []string{"ns/a"},
+		},
+		"all workloads seen": {
+			cqName: "cq",
+			seen:   map[string]string{"ns/a": "12", "ns/b": "5", "ns/c": "abc"},
+		},
+		"unknown ClusterQueue": {
+			cqName: "unknown",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var gotKeys []string
+			for _, wi := range cache.workloadsSince(tc.cqName, tc.seen) {
+				gotKeys = append(gotKeys, workload.Key(wi.Obj))
+			}
+			if diff := cmp.Diff(tc.wantKeys, gotKeys); diff != "" {
+				t.Errorf("Unexpected workloads (-want,+got):\n%s", diff)
+			}
+		})
+	}
+
+	infos := cache.workloadsSince("cq", nil)
+	infos[0].Obj.ResourceVersion = "13"
+	if again := cache.workloadsSince("cq", map[string]string{"ns/a": "12"}); len(again) != 2 {
+		t.Error("Modifying the returned workloads changed the cache")
+	}
+}
+
+func TestAllAdmittedWorkloads(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	for _, name := range []string{"b", "a"} {
+		cq := utiltesting.MakeClusterQueue(name).
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+			Obj()
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("w2", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+			Admitted(true).
+			Obj(),
+		utiltesting.MakeWorkload("w1", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("b").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+			Admitted(true).
+			Obj(),
+		utiltesting.MakeWorkload("w1", "other").
+			ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+			Admitted(true).
+			Obj(),
+		utiltesting.MakeWorkload("reserving", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+			Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %q", wl.Name)
+		}
+	}
+
+	type queuedWorkload struct {
+		ClusterQueue string
+		Key          string
+	}
+	infos := cache.allAdmittedWorkloads()
+	got := make([]queuedWorkload, len(infos))
+	for i, wi := range infos {
+		got[i] = queuedWorkload{ClusterQueue: wi.ClusterQueue, Key: workload.Key(wi.Obj)}
+	}
+	want := []queuedWorkload{
+		{ClusterQueue: "a", Key: "ns/w2"},
+		{ClusterQueue: "a", Key: "other/w1"},
+		{ClusterQueue: "b", Key: "ns/w1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected workloads (-want,+got):\n%s", diff)
+	}
+
+	infos[0].Obj.Name = "changed"
+	infos[0].TotalRequests[0].Requests[corev1.ResourceCPU] = 0
+	again := cache.allAdmittedWorkloads()
+	if again[0].Obj.Name != "w2" || again[0].TotalRequests[0].Requests[corev1.ResourceCPU] != 1_000 {
+		t.Error("Modifying the returned workloads changed the cache")
+	}
+}
+
+func TestGetClusterQueue(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10", "5").Obj()).
+		Cohort("one").
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "2").Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(wl) {
+		t.Fatalf("Failed adding workload %q", wl.Name)
+	}
+
+	if _, found := cache.getClusterQueue("unknown"); found {
+		t.Error("Found an unknown ClusterQueue")
+	}
+
+	got, found := cache.getClusterQueue("cq")
+	if !found {
+		t.Fatal("ClusterQueue not found")
+	}
+	if got.Cohort == nil || got.Cohort.Name != "one" {
+		t.Errorf("Unexpected cohort %v, want one", got.Cohort)
+	}
+	if got.Status != active {
+		t.Errorf("Unexpected status %v, want %v", got.Status, active)
+	}
+	wantUsage := FlavorResourceQuantities{"default": {corev1.ResourceCPU: 2_000}}
+	if diff := cmp.Diff(wantUsage, got.Usage); diff != "" {
+		t.Errorf("Unexpected usage (-want,+got):\n%s", diff)
+	}
+	if _, found := got.Workloads["ns/wl"]; !found {
+		t.Error("Workload ns/wl not found in the copy")
+	}
+
+	got.Usage["default"][corev1.ResourceCPU] = 0
+	got.RGByResource[corev1.ResourceCPU].Flavors[0].Resources[corev1.ResourceCPU].Nominal = 0
+	*got.ResourceGroups[0].Flavors[0].Resources[corev1.ResourceCPU].BorrowingLimit = 0
+	got.Workloads["ns/wl"].Obj.Name = "changed"
+	delete(got.Workloads, "ns/wl")
+	got.Cohort.Name = "changed"
+
+	again, _ := cache.getClusterQueue("cq")
+	rQuota := again.ResourceGroups[0].Flavors[0].Resources[corev1.ResourceCPU]
+	if again.Usage["default"][corev1.ResourceCPU] != 2_000 ||
+		rQuota.Nominal != 10_000 ||
+		*rQuota.BorrowingLimit != 5_000 ||
+		again.Workloads["ns/wl"] == nil ||
+		again.Workloads["ns/wl"].Obj.Name != "wl" ||
+		again.Cohort.Name != "one" {
+		t.Error("Modifying the returned ClusterQueue changed the cache")
+	}
+}
+
+func TestWorkloadsForOwner(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	for _, name := range []string{"a", "b"} {
+		cq := utiltesting.MakeClusterQueue(name).
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+			Obj()
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	jobGVK := batchv1.SchemeGroupVersion.WithKind("Job")
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("w2", "ns").
+			ControllerReference(jobGVK, "job", "job-uid").
+			ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("w1", "ns").
+			OwnerReference(jobGVK, "job", "job-uid").
+			ReserveQuota(utiltesting.MakeAdmission("b").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("other", "ns").
+			ControllerReference(jobGVK, "other-job", "other-uid").
+			ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+			Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %q", wl.Name)
+		}
+	}
+
+	cases := map[string]struct {
+		ownerUID types.UID
+		want     []string
+	}{
+		"shared owner": {
+			ownerUID: "job-uid",
+			want:     []string{"ns/w1", "ns/w2"},
+		},
+		"different owner": {
+			ownerUID: "other-uid",
+			want:     []string{"ns/other"},
+		},
+		"unknown owner": {
+			ownerUID: "unknown-uid",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			for _, wi := range cache.workloadsForOwner(tc.ownerUID) {
+				got = append(got, workload.Key(wi.Obj))
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected workloads (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOnClusterQueueUsageChanged(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	for _, name := range []string{"a", "b"} {
+		cq := utiltesting.MakeClusterQueue(name).
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+			Obj()
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	var got []string
+	cache.OnClusterQueueUsageChanged = func(cqName string) {
+		// Reading the cache would deadlock if the lock was still held.
+		cache.ClusterQueueEmpty(cqName)
+		got = append(got, cqName)
+	}
+
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		Request(corev1.ResourceCPU, "2").
+		ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "2").Obj()).
+		Obj()
+	movedWl := wl.DeepCopy()
+	movedWl.Status.Admission.ClusterQueue = "b"
+	steps := []struct {
+		name string
+		do   func() error
+		want []string
+	}{
+		{
+			name: "assume",
+			do:   func() error { return cache.AssumeWorkload(wl) },
+			want: []string{"a"},
+		},
+		{
+			name: "forget",
+			do:   func() error { return cache.ForgetWorkload(wl) },
+			want: []string{"a"},
+		},
+		{
+			name: "add",
+			do: func() error {
+				if !cache.AddOrUpdateWorkload(wl) {
+					return errors.New("workload not added")
+				}
+				return nil
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "update to another ClusterQueue",
+			do:   func() error { return cache.UpdateWorkload(wl, movedWl) },
+			want: []string{"a", "b"},
+		},
+		{
+			name: "delete",
+			do:   func() error { return cache.DeleteWorkload(movedWl) },
+			want: []string{"b"},
+		},
+		{
+			name: "delete missing workload",
+			do:   func() error { return cache.DeleteWorkload(movedWl) },
+		},
+		{
+			name: "hold and release",
+			do: func() error {
+				release, err := cache.reserve("b", Resources{corev1.ResourceCPU: 1_000}, "holder")
+				if err != nil {
+					return err
+				}
+				release()
+				return nil
+			},
+			want: []string{"b", "b"},
+		},
+	}
+	for _, step := range steps {
+		got = nil
+		if err := step.do(); err != nil {
+			t.Fatalf("Failed to %s: %v", step.name, err)
+		}
+		if diff := cmp.Diff(step.want, got); diff != "" {
+			t.Errorf("Unexpected notified ClusterQueues after %s (-want,+got):\n%s", step.name, diff)
+		}
+	}
+}
+
+func TestEffectivePriority(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	for _, cq := range []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("boosted").
+			Annotations(map[string]string{constants.PriorityOffsetAnnotation: "5"}).Obj(),
+		utiltesting.MakeClusterQueue("penalized").
+			Annotations(map[string]string{constants.PriorityOffsetAnnotation: "-5"}).Obj(),
+	} {
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	invalid := utiltesting.MakeClusterQueue("invalid").
+		Annotations(map[string]string{constants.PriorityOffsetAnnotation: "high"}).Obj()
+	if err := cache.AddClusterQueue(context.Background(), invalid); !errors.Is(err, errInvalidPriorityOffset) {
+		t.Errorf("Unexpected error adding a ClusterQueue with an invalid priority offset: %v", err)
+	}
+
+	cases := map[string]struct {
+		wl   *kueue.Workload
+		cq   string
+		want int32
+	}{
+		"boosted": {
+			wl:   utiltesting.MakeWorkload("a", "ns").Priority(10).Obj(),
+			cq:   "boosted",
+			want: 15,
+		},
+		"penalized": {
+			wl:   utiltesting.MakeWorkload("b", "ns").Priority(10).Obj(),
+			cq:   "penalized",
+			want: 5,
+		},
+		"missing ClusterQueue": {
+			wl:   utiltesting.MakeWorkload("c", "ns").Priority(10).Obj(),
+			cq:   "missing",
+			want: 10,
+		},
+		"capped": {
+			wl:   utiltesting.MakeWorkload("d", "ns").Priority(math.MaxInt32).Obj(),
+			cq:   "boosted",
+			want: math.MaxInt32,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := cache.EffectivePriority(tc.wl, tc.cq); got != tc.want {
+				t.Errorf("Unexpected effective priority, got %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRemoveFlavorFromClusterQueue(t *testing.T) {
+	cases := map[string]struct {
+		flavor      string
+		wantErr     error
+		wantFlavors []kueue.ResourceFlavorReference
+		wantUsage   FlavorResourceQuantities
+	}{
+		"unused flavor": {
+			flavor:      "spot",
+			wantFlavors: []kueue.ResourceFlavorReference{"on-demand"},
+			wantUsage: FlavorResourceQuantities{
+				"on-demand": {corev1.ResourceCPU: 2_000, corev1.ResourceMemory: 0},
+			},
+		},
+		"flavor in use": {
+			flavor:      "on-demand",
+			wantErr:     errFlavorInUse,
+			wantFlavors: []kueue.ResourceFlavorReference{"on-demand", "spot"},
+			wantUsage: FlavorResourceQuantities{
+				"on-demand": {corev1.ResourceCPU: 2_000, corev1.ResourceMemory: 0},
+				"spot":      {corev1.ResourceCPU: 0, corev1.ResourceMemory: 0},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+			cq := utiltesting.MakeClusterQueue("cq").
+				ResourceGroup(
+					*utiltesting.MakeFlavorQuotas("on-demand").
+						Resource(corev1.ResourceCPU, "10").
+						Resource(corev1.ResourceMemory, "10Gi").
+						Obj(),
+					*utiltesting.MakeFlavorQuotas("spot").
+						Resource(corev1.ResourceCPU, "10").
+						Resource(corev1.ResourceMemory, "10Gi").
+						Obj(),
+				).
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			wl := utiltesting.MakeWorkload("wl", "ns").
+				ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "on-demand", "2").Obj()).
+				Obj()
+			if !cache.AddOrUpdateWorkload(wl) {
+				t.Fatal("Failed adding workload")
+			}
+			snapshot := cache.Snapshot()
+
+			err := cache.removeFlavorFromClusterQueue("cq", corev1.ResourceCPU, tc.flavor)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Unexpected error, got %v, want %v", err, tc.wantErr)
+			}
+			var gotFlavors []kueue.ResourceFlavorReference
+			for _, flvQuotas := range cache.clusterQueues["cq"].ResourceGroups[0].Flavors {
+				gotFlavors = append(gotFlavors, flvQuotas.Name)
+			}
+			if diff := cmp.Diff(tc.wantFlavors, gotFlavors); diff != "" {
+				t.Errorf("Unexpected flavors (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantUsage, cache.clusterQueues["cq"].Usage); diff != "" {
+				t.Errorf("Unexpected usage (-want,+got):\n%s", diff)
+			}
+			if got := len(snapshot.ClusterQueues["cq"].ResourceGroups[0].Flavors); got != 2 {
+				t.Errorf("The snapshot was modified, got %d flavors, want 2", got)
+			}
+		})
+	}
+}
+
+func TestCohortBorrowedResourcesMetric(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cqA := utiltesting.MakeClusterQueue("borrowing-a").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "5").Obj()).
+		Cohort("borrowing").
+		Obj()
+	cqB := utiltesting.MakeClusterQueue("borrowing-b").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "5").Obj()).
+		Cohort("borrowing").
+		Obj()
+	for _, cq := range []*kueue.ClusterQueue{cqA, cqB} {
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue %s: %v", cq.Name, err)
+		}
+	}
+	wantBorrowed := func(step string, want []testingmetrics.GaugeDataPoint) {
+		t.Helper()
+		got := testingmetrics.CollectFilteredGaugeVec(metrics.CohortBorrowedResources, map[string]string{"cohort": "borrowing"})
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unexpected borrowed resources %s (-want,+got):\n%s", step, diff)
+		}
+	}
+	borrowedCPU := func(v float64) []testingmetrics.GaugeDataPoint {
+		return []testingmetrics.GaugeDataPoint{{
+			Labels: map[string]string{"cohort": "borrowing", "flavor": "default", "resource": "cpu"},
+			Value:  v,
+		}}
+	}
+	wantBorrowed("without workloads", borrowedCPU(0))
+
+	wl := utiltesting.MakeWorkload("borrower", "ns").
+		Request(corev1.ResourceCPU, "7500m").
+		ReserveQuota(utiltesting.MakeAdmission("borrowing-a").Assignment(corev1.ResourceCPU, "default", "7500m").Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(wl) {
+		t.Fatalf("Failed adding workload")
+	}
+	wantBorrowed("after admitting a borrowing workload", borrowedCPU(2.5))
+
+	if err := cache.DeleteWorkload(wl); err != nil {
+		t.Fatalf("Failed deleting workload: %v", err)
+	}
+	wantBorrowed("after deleting the borrowing workload", borrowedCPU(0))
+
+	cache.DeleteClusterQueue(cqA)
+	cache.DeleteClusterQueue(cqB)
+	wantBorrowed("after deleting the cohort", []testingmetrics.GaugeDataPoint{})
+}
+
+func TestPodSetNodeSelectors(t *testing.T) {
+	flavors := []*kueue.ResourceFlavor{
+		utiltesting.MakeResourceFlavor("on-demand").Label("instance-type", "on-demand").Label("zone", "a").Obj(),
+		utiltesting.MakeResourceFlavor("spot").Label("instance-type", "spot").Obj(),
+		utiltesting.MakeResourceFlavor("a100").Label("gpu-model", "a100").Label("zone", "b").Obj(),
+		utiltesting.MakeResourceFlavor("default").Obj(),
+	}
+	cases := map[string]struct {
+		admission *kueue.Admission
+		want      map[string]map[string]string
+		wantErr   error
+	}{
+		"driver on on-demand and workers on spot": {
+			admission: utiltesting.MakeAdmission("cq").PodSets(
+				kueue.PodSetAssignment{
+					Name:    "driver",
+					Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{corev1.ResourceCPU: "on-demand"},
+				},
+				kueue.PodSetAssignment{
+					Name:    "workers",
+					Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{corev1.ResourceCPU: "spot"},
+				},
+			).Obj(),
+			want: map[string]map[string]string{
+				"driver":  {"instance-type": "on-demand", "zone": "a"},
+				"workers": {"instance-type": "spot"},
+			},
+		},
+		"labels of multiple flavors are merged": {
+			admission: utiltesting.MakeAdmission("cq").PodSets(
+				kueue.PodSetAssignment{
+					Name: "main",
+					Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{
+						corev1.ResourceCPU: "on-demand",
+						"nvidia.com/gpu":   "a100",
+					},
+				},
+			).Obj(),
+			want: map[string]map[string]string{
+				"main": {"instance-type": "on-demand", "gpu-model": "a100", "zone": "a"},
+			},
+		},
+		"flavor without labels": {
+			admission: utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "1").Obj(),
+			want: map[string]map[string]string{
+				"main": {},
+			},
+		},
+		"unknown flavor": {
+			admission: utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "unknown", "1").Obj(),
+			wantErr:   errFlavorNotFound,
+		},
+		"not admitted": {
+			wantErr: errWorkloadNotAdmitted,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			for _, rf := range flavors {
+				cache.AddOrUpdateResourceFlavor(rf)
+			}
+			wl := utiltesting.MakeWorkload("wl", "ns").Obj()
+			wl.Status.Admission = tc.admission
+
+			got, err := cache.podSetNodeSelectors(wl)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Unexpected error, got %v, want %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected node selectors (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWorkloadFootprint(t *testing.T) {
+	cases := map[string]struct {
+		workload    *kueue.Workload
+		wantMinimum Resources
+		wantDesired Resources
+	}{
+		"without minimum count": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				PodSets(*utiltesting.MakePodSet("main", 4).Request(corev1.ResourceCPU, "1").Obj()).
+				Obj(),
+			wantMinimum: Resources{corev1.ResourceCPU: 4_000},
+			wantDesired: Resources{corev1.ResourceCPU: 4_000},
+		},
+		"elastic gang": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				PodSets(
+					*utiltesting.MakePodSet("driver", 1).Request(corev1.ResourceCPU, "2").Obj(),
+					*utiltesting.MakePodSet("workers", 8).
+						SetMinimumCount(2).
+						Request(corev1.ResourceCPU, "1").
+						Request("example.com/gpu", "1").
+						Obj(),
+				).
+				Obj(),
+			wantMinimum: Resources{corev1.ResourceCPU: 4_000, "example.com/gpu": 2},
+			wantDesired: Resources{corev1.ResourceCPU: 10_000, "example.com/gpu": 8},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gotMinimum, gotDesired := WorkloadFootprint(tc.workload)
+			if diff := cmp.Diff(tc.wantMinimum, gotMinimum); diff != "" {
+				t.Errorf("Unexpected minimum footprint (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantDesired, gotDesired); diff != "" {
+				t.Errorf("Unexpected desired footprint (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUsageOfGangAdmittedAtMinimum(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	wl := utiltesting.MakeWorkload("gang", "ns").
+		PodSets(*utiltesting.MakePodSet("main", 8).SetMinimumCount(3).Request(corev1.ResourceCPU, "2").Obj()).
+		ReserveQuota(utiltesting.MakeAdmission("cq").
+			Assignment(corev1.ResourceCPU, "default", "6").
+			AssignmentPodCount(3).
+			Obj()).
+		Obj()
+	if err := cache.AssumeWorkload(wl); err != nil {
+		t.Fatalf("Failed assuming workload: %v", err)
+	}
+
+	want := FlavorResourceQuantities{"default": {corev1.ResourceCPU: 6_000}}
+	if diff := cmp.Diff(want, cache.Snapshot().ClusterQueues["cq"].Usage); diff != "" {
+		t.Errorf("Unexpected usage (-want,+got):\n%s", diff)
+	}
+	if minimum, _ := WorkloadFootprint(wl); minimum[corev1.ResourceCPU] != want["default"][corev1.ResourceCPU] {
+		t.Errorf("The usage doesn't match the minimum footprint %v", minimum)
+	}
+}
+
+func TestFinishedWorkloadsDontHoldQuota(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	admission := utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "2").Obj()
+	running := utiltesting.MakeWorkload("running", "ns").ReserveQuota(admission).Obj()
+	finished := utiltesting.MakeWorkload("finished", "ns").ReserveQuota(admission).Finished().Obj()
+	checkUsage := func(step string, cpu int64) {
+		t.Helper()
+		want := FlavorResourceQuantities{"default": {corev1.ResourceCPU: cpu}}
+		if diff := cmp.Diff(want, cache.Snapshot().ClusterQueues["cq"].Usage); diff != "" {
+			t.Errorf("Unexpected usage %s (-want,+got):\n%s", step, diff)
+		}
+	}
+
+	if cache.AddOrUpdateWorkload(finished) {
+		t.Error("A finished workload was added")
+	}
+	if !cache.AddOrUpdateWorkload(running) {
+		t.Fatal("Failed adding the running workload")
+	}
+	checkUsage("with a running and a finished workload", 2_000)
+
+	var notified []string
+	cache.OnClusterQueueUsageChanged = func(cqName string) {
+		notified = append(notified, cqName)
+	}
+	runningFinished := running.DeepCopy()
+	apimeta.SetStatusCondition(&runningFinished.Status.Conditions, metav1.Condition{
+		Type:   kueue.WorkloadFinished,
+		Status: metav1.ConditionTrue,
+		Reason: "ByTest",
+	})
+	if cache.AddOrUpdateWorkload(runningFinished) {
+		t.Error("The workload was kept after it finished")
+	}
+	checkUsage("after the workload finished", 0)
+	if diff := cmp.Diff([]string{"cq"}, notified); diff != "" {
+		t.Errorf("Unexpected notifications (-want,+got):\n%s", diff)
+	}
+
+	if err := cache.UpdateWorkload(finished, finished); err != nil {
+		t.Errorf("Failed updating the finished workload: %v", err)
+	}
+	checkUsage("after updating the finished workload", 0)
+
+	otherCQ := finished.DeepCopy()
+	otherCQ.Status.Admission.ClusterQueue = "unknown"
+	if err := cache.DeleteWorkload(otherCQ); err != nil {
+		t.Errorf("Deleting a finished workload failed: %v", err)
+	}
+}
+
+func TestClear(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	var notified []string
+	cache.OnClusterQueueUsageChanged = func(cqName string) {
+		notified = append(notified, cqName)
+	}
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("check").Active(metav1.ConditionTrue).Obj())
+	for _, name := range []string{"a", "b"} {
+		cq := utiltesting.MakeClusterQueue(name).
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+			Cohort("one").
+			Obj()
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	admitted := utiltesting.MakeWorkload("admitted", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "2").Obj()).
+		Admitted(true).
+		Obj()
+	if !cache.AddOrUpdateWorkload(admitted) {
+		t.Fatal("Failed adding the admitted workload")
+	}
+	assumed := utiltesting.MakeWorkload("assumed", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("b").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+		Obj()
+	if err := cache.AssumeWorkload(assumed); err != nil {
+		t.Fatalf("Failed assuming the workload: %v", err)
+	}
+	cache.SetPendingDemand("a", workload.Requests{corev1.ResourceCPU: 1_000})
+
+	cache.clearAll()
+
+	snapshot := cache.Snapshot()
+	if len(snapshot.ClusterQueues) != 0 || len(snapshot.ResourceFlavors) != 0 || snapshot.InactiveClusterQueueSets.Len() != 0 {
+		t.Errorf("Unexpected snapshot after clearing: %+v", snapshot)
+	}
+	if members := cache.cohortMembers("one"); len(members) != 0 {
+		t.Errorf("Unexpected cohort members after clearing: %v", members)
+	}
+	if cache.ClusterQueueActive("a") {
+		t.Error("ClusterQueue a is still active after clearing")
+	}
+	if wls := cache.allAdmittedWorkloads(); len(wls) != 0 {
+		t.Errorf("Unexpected admitted workloads after clearing: %d", len(wls))
+	}
+	if cache.IsAssumedOrAdmittedWorkload(*workload.NewInfo(assumed)) {
+		t.Error("The assumed workload is still assumed after clearing")
+	}
+	if len(cache.assumedWorkloads) != 0 {
+		t.Errorf("Unexpected assumed workloads after clearing: %v", cache.assumedWorkloads)
+	}
+	if history := cache.workloadTransitions(admitted); len(history) != 0 {
+		t.Errorf("Unexpected workload history after clearing: %v", history)
+	}
+	if entries := cache.auditLog.list(0); len(entries) != 0 {
+		t.Errorf("Unexpected audit log after clearing: %v", entries)
+	}
+
+	notified = nil
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("a").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue after clearing: %v", err)
+	}
+	if err := cache.AssumeWorkload(utiltesting.MakeWorkload("new", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "4").Obj()).
+		Obj()); err != nil {
+		t.Fatalf("Failed assuming a workload after clearing: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a"}, notified); diff != "" {
+		t.Errorf("Unexpected notifications after clearing (-want,+got):\n%s", diff)
+	}
+	if entries := cache.auditLog.list(0); len(entries) != 1 {
+		t.Errorf("Unexpected audit log after admitting again: %v", entries)
+	}
+}
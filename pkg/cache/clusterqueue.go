@@ -19,7 +19,12 @@ package cache
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -32,11 +37,14 @@ import (
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
 	"sigs.k8s.io/kueue/pkg/features"
 	"sigs.k8s.io/kueue/pkg/metrics"
+	"sigs.k8s.io/kueue/pkg/util/priority"
+	"sigs.k8s.io/kueue/pkg/util/resource"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
 
 var (
 	errQueueAlreadyExists = errors.New("queue already exists")
+	errFlavorInUse        = errors.New("flavor is in use")
 )
 
 // ClusterQueue is the internal implementation of kueue.ClusterQueue that
@@ -56,11 +64,47 @@ type ClusterQueue struct {
 	AdmissionChecks   sets.Set[string]
 	Status            metrics.ClusterQueueStatus
 	// GuaranteedQuota records how much resource quota the ClusterQueue reserved
-	// when feature LendingLimit is enabled and flavor's lendingLimit is not nil.
+	// when feature LendingLimit is enabled and flavor's lendingLimit is not nil,
+	// or for its Headroom.
 	GuaranteedQuota FlavorResourceQuantities
 	// AllocatableResourceGeneration will be increased when some admitted workloads are
 	// deleted, or the resource groups are changed.
 	AllocatableResourceGeneration int64
+	// OvercommitQuota records how much resource quota the ClusterQueue can use
+	// above its nominal quota, by the overcommit ratios of its flavors. It's
+	// only available to the ClusterQueue and it's not accounted in the cohort.
+	OvercommitQuota FlavorResourceQuantities
+	// ResourceSubstitutes holds, per resource, the resources whose quota can be
+	// consumed, in order, when the quota of the resource is exhausted.
+	ResourceSubstitutes map[corev1.ResourceName][]corev1.ResourceName
+	// PriorityOffset is added to the priority of the workloads in the
+	// ClusterQueue to obtain their effective priority, as declared in the
+	// PriorityOffsetAnnotation.
+	PriorityOffset int32
+	// PreemptionCounts holds, keyed by workload key, how many times each
+	// workload was preempted, as recorded by RequeuePreempted. Each
+	// preemption raises the effective priority of the workload.
+	PreemptionCounts map[string]int32
+	// SecondaryCohorts are the cohorts, besides Cohort, that the ClusterQueue
+	// is a member of. It lends its unused quota to them, but it only borrows
+	// from Cohort, as the usage it borrows is only accounted in Cohort.
+	SecondaryCohorts []*Cohort
+	// BorrowBoost holds, per resource, the amount that the ClusterQueue can
+	// borrow beyond the borrowing limit of its flavors until the deadline
+	// declared in the BorrowBoostAnnotation. It's only populated in a snapshot
+	// taken before the deadline.
+	BorrowBoost Resources
+	// BorrowingDisabled is whether borrowing is paused in all the
+	// ClusterQueues with WithBorrowingPaused or SetBorrowingPaused.
+	BorrowingDisabled bool
+	// BorrowingForbidden are the resources that the ClusterQueue can't
+	// borrow, declared in the BorrowingForbiddenResourcesAnnotation.
+	BorrowingForbidden sets.Set[corev1.ResourceName]
+	// Headroom holds, per resource, the amount of the nominal quota of each
+	// flavor that the ClusterQueue never lends to its cohorts, declared in the
+	// HeadroomAnnotation. Unlike the lending limit, it's an absolute amount
+	// that stays available for the bursts of the ClusterQueue.
+	Headroom Resources
 
 	// The following fields are not populated in a snapshot.
 
@@ -72,6 +116,26 @@ type ClusterQueue struct {
 	hasMultipleSingleInstanceControllersChecks bool
 	admittedWorkloadsCount                     int
 	isStopped                                  bool
+	// holds are the quota reservations that aren't backed by a workload,
+	// keyed by holder. Their usage is included in Usage.
+	holds map[string]FlavorResourceQuantities
+	// scheduledReservation is the reservation declared in the
+	// ScheduledReservationAnnotation, if any.
+	scheduledReservation *scheduledHold
+	// borrowBoostUntil is the deadline of BorrowBoost.
+	borrowBoostUntil time.Time
+	// nominalQuotaPercentages are the nominal quotas declared as a percentage
+	// of the total of the cohort. See applyQuotaPercentages.
+	nominalQuotaPercentages quotaPercentages
+	// softQuotas are the usage thresholds declared in the
+	// SoftQuotasAnnotation.
+	softQuotas FlavorResourceQuantities
+	// cohortNominal and cohortUsage are what the ClusterQueue currently
+	// contributes to the capacity aggregated by its cohorts.
+	cohortNominal FlavorResourceQuantities
+	cohortUsage   FlavorResourceQuantities
+	// metrics records the metrics of the ClusterQueue. See recorder.
+	metrics MetricsRecorder
 }
 
 // Cohort is a set of ClusterQueues that can borrow resources from each other.
@@ -86,6 +150,13 @@ type Cohort struct {
 	// This field will only be set in snapshot. This field equals to
 	// the sum of allocatable generation among its members.
 	AllocatableResourceGeneration int64
+
+	// The following fields are not populated in a snapshot.
+
+	// activeNominal and activeUsage are the sums of the nominal quotas and of
+	// the usage of the active members, maintained by syncCohortCapacity.
+	activeNominal FlavorResourceQuantities
+	activeUsage   FlavorResourceQuantities
 }
 
 type ResourceGroup struct {
@@ -104,11 +175,15 @@ type FlavorQuotas struct {
 }
 
 type ResourceQuota struct {
-	Nominal        int64
-	BorrowingLimit *int64
-	LendingLimit   *int64
+	Nominal         int64
+	BorrowingLimit  *int64
+	LendingLimit    *int64
+	OvercommitRatio *float64
 }
 
+// Resources holds quantities for a set of resources.
+type Resources map[corev1.ResourceName]int64
+
 type FlavorResourceQuantities map[kueue.ResourceFlavorReference]map[corev1.ResourceName]int64
 
 type queue struct {
@@ -127,6 +202,65 @@ func newCohort(name string, size int) *Cohort {
 	}
 }
 
+// SortedMembers returns the members of the cohort sorted by name.
+// Callers that need to iterate over the members in a reproducible order,
+// such as the fairness and preemption computations, should use it instead
+// of ranging over Members.
+func (c *Cohort) SortedMembers() []*ClusterQueue {
+	members := c.Members.UnsortedList()
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	return members
+}
+
+// reportBorrowedResources reports the resources that the members of the
+// cohort borrow above their nominal quota, in each flavor.
+func (c *Cohort) reportBorrowedResources(recorder MetricsRecorder) {
+	for flavor, resources := range c.borrowedResources() {
+		for rName, v := range resources {
+			q := workload.ResourceQuantity(rName, v)
+			recorder.SetCohortBorrowedResource(c.Name, flavor, rName, resource.QuantityToFloat(&q))
+		}
+	}
+}
+
+// borrowedResources returns, in each flavor of the members of the cohort, the
+// sum of the usage of the members above their nominal quota.
+func (c *Cohort) borrowedResources() FlavorResourceQuantities {
+	borrowed := make(FlavorResourceQuantities)
+	for cq := range c.Members {
+		for _, rg := range cq.ResourceGroups {
+			for _, flvQuotas := range rg.Flavors {
+				if borrowed[flvQuotas.Name] == nil {
+					borrowed[flvQuotas.Name] = make(map[corev1.ResourceName]int64, len(flvQuotas.Resources))
+				}
+				for rName, rQuota := range flvQuotas.Resources {
+					borrowed[flvQuotas.Name][rName] += max(0, cq.Usage[flvQuotas.Name][rName]-rQuota.Nominal)
+				}
+			}
+		}
+	}
+	return borrowed
+}
+
+// Cohorts returns the cohorts of the ClusterQueue in order: its
+// primary cohort followed by the secondary ones.
+func (c *ClusterQueue) Cohorts() []*Cohort {
+	if c.Cohort == nil {
+		return nil
+	}
+	return append([]*Cohort{c.Cohort}, c.SecondaryCohorts...)
+}
+
+func (c *ClusterQueue) cohortNames() []string {
+	var names []string
+	for _, cohort := range c.Cohorts() {
+		names = append(names, cohort.Name)
+	}
+	return names
+}
+
 func (c *ClusterQueue) FitInCohort(q FlavorResourceQuantities) bool {
 	for flavor, qResources := range q {
 		if _, flavorFound := c.Cohort.RequestableResources[flavor]; flavorFound {
@@ -162,6 +296,56 @@ func (c *ClusterQueue) IsBorrowing() bool {
 	return false
 }
 
+// OvercommittedNominal returns the nominal quota scaled by the overcommit
+// ratio, if any.
+func (q *ResourceQuota) OvercommittedNominal() int64 {
+	return overcommit(q.Nominal, q.OvercommitRatio)
+}
+
+// requiredNominal returns the smallest nominal quota whose overcommitted
+// value covers the quantity.
+func (q *ResourceQuota) requiredNominal(quantity int64) int64 {
+	if q.OvercommitRatio == nil || *q.OvercommitRatio <= 1 {
+		return quantity
+	}
+	nominal := int64(math.Ceil(float64(quantity) / *q.OvercommitRatio))
+	// Guard against the truncation in overcommit.
+	for overcommit(nominal, q.OvercommitRatio) < quantity {
+		nominal++
+	}
+	return nominal
+}
+
+func overcommit(nominal int64, ratio *float64) int64 {
+	if ratio == nil || *ratio <= 1 {
+		return nominal
+	}
+	return int64(float64(nominal) * *ratio)
+}
+
+// EffectivePriority returns the priority of the workload plus the priority
+// offset of the ClusterQueue and the boost for the preemptions of the
+// workload, capped to the int32 range.
+func (c *ClusterQueue) EffectivePriority(wl *kueue.Workload) int32 {
+	p := int64(priority.Priority(wl)) + int64(c.PriorityOffset) + c.preemptionPriorityBoost(wl)
+	return int32(max(min(p, math.MaxInt32), math.MinInt32))
+}
+
+// idleNominal returns, per resource, the nominal quota of the ClusterQueue
+// that it doesn't use. The idle quota is floored at zero in each flavor before
+// adding up the flavors.
+func (c *ClusterQueue) idleNominal() Resources {
+	idle := make(Resources)
+	for _, rg := range c.ResourceGroups {
+		for _, flvQuotas := range rg.Flavors {
+			for rName, rQuota := range flvQuotas.Resources {
+				idle[rName] += max(0, rQuota.Nominal-c.Usage[flvQuotas.Name][rName])
+			}
+		}
+	}
+	return idle
+}
+
 func (c *ClusterQueue) Active() bool {
 	return c.Status == active
 }
@@ -174,6 +358,47 @@ var defaultPreemption = kueue.ClusterQueuePreemption{
 var defaultFlavorFungibility = kueue.FlavorFungibility{WhenCanBorrow: kueue.Borrow, WhenCanPreempt: kueue.TryNextFlavor}
 
 func (c *ClusterQueue) update(in *kueue.ClusterQueue, resourceFlavors map[kueue.ResourceFlavorReference]*kueue.ResourceFlavor, admissionChecks map[string]AdmissionCheck) error {
+	percentages, err := nominalQuotaPercentages(in)
+	if err != nil {
+		return err
+	}
+	c.nominalQuotaPercentages = percentages
+	softQuotas, err := parseSoftQuotas(in)
+	if err != nil {
+		return err
+	}
+	c.softQuotas = softQuotas
+	if forbidden := borrowingForbiddenResources(in); !forbidden.Equal(c.BorrowingForbidden) {
+		c.BorrowingForbidden = forbidden
+		c.AllocatableResourceGeneration++
+	}
+	offset, err := priorityOffset(in)
+	if err != nil {
+		return err
+	}
+	c.PriorityOffset = offset
+	scheduledReservation, err := parseScheduledReservation(in)
+	if err != nil {
+		return err
+	}
+	c.scheduledReservation = scheduledReservation
+	boost, until, err := parseBorrowBoost(in)
+	if err != nil {
+		return err
+	}
+	if !maps.Equal(boost, c.BorrowBoost) || !until.Equal(c.borrowBoostUntil) {
+		c.BorrowBoost = boost
+		c.borrowBoostUntil = until
+		c.AllocatableResourceGeneration++
+	}
+	headroom, err := parseHeadroom(in)
+	if err != nil {
+		return err
+	}
+	if !maps.Equal(headroom, c.Headroom) {
+		c.Headroom = headroom
+		c.AllocatableResourceGeneration++
+	}
 	c.updateResourceGroups(in.Spec.ResourceGroups)
 	nsSelector, err := metav1.LabelSelectorAsSelector(in.Spec.NamespaceSelector)
 	if err != nil {
@@ -208,27 +433,52 @@ func (c *ClusterQueue) update(in *kueue.ClusterQueue, resourceFlavors map[kueue.
 		c.FlavorFungibility = defaultFlavorFungibility
 	}
 
-	if features.Enabled(features.LendingLimit) {
-		var guaranteedQuota FlavorResourceQuantities
-		for _, rg := range c.ResourceGroups {
-			for _, flvQuotas := range rg.Flavors {
-				for rName, rQuota := range flvQuotas.Resources {
-					if rQuota.LendingLimit != nil {
-						if guaranteedQuota == nil {
-							guaranteedQuota = make(FlavorResourceQuantities)
-						}
-						if guaranteedQuota[flvQuotas.Name] == nil {
-							guaranteedQuota[flvQuotas.Name] = make(map[corev1.ResourceName]int64)
-						}
-						guaranteedQuota[flvQuotas.Name][rName] = rQuota.Nominal - *rQuota.LendingLimit
+	c.updateGuaranteedQuota()
+	c.syncCohortCapacity()
+	return nil
+}
+
+// updateGuaranteedQuota computes the quota that the ClusterQueue doesn't lend
+// to its cohort from the nominal quotas, the lending limits and the headroom.
+// The headroom of a flavor is capped at its nominal quota. It also computes
+// the quota that the ClusterQueue can use above the nominal quotas from the
+// overcommit ratios.
+func (c *ClusterQueue) updateGuaranteedQuota() {
+	var guaranteedQuota, overcommitQuota FlavorResourceQuantities
+	for _, rg := range c.ResourceGroups {
+		for _, flvQuotas := range rg.Flavors {
+			for rName, rQuota := range flvQuotas.Resources {
+				if extra := rQuota.OvercommittedNominal() - rQuota.Nominal; extra > 0 {
+					if overcommitQuota == nil {
+						overcommitQuota = make(FlavorResourceQuantities)
 					}
+					if overcommitQuota[flvQuotas.Name] == nil {
+						overcommitQuota[flvQuotas.Name] = make(map[corev1.ResourceName]int64)
+					}
+					overcommitQuota[flvQuotas.Name][rName] = extra
+				}
+				hasLendingLimit := features.Enabled(features.LendingLimit) && rQuota.LendingLimit != nil
+				headroom, hasHeadroom := c.Headroom[rName]
+				if !hasLendingLimit && !hasHeadroom {
+					continue
 				}
+				var guaranteed int64
+				if hasLendingLimit {
+					guaranteed = rQuota.Nominal - *rQuota.LendingLimit
+				}
+				guaranteed = max(guaranteed, min(headroom, rQuota.Nominal))
+				if guaranteedQuota == nil {
+					guaranteedQuota = make(FlavorResourceQuantities)
+				}
+				if guaranteedQuota[flvQuotas.Name] == nil {
+					guaranteedQuota[flvQuotas.Name] = make(map[corev1.ResourceName]int64)
+				}
+				guaranteedQuota[flvQuotas.Name][rName] = guaranteed
 			}
 		}
-		c.GuaranteedQuota = guaranteedQuota
 	}
-
-	return nil
+	c.GuaranteedQuota = guaranteedQuota
+	c.OvercommitQuota = overcommitQuota
 }
 
 func filterQuantities(orig FlavorResourceQuantities, resourceGroups []kueue.ResourceGroup) FlavorResourceQuantities {
@@ -271,6 +521,9 @@ func (c *ClusterQueue) updateResourceGroups(in []kueue.ResourceGroup) {
 				if features.Enabled(features.LendingLimit) && rIn.LendingLimit != nil {
 					rQuota.LendingLimit = ptr.To(workload.ResourceValue(rIn.Name, *rIn.LendingLimit))
 				}
+				if rIn.OvercommitRatio != nil {
+					rQuota.OvercommitRatio = ptr.To(*rIn.OvercommitRatio)
+				}
 				fQuotas.Resources[rIn.Name] = &rQuota
 			}
 			rg.Flavors = append(rg.Flavors, fQuotas)
@@ -283,6 +536,49 @@ func (c *ClusterQueue) updateResourceGroups(in []kueue.ResourceGroup) {
 	c.UpdateRGByResource()
 }
 
+// removeFlavor removes the flavor from the resource group that covers the
+// resource, along with its usage of the covered resources. It fails if any
+// of the covered resources is in use in the flavor.
+func (c *ClusterQueue) removeFlavor(rName corev1.ResourceName, fName kueue.ResourceFlavorReference, resourceFlavors map[kueue.ResourceFlavorReference]*kueue.ResourceFlavor) error {
+	rgIdx := slices.IndexFunc(c.ResourceGroups, func(rg ResourceGroup) bool {
+		return rg.CoveredResources.Has(rName)
+	})
+	if rgIdx < 0 {
+		return fmt.Errorf("resource %s is not covered by the ClusterQueue", rName)
+	}
+	rg := c.ResourceGroups[rgIdx]
+	fIdx := slices.IndexFunc(rg.Flavors, func(f FlavorQuotas) bool {
+		return f.Name == fName
+	})
+	if fIdx < 0 {
+		return fmt.Errorf("flavor %s is not defined for resource %s", fName, rName)
+	}
+	for _, r := range sets.List(rg.CoveredResources) {
+		if c.Usage[fName][r] > 0 {
+			return fmt.Errorf("%w: %s is used in flavor %s", errFlavorInUse, r, fName)
+		}
+	}
+
+	// The resource groups are shared with the snapshots, so they are copied
+	// instead of modified in place.
+	c.ResourceGroups = slices.Clone(c.ResourceGroups)
+	rg.Flavors = slices.Delete(slices.Clone(rg.Flavors), fIdx, fIdx+1)
+	rg.LabelKeys = nil
+	c.ResourceGroups[rgIdx] = rg
+	for _, quantities := range []FlavorResourceQuantities{c.Usage, c.AdmittedUsage, c.GuaranteedQuota} {
+		for r := range rg.CoveredResources {
+			delete(quantities[fName], r)
+		}
+		if len(quantities[fName]) == 0 {
+			delete(quantities, fName)
+		}
+	}
+	c.AllocatableResourceGeneration++
+	c.UpdateRGByResource()
+	c.UpdateWithFlavors(resourceFlavors)
+	return nil
+}
+
 func (c *ClusterQueue) UpdateRGByResource() {
 	c.RGByResource = make(map[corev1.ResourceName]*ResourceGroup)
 	for i := range c.ResourceGroups {
@@ -303,37 +599,69 @@ func (c *ClusterQueue) updateQueueStatus() {
 	}
 	if status != c.Status {
 		c.Status = status
-		metrics.ReportClusterQueueStatus(c.Name, c.Status)
+		c.recorder().SetClusterQueueStatus(c.Name, c.Status)
+		c.syncCohortCapacity()
+	}
+}
+
+// ClusterQueueStatusReason is the reason why a ClusterQueue is, or isn't,
+// active.
+type ClusterQueueStatusReason string
+
+const (
+	ReasonReady                                  ClusterQueueStatusReason = "Ready"
+	ReasonNotFound                               ClusterQueueStatusReason = "NotFound"
+	ReasonTerminating                            ClusterQueueStatusReason = "Terminating"
+	ReasonStopped                                ClusterQueueStatusReason = "Stopped"
+	ReasonFlavorNotFound                         ClusterQueueStatusReason = "FlavorNotFound"
+	ReasonCheckNotFoundOrInactive                ClusterQueueStatusReason = "CheckNotFoundOrInactive"
+	ReasonMultipleSingleInstanceControllerChecks ClusterQueueStatusReason = "MultipleSingleInstanceControllerChecks"
+	ReasonUnknown                                ClusterQueueStatusReason = "Unknown"
+)
+
+func (c *ClusterQueue) readiness() (metav1.ConditionStatus, string, string) {
+	if c.Status == active {
+		return metav1.ConditionTrue, string(ReasonReady), "Can admit new workloads"
 	}
+	reason, msg := c.inactiveReason()
+	return metav1.ConditionFalse, string(reason), msg
 }
 
-func (c *ClusterQueue) inactiveReason() (string, string) {
+func (c *ClusterQueue) inactiveReason() (ClusterQueueStatusReason, string) {
 	switch c.Status {
 	case terminating:
-		return "Terminating", "Can't admit new workloads; clusterQueue is terminating"
+		return ReasonTerminating, "Can't admit new workloads; clusterQueue is terminating"
 	case pending:
-		reasons := make([]string, 0, 3)
-		if c.isStopped {
-			reasons = append(reasons, "Stopped")
-		}
-		if c.hasMissingFlavors {
-			reasons = append(reasons, "FlavorNotFound")
-		}
-		if c.hasMissingOrInactiveAdmissionChecks {
-			reasons = append(reasons, "CheckNotFoundOrInactive")
-		}
-
-		if c.hasMultipleSingleInstanceControllersChecks {
-			reasons = append(reasons, "MultipleSingleInstanceControllerChecks")
-		}
-
+		reasons := c.pendingReasons()
 		if len(reasons) == 0 {
-			return "Unknown", "Can't admit new workloads."
+			return ReasonUnknown, "Can't admit new workloads."
 		}
+		names := make([]string, len(reasons))
+		for i, reason := range reasons {
+			names[i] = string(reason)
+		}
+		return reasons[0], strings.Join([]string{"Can't admit new workloads:", strings.Join(names, ", ")}, " ")
+	}
+	return ReasonReady, "Can admit new flavors"
+}
 
-		return reasons[0], strings.Join([]string{"Can't admit new workloads:", strings.Join(reasons, ", ")}, " ")
+// pendingReasons returns the reasons why the pending ClusterQueue can't admit
+// workloads, the most relevant first.
+func (c *ClusterQueue) pendingReasons() []ClusterQueueStatusReason {
+	reasons := make([]ClusterQueueStatusReason, 0, 4)
+	if c.isStopped {
+		reasons = append(reasons, ReasonStopped)
+	}
+	if c.hasMissingFlavors {
+		reasons = append(reasons, ReasonFlavorNotFound)
 	}
-	return "Ready", "Can admit new flavors"
+	if c.hasMissingOrInactiveAdmissionChecks {
+		reasons = append(reasons, ReasonCheckNotFoundOrInactive)
+	}
+	if c.hasMultipleSingleInstanceControllersChecks {
+		reasons = append(reasons, ReasonMultipleSingleInstanceControllerChecks)
+	}
+	return reasons
 }
 
 // UpdateWithFlavors updates a ClusterQueue based on the passed ResourceFlavors set.
@@ -446,8 +774,7 @@ func (c *ClusterQueue) deleteWorkload(w *kueue.Workload) {
 }
 
 func (c *ClusterQueue) reportActiveWorkloads() {
-	metrics.AdmittedActiveWorkloads.WithLabelValues(c.Name).Set(float64(c.admittedWorkloadsCount))
-	metrics.ReservingActiveWorkloads.WithLabelValues(c.Name).Set(float64(len(c.Workloads)))
+	c.recorder().SetActiveWorkloads(c.Name, len(c.Workloads), c.admittedWorkloadsCount)
 }
 
 // updateWorkloadUsage updates the usage of the ClusterQueue for the workload
@@ -468,6 +795,7 @@ func (c *ClusterQueue) updateWorkloadUsage(wi *workload.Info, m int64) {
 			lq.admittedWorkloads += int(m)
 		}
 	}
+	c.syncCohortCapacity()
 }
 
 func updateUsage(wi *workload.Info, flvUsage FlavorResourceQuantities, m int64) {
@@ -484,23 +812,16 @@ func updateUsage(wi *workload.Info, flvUsage FlavorResourceQuantities, m int64)
 	}
 }
 
-func updateCohortUsage(wi *workload.Info, cq *ClusterQueue, m int64) {
+func updateCohortUsage(wi *workload.Info, cq *ClusterQueue, cohort *Cohort, m int64) {
 	for _, ps := range wi.TotalRequests {
 		for wlRes, wlResFlv := range ps.Flavors {
 			v, wlResExist := ps.Requests[wlRes]
-			flv, flvExist := cq.Cohort.Usage[wlResFlv]
+			flv, flvExist := cohort.Usage[wlResFlv]
 			if flvExist && wlResExist {
 				if _, exists := flv[wlRes]; exists {
-					after := cq.Usage[wlResFlv][wlRes] - cq.guaranteedQuota(wlResFlv, wlRes)
-					// rollback update cq.Usage
+					after := cq.Usage[wlResFlv][wlRes]
 					before := after - v*m
-					if before > 0 {
-						flv[wlRes] -= before
-					}
-					// simulate updating cq.Usage
-					if after > 0 {
-						flv[wlRes] += after
-					}
+					flv[wlRes] += cq.usageInCohort(wlResFlv, wlRes, after) - cq.usageInCohort(wlResFlv, wlRes, before)
 				}
 			}
 		}
@@ -588,21 +909,58 @@ func (c *ClusterQueue) RequestableCohortQuota(fName kueue.ResourceFlavorReferenc
 
 	// When feature LendingLimit enabled, cohort.requestableResource accumulated the lendingLimit if not null
 	// rather than the flavor's quota, then the total available quota should include its own guaranteed resources.
-	requestableCohortQuota += c.guaranteedQuota(fName, rName)
+	// The overcommitted quota is only available to the ClusterQueue as well.
+	requestableCohortQuota += c.guaranteedQuota(fName, rName) + c.overcommitQuota(fName, rName)
 
 	return requestableCohortQuota
 }
 
+// tracksGuaranteedUsage returns whether the usage of the ClusterQueue is
+// accounted in its cohorts only above its guaranteed quota.
+func (c *ClusterQueue) tracksGuaranteedUsage() bool {
+	return features.Enabled(features.LendingLimit) || len(c.Headroom) > 0 || len(c.OvercommitQuota) > 0
+}
+
 func (c *ClusterQueue) guaranteedQuota(fName kueue.ResourceFlavorReference, rName corev1.ResourceName) (val int64) {
-	if !features.Enabled(features.LendingLimit) {
-		return 0
-	}
 	if c.GuaranteedQuota == nil || c.GuaranteedQuota[fName] == nil {
 		return 0
 	}
 	return c.GuaranteedQuota[fName][rName]
 }
 
+func (c *ClusterQueue) overcommitQuota(fName kueue.ResourceFlavorReference, rName corev1.ResourceName) int64 {
+	if c.OvercommitQuota == nil || c.OvercommitQuota[fName] == nil {
+		return 0
+	}
+	return c.OvercommitQuota[fName][rName]
+}
+
+// overcommittedUsage returns the part of the usage that the ClusterQueue
+// takes from its overcommitted quota, which is the usage above the nominal
+// quota up to the overcommitted nominal quota.
+func (c *ClusterQueue) overcommittedUsage(fName kueue.ResourceFlavorReference, rName corev1.ResourceName, usage int64) int64 {
+	extra := c.overcommitQuota(fName, rName)
+	if extra == 0 {
+		return 0
+	}
+	var nominal int64
+	if rg := c.RGByResource[rName]; rg != nil {
+		for _, flvQuotas := range rg.Flavors {
+			if flvQuotas.Name == fName {
+				nominal = flvQuotas.Resources[rName].Nominal
+			}
+		}
+	}
+	return min(max(usage-nominal, 0), extra)
+}
+
+// usageInCohort returns the part of the usage that is accounted in the
+// cohort, which excludes the usage of the guaranteed and the overcommitted
+// quotas.
+func (c *ClusterQueue) usageInCohort(fName kueue.ResourceFlavorReference, rName corev1.ResourceName, usage int64) int64 {
+	return max(usage-c.overcommittedUsage(fName, rName, usage)-c.guaranteedQuota(fName, rName), 0)
+}
+
 // UsedCohortQuota returns the used quota by the flavor and resource name in the cohort.
 // Note that when LendingLimit enabled, the usage is not equal to the total used quota but the one
 // minus the guaranteed resources, this is only for judging whether workloads fit in the cohort.
@@ -613,16 +971,12 @@ func (c *ClusterQueue) UsedCohortQuota(fName kueue.ResourceFlavorReference, rNam
 
 	cohortUsage := c.Cohort.Usage[fName][rName]
 
-	// When feature LendingLimit enabled, cohortUsage is the sum of usage in LendingLimit.
-	// If cqUsage < c.guaranteedQuota, it means the cq is not using all its guaranteedQuota,
-	// need to count the cqUsage in, otherwise need to count the guaranteedQuota in.
-	if features.Enabled(features.LendingLimit) {
+	// When feature LendingLimit enabled, the cq has headroom or overcommits its quota,
+	// cohortUsage is the sum of usage above the guaranteed quotas, without the usage of the
+	// overcommitted quotas. The cq needs to count in the usage that is not in the cohort.
+	if c.tracksGuaranteedUsage() {
 		cqUsage := c.Usage[fName][rName]
-		if cqUsage < c.guaranteedQuota(fName, rName) {
-			cohortUsage += cqUsage
-		} else {
-			cohortUsage += c.guaranteedQuota(fName, rName)
-		}
+		cohortUsage += cqUsage - c.usageInCohort(fName, rName, cqUsage)
 	}
 
 	return cohortUsage
@@ -17,16 +17,19 @@ limitations under the License.
 package cache
 
 import (
+	"context"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
 	"sigs.k8s.io/kueue/pkg/features"
 	"sigs.k8s.io/kueue/pkg/metrics"
 	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
 )
 
 func TestClusterQueueUpdateWithFlavors(t *testing.T) {
@@ -471,7 +474,7 @@ func TestClusterQueueUpdateWithAdmissionCheck(t *testing.T) {
 		cqStatus        metrics.ClusterQueueStatus
 		admissionChecks map[string]AdmissionCheck
 		wantStatus      metrics.ClusterQueueStatus
-		wantReason      string
+		wantReason      ClusterQueueStatusReason
 	}{
 		{
 			name:     "Pending clusterQueue updated valid AC list",
@@ -620,3 +623,159 @@ func TestClusterQueueUpdateWithAdmissionCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestOvercommittedNominal(t *testing.T) {
+	cases := map[string]struct {
+		ratio *float64
+		want  int64
+	}{
+		"no ratio": {
+			want: 4_000,
+		},
+		"ratio 1.0": {
+			ratio: ptr.To(1.0),
+			want:  4_000,
+		},
+		"ratio 1.5": {
+			ratio: ptr.To(1.5),
+			want:  6_000,
+		},
+		"ratio 2.0": {
+			ratio: ptr.To(2.0),
+			want:  8_000,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rQuota := &ResourceQuota{Nominal: 4_000, OvercommitRatio: tc.ratio}
+			if got := rQuota.OvercommittedNominal(); got != tc.want {
+				t.Errorf("Unexpected overcommitted nominal, got %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOvercommittedUsageInCohort(t *testing.T) {
+	cases := map[string]struct {
+		usage           string
+		wantCohortUsage int64
+	}{
+		"within nominal quota": {
+			usage:           "3",
+			wantCohortUsage: 3_000,
+		},
+		"within overcommitted quota": {
+			usage:           "6",
+			wantCohortUsage: 4_000,
+		},
+		"borrowing above the overcommitted quota": {
+			usage:           "10",
+			wantCohortUsage: 6_000,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			clusterQueues := []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("overcommit").
+					Cohort("cohort").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "4").
+						OvercommitRatio(corev1.ResourceCPU, 2).Obj()).
+					Obj(),
+				utiltesting.MakeClusterQueue("other").
+					Cohort("cohort").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "4").Obj()).
+					Obj(),
+			}
+			for _, cq := range clusterQueues {
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			wl := utiltesting.MakeWorkload("wl", "ns").
+				ReserveQuota(utiltesting.MakeAdmission("overcommit").Assignment(corev1.ResourceCPU, "default", tc.usage).Obj()).
+				Obj()
+			if !cache.AddOrUpdateWorkload(wl) {
+				t.Fatalf("Failed adding workload")
+			}
+
+			snapshot := cache.Snapshot()
+			cq := snapshot.ClusterQueues["overcommit"]
+			wantRequestable := FlavorResourceQuantities{"default": {corev1.ResourceCPU: 8_000}}
+			if diff := cmp.Diff(wantRequestable, cq.Cohort.RequestableResources); diff != "" {
+				t.Errorf("Unexpected cohort requestable resources (-want,+got):\n%s", diff)
+			}
+			wantUsage := FlavorResourceQuantities{"default": {corev1.ResourceCPU: tc.wantCohortUsage}}
+			if diff := cmp.Diff(wantUsage, cq.Cohort.Usage); diff != "" {
+				t.Errorf("Unexpected cohort usage (-want,+got):\n%s", diff)
+			}
+			if got, want := cq.RequestableCohortQuota("default", corev1.ResourceCPU), int64(12_000); got != want {
+				t.Errorf("Unexpected requestable cohort quota, got %d, want %d", got, want)
+			}
+
+			// Removing the workload from the snapshot gives back the usage to the cohort.
+			snapshot.RemoveWorkload(workload.NewInfo(wl))
+			wantUsage = FlavorResourceQuantities{"default": {corev1.ResourceCPU: 0}}
+			if diff := cmp.Diff(wantUsage, cq.Cohort.Usage); diff != "" {
+				t.Errorf("Unexpected cohort usage after removing the workload (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResourceScales(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+			Resource(corev1.ResourceCPU, "1500m").
+			Resource(corev1.ResourceMemory, "1Gi").
+			Resource(corev1.ResourceEphemeralStorage, "2G").
+			Resource("example.com/gpu", "2").
+			Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("cq").
+			Assignment(corev1.ResourceCPU, "default", "500m").
+			Assignment(corev1.ResourceMemory, "default", "512Mi").
+			Assignment(corev1.ResourceEphemeralStorage, "default", "1G").
+			Assignment("example.com/gpu", "default", "1").
+			Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(wl) {
+		t.Fatal("Failed adding workload")
+	}
+
+	// Only cpu is scaled to milli-units. Memory and ephemeral-storage are in
+	// bytes and extended resources in integer counts.
+	wantNominal := Resources{
+		corev1.ResourceCPU:              1_500,
+		corev1.ResourceMemory:           1 << 30,
+		corev1.ResourceEphemeralStorage: 2_000_000_000,
+		"example.com/gpu":               2,
+	}
+	gotNominal := make(Resources)
+	for rName, rQuota := range cache.clusterQueues["cq"].ResourceGroups[0].Flavors[0].Resources {
+		gotNominal[rName] = rQuota.Nominal
+	}
+	if diff := cmp.Diff(wantNominal, gotNominal); diff != "" {
+		t.Errorf("Unexpected nominal quota (-want,+got):\n%s", diff)
+	}
+	wantUsage := FlavorResourceQuantities{
+		"default": {
+			corev1.ResourceCPU:              500,
+			corev1.ResourceMemory:           512 << 20,
+			corev1.ResourceEphemeralStorage: 1_000_000_000,
+			"example.com/gpu":               1,
+		},
+	}
+	if diff := cmp.Diff(wantUsage, cache.clusterQueues["cq"].Usage); diff != "" {
+		t.Errorf("Unexpected usage (-want,+got):\n%s", diff)
+	}
+}
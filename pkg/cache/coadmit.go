@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+var errCoAdmitClusterQueueMismatch = errors.New("the workload has a quota reservation in another ClusterQueue")
+
+// coAdmit assumes the workloads, keyed by the name of the ClusterQueue where
+// each one has its quota reservation, all together or none of them, so that
+// a gang spanning multiple ClusterQueues is never partially admitted. The
+// workloads are assumed if, with the usage of all of them, none of their
+// ClusterQueues uses more quota in the assigned flavors than it can, borrowing
+// from its cohorts. Returns false, without assuming any workload, if they
+// don't fit together or one of the ClusterQueues isn't active, and an error if
+// a workload can't be assumed, as in AssumeWorkload.
+func (c *Cache) coAdmit(requests map[string]*kueue.Workload) (bool, error) {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
+	c.Lock()
+	defer c.Unlock()
+
+	cqNames := make([]string, 0, len(requests))
+	for cqName := range requests {
+		cqNames = append(cqNames, cqName)
+	}
+	sort.Strings(cqNames)
+	clusterQueues := make(map[string]*ClusterQueue, len(requests))
+	for _, cqName := range cqNames {
+		w := requests[cqName]
+		cq, err := c.assumableClusterQueue(w)
+		if err != nil {
+			return false, fmt.Errorf("workload %s: %w", workload.Key(w), err)
+		}
+		if cq.Name != cqName {
+			return false, fmt.Errorf("workload %s: %w: %q", workload.Key(w), errCoAdmitClusterQueueMismatch, cq.Name)
+		}
+		if _, exist := cq.Workloads[workload.Key(w)]; exist {
+			return false, fmt.Errorf("workload %s already exists in ClusterQueue %q", workload.Key(w), cq.Name)
+		}
+		clusterQueues[cqName] = cq
+	}
+
+	snap := c.snapshot()
+	infos := make(map[string]*workload.Info, len(requests))
+	for _, cqName := range cqNames {
+		if _, active := snap.ClusterQueues[cqName]; !active {
+			return false, nil
+		}
+		wi := workload.NewInfo(requests[cqName])
+		wi.ClusterQueue = cqName
+		snap.AddWorkload(wi)
+		infos[cqName] = wi
+	}
+	for _, cqName := range cqNames {
+		if !fitsWithUsage(snap.ClusterQueues[cqName], infos[cqName]) {
+			return false, nil
+		}
+	}
+
+	for _, cqName := range cqNames {
+		if err := c.assumeWorkload(clusterQueues[cqName], requests[cqName]); err != nil {
+			return false, err
+		}
+		changed = append(changed, cqName)
+	}
+	return true, nil
+}
+
+// fitsWithUsage returns whether the ClusterQueue of the snapshot, whose usage
+// already includes the workload, doesn't use more than it can in the flavors
+// assigned to the workload.
+func fitsWithUsage(cq *ClusterQueue, wi *workload.Info) bool {
+	for _, psr := range wi.TotalRequests {
+		for rName, fName := range psr.Flavors {
+			rg := cq.RGByResource[rName]
+			if rg == nil {
+				return false
+			}
+			var rQuota *ResourceQuota
+			for _, flvQuotas := range rg.Flavors {
+				if flvQuotas.Name == fName {
+					rQuota = flvQuotas.Resources[rName]
+					break
+				}
+			}
+			if rQuota == nil || availableQuota(cq, fName, rName, rQuota) < 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
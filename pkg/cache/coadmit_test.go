@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestCoAdmit(t *testing.T) {
+	admitted := func(name, cqName, cpu string) *kueue.Workload {
+		return utiltesting.MakeWorkload(name, "ns").
+			Request(corev1.ResourceCPU, cpu).
+			ReserveQuota(utiltesting.MakeAdmission(cqName).Assignment(corev1.ResourceCPU, "default", cpu).Obj()).
+			Obj()
+	}
+	cases := map[string]struct {
+		requests    map[string]*kueue.Workload
+		wantFit     bool
+		wantErr     error
+		wantAssumed []string
+	}{
+		"first workload alone fits borrowing": {
+			requests:    map[string]*kueue.Workload{"a": admitted("wa", "a", "6")},
+			wantFit:     true,
+			wantAssumed: []string{"ns/wa"},
+		},
+		"second workload alone fits": {
+			requests:    map[string]*kueue.Workload{"b": admitted("wb", "b", "3")},
+			wantFit:     true,
+			wantAssumed: []string{"ns/wb"},
+		},
+		"workloads don't fit together": {
+			requests: map[string]*kueue.Workload{
+				"a": admitted("wa", "a", "6"),
+				"b": admitted("wb", "b", "3"),
+			},
+		},
+		"workloads fit together": {
+			requests: map[string]*kueue.Workload{
+				"a": admitted("wa", "a", "5"),
+				"b": admitted("wb", "b", "3"),
+			},
+			wantFit:     true,
+			wantAssumed: []string{"ns/wa", "ns/wb"},
+		},
+		"workload reserving quota in another ClusterQueue": {
+			requests: map[string]*kueue.Workload{
+				"a": admitted("wa", "a", "1"),
+				"b": admitted("wb", "a", "1"),
+			},
+			wantErr: errCoAdmitClusterQueueMismatch,
+		},
+		"workload without quota reservation": {
+			requests: map[string]*kueue.Workload{
+				"a": admitted("wa", "a", "1"),
+				"b": utiltesting.MakeWorkload("wb", "ns").Request(corev1.ResourceCPU, "1").Obj(),
+			},
+			wantErr: errWorkloadNotAdmitted,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			for _, cqName := range []string{"a", "b"} {
+				cq := utiltesting.MakeClusterQueue(cqName).
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+					Cohort("one").
+					Obj()
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			gotFit, gotErr := cache.coAdmit(tc.requests)
+			if !errors.Is(gotErr, tc.wantErr) {
+				t.Errorf("Unexpected error: %v, want %v", gotErr, tc.wantErr)
+			}
+			if gotFit != tc.wantFit {
+				t.Errorf("Unexpected result, got %t, want %t", gotFit, tc.wantFit)
+			}
+			gotAssumed := sets.List(sets.KeySet(cache.assumedWorkloads))
+			if diff := cmp.Diff(tc.wantAssumed, gotAssumed, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Unexpected assumed workloads (-want,+got):\n%s", diff)
+			}
+			var usage int64
+			for _, cqName := range []string{"a", "b"} {
+				usage += cache.clusterQueues[cqName].Usage["default"][corev1.ResourceCPU]
+			}
+			if !tc.wantFit && usage != 0 {
+				t.Errorf("Unexpected usage %d after failing to co-admit", usage)
+			}
+		})
+	}
+}
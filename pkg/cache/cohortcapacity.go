@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// syncCohortCapacity updates the capacity aggregated by the cohorts of the
+// ClusterQueue with its current nominal quota and usage, or without them if
+// it's not active. The usage of the overcommitted quota is left out. It must be called whenever any of them changes, so that
+// the cohorts don't need to iterate over their members.
+func (c *ClusterQueue) syncCohortCapacity() {
+	var nominal, usage FlavorResourceQuantities
+	if c.Active() {
+		nominal = make(FlavorResourceQuantities)
+		for _, rg := range c.ResourceGroups {
+			for _, flvQuotas := range rg.Flavors {
+				for rName, rQuota := range flvQuotas.Resources {
+					if nominal[flvQuotas.Name] == nil {
+						nominal[flvQuotas.Name] = make(map[corev1.ResourceName]int64)
+					}
+					nominal[flvQuotas.Name][rName] += rQuota.Nominal
+				}
+			}
+		}
+		usage = cloneFlavorResourceQuantities(c.Usage)
+		for fName, resources := range usage {
+			for rName, val := range resources {
+				resources[rName] = val - c.overcommittedUsage(fName, rName, val)
+			}
+		}
+	}
+	for _, cohort := range c.Cohorts() {
+		cohort.addCapacity(c.cohortNominal, c.cohortUsage, -1)
+		cohort.addCapacity(nominal, usage, 1)
+	}
+	c.cohortNominal, c.cohortUsage = nominal, usage
+}
+
+// addCapacity adds, or removes with m = -1, the nominal quota and usage of a
+// member to the capacity aggregated by the cohort.
+func (c *Cohort) addCapacity(nominal, usage FlavorResourceQuantities, m int64) {
+	if c.activeNominal == nil {
+		c.activeNominal = make(FlavorResourceQuantities)
+		c.activeUsage = make(FlavorResourceQuantities)
+	}
+	addQuantities(c.activeNominal, nominal, m)
+	addQuantities(c.activeUsage, usage, m)
+}
+
+// addQuantities adds src times m to dst, dropping the quantities that become
+// zero so that dst doesn't grow with the flavors and resources that are no
+// longer in use.
+func addQuantities(dst, src FlavorResourceQuantities, m int64) {
+	for fName, resources := range src {
+		for rName, val := range resources {
+			if val == 0 {
+				continue
+			}
+			if dst[fName] == nil {
+				dst[fName] = make(map[corev1.ResourceName]int64)
+			}
+			dst[fName][rName] += val * m
+			if dst[fName][rName] == 0 {
+				delete(dst[fName], rName)
+				if len(dst[fName]) == 0 {
+					delete(dst, fName)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+// recomputedCohortCapacity sums the nominal quota and the usage of the active
+// members of the cohort from scratch.
+func recomputedCohortCapacity(cohort *Cohort) (FlavorResourceQuantities, FlavorResourceQuantities) {
+	nominal := make(FlavorResourceQuantities)
+	usage := make(FlavorResourceQuantities)
+	for cq := range cohort.Members {
+		if !cq.Active() {
+			continue
+		}
+		for _, rg := range cq.ResourceGroups {
+			for _, flvQuotas := range rg.Flavors {
+				for rName, rQuota := range flvQuotas.Resources {
+					addQuantities(nominal, FlavorResourceQuantities{flvQuotas.Name: {rName: rQuota.Nominal}}, 1)
+				}
+			}
+		}
+		addQuantities(usage, cq.Usage, 1)
+	}
+	return nominal, usage
+}
+
+func TestCohortCapacityIncremental(t *testing.T) {
+	ctx := context.Background()
+	cache := New(utiltesting.NewFakeClient())
+	spot := utiltesting.MakeResourceFlavor("spot").Obj()
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cache.AddOrUpdateResourceFlavor(spot)
+	makeCQ := func(name, cohort, nominal string) *utiltesting.ClusterQueueWrapper {
+		return utiltesting.MakeClusterQueue(name).
+			ResourceGroup(
+				*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, nominal).Obj(),
+				*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "4").Obj(),
+			).
+			Cohort(cohort)
+	}
+	c := makeCQ("c", "two", "3").Obj()
+	c.Annotations = map[string]string{constants.SecondaryCohortsAnnotation: "one"}
+	running := utiltesting.MakeWorkload("running", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "6").Obj()).
+		Obj()
+
+	mutations := []struct {
+		name   string
+		mutate func() error
+	}{
+		{
+			name: "add ClusterQueues",
+			mutate: func() error {
+				for _, cq := range []*kueue.ClusterQueue{makeCQ("a", "one", "10").Obj(), makeCQ("b", "one", "5").Obj(), c} {
+					if err := cache.AddClusterQueue(ctx, cq); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name: "add workloads",
+			mutate: func() error {
+				cache.AddOrUpdateWorkload(running)
+				cache.AddOrUpdateWorkload(utiltesting.MakeWorkload("spot", "ns").
+					ReserveQuota(utiltesting.MakeAdmission("c").Assignment(corev1.ResourceCPU, "spot", "2").Obj()).
+					Obj())
+				return nil
+			},
+		},
+		{
+			name: "reserve quota",
+			mutate: func() error {
+				_, err := cache.reserve("b", Resources{corev1.ResourceCPU: 1_000}, "holder")
+				return err
+			},
+		},
+		{
+			name: "update the quota of a ClusterQueue",
+			mutate: func() error {
+				return cache.UpdateClusterQueue(makeCQ("a", "one", "20").Obj())
+			},
+		},
+		{
+			name: "stop a ClusterQueue",
+			mutate: func() error {
+				return cache.UpdateClusterQueue(makeCQ("b", "one", "5").StopPolicy(kueue.Hold).Obj())
+			},
+		},
+		{
+			name: "move a ClusterQueue to another cohort",
+			mutate: func() error {
+				return cache.UpdateClusterQueue(makeCQ("a", "two", "20").Obj())
+			},
+		},
+		{
+			name: "delete a workload",
+			mutate: func() error {
+				return cache.DeleteWorkload(running)
+			},
+		},
+		{
+			name: "delete a flavor",
+			mutate: func() error {
+				cache.DeleteResourceFlavor(spot)
+				return nil
+			},
+		},
+		{
+			name: "delete a ClusterQueue",
+			mutate: func() error {
+				cache.DeleteClusterQueue(c)
+				return nil
+			},
+		},
+	}
+	for _, m := range mutations {
+		if err := m.mutate(); err != nil {
+			t.Fatalf("Failed to %s: %v", m.name, err)
+		}
+		for name, cohort := range cache.cohorts {
+			wantNominal, wantUsage := recomputedCohortCapacity(cohort)
+			if diff := cmp.Diff(wantNominal, cohort.activeNominal); diff != "" {
+				t.Errorf("Unexpected nominal quota of cohort %s after the step %q (-want,+got):\n%s", name, m.name, diff)
+			}
+			if diff := cmp.Diff(wantUsage, cohort.activeUsage); diff != "" {
+				t.Errorf("Unexpected usage of cohort %s after the step %q (-want,+got):\n%s", name, m.name, diff)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const cohortUsageHistorySize = 60
+
+// UsageSample is the usage of the active members of a cohort, per flavor and
+// resource, at some point in time.
+type UsageSample struct {
+	Time  time.Time                `json:"time"`
+	Usage FlavorResourceQuantities `json:"usage"`
+}
+
+// usageHistory is a ring buffer holding the last usage samples of a cohort.
+type usageHistory struct {
+	samples []UsageSample
+	// start is the index of the oldest sample.
+	start int
+	count int
+}
+
+func (h *usageHistory) add(s UsageSample) {
+	if h.count < len(h.samples) {
+		h.samples[(h.start+h.count)%len(h.samples)] = s
+		h.count++
+		return
+	}
+	h.samples[h.start] = s
+	h.start = (h.start + 1) % len(h.samples)
+}
+
+// SampleCohortUsage records the current usage of every cohort as taken at
+// now, which callers should read from the clock of the cache. Only the last
+// samples of each cohort are kept.
+func (c *Cache) SampleCohortUsage(now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	for name, cohort := range c.cohorts {
+		h, found := c.cohortUsageHistories[name]
+		if !found {
+			h = &usageHistory{samples: make([]UsageSample, cohortUsageHistorySize)}
+			c.cohortUsageHistories[name] = h
+		}
+		usage := cloneFlavorResourceQuantities(cohort.activeUsage)
+		if usage == nil {
+			usage = make(FlavorResourceQuantities)
+		}
+		h.add(UsageSample{Time: now, Usage: usage})
+	}
+}
+
+// RunCohortUsageSampler calls SampleCohortUsage with the time of the clock of
+// the cache every period until the context is done.
+func (c *Cache) RunCohortUsageSampler(ctx context.Context, period time.Duration) {
+	wait.UntilWithContext(ctx, func(context.Context) {
+		c.SampleCohortUsage(c.clock.Now())
+	}, period)
+}
+
+// cohortUsageHistory returns the last usage samples of the cohort, from the
+// oldest to the newest.
+func (c *Cache) cohortUsageHistory(cohortName string) []UsageSample {
+	h, found := c.cohortUsageHistories[cohortName]
+	if !found {
+		return nil
+	}
+	result := make([]UsageSample, h.count)
+	for i := range result {
+		s := h.samples[(h.start+i)%len(h.samples)]
+		s.Usage = cloneFlavorResourceQuantities(s.Usage)
+		result[i] = s
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestCohortUsageHistory(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	cases := map[string]struct {
+		cohortName string
+		samples    int
+		// wantFirst is the index of the oldest sample kept.
+		wantFirst int
+		wantNil   bool
+	}{
+		"no samples": {
+			cohortName: "one",
+			wantNil:    true,
+		},
+		"samples accumulate": {
+			cohortName: "one",
+			samples:    3,
+		},
+		"buffer full": {
+			cohortName: "one",
+			samples:    cohortUsageHistorySize,
+		},
+		"buffer wraps at capacity": {
+			cohortName: "one",
+			samples:    cohortUsageHistorySize + 5,
+			wantFirst:  5,
+		},
+		"missing cohort": {
+			cohortName: "missing",
+			samples:    3,
+			wantNil:    true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeClock := testingclock.NewFakeClock(now)
+			cache := New(utiltesting.NewFakeClient(), WithClock(fakeClock))
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			cq := utiltesting.MakeClusterQueue("a").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "100").Obj()).
+				Cohort("one").
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			var want []UsageSample
+			for i := 0; i < tc.samples; i++ {
+				w := utiltesting.MakeWorkload(fmt.Sprintf("w%d", i), "ns").
+					Request(corev1.ResourceCPU, "1").
+					ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+					Obj()
+				if !cache.AddOrUpdateWorkload(w) {
+					t.Fatalf("Failed adding workload %s", w.Name)
+				}
+				cache.SampleCohortUsage(fakeClock.Now())
+				if i >= tc.wantFirst {
+					want = append(want, UsageSample{
+						Time:  fakeClock.Now(),
+						Usage: FlavorResourceQuantities{"default": {corev1.ResourceCPU: int64(i+1) * 1_000}},
+					})
+				}
+				fakeClock.Step(time.Minute)
+			}
+			if tc.wantNil {
+				want = nil
+			}
+			got := cache.cohortUsageHistory(tc.cohortName)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Unexpected usage history (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
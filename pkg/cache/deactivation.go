@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+
+	"k8s.io/utils/ptr"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+var errWorkloadInactive = errors.New("workload is inactive")
+
+// SetWorkloadActive updates the cache when the workload is activated or
+// deactivated through its .spec.active. A deactivated workload releases the
+// capacity it holds if it's assumed, and it can't be assumed until it's active
+// again. A deactivated workload that holds a quota reservation is accounted as
+// evicted; its usage is released once it's deleted from the cache, as for any
+// other eviction.
+func (c *Cache) SetWorkloadActive(w *kueue.Workload, active bool) {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
+	c.Lock()
+	defer c.Unlock()
+
+	if active {
+		// The workload might be waiting as inadmissible in its ClusterQueue.
+		if cq := c.clusterQueueForLocalQueue(workload.QueueKey(w)); cq != nil {
+			changed = append(changed, cq.Name)
+		}
+		return
+	}
+
+	k := workload.Key(w)
+	c.forgetQueued(w)
+	if cqName, assumed := c.assumedWorkloads[k]; assumed {
+		if cq, found := c.clusterQueues[cqName]; found {
+			cq.deleteWorkload(w)
+			changed = append(changed, cq.Name)
+		}
+		delete(c.assumedWorkloads, k)
+		c.recordTransition(w, WorkloadStatePending, "Deactivated")
+		if c.podsReadyTracking {
+			c.podsReadyCond.Broadcast()
+		}
+		return
+	}
+	if cq := c.clusterQueueForWorkload(w); cq != nil && cq.Workloads[k] != nil {
+		c.recordTransition(w, WorkloadStateEvicted, kueue.WorkloadEvictedByDeactivation)
+	}
+}
+
+// IsWorkloadActive returns whether the workload is active in its spec.
+func (c *Cache) IsWorkloadActive(w *kueue.Workload) bool {
+	return isWorkloadActive(w)
+}
+
+func isWorkloadActive(w *kueue.Workload) bool {
+	return ptr.Deref(w.Spec.Active, true)
+}
+
+// clusterQueueForLocalQueue returns the ClusterQueue of the LocalQueue with the
+// key, or nil if the LocalQueue is unknown.
+func (c *Cache) clusterQueueForLocalQueue(qKey string) *ClusterQueue {
+	for _, cq := range c.clusterQueues {
+		if _, found := cq.localQueues[qKey]; found {
+			return cq
+		}
+	}
+	return nil
+}
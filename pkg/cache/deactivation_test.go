@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestSetWorkloadActive(t *testing.T) {
+	admission := utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "2").Obj()
+	cases := map[string]struct {
+		assume      bool
+		admit       bool
+		wantUsage   FlavorResourceQuantities
+		wantHistory []Transition
+		wantNotify  []string
+	}{
+		"pending workload": {
+			wantUsage: FlavorResourceQuantities{"default": {corev1.ResourceCPU: 0}},
+		},
+		"assumed workload": {
+			assume:    true,
+			wantUsage: FlavorResourceQuantities{"default": {corev1.ResourceCPU: 0}},
+			wantHistory: []Transition{
+				{From: WorkloadStatePending, To: WorkloadStateAssumed, Reason: "Assumed"},
+				{From: WorkloadStateAssumed, To: WorkloadStatePending, Reason: "Deactivated"},
+			},
+			wantNotify: []string{"cq"},
+		},
+		"admitted workload": {
+			admit:     true,
+			wantUsage: FlavorResourceQuantities{"default": {corev1.ResourceCPU: 2_000}},
+			wantHistory: []Transition{
+				{From: WorkloadStatePending, To: WorkloadStateAdmitted, Reason: kueue.WorkloadQuotaReserved},
+				{From: WorkloadStateAdmitted, To: WorkloadStateEvicted, Reason: kueue.WorkloadEvictedByDeactivation},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			var notified []string
+			cache.OnClusterQueueUsageChanged = func(cqName string) {
+				notified = append(notified, cqName)
+			}
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			cq := utiltesting.MakeClusterQueue("cq").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			wl := utiltesting.MakeWorkload("wl", "ns").Queue("lq").Obj()
+			switch {
+			case tc.assume:
+				wl = utiltesting.MakeWorkload("wl", "ns").Queue("lq").ReserveQuota(admission).Obj()
+				if err := cache.AssumeWorkload(wl); err != nil {
+					t.Fatalf("Failed assuming the workload: %v", err)
+				}
+			case tc.admit:
+				wl = utiltesting.MakeWorkload("wl", "ns").Queue("lq").ReserveQuota(admission).Obj()
+				if !cache.AddOrUpdateWorkload(wl) {
+					t.Fatal("Failed adding the workload")
+				}
+			}
+			notified = nil
+
+			wl.Spec.Active = ptr.To(false)
+			cache.SetWorkloadActive(wl, false)
+			if cache.IsWorkloadActive(wl) {
+				t.Error("The workload is active after being deactivated")
+			}
+			if diff := cmp.Diff(tc.wantUsage, cache.clusterQueues["cq"].Usage); diff != "" {
+				t.Errorf("Unexpected usage (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantHistory, cache.workloadTransitions(wl), cmpopts.IgnoreFields(Transition{}, "Time")); diff != "" {
+				t.Errorf("Unexpected history (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantNotify, notified); diff != "" {
+				t.Errorf("Unexpected notifications (-want,+got):\n%s", diff)
+			}
+
+			reserved := utiltesting.MakeWorkload("wl", "ns").Queue("lq").Active(false).ReserveQuota(admission).Obj()
+			if err := cache.AssumeWorkload(reserved); !errors.Is(err, errWorkloadInactive) {
+				t.Errorf("Unexpected error assuming a deactivated workload, got %v, want %v", err, errWorkloadInactive)
+			}
+
+			if err := cache.AddLocalQueue(utiltesting.MakeLocalQueue("lq", "ns").ClusterQueue("cq").Obj()); err != nil {
+				t.Fatalf("Failed adding the LocalQueue: %v", err)
+			}
+			notified = nil
+			wl.Spec.Active = ptr.To(true)
+			cache.SetWorkloadActive(wl, true)
+			if !cache.IsWorkloadActive(wl) {
+				t.Error("The workload is inactive after being activated")
+			}
+			if diff := cmp.Diff([]string{"cq"}, notified); diff != "" {
+				t.Errorf("Unexpected notifications after activating the workload (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsWorkloadActiveInSpec(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	if cache.IsWorkloadActive(utiltesting.MakeWorkload("wl", "ns").Active(false).Obj()) {
+		t.Error("The workload is active while its spec is not")
+	}
+	if !cache.IsWorkloadActive(utiltesting.MakeWorkload("wl", "ns").Obj()) {
+		t.Error("The workload is inactive while its spec doesn't set it")
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// nextDrainVictim returns the next workload to evict to drain the stopped
+// ClusterQueue, so that the caller can drain it gradually, at its own rate,
+// instead of evicting all the workloads at once. The victim is the workload
+// with the lowest effective priority and, for equal priority, the one with the
+// shortest termination grace period and the most recently reserved. Workloads that are already being evicted are skipped.
+// Returns nil if the ClusterQueue doesn't exist, isn't stopped or has nothing
+// left to evict. The returned info is a copy that the caller can modify.
+func (c *Cache) nextDrainVictim(cqName string) *workload.Info {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok || !cq.isStopped {
+		return nil
+	}
+	var candidates []*workload.Info
+	for _, wi := range cq.Workloads {
+		if !apimeta.IsStatusConditionTrue(wi.Obj.Status.Conditions, kueue.WorkloadEvicted) {
+			candidates = append(candidates, wi)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sortPreemptionCandidates(candidates, map[string]*ClusterQueue{cq.Name: cq})
+	return cloneInfo(candidates[0])
+}
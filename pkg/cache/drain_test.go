@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestNextDrainVictim(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	makeCQ := func(name string) *utiltesting.ClusterQueueWrapper {
+		return utiltesting.MakeClusterQueue(name).
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj())
+	}
+	for _, cq := range []*kueue.ClusterQueue{makeCQ("running").Obj(), makeCQ("empty").StopPolicy(kueue.Hold).Obj()} {
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	makeWorkload := func(name string, priority int32) *kueue.Workload {
+		return utiltesting.MakeWorkload(name, "ns").
+			Priority(priority).
+			ReserveQuota(utiltesting.MakeAdmission("running").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+			Obj()
+	}
+	workloads := []*kueue.Workload{
+		makeWorkload("high", 10),
+		makeWorkload("low", -5),
+		makeWorkload("mid", 0),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %q", wl.Name)
+		}
+	}
+
+	if victim := cache.nextDrainVictim("running"); victim != nil {
+		t.Errorf("Unexpected victim %q in a ClusterQueue that isn't stopped", victim.Obj.Name)
+	}
+	if victim := cache.nextDrainVictim("empty"); victim != nil {
+		t.Errorf("Unexpected victim %q in an empty ClusterQueue", victim.Obj.Name)
+	}
+	if victim := cache.nextDrainVictim("unknown"); victim != nil {
+		t.Errorf("Unexpected victim %q in an unknown ClusterQueue", victim.Obj.Name)
+	}
+
+	if err := cache.UpdateClusterQueue(makeCQ("running").StopPolicy(kueue.HoldAndDrain).Obj()); err != nil {
+		t.Fatalf("Failed stopping ClusterQueue: %v", err)
+	}
+	var got []string
+	for victim := cache.nextDrainVictim("running"); victim != nil; victim = cache.nextDrainVictim("running") {
+		got = append(got, victim.Obj.Name)
+		if len(got) > len(workloads) {
+			t.Fatalf("Too many victims: %v", got)
+		}
+		// The caller evicts the victim.
+		evicted := victim.Obj.DeepCopy()
+		evicted.Status.Conditions = append(evicted.Status.Conditions, metav1.Condition{
+			Type:   kueue.WorkloadEvicted,
+			Status: metav1.ConditionTrue,
+			Reason: kueue.WorkloadEvictedByClusterQueueStopped,
+		})
+		if err := cache.UpdateWorkload(victim.Obj, evicted); err != nil {
+			t.Fatalf("Failed updating workload: %v", err)
+		}
+	}
+	if diff := cmp.Diff([]string{"low", "mid", "high"}, got); diff != "" {
+		t.Errorf("Unexpected victims (-want,+got):\n%s", diff)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/metrics"
+)
+
+// StateDump is the state of the cache, as serialized by DumpState.
+type StateDump struct {
+	ClusterQueues   []ClusterQueueDump              `json:"clusterQueues"`
+	Cohorts         []CohortDump                    `json:"cohorts"`
+	ResourceFlavors []kueue.ResourceFlavorReference `json:"resourceFlavors"`
+	// AssumedWorkloads maps the keys of the assumed workloads to the name of
+	// their ClusterQueue.
+	AssumedWorkloads map[string]string `json:"assumedWorkloads"`
+	// WorkloadHistories are the last state transitions of each workload,
+	// keyed by workload key, from the oldest to the newest.
+	WorkloadHistories map[string][]Transition `json:"workloadHistories,omitempty"`
+	// AuditLog are the last admissions and evictions recorded by the cache,
+	// from the oldest to the newest.
+	AuditLog []AuditEntry `json:"auditLog,omitempty"`
+}
+
+// ClusterQueueDump is the state of a ClusterQueue in a StateDump.
+type ClusterQueueDump struct {
+	Name          string                     `json:"name"`
+	Cohort        string                     `json:"cohort,omitempty"`
+	Status        metrics.ClusterQueueStatus `json:"status"`
+	Nominal       FlavorResourceQuantities   `json:"nominal"`
+	Usage         FlavorResourceQuantities   `json:"usage"`
+	AdmittedUsage FlavorResourceQuantities   `json:"admittedUsage"`
+	// Workloads are the keys of the workloads holding a quota reservation in
+	// the ClusterQueue, sorted.
+	Workloads []string `json:"workloads"`
+}
+
+// CohortDump is the state of a cohort in a StateDump.
+type CohortDump struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+	// UsageHistory are the last usage samples of the cohort, from the oldest
+	// to the newest.
+	UsageHistory []UsageSample `json:"usageHistory,omitempty"`
+}
+
+// DumpState serializes the ClusterQueues, cohorts with their usage history,
+// flavors, assumed workloads, workload histories and audit log of the cache to
+// JSON, for diagnostics. The state is taken under the read lock, so it's consistent.
+func (c *Cache) DumpState() ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+	dump := StateDump{
+		ClusterQueues:     make([]ClusterQueueDump, 0, len(c.clusterQueues)),
+		Cohorts:           make([]CohortDump, 0, len(c.cohorts)),
+		ResourceFlavors:   make([]kueue.ResourceFlavorReference, 0, len(c.resourceFlavors)),
+		AssumedWorkloads:  c.assumedWorkloads,
+		WorkloadHistories: make(map[string][]Transition, len(c.workloadHistories)),
+		AuditLog:          c.auditLog.list(0),
+	}
+	for _, cq := range c.clusterQueues {
+		cqDump := ClusterQueueDump{
+			Name:          cq.Name,
+			Status:        cq.Status,
+			Nominal:       make(FlavorResourceQuantities),
+			Usage:         cq.Usage,
+			AdmittedUsage: cq.AdmittedUsage,
+			Workloads:     sets.List(sets.KeySet(cq.Workloads)),
+		}
+		if cq.Cohort != nil {
+			cqDump.Cohort = cq.Cohort.Name
+		}
+		for _, rg := range cq.ResourceGroups {
+			for _, flvQuotas := range rg.Flavors {
+				cqDump.Nominal[flvQuotas.Name] = make(map[corev1.ResourceName]int64, len(flvQuotas.Resources))
+				for rName, rQuota := range flvQuotas.Resources {
+					cqDump.Nominal[flvQuotas.Name][rName] = rQuota.Nominal
+				}
+			}
+		}
+		dump.ClusterQueues = append(dump.ClusterQueues, cqDump)
+	}
+	slices.SortFunc(dump.ClusterQueues, func(a, b ClusterQueueDump) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, cohort := range c.cohorts {
+		members := make([]string, 0, cohort.Members.Len())
+		for _, cq := range cohort.SortedMembers() {
+			members = append(members, cq.Name)
+		}
+		dump.Cohorts = append(dump.Cohorts, CohortDump{
+			Name:         cohort.Name,
+			Members:      members,
+			UsageHistory: c.cohortUsageHistory(cohort.Name),
+		})
+	}
+	slices.SortFunc(dump.Cohorts, func(a, b CohortDump) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for name := range c.resourceFlavors {
+		dump.ResourceFlavors = append(dump.ResourceFlavors, name)
+	}
+	slices.Sort(dump.ResourceFlavors)
+	for key, h := range c.workloadHistories {
+		dump.WorkloadHistories[key] = h.list()
+	}
+	return json.Marshal(dump)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestDumpState(t *testing.T) {
+	now := time.Date(2024, time.May, 1, 21, 0, 0, 0, time.UTC)
+	cache := New(utiltesting.NewFakeClient(), WithClock(testingclock.NewFakeClock(now)))
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	clusterQueues := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("b").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+			Cohort("one").
+			Obj(),
+		utiltesting.MakeClusterQueue("a").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "6").Obj()).
+			Cohort("one").
+			Obj(),
+	}
+	for _, cq := range clusterQueues {
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	admitted := utiltesting.MakeWorkload("admitted", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+		Admitted(true).
+		Obj()
+	if !cache.AddOrUpdateWorkload(admitted) {
+		t.Fatal("Failed adding workload")
+	}
+	assumed := utiltesting.MakeWorkload("assumed", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("b").Assignment(corev1.ResourceCPU, "default", "2").Obj()).
+		Obj()
+	if err := cache.AssumeWorkload(assumed); err != nil {
+		t.Fatalf("Failed assuming workload: %v", err)
+	}
+	sampledAt := time.Date(2024, time.May, 1, 22, 0, 0, 0, time.UTC)
+	cache.SampleCohortUsage(sampledAt)
+
+	data, err := cache.DumpState()
+	if err != nil {
+		t.Fatalf("Failed dumping the state: %v", err)
+	}
+	var got StateDump
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Failed unmarshalling the dump: %v", err)
+	}
+	want := StateDump{
+		ClusterQueues: []ClusterQueueDump{
+			{
+				Name:          "a",
+				Cohort:        "one",
+				Status:        active,
+				Nominal:       FlavorResourceQuantities{"default": {corev1.ResourceCPU: 6_000}},
+				Usage:         FlavorResourceQuantities{"default": {corev1.ResourceCPU: 1_000}},
+				AdmittedUsage: FlavorResourceQuantities{"default": {corev1.ResourceCPU: 1_000}},
+				Workloads:     []string{"ns/admitted"},
+			},
+			{
+				Name:          "b",
+				Cohort:        "one",
+				Status:        active,
+				Nominal:       FlavorResourceQuantities{"default": {corev1.ResourceCPU: 4_000}},
+				Usage:         FlavorResourceQuantities{"default": {corev1.ResourceCPU: 2_000}},
+				AdmittedUsage: FlavorResourceQuantities{"default": {corev1.ResourceCPU: 0}},
+				Workloads:     []string{"ns/assumed"},
+			},
+		},
+		Cohorts: []CohortDump{{
+			Name:    "one",
+			Members: []string{"a", "b"},
+			UsageHistory: []UsageSample{{
+				Time:  sampledAt,
+				Usage: FlavorResourceQuantities{"default": {corev1.ResourceCPU: 3_000}},
+			}},
+		}},
+		ResourceFlavors:  []kueue.ResourceFlavorReference{"default"},
+		AssumedWorkloads: map[string]string{"ns/assumed": "b"},
+		WorkloadHistories: map[string][]Transition{
+			"ns/admitted": {{Time: now, From: WorkloadStatePending, To: WorkloadStateAdmitted, Reason: kueue.WorkloadQuotaReserved}},
+			"ns/assumed":  {{Time: now, From: WorkloadStatePending, To: WorkloadStateAssumed, Reason: "Assumed"}},
+		},
+		AuditLog: []AuditEntry{
+			{
+				Time:         now,
+				Action:       AuditActionAdmit,
+				Workload:     "ns/admitted",
+				ClusterQueue: "a",
+				Flavors:      map[string]map[corev1.ResourceName]kueue.ResourceFlavorReference{"main": {corev1.ResourceCPU: "default"}},
+				Reason:       kueue.WorkloadQuotaReserved,
+			},
+			{
+				Time:         now,
+				Action:       AuditActionAdmit,
+				Workload:     "ns/assumed",
+				ClusterQueue: "b",
+				Flavors:      map[string]map[corev1.ResourceName]kueue.ResourceFlavorReference{"main": {corev1.ResourceCPU: "default"}},
+				Reason:       "Assumed",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected dump (-want,+got):\n%s", diff)
+	}
+}
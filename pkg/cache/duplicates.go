@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// duplicatePendingWorkloads groups, by their workload.Fingerprint, the keys
+// of the pending workloads of the ClusterQueue, among the given ones, that
+// have the same shape as some other. The workloads that hold quota in the
+// cache are not pending. The keys in a group are sorted, and the groups are
+// sorted by their first key. Returns nil if the ClusterQueue doesn't exist.
+func (c *Cache) duplicatePendingWorkloads(cqName string, pending []*workload.Info) [][]string {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	byFingerprint := make(map[string][]string)
+	for _, wi := range pending {
+		k := workload.Key(wi.Obj)
+		if wi.ClusterQueue != cqName || workload.HasQuotaReservation(wi.Obj) || cq.Workloads[k] != nil {
+			continue
+		}
+		fingerprint := workload.Fingerprint(wi.Obj)
+		byFingerprint[fingerprint] = append(byFingerprint[fingerprint], k)
+	}
+	var groups [][]string
+	for _, keys := range byFingerprint {
+		if len(keys) > 1 {
+			sort.Strings(keys)
+			groups = append(groups, keys)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][0] < groups[j][0]
+	})
+	return groups
+}
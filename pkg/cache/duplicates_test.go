@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+func TestDuplicatePendingWorkloads(t *testing.T) {
+	pendingInfo := func(w *kueue.Workload, cqName string) *workload.Info {
+		wi := workload.NewInfo(w)
+		wi.ClusterQueue = cqName
+		return wi
+	}
+	small := func(name string) *kueue.Workload {
+		return utiltesting.MakeWorkload(name, "ns").Request(corev1.ResourceCPU, "1").Obj()
+	}
+	large := func(name string) *kueue.Workload {
+		return utiltesting.MakeWorkload(name, "ns").Request(corev1.ResourceCPU, "4").Obj()
+	}
+	cases := map[string]struct {
+		cqName  string
+		pending []*workload.Info
+		want    [][]string
+	}{
+		"no duplicates": {
+			cqName: "cq",
+			pending: []*workload.Info{
+				pendingInfo(small("a"), "cq"),
+				pendingInfo(large("b"), "cq"),
+			},
+		},
+		"three workloads with the same shape": {
+			cqName: "cq",
+			pending: []*workload.Info{
+				pendingInfo(small("c"), "cq"),
+				pendingInfo(large("d"), "cq"),
+				pendingInfo(small("a"), "cq"),
+				pendingInfo(small("b"), "cq"),
+			},
+			want: [][]string{{"ns/a", "ns/b", "ns/c"}},
+		},
+		"several groups": {
+			cqName: "cq",
+			pending: []*workload.Info{
+				pendingInfo(large("d"), "cq"),
+				pendingInfo(small("b"), "cq"),
+				pendingInfo(large("a"), "cq"),
+				pendingInfo(small("c"), "cq"),
+			},
+			want: [][]string{{"ns/a", "ns/d"}, {"ns/b", "ns/c"}},
+		},
+		"workloads of other ClusterQueues or holding quota": {
+			cqName: "cq",
+			pending: []*workload.Info{
+				pendingInfo(small("a"), "cq"),
+				pendingInfo(small("b"), "other"),
+				pendingInfo(utiltesting.MakeWorkload("admitted", "ns").
+					Request(corev1.ResourceCPU, "1").
+					ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+					Obj(), "cq"),
+			},
+		},
+		"missing ClusterQueue": {
+			cqName: "missing",
+			pending: []*workload.Info{
+				pendingInfo(small("a"), "missing"),
+				pendingInfo(small("b"), "missing"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			cq := utiltesting.MakeClusterQueue("cq").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			got := cache.duplicatePendingWorkloads(tc.cqName, tc.pending)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected duplicate workloads (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// admissionRateWindow is the number of recent admissions of a ClusterQueue
+// from which its admission rate is computed.
+const admissionRateWindow = 20
+
+// estimatedWaitTime returns a rough estimate of how long the pending workload
+// will wait until it's admitted, given its position in the queue of its
+// ClusterQueue, where 0 is the head. The estimate is the average interval
+// between the recent admissions of the ClusterQueue, multiplied by the number
+// of workloads to be admitted up to and including this one.
+// Returns false if the ClusterQueue of the workload is unknown or it didn't
+// admit at least two workloads yet. Only the quota reservations made with
+// AssumeWorkload count as admissions, so the history is empty after a
+// restart.
+func (c *Cache) estimatedWaitTime(w *kueue.Workload, position int) (time.Duration, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	if position < 0 {
+		return 0, false
+	}
+	cq := c.clusterQueueForLocalQueue(workload.QueueKey(w))
+	if cq == nil {
+		return 0, false
+	}
+	times := c.admissionTimes[cq.Name]
+	if len(times) < 2 {
+		return 0, false
+	}
+	interval := times[len(times)-1].Sub(times[0]) / time.Duration(len(times)-1)
+	return interval * time.Duration(position+1), true
+}
+
+// recordAdmissionTime records the time of an admission in the ClusterQueue,
+// keeping the last admissionRateWindow ones.
+func (c *Cache) recordAdmissionTime(cqName string) {
+	times := append(c.admissionTimes[cqName], c.clock.Now())
+	if len(times) > admissionRateWindow {
+		times = times[len(times)-admissionRateWindow:]
+	}
+	c.admissionTimes[cqName] = times
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestEstimatedWaitTime(t *testing.T) {
+	pending := utiltesting.MakeWorkload("pending", "ns").Queue("lq").Request(corev1.ResourceCPU, "1").Obj()
+	cases := map[string]struct {
+		// admissionIntervals are the intervals between the admissions in the
+		// ClusterQueue.
+		admissionIntervals []time.Duration
+		workload           *kueue.Workload
+		position           int
+		wantWait           time.Duration
+		wantOk             bool
+	}{
+		"no admissions": {
+			workload: pending,
+		},
+		"single admission": {
+			admissionIntervals: []time.Duration{0},
+			workload:           pending,
+		},
+		"head of the queue": {
+			admissionIntervals: []time.Duration{0, time.Minute, time.Minute},
+			workload:           pending,
+			wantWait:           time.Minute,
+			wantOk:             true,
+		},
+		"average interval times the position": {
+			admissionIntervals: []time.Duration{0, time.Minute, 3 * time.Minute},
+			workload:           pending,
+			position:           4,
+			wantWait:           10 * time.Minute,
+			wantOk:             true,
+		},
+		"only the recent admissions count": {
+			admissionIntervals: append([]time.Duration{0, time.Hour}, repeatDuration(time.Minute, admissionRateWindow-1)...),
+			workload:           pending,
+			wantWait:           time.Minute,
+			wantOk:             true,
+		},
+		"unknown queue": {
+			admissionIntervals: []time.Duration{0, time.Minute},
+			workload:           utiltesting.MakeWorkload("pending", "ns").Queue("other").Request(corev1.ResourceCPU, "1").Obj(),
+		},
+		"negative position": {
+			admissionIntervals: []time.Duration{0, time.Minute},
+			workload:           pending,
+			position:           -1,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			cache := New(utiltesting.NewFakeClient(), WithClock(fakeClock))
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			cq := utiltesting.MakeClusterQueue("cq").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "100").Obj()).
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			if err := cache.AddLocalQueue(utiltesting.MakeLocalQueue("lq", "ns").ClusterQueue("cq").Obj()); err != nil {
+				t.Fatalf("Failed adding LocalQueue: %v", err)
+			}
+			for i, interval := range tc.admissionIntervals {
+				fakeClock.Step(interval)
+				wl := utiltesting.MakeWorkload(fmt.Sprintf("admitted-%d", i), "ns").
+					Queue("lq").
+					Request(corev1.ResourceCPU, "1").
+					ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+					Obj()
+				if err := cache.AssumeWorkload(wl); err != nil {
+					t.Fatalf("Failed assuming workload: %v", err)
+				}
+			}
+			fakeClock.Step(time.Hour)
+
+			gotWait, gotOk := cache.estimatedWaitTime(tc.workload, tc.position)
+			if gotWait != tc.wantWait || gotOk != tc.wantOk {
+				t.Errorf("Unexpected estimated wait (%v, %t), want (%v, %t)", gotWait, gotOk, tc.wantWait, tc.wantOk)
+			}
+		})
+	}
+}
+
+func repeatDuration(d time.Duration, n int) []time.Duration {
+	durations := make([]time.Duration, n)
+	for i := range durations {
+		durations[i] = d
+	}
+	return durations
+}
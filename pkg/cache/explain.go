@@ -0,0 +1,173 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// explainUnschedulable returns why the workload can't be assigned flavors in
+// the ClusterQueue, in the style of the explanations of the kube-scheduler.
+// For each PodSet and resource group without a suitable flavor, it reports
+// why each flavor was rejected: the flavor doesn't exist or is disabled, it
+// is excluded by the workload, it has a taint that the PodSet doesn't tolerate, its node labels don't match
+// the node selector of the PodSet, or it doesn't have enough quota, including
+// the quota that can be borrowed from the cohort, for the requests of the
+// PodSet. The PodSets are considered in isolation, without the usage of the
+// other PodSets of the workload.
+// Returns an empty string if each PodSet has a suitable flavor.
+func (c *Cache) explainUnschedulable(w *kueue.Workload, cqName string) string {
+	snap := c.Snapshot()
+	cq, ok := snap.ClusterQueues[cqName]
+	if !ok {
+		if snap.InactiveClusterQueueSets.Has(cqName) {
+			return fmt.Sprintf("ClusterQueue %s is inactive", cqName)
+		}
+		return fmt.Sprintf("ClusterQueue %s not found", cqName)
+	}
+
+	excluded := workload.ExcludedFlavors(w)
+	var explanations []string
+	for _, psr := range workload.NewInfo(w).TotalRequests {
+		ps := podSetByName(w, psr.Name)
+		if ps == nil {
+			continue
+		}
+		var uncovered []string
+		var rgs []*ResourceGroup
+		for rName, val := range psr.Requests {
+			rg := cq.RGByResource[rName]
+			if rg == nil {
+				if val > 0 {
+					uncovered = append(uncovered, string(rName))
+				}
+				continue
+			}
+			if !slices.Contains(rgs, rg) {
+				rgs = append(rgs, rg)
+			}
+		}
+		if len(uncovered) > 0 {
+			sort.Strings(uncovered)
+			explanations = append(explanations, fmt.Sprintf("PodSet %s: resources %s are not covered by the ClusterQueue", psr.Name, strings.Join(uncovered, ", ")))
+		}
+		sort.Slice(rgs, func(i, j int) bool {
+			return firstResource(rgs[i]) < firstResource(rgs[j])
+		})
+		for _, rg := range rgs {
+			if explanation := explainResourceGroup(&snap, cq, rg, ps, psr.Requests, excluded); explanation != "" {
+				explanations = append(explanations, fmt.Sprintf("PodSet %s: %s", psr.Name, explanation))
+			}
+		}
+	}
+	return strings.Join(explanations, "; ")
+}
+
+// explainResourceGroup returns why none of the flavors of the resource group
+// can be assigned to the PodSet, or an empty string if one of them can.
+func explainResourceGroup(snap *Snapshot, cq *ClusterQueue, rg *ResourceGroup, ps *kueue.PodSet, requests workload.Requests, excluded sets.Set[kueue.ResourceFlavorReference]) string {
+	rejections := make([]string, 0, len(rg.Flavors))
+	for _, flvQuotas := range rg.Flavors {
+		reason := rejectFlavor(snap, cq, flvQuotas, ps, requests, excluded)
+		if reason == "" {
+			return ""
+		}
+		rejections = append(rejections, fmt.Sprintf("flavor %s %s", flvQuotas.Name, reason))
+	}
+	covered := sets.List(rg.CoveredResources)
+	names := make([]string, len(covered))
+	for i, rName := range covered {
+		names[i] = string(rName)
+	}
+	return fmt.Sprintf("0/%d flavors are available for %s: %s", len(rg.Flavors), strings.Join(names, ", "), strings.Join(rejections, ", "))
+}
+
+// rejectFlavor returns why the flavor can't be assigned to the PodSet, or an
+// empty string if it can. The excluded flavors are the ones the workload
+// declared it must not be assigned.
+func rejectFlavor(snap *Snapshot, cq *ClusterQueue, flvQuotas FlavorQuotas, ps *kueue.PodSet, requests workload.Requests, excluded sets.Set[kueue.ResourceFlavorReference]) string {
+	rf, found := snap.ResourceFlavors[flvQuotas.Name]
+	if !found {
+		return "not found"
+	}
+	if snap.DisabledFlavors.Has(flvQuotas.Name) {
+		return "is disabled"
+	}
+	if excluded.Has(flvQuotas.Name) {
+		return "is excluded by the workload"
+	}
+	spec := &ps.Template.Spec
+	taint, untolerated := corev1helpers.FindMatchingUntoleratedTaint(rf.Spec.NodeTaints, spec.Tolerations, func(t *corev1.Taint) bool {
+		return t.Effect == corev1.TaintEffectNoSchedule || t.Effect == corev1.TaintEffectNoExecute
+	})
+	if untolerated {
+		return fmt.Sprintf("has untolerated taint %s", taint.ToString())
+	}
+	if !flavorMatchesNodeSelector(rf, spec.NodeSelector) {
+		return "doesn't match the node selector"
+	}
+	if !flavorSatisfiesSpread(rf, snap.FlavorTopologyKeys[flvQuotas.Name], snap.FlavorTopologyDomains[flvQuotas.Name], spec.TopologySpreadConstraints) {
+		return "doesn't have enough topology domains for the spread constraints"
+	}
+	rNames := make([]corev1.ResourceName, 0, len(flvQuotas.Resources))
+	for rName := range flvQuotas.Resources {
+		rNames = append(rNames, rName)
+	}
+	sort.Slice(rNames, func(i, j int) bool { return rNames[i] < rNames[j] })
+	for _, rName := range rNames {
+		val, requested := requests[rName]
+		if !requested {
+			continue
+		}
+		if available := availableQuota(cq, flvQuotas.Name, rName, flvQuotas.Resources[rName]); val > available {
+			requestedQ := workload.ResourceQuantity(rName, val)
+			availableQ := workload.ResourceQuantity(rName, max(0, available))
+			return fmt.Sprintf("has insufficient quota for %s (requested %s, available %s)", rName, requestedQ.String(), availableQ.String())
+		}
+	}
+	return ""
+}
+
+// availableQuota returns how much of the resource in the flavor the
+// ClusterQueue in the snapshot can still use, borrowing from its cohort
+// within its borrowing limit.
+func availableQuota(cq *ClusterQueue, fName kueue.ResourceFlavorReference, rName corev1.ResourceName, rQuota *ResourceQuota) int64 {
+	used := cq.Usage[fName][rName]
+	nominal := rQuota.OvercommittedNominal()
+	if cq.Cohort == nil {
+		return nominal - used
+	}
+	available := cq.RequestableCohortQuota(fName, rName) - cq.UsedCohortQuota(fName, rName)
+	if limit := cq.BoostedBorrowingLimit(rName, rQuota.BorrowingLimit); limit != nil {
+		available = min(available, nominal+*limit-used)
+	}
+	return available
+}
+
+func firstResource(rg *ResourceGroup) corev1.ResourceName {
+	return sets.List(rg.CoveredResources)[0]
+}
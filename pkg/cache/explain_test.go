@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestExplainUnschedulable(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	disabled := utiltesting.MakeResourceFlavor("disabled").Obj()
+	disabled.Annotations = map[string]string{constants.ResourceFlavorDisabledAnnotation: "true"}
+	cache.AddOrUpdateResourceFlavor(disabled)
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("tainted").
+		Taint(corev1.Taint{Key: "spot", Value: "true", Effect: corev1.TaintEffectNoSchedule}).
+		Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("labeled").Label("zone", "b").Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("small").Label("zone", "a").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(
+			*utiltesting.MakeFlavorQuotas("disabled").Resource(corev1.ResourceCPU, "10").Obj(),
+			*utiltesting.MakeFlavorQuotas("tainted").Resource(corev1.ResourceCPU, "10").Obj(),
+			*utiltesting.MakeFlavorQuotas("labeled").Resource(corev1.ResourceCPU, "10").Obj(),
+			*utiltesting.MakeFlavorQuotas("small").Resource(corev1.ResourceCPU, "1").Obj(),
+		).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+
+	cases := map[string]struct {
+		workload *kueue.Workload
+		cqName   string
+		want     string
+	}{
+		"every flavor rejected": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				Request(corev1.ResourceCPU, "2").
+				NodeSelector(map[string]string{"zone": "a"}).
+				Obj(),
+			cqName: "cq",
+			want: "PodSet main: 0/4 flavors are available for cpu: " +
+				"flavor disabled is disabled, " +
+				"flavor tainted has untolerated taint spot=true:NoSchedule, " +
+				"flavor labeled doesn't match the node selector, " +
+				"flavor small has insufficient quota for cpu (requested 2, available 1)",
+		},
+		"fits in a flavor": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				Request(corev1.ResourceCPU, "1").
+				NodeSelector(map[string]string{"zone": "a"}).
+				Obj(),
+			cqName: "cq",
+		},
+		"resource not covered": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				Request(corev1.ResourceCPU, "1").
+				Request("example.com/gpu", "1").
+				NodeSelector(map[string]string{"zone": "a"}).
+				Obj(),
+			cqName: "cq",
+			want:   "PodSet main: resources example.com/gpu are not covered by the ClusterQueue",
+		},
+		"remaining flavors rejected after exclusions": {
+			workload: utiltesting.MakeWorkload("wl", "ns").
+				Annotations(map[string]string{constants.ExcludedFlavorsAnnotation: "small"}).
+				Request(corev1.ResourceCPU, "1").
+				NodeSelector(map[string]string{"zone": "a"}).
+				Obj(),
+			cqName: "cq",
+			want: "PodSet main: 0/4 flavors are available for cpu: " +
+				"flavor disabled is disabled, " +
+				"flavor tainted has untolerated taint spot=true:NoSchedule, " +
+				"flavor labeled doesn't match the node selector, " +
+				"flavor small is excluded by the workload",
+		},
+		"unknown ClusterQueue": {
+			workload: utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, "1").Obj(),
+			cqName:   "unknown",
+			want:     "ClusterQueue unknown not found",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := cache.explainUnschedulable(tc.workload, tc.cqName); got != tc.want {
+				t.Errorf("Unexpected explanation\ngot:  %q\nwant: %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+)
+
+// defaultFlavorShareWeight is the share weight of the flavors that didn't set
+// one.
+const defaultFlavorShareWeight = 1.0
+
+// flavorShareWeightFromAnnotation returns the weight of the usage of the
+// ResourceFlavor in the fair sharing computations, declared in the
+// ResourceFlavorShareWeightAnnotation. A weight below 1 makes the usage of a
+// cheap flavor, such as spot capacity, count less towards the share of a
+// ClusterQueue than the usage of the other flavors. Invalid weights are
+// ignored.
+func flavorShareWeightFromAnnotation(rf *kueue.ResourceFlavor) float64 {
+	value, found := rf.Annotations[constants.ResourceFlavorShareWeightAnnotation]
+	if !found {
+		return defaultFlavorShareWeight
+	}
+	weight, err := strconv.ParseFloat(value, 64)
+	if err != nil || weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+		return defaultFlavorShareWeight
+	}
+	return weight
+}
+
+// flavorShareWeight returns the share weight of the ResourceFlavor among the
+// given weights.
+func flavorShareWeight(weights map[kueue.ResourceFlavorReference]float64, fName kueue.ResourceFlavorReference) float64 {
+	if weight, found := weights[fName]; found {
+		return weight
+	}
+	return defaultFlavorShareWeight
+}
+
+// DominantResourceShare returns the share of the ClusterQueue in its cohort:
+// the highest, among its resources, fraction of the nominal quota of the
+// cohort that the ClusterQueue borrows. The borrowing in each flavor is
+// multiplied by the share weight of the flavor.
+// Returns 0 if the ClusterQueue isn't in the snapshot or doesn't belong to a
+// cohort.
+func (s *Snapshot) DominantResourceShare(cqName string) float64 {
+	cq := s.ClusterQueues[cqName]
+	if cq == nil || cq.Cohort == nil {
+		return 0
+	}
+	return dominantResourceShare(cq, cohortNominal(cq.Cohort), s.FlavorShareWeights)
+}
+
+// clusterQueueShare returns the dominant resource share of the ClusterQueue
+// in its cohort, see Snapshot.DominantResourceShare.
+func (c *Cache) clusterQueueShare(cqName string) (float64, error) {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return 0, errCqNotFound
+	}
+	if cq.Cohort == nil {
+		return 0, nil
+	}
+	return dominantResourceShare(cq, cohortNominal(cq.Cohort), c.flavorShareWeights), nil
+}
+
+// fairShareOrder returns the names of the active ClusterQueues in the cohort,
+// from the least to the most satisfied one, according to their dominant
+// resource share. The ClusterQueues first in the order should be allowed to
+// borrow first.
+func (c *Cache) fairShareOrder(cohortName string) ([]string, error) {
+	c.RLock()
+	defer c.RUnlock()
+	cohort, ok := c.cohorts[cohortName]
+	if !ok {
+		return nil, errCohortNotFound
+	}
+	nominal := cohortNominal(cohort)
+	shares := make(map[string]float64, cohort.Members.Len())
+	var names []string
+	for _, cq := range cohort.SortedMembers() {
+		if !cq.Active() {
+			continue
+		}
+		names = append(names, cq.Name)
+		shares[cq.Name] = dominantResourceShare(cq, nominal, c.flavorShareWeights)
+	}
+	// The members are sorted by name, so ties keep that order.
+	sort.SliceStable(names, func(i, j int) bool {
+		return shares[names[i]] < shares[names[j]]
+	})
+	return names, nil
+}
+
+func dominantResourceShare(cq *ClusterQueue, nominal Resources, weights map[kueue.ResourceFlavorReference]float64) float64 {
+	borrowed := make(map[corev1.ResourceName]float64)
+	for _, rg := range cq.ResourceGroups {
+		for _, flvQuotas := range rg.Flavors {
+			weight := flavorShareWeight(weights, flvQuotas.Name)
+			for rName, rQuota := range flvQuotas.Resources {
+				if val := cq.Usage[flvQuotas.Name][rName] - rQuota.Nominal; val > 0 {
+					borrowed[rName] += float64(val) * weight
+				}
+			}
+		}
+	}
+	var drs float64
+	for rName, val := range borrowed {
+		if nominal[rName] > 0 {
+			drs = max(drs, val/float64(nominal[rName]))
+		}
+	}
+	return drs
+}
+
+// cohortNominal returns, per resource, the nominal quota of all the members of
+// the cohort.
+func cohortNominal(cohort *Cohort) Resources {
+	nominal := make(Resources)
+	for cq := range cohort.Members {
+		for _, rg := range cq.ResourceGroups {
+			for _, flvQuotas := range rg.Flavors {
+				for rName, rQuota := range flvQuotas.Resources {
+					nominal[rName] += rQuota.Nominal
+				}
+			}
+		}
+	}
+	return nominal
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestFairShareOrder(t *testing.T) {
+	cases := map[string]struct {
+		spotWeight string
+		wantOrder  []string
+		wantShares map[string]float64
+	}{
+		"default weights": {
+			spotWeight: "1",
+			wantOrder:  []string{"lender", "a", "b"},
+			wantShares: map[string]float64{"lender": 0, "a": 0.2, "b": 0.3},
+		},
+		"discounted spot usage": {
+			spotWeight: "0.5",
+			wantOrder:  []string{"lender", "b", "a"},
+			wantShares: map[string]float64{"lender": 0, "a": 0.2, "b": 0.15},
+		},
+		"free spot usage": {
+			spotWeight: "0",
+			wantOrder:  []string{"b", "lender", "a"},
+			wantShares: map[string]float64{"lender": 0, "a": 0.2, "b": 0},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+			spot := utiltesting.MakeResourceFlavor("spot").Obj()
+			spot.Annotations = map[string]string{constants.ResourceFlavorShareWeightAnnotation: tc.spotWeight}
+			cache.AddOrUpdateResourceFlavor(spot)
+			clusterQueues := []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("lender").
+					ResourceGroup(
+						*utiltesting.MakeFlavorQuotas("on-demand").Resource(corev1.ResourceCPU, "10").Obj(),
+						*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "10").Obj(),
+					).
+					Cohort("one").
+					Obj(),
+				utiltesting.MakeClusterQueue("a").
+					ResourceGroup(
+						*utiltesting.MakeFlavorQuotas("on-demand").Resource(corev1.ResourceCPU, "0").Obj(),
+						*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "0").Obj(),
+					).
+					Cohort("one").
+					Obj(),
+				utiltesting.MakeClusterQueue("b").
+					ResourceGroup(
+						*utiltesting.MakeFlavorQuotas("on-demand").Resource(corev1.ResourceCPU, "0").Obj(),
+						*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "0").Obj(),
+					).
+					Cohort("one").
+					Obj(),
+			}
+			for _, cq := range clusterQueues {
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			workloads := []*kueue.Workload{
+				utiltesting.MakeWorkload("on-demand", "ns").
+					ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "on-demand", "4").Obj()).
+					Obj(),
+				utiltesting.MakeWorkload("spot", "ns").
+					ReserveQuota(utiltesting.MakeAdmission("b").Assignment(corev1.ResourceCPU, "spot", "6").Obj()).
+					Obj(),
+			}
+			for _, wl := range workloads {
+				if !cache.AddOrUpdateWorkload(wl) {
+					t.Fatalf("Failed adding workload %q", wl.Name)
+				}
+			}
+
+			gotOrder, err := cache.fairShareOrder("one")
+			if err != nil {
+				t.Fatalf("Failed getting the fair share order: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantOrder, gotOrder); diff != "" {
+				t.Errorf("Unexpected order (-want,+got):\n%s", diff)
+			}
+			gotShares := make(map[string]float64)
+			for _, cqName := range gotOrder {
+				if gotShares[cqName], err = cache.clusterQueueShare(cqName); err != nil {
+					t.Fatalf("Failed getting the share of %q: %v", cqName, err)
+				}
+			}
+			if diff := cmp.Diff(tc.wantShares, gotShares); diff != "" {
+				t.Errorf("Unexpected shares (-want,+got):\n%s", diff)
+			}
+			snapshot := cache.Snapshot()
+			gotSnapshotShares := make(map[string]float64)
+			for _, cqName := range gotOrder {
+				gotSnapshotShares[cqName] = snapshot.DominantResourceShare(cqName)
+			}
+			if diff := cmp.Diff(tc.wantShares, gotSnapshotShares); diff != "" {
+				t.Errorf("Unexpected shares in the snapshot (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFlavorShareWeight(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	if got := flavorShareWeight(cache.flavorShareWeights, "spot"); got != defaultFlavorShareWeight {
+		t.Errorf("Unexpected default weight %v", got)
+	}
+	spot := utiltesting.MakeResourceFlavor("spot").Obj()
+	spot.Annotations = map[string]string{constants.ResourceFlavorShareWeightAnnotation: "0.25"}
+	cache.AddOrUpdateResourceFlavor(spot)
+	if got := flavorShareWeight(cache.flavorShareWeights, "spot"); got != 0.25 {
+		t.Errorf("Unexpected weight %v, want 0.25", got)
+	}
+	for _, weight := range []string{"-1", "NaN", "+Inf", "cheap"} {
+		spot.Annotations[constants.ResourceFlavorShareWeightAnnotation] = weight
+		cache.AddOrUpdateResourceFlavor(spot)
+		if got := flavorShareWeight(cache.flavorShareWeights, "spot"); got != defaultFlavorShareWeight {
+			t.Errorf("Unexpected weight %v for the invalid annotation %q, want the default", got, weight)
+		}
+	}
+	spot.Annotations[constants.ResourceFlavorShareWeightAnnotation] = "0.25"
+	cache.AddOrUpdateResourceFlavor(spot)
+	cache.DeleteResourceFlavor(spot)
+	if got := flavorShareWeight(cache.flavorShareWeights, "spot"); got != defaultFlavorShareWeight {
+		t.Errorf("Unexpected weight %v after deleting the flavor, want the default", got)
+	}
+	if _, err := cache.fairShareOrder("nonexistent"); !errors.Is(err, errCohortNotFound) {
+		t.Errorf("Unexpected error for a nonexistent cohort, got %v, want %v", err, errCohortNotFound)
+	}
+	if _, err := cache.clusterQueueShare("nonexistent"); !errors.Is(err, errCqNotFound) {
+		t.Errorf("Unexpected error for a nonexistent ClusterQueue, got %v, want %v", err, errCqNotFound)
+	}
+}
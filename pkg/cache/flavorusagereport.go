@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+)
+
+// FlavorUsageReport is the quota and usage of each flavor and resource of a
+// ClusterQueue, as returned by flavorUsageReport.
+type FlavorUsageReport struct {
+	ClusterQueue string `json:"clusterQueue"`
+	// Flavors are in the order of the resource groups of the ClusterQueue.
+	Flavors []FlavorUsageReportEntry `json:"flavors"`
+}
+
+// FlavorUsageReportEntry is the quota and usage of the resources of a flavor
+// in a FlavorUsageReport.
+type FlavorUsageReportEntry struct {
+	Name kueue.ResourceFlavorReference `json:"name"`
+	// Missing is true if the ResourceFlavor doesn't exist, which keeps the
+	// ClusterQueue pending. The nominal quota of a missing flavor is reported
+	// as zero.
+	Missing bool `json:"missing,omitempty"`
+	// Resources are sorted by name.
+	Resources []ResourceUsageReportEntry `json:"resources"`
+}
+
+// ResourceUsageReportEntry is the quota and usage of a resource in a flavor.
+type ResourceUsageReportEntry struct {
+	Name    corev1.ResourceName `json:"name"`
+	Nominal int64               `json:"nominal"`
+	Usage   int64               `json:"usage"`
+	// Borrowed is the usage above the nominal quota.
+	Borrowed int64 `json:"borrowed"`
+	// BorrowableRemaining is how much more the ClusterQueue can currently
+	// borrow from its cohort, within its borrowing limit. It's zero for
+	// ClusterQueues that don't belong to a cohort or aren't active.
+	BorrowableRemaining int64 `json:"borrowableRemaining"`
+}
+
+// flavorUsageReport returns, for each flavor and resource of the ClusterQueue,
+// its nominal quota, usage, the amount borrowed from the cohort and the amount
+// that can still be borrowed. Pending ClusterQueues are reported too, with
+// their missing flavors flagged.
+func (c *Cache) flavorUsageReport(cqName string) (FlavorUsageReport, error) {
+	snap := c.Snapshot()
+	cq, active := snap.ClusterQueues[cqName]
+	if !active {
+		// Inactive ClusterQueues are not in the snapshot.
+		c.RLock()
+		cached, ok := c.clusterQueues[cqName]
+		if ok {
+			cq = cached.snapshot()
+		}
+		c.RUnlock()
+		if !ok {
+			return FlavorUsageReport{}, errCqNotFound
+		}
+	}
+	report := FlavorUsageReport{ClusterQueue: cqName}
+	for _, rg := range cq.ResourceGroups {
+		for _, flvQuotas := range rg.Flavors {
+			_, found := snap.ResourceFlavors[flvQuotas.Name]
+			entry := FlavorUsageReportEntry{
+				Name:      flvQuotas.Name,
+				Missing:   !found,
+				Resources: make([]ResourceUsageReportEntry, 0, len(flvQuotas.Resources)),
+			}
+			for rName, rQuota := range flvQuotas.Resources {
+				resEntry := ResourceUsageReportEntry{
+					Name:  rName,
+					Usage: cq.Usage[flvQuotas.Name][rName],
+				}
+				var overcommittedNominal int64
+				if found {
+					resEntry.Nominal = rQuota.Nominal
+					overcommittedNominal = rQuota.OvercommittedNominal()
+				}
+				resEntry.Borrowed = max(0, resEntry.Usage-overcommittedNominal)
+				if active && cq.Cohort != nil {
+					unusedNominal := max(0, rQuota.OvercommittedNominal()-resEntry.Usage)
+					resEntry.BorrowableRemaining = max(0, availableQuota(cq, flvQuotas.Name, rName, rQuota)-unusedNominal)
+				}
+				entry.Resources = append(entry.Resources, resEntry)
+			}
+			sort.Slice(entry.Resources, func(i, j int) bool {
+				return entry.Resources[i].Name < entry.Resources[j].Name
+			})
+			report.Flavors = append(report.Flavors, entry)
+		}
+	}
+	return report, nil
+}
+
+// admittedFlavorAssignments returns, keyed by workload key, a copy of the
+// admission of each workload holding quota in the ClusterQueue, including the
+// assumed ones, with the flavors assigned to its PodSets. Returns nil if the
+// ClusterQueue doesn't exist.
+func (c *Cache) admittedFlavorAssignments(cqName string) map[string]*kueue.Admission {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	assignments := make(map[string]*kueue.Admission, len(cq.Workloads))
+	for key, wi := range cq.Workloads {
+		if wi.Obj.Status.Admission != nil {
+			assignments[key] = wi.Obj.Status.Admission.DeepCopy()
+		}
+	}
+	return assignments
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestFlavorUsageReport(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	clusterQueues := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("borrowing").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+				Resource(corev1.ResourceCPU, "4", "3").
+				Resource(corev1.ResourceMemory, "4Gi").
+				Obj()).
+			Cohort("one").
+			Obj(),
+		utiltesting.MakeClusterQueue("lending").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+				Resource(corev1.ResourceCPU, "6").
+				Resource(corev1.ResourceMemory, "4Gi").
+				Obj()).
+			Cohort("one").
+			Obj(),
+		utiltesting.MakeClusterQueue("pending").
+			ResourceGroup(
+				*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "2").Obj(),
+				*utiltesting.MakeFlavorQuotas("missing").Resource(corev1.ResourceCPU, "2").Obj(),
+			).
+			Obj(),
+	}
+	for _, cq := range clusterQueues {
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("borrowing").
+			Assignment(corev1.ResourceCPU, "default", "6").
+			Assignment(corev1.ResourceMemory, "default", "1Gi").
+			Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(wl) {
+		t.Fatalf("Failed adding workload %q", wl.Name)
+	}
+
+	cases := map[string]struct {
+		cqName  string
+		want    FlavorUsageReport
+		wantErr error
+	}{
+		"borrowing queue": {
+			cqName: "borrowing",
+			want: FlavorUsageReport{
+				ClusterQueue: "borrowing",
+				Flavors: []FlavorUsageReportEntry{{
+					Name: "default",
+					Resources: []ResourceUsageReportEntry{
+						{
+							Name:     corev1.ResourceCPU,
+							Nominal:  4_000,
+							Usage:    6_000,
+							Borrowed: 2_000,
+							// Limited by the borrowing limit, the cohort has 4 unused.
+							BorrowableRemaining: 1_000,
+						},
+						{
+							Name:                corev1.ResourceMemory,
+							Nominal:             4 * utiltesting.Gi,
+							Usage:               utiltesting.Gi,
+							BorrowableRemaining: 4 * utiltesting.Gi,
+						},
+					},
+				}},
+			},
+		},
+		"lending queue": {
+			cqName: "lending",
+			want: FlavorUsageReport{
+				ClusterQueue: "lending",
+				Flavors: []FlavorUsageReportEntry{{
+					Name: "default",
+					Resources: []ResourceUsageReportEntry{
+						{
+							Name:    corev1.ResourceCPU,
+							Nominal: 6_000,
+						},
+						{
+							Name:                corev1.ResourceMemory,
+							Nominal:             4 * utiltesting.Gi,
+							BorrowableRemaining: 3 * utiltesting.Gi,
+						},
+					},
+				}},
+			},
+		},
+		"pending queue with a missing flavor": {
+			cqName: "pending",
+			want: FlavorUsageReport{
+				ClusterQueue: "pending",
+				Flavors: []FlavorUsageReportEntry{
+					{
+						Name: "default",
+						Resources: []ResourceUsageReportEntry{
+							{Name: corev1.ResourceCPU, Nominal: 2_000},
+						},
+					},
+					{
+						Name:    "missing",
+						Missing: true,
+						Resources: []ResourceUsageReportEntry{
+							{Name: corev1.ResourceCPU},
+						},
+					},
+				},
+			},
+		},
+		"unknown ClusterQueue": {
+			cqName:  "unknown",
+			wantErr: errCqNotFound,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := cache.flavorUsageReport(tc.cqName)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Unexpected error: %v, want %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected report (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAdmittedFlavorAssignments(t *testing.T) {
+	onDemand := utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "on-demand", "2").Obj()
+	spot := utiltesting.MakeAdmission("cq").
+		PodSets(
+			kueue.PodSetAssignment{
+				Name:    "driver",
+				Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{corev1.ResourceCPU: "on-demand"},
+				ResourceUsage: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("1"),
+				},
+				Count: ptr.To[int32](1),
+			},
+			kueue.PodSetAssignment{
+				Name:    "workers",
+				Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{corev1.ResourceCPU: "spot"},
+				ResourceUsage: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("4"),
+				},
+				Count: ptr.To[int32](4),
+			},
+		).
+		Obj()
+	cases := map[string]struct {
+		cqName    string
+		workloads []*kueue.Workload
+		assumed   []*kueue.Workload
+		want      map[string]*kueue.Admission
+	}{
+		"no workloads": {
+			cqName: "cq",
+			want:   map[string]*kueue.Admission{},
+		},
+		"admitted and assumed workloads": {
+			cqName: "cq",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("on-demand", "ns").
+					Request(corev1.ResourceCPU, "2").
+					ReserveQuota(onDemand).
+					Admitted(true).
+					Obj(),
+				utiltesting.MakeWorkload("other", "ns").
+					Request(corev1.ResourceCPU, "2").
+					ReserveQuota(utiltesting.MakeAdmission("other").Assignment(corev1.ResourceCPU, "on-demand", "2").Obj()).
+					Obj(),
+			},
+			assumed: []*kueue.Workload{
+				utiltesting.MakeWorkload("spot", "ns").
+					PodSets(
+						*utiltesting.MakePodSet("driver", 1).Request(corev1.ResourceCPU, "1").Obj(),
+						*utiltesting.MakePodSet("workers", 4).Request(corev1.ResourceCPU, "1").Obj(),
+					).
+					ReserveQuota(spot).
+					Obj(),
+			},
+			want: map[string]*kueue.Admission{
+				"ns/on-demand": onDemand,
+				"ns/spot":      spot,
+			},
+		},
+		"missing ClusterQueue": {
+			cqName: "missing",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+			for _, cqName := range []string{"cq", "other"} {
+				cq := utiltesting.MakeClusterQueue(cqName).
+					ResourceGroup(
+						*utiltesting.MakeFlavorQuotas("on-demand").Resource(corev1.ResourceCPU, "10").Obj(),
+						*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "10").Obj(),
+					).
+					Obj()
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			for _, w := range tc.workloads {
+				if !cache.AddOrUpdateWorkload(w) {
+					t.Fatalf("Failed adding workload %s", w.Name)
+				}
+			}
+			for _, w := range tc.assumed {
+				if err := cache.AssumeWorkload(w); err != nil {
+					t.Fatalf("Failed assuming workload %s: %v", w.Name, err)
+				}
+			}
+			got := cache.admittedFlavorAssignments(tc.cqName)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected flavor assignments (-want,+got):\n%s", diff)
+			}
+			for _, admission := range got {
+				for i := range admission.PodSetAssignments {
+					admission.PodSetAssignments[i].Flavors[corev1.ResourceCPU] = "mutated"
+				}
+			}
+			if diff := cmp.Diff(tc.want, cache.admittedFlavorAssignments(tc.cqName)); diff != "" {
+				t.Errorf("Mutating the result changed the flavor assignments (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// workloadsUnblockedByFlavor simulates adding the flavor, with its quotas, to
+// the ClusterQueue and returns the pending workloads that assignFlavors can't
+// assign flavors in the ClusterQueue, but could with the flavor. Each
+// workload is evaluated on its own, so the returned workloads might not fit
+// all together.
+// The flavor is added after the flavors of the resource group covering its
+// resources, without quota for the covered resources that it doesn't list,
+// or in a new resource group if none of its resources is covered. A
+// ResourceFlavor that doesn't exist is simulated without node labels and
+// taints, and a disabled one as enabled.
+// Returns nil if the ClusterQueue doesn't exist or is inactive, if it
+// already has the flavor, or if the resources of the flavor are not covered
+// by a single resource group. The state of the cache is not modified.
+func (c *Cache) workloadsUnblockedByFlavor(cqName string, flavor FlavorQuotas, pending []*workload.Info) []*workload.Info {
+	c.RLock()
+	defer c.RUnlock()
+	before := c.snapshot()
+	if _, ok := before.ClusterQueues[cqName]; !ok {
+		return nil
+	}
+	after := c.snapshot()
+	if !after.addFlavor(cqName, flavor) {
+		return nil
+	}
+	var unblocked []*workload.Info
+	for _, wi := range pending {
+		if _, err := assignFlavorsInSnapshot(&before, wi.Obj, cqName); err == nil {
+			continue
+		}
+		if _, err := assignFlavorsInSnapshot(&after, wi.Obj, cqName); err == nil {
+			unblocked = append(unblocked, wi)
+		}
+	}
+	return unblocked
+}
+
+// addFlavor adds the flavor to the ClusterQueue of the snapshot and to the
+// quota of its cohorts, as described in workloadsUnblockedByFlavor. Returns
+// false if the flavor can't be added.
+func (s *Snapshot) addFlavor(cqName string, flavor FlavorQuotas) bool {
+	cq := s.ClusterQueues[cqName]
+	rgIdx := -1
+	uncovered := false
+	for rName := range flavor.Resources {
+		i := slices.IndexFunc(cq.ResourceGroups, func(rg ResourceGroup) bool {
+			return rg.CoveredResources.Has(rName)
+		})
+		switch {
+		case i < 0:
+			uncovered = true
+		case rgIdx >= 0 && rgIdx != i:
+			return false
+		default:
+			rgIdx = i
+		}
+	}
+	if len(flavor.Resources) == 0 || (rgIdx >= 0 && uncovered) {
+		return false
+	}
+	for _, rg := range cq.ResourceGroups {
+		if slices.ContainsFunc(rg.Flavors, func(flvQuotas FlavorQuotas) bool { return flvQuotas.Name == flavor.Name }) {
+			return false
+		}
+	}
+
+	rf, found := s.ResourceFlavors[flavor.Name]
+	if !found {
+		rf = &kueue.ResourceFlavor{ObjectMeta: metav1.ObjectMeta{Name: string(flavor.Name)}}
+		s.ResourceFlavors[flavor.Name] = rf
+	}
+	s.DisabledFlavors.Delete(flavor.Name)
+
+	// The resource groups are shared with the cache, so they are copied
+	// instead of modified in place.
+	added := FlavorQuotas{Name: flavor.Name, Resources: maps.Clone(flavor.Resources)}
+	rgs := slices.Clone(cq.ResourceGroups)
+	if rgIdx < 0 {
+		rgs = append(rgs, ResourceGroup{
+			CoveredResources: sets.KeySet(flavor.Resources),
+			Flavors:          []FlavorQuotas{added},
+			LabelKeys:        sets.KeySet(rf.Spec.NodeLabels),
+		})
+	} else {
+		rg := &rgs[rgIdx]
+		for rName := range rg.CoveredResources {
+			if _, listed := added.Resources[rName]; !listed {
+				added.Resources[rName] = &ResourceQuota{}
+			}
+		}
+		rg.Flavors = append(slices.Clone(rg.Flavors), added)
+		rg.LabelKeys = rg.LabelKeys.Union(sets.KeySet(rf.Spec.NodeLabels))
+	}
+	cq.ResourceGroups = rgs
+	cq.UpdateRGByResource()
+	cq.updateGuaranteedQuota()
+
+	usage := make(map[corev1.ResourceName]int64, len(added.Resources))
+	requestable := make(map[corev1.ResourceName]int64, len(added.Resources))
+	for rName, rQuota := range added.Resources {
+		usage[rName] = 0
+		requestable[rName] = rQuota.Nominal - cq.guaranteedQuota(flavor.Name, rName)
+	}
+	cq.Usage[flavor.Name] = usage
+	for _, cohort := range cq.Cohorts() {
+		if cohort.RequestableResources == nil {
+			cohort.RequestableResources = make(FlavorResourceQuantities)
+		}
+		if cohort.Usage == nil {
+			cohort.Usage = make(FlavorResourceQuantities)
+		}
+		cohortRequestable := cohort.RequestableResources[flavor.Name]
+		if cohortRequestable == nil {
+			cohortRequestable = make(map[corev1.ResourceName]int64, len(requestable))
+			cohort.RequestableResources[flavor.Name] = cohortRequestable
+		}
+		cohortUsage := cohort.Usage[flavor.Name]
+		if cohortUsage == nil {
+			cohortUsage = make(map[corev1.ResourceName]int64, len(requestable))
+			cohort.Usage[flavor.Name] = cohortUsage
+		}
+		for rName, val := range requestable {
+			cohortRequestable[rName] += val
+			if _, tracked := cohortUsage[rName]; !tracked {
+				cohortUsage[rName] = 0
+			}
+		}
+	}
+	return true
+}
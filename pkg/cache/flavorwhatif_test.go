@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+func TestWorkloadsUnblockedByFlavor(t *testing.T) {
+	pendingWorkload := func(name string, requests ...string) *workload.Info {
+		w := utiltesting.MakeWorkload(name, "ns")
+		for i := 0; i < len(requests); i += 2 {
+			w.Request(corev1.ResourceName(requests[i]), requests[i+1])
+		}
+		return workload.NewInfo(w.Obj())
+	}
+	pending := []*workload.Info{
+		pendingWorkload("cpu", "cpu", "1"),
+		pendingWorkload("cpu-and-gpu", "cpu", "1", string(resourceGPU), "1"),
+		pendingWorkload("gpu", string(resourceGPU), "2"),
+		pendingWorkload("too-many-gpus", string(resourceGPU), "8"),
+		pendingWorkload("more-cpu", "cpu", "4"),
+		pendingWorkload("too-much-cpu", "cpu", "20"),
+	}
+	cases := map[string]struct {
+		cqName string
+		flavor FlavorQuotas
+		want   []string
+	}{
+		"gpu flavor unblocks two workloads": {
+			cqName: "cq",
+			flavor: FlavorQuotas{
+				Name:      "gpu",
+				Resources: map[corev1.ResourceName]*ResourceQuota{resourceGPU: {Nominal: 4}},
+			},
+			want: []string{"ns/cpu-and-gpu", "ns/gpu"},
+		},
+		"second cpu flavor unblocks the workload that doesn't fit in the first": {
+			cqName: "cq",
+			flavor: FlavorQuotas{
+				Name:      "extra",
+				Resources: map[corev1.ResourceName]*ResourceQuota{corev1.ResourceCPU: {Nominal: 5_000}},
+			},
+			want: []string{"ns/more-cpu"},
+		},
+		"cpu flavor borrowing from the cohort": {
+			cqName: "cq",
+			flavor: FlavorQuotas{
+				Name:      "lent",
+				Resources: map[corev1.ResourceName]*ResourceQuota{corev1.ResourceCPU: {Nominal: 0}},
+			},
+			want: []string{"ns/more-cpu"},
+		},
+		"flavor already in the ClusterQueue": {
+			cqName: "cq",
+			flavor: FlavorQuotas{
+				Name:      "default",
+				Resources: map[corev1.ResourceName]*ResourceQuota{resourceGPU: {Nominal: 4}},
+			},
+		},
+		"flavor with covered and uncovered resources": {
+			cqName: "cq",
+			flavor: FlavorQuotas{
+				Name: "gpu",
+				Resources: map[corev1.ResourceName]*ResourceQuota{
+					corev1.ResourceCPU: {Nominal: 4_000},
+					resourceGPU:        {Nominal: 4},
+				},
+			},
+		},
+		"missing ClusterQueue": {
+			cqName: "missing",
+			flavor: FlavorQuotas{
+				Name:      "gpu",
+				Resources: map[corev1.ResourceName]*ResourceQuota{resourceGPU: {Nominal: 4}},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("lent").Obj())
+			cq := utiltesting.MakeClusterQueue("cq").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+				Cohort("one").
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			lender := utiltesting.MakeClusterQueue("lender").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("lent").Resource(corev1.ResourceCPU, "5").Obj()).
+				Cohort("one").
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), lender); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			admitted := utiltesting.MakeWorkload("admitted", "ns").
+				Request(corev1.ResourceCPU, "8").
+				ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "8").Obj()).
+				Obj()
+			if !cache.AddOrUpdateWorkload(admitted) {
+				t.Fatal("Failed adding the admitted workload")
+			}
+			before := cache.Snapshot()
+
+			var got []string
+			for _, wi := range cache.workloadsUnblockedByFlavor(tc.cqName, tc.flavor, pending) {
+				got = append(got, workload.Key(wi.Obj))
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected unblocked workloads (-want,+got):\n%s", diff)
+			}
+			after := cache.Snapshot()
+			if diff := cmp.Diff(before.ClusterQueues["cq"].ResourceGroups, after.ClusterQueues["cq"].ResourceGroups); diff != "" {
+				t.Errorf("The simulation modified the resource groups (-before,+after):\n%s", diff)
+			}
+			if diff := cmp.Diff(before.ClusterQueues["cq"].Usage, after.ClusterQueues["cq"].Usage); diff != "" {
+				t.Errorf("The simulation modified the usage (-before,+after):\n%s", diff)
+			}
+			if diff := cmp.Diff(sets.KeySet(before.ResourceFlavors), sets.KeySet(after.ResourceFlavors)); diff != "" {
+				t.Errorf("The simulation modified the ResourceFlavors (-before,+after):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	"sigs.k8s.io/kueue/pkg/util/limitrange"
+)
+
+// fractionGranularity returns, in milli-units, the granularity of the
+// fractions of the resource in the flavor, declared in its
+// ResourceFlavorFractionalResourcesAnnotation. Returns 0 if the resource isn't
+// fractional in the flavor or its granularity is invalid.
+func fractionGranularity(rf *kueue.ResourceFlavor, rName corev1.ResourceName) int64 {
+	value, found := rf.Annotations[constants.ResourceFlavorFractionalResourcesAnnotation]
+	if !found {
+		return 0
+	}
+	for _, entry := range strings.Split(value, ",") {
+		name, granularity, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || corev1.ResourceName(name) != rName {
+			continue
+		}
+		q, err := resource.ParseQuantity(granularity)
+		if err != nil || q.MilliValue() <= 0 {
+			return 0
+		}
+		return q.MilliValue()
+	}
+	return 0
+}
+
+// fractionalUsage returns, keyed by flavor name, the usage of the resource in
+// the ClusterQueue in whole devices. In the flavors where the resource is
+// fractional, each pod's request is rounded up to the granularity of the
+// flavor and the fractions are added up, so that two pods requesting half a
+// GPU use one GPU. In the rest of the flavors, it's the usage of the
+// ClusterQueue.
+// The quota is still enforced on the usage rounded up per pod.
+// Returns nil if the ClusterQueue doesn't exist or doesn't have the resource.
+func (c *Cache) fractionalUsage(cqName string, rName corev1.ResourceName) map[string]float64 {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	rg := cq.RGByResource[rName]
+	if rg == nil {
+		return nil
+	}
+	granularities := make(map[kueue.ResourceFlavorReference]int64, len(rg.Flavors))
+	usage := make(map[string]float64, len(rg.Flavors))
+	for _, flvQuotas := range rg.Flavors {
+		if rf, found := c.resourceFlavors[flvQuotas.Name]; found {
+			if granularity := fractionGranularity(rf, rName); granularity > 0 {
+				granularities[flvQuotas.Name] = granularity
+				usage[string(flvQuotas.Name)] = 0
+				continue
+			}
+		}
+		usage[string(flvQuotas.Name)] = float64(cq.Usage[flvQuotas.Name][rName])
+	}
+	if len(granularities) == 0 {
+		return usage
+	}
+	milliUsage := make(map[kueue.ResourceFlavorReference]int64, len(granularities))
+	for _, wi := range cq.Workloads {
+		for _, psr := range wi.TotalRequests {
+			granularity, fractional := granularities[psr.Flavors[rName]]
+			if !fractional {
+				continue
+			}
+			ps := podSetByName(wi.Obj, psr.Name)
+			if ps == nil {
+				continue
+			}
+			perPod, found := limitrange.TotalRequests(&ps.Template.Spec)[rName]
+			if !found {
+				continue
+			}
+			fractions := (perPod.MilliValue() + granularity - 1) / granularity
+			milliUsage[psr.Flavors[rName]] += fractions * granularity * int64(psr.Count)
+		}
+	}
+	for fName, milli := range milliUsage {
+		usage[string(fName)] = float64(milli) / 1000
+	}
+	return usage
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+const resourceGPU corev1.ResourceName = "nvidia.com/gpu"
+
+func TestFractionalUsage(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	shared := utiltesting.MakeResourceFlavor("shared").Obj()
+	shared.Annotations = map[string]string{constants.ResourceFlavorFractionalResourcesAnnotation: "nvidia.com/gpu=0.25"}
+	cache.AddOrUpdateResourceFlavor(shared)
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("whole").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(
+			*utiltesting.MakeFlavorQuotas("shared").Resource(resourceGPU, "4").Obj(),
+			*utiltesting.MakeFlavorQuotas("whole").Resource(resourceGPU, "4").Obj(),
+		).
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("shared").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	workloads := []*kueue.Workload{
+		// Two pods with half a GPU each share one GPU.
+		utiltesting.MakeWorkload("halves", "ns").
+			PodSets(*utiltesting.MakePodSet("main", 2).Request(resourceGPU, "0.5").Obj()).
+			ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(resourceGPU, "shared", "1").AssignmentPodCount(2).Obj()).
+			Obj(),
+		// Rounded up to 0.5, the granularity of the flavor being 0.25.
+		utiltesting.MakeWorkload("rounded", "ns").
+			PodSets(*utiltesting.MakePodSet("main", 1).Request(resourceGPU, "0.3").Obj()).
+			ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(resourceGPU, "shared", "1").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("whole", "ns").
+			PodSets(*utiltesting.MakePodSet("main", 1).Request(resourceGPU, "1").Obj()).
+			ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(resourceGPU, "whole", "1").Obj()).
+			Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %q", wl.Name)
+		}
+	}
+
+	cases := map[string]struct {
+		cqName   string
+		resource corev1.ResourceName
+		want     map[string]float64
+	}{
+		"fractional and whole GPUs": {
+			cqName:   "cq",
+			resource: resourceGPU,
+			want:     map[string]float64{"shared": 1.5, "whole": 1},
+		},
+		"resource that isn't fractional": {
+			cqName:   "cq",
+			resource: corev1.ResourceCPU,
+			want:     map[string]float64{"shared": 0},
+		},
+		"resource not in the ClusterQueue": {
+			cqName:   "cq",
+			resource: corev1.ResourceMemory,
+		},
+		"unknown ClusterQueue": {
+			cqName:   "unknown",
+			resource: resourceGPU,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := cache.fractionalUsage(tc.cqName, tc.resource)
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+				t.Errorf("Unexpected usage (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+var errInvalidHeadroom = errors.New("invalid headroom")
+
+// parseHeadroom returns the headroom of the ClusterQueue declared in the
+// HeadroomAnnotation.
+func parseHeadroom(cq *kueue.ClusterQueue) (Resources, error) {
+	value, found := cq.Annotations[constants.HeadroomAnnotation]
+	if !found {
+		return nil, nil
+	}
+	headroom, err := parseResourceQuantities(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidHeadroom, err)
+	}
+	return headroom, nil
+}
+
+// parseResourceQuantities parses a comma separated list of
+// <resource>=<quantity> entries, for example "cpu=4,memory=8Gi". The
+// quantities can't be negative.
+func parseResourceQuantities(value string) (Resources, error) {
+	quantities := make(Resources)
+	for _, entry := range strings.Split(value, ",") {
+		rName, quantity, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || rName == "" {
+			return nil, fmt.Errorf("%q, expected <resource>=<quantity>", entry)
+		}
+		q, err := resource.ParseQuantity(quantity)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", entry, err)
+		}
+		if q.Sign() < 0 {
+			return nil, fmt.Errorf("%q is negative", entry)
+		}
+		quantities[corev1.ResourceName(rName)] = workload.ResourceValue(corev1.ResourceName(rName), q)
+	}
+	return quantities, nil
+}
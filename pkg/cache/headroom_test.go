@@ -0,0 +1,167 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	"sigs.k8s.io/kueue/pkg/features"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestHeadroom(t *testing.T) {
+	cases := map[string]struct {
+		enableLendingLimit bool
+		headroom           string
+		lendingLimit       string
+		lenderUsage        string
+		cqName             string
+		request            string
+		wantAddErr         error
+		wantHeadroom       Resources
+		wantFit            bool
+	}{
+		"peer borrows all the quota without headroom": {
+			cqName:  "borrower",
+			request: "10",
+			wantFit: true,
+		},
+		"peer borrows up to the headroom": {
+			headroom:     "cpu=4",
+			cqName:       "borrower",
+			request:      "6",
+			wantHeadroom: Resources{corev1.ResourceCPU: 4_000},
+			wantFit:      true,
+		},
+		"peer can't borrow into the headroom": {
+			headroom:     "cpu=4",
+			cqName:       "borrower",
+			request:      "7",
+			wantHeadroom: Resources{corev1.ResourceCPU: 4_000},
+		},
+		"peer can't borrow into the headroom partially used by its owner": {
+			headroom:     "cpu=4",
+			lenderUsage:  "8",
+			cqName:       "borrower",
+			request:      "3",
+			wantHeadroom: Resources{corev1.ResourceCPU: 4_000},
+		},
+		"peer borrows the quota left above the headroom by its owner": {
+			headroom:     "cpu=4",
+			lenderUsage:  "8",
+			cqName:       "borrower",
+			request:      "2",
+			wantHeadroom: Resources{corev1.ResourceCPU: 4_000},
+			wantFit:      true,
+		},
+		"headroom above the nominal quota": {
+			headroom:     "cpu=20",
+			cqName:       "borrower",
+			request:      "1",
+			wantHeadroom: Resources{corev1.ResourceCPU: 20_000},
+		},
+		"owner uses its headroom": {
+			headroom:     "cpu=4",
+			cqName:       "lender",
+			request:      "10",
+			wantHeadroom: Resources{corev1.ResourceCPU: 4_000},
+			wantFit:      true,
+		},
+		"headroom of another resource": {
+			headroom:     "memory=4Gi",
+			cqName:       "borrower",
+			request:      "10",
+			wantHeadroom: Resources{corev1.ResourceMemory: 4 * 1024 * 1024 * 1024},
+			wantFit:      true,
+		},
+		"headroom larger than the quota kept by the lending limit": {
+			enableLendingLimit: true,
+			headroom:           "cpu=4",
+			lendingLimit:       "8",
+			cqName:             "borrower",
+			request:            "7",
+			wantHeadroom:       Resources{corev1.ResourceCPU: 4_000},
+		},
+		"lending limit keeping more quota than the headroom": {
+			enableLendingLimit: true,
+			headroom:           "cpu=4",
+			lendingLimit:       "3",
+			cqName:             "borrower",
+			request:            "4",
+			wantHeadroom:       Resources{corev1.ResourceCPU: 4_000},
+		},
+		"invalid headroom": {
+			headroom:   "cpu",
+			wantAddErr: errInvalidHeadroom,
+		},
+		"negative headroom": {
+			headroom:   "cpu=-1",
+			wantAddErr: errInvalidHeadroom,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			defer features.SetFeatureGateDuringTest(t, features.LendingLimit, tc.enableLendingLimit)()
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			lender := utiltesting.MakeClusterQueue("lender").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10", "", tc.lendingLimit).Obj()).
+				Cohort("one").
+				Obj()
+			if tc.headroom != "" {
+				lender.Annotations = map[string]string{constants.HeadroomAnnotation: tc.headroom}
+			}
+			err := cache.AddClusterQueue(context.Background(), lender)
+			if !errors.Is(err, tc.wantAddErr) {
+				t.Fatalf("Unexpected error adding ClusterQueue: %v, want %v", err, tc.wantAddErr)
+			}
+			if err != nil {
+				return
+			}
+			borrower := utiltesting.MakeClusterQueue("borrower").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "0").Obj()).
+				Cohort("one").
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), borrower); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantHeadroom, cache.clusterQueues["lender"].Headroom); diff != "" {
+				t.Errorf("Unexpected headroom (-want,+got):\n%s", diff)
+			}
+			if tc.lenderUsage != "" {
+				w := utiltesting.MakeWorkload("used", "ns").
+					Request(corev1.ResourceCPU, tc.lenderUsage).
+					ReserveQuota(utiltesting.MakeAdmission("lender").Assignment(corev1.ResourceCPU, "default", tc.lenderUsage).Obj()).
+					Obj()
+				if !cache.AddOrUpdateWorkload(w) {
+					t.Fatal("Failed adding the workload of the lender")
+				}
+			}
+			w := utiltesting.MakeWorkload("w", "ns").Request(corev1.ResourceCPU, tc.request).Obj()
+			_, err = cache.assignFlavors(w, tc.cqName)
+			if gotFit := err == nil; gotFit != tc.wantFit {
+				t.Errorf("Unexpected fit %t, want %t, error: %v", gotFit, tc.wantFit, err)
+			}
+		})
+	}
+}
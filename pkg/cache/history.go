@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+const defaultWorkloadHistorySize = 10
+
+// WorkloadState is the state of a workload as observed by the cache.
+type WorkloadState string
+
+const (
+	WorkloadStatePending  WorkloadState = "Pending"
+	WorkloadStateAssumed  WorkloadState = "Assumed"
+	WorkloadStateAdmitted WorkloadState = "Admitted"
+	WorkloadStateEvicted  WorkloadState = "Evicted"
+)
+
+// Transition is a change in the state of a workload observed by the cache.
+type Transition struct {
+	Time   time.Time     `json:"time"`
+	From   WorkloadState `json:"from"`
+	To     WorkloadState `json:"to"`
+	Reason string        `json:"reason,omitempty"`
+}
+
+// workloadHistory is a ring buffer holding the last transitions of a workload.
+type workloadHistory struct {
+	transitions []Transition
+	// start is the index of the oldest transition.
+	start int
+	count int
+}
+
+func (h *workloadHistory) add(t Transition) {
+	if h.count < len(h.transitions) {
+		h.transitions[(h.start+h.count)%len(h.transitions)] = t
+		h.count++
+		return
+	}
+	h.transitions[h.start] = t
+	h.start = (h.start + 1) % len(h.transitions)
+}
+
+// list returns the transitions from the oldest to the newest.
+func (h *workloadHistory) list() []Transition {
+	result := make([]Transition, h.count)
+	for i := range result {
+		result[i] = h.transitions[(h.start+i)%len(h.transitions)]
+	}
+	return result
+}
+
+func (h *workloadHistory) last() WorkloadState {
+	if h.count == 0 {
+		return WorkloadStatePending
+	}
+	return h.transitions[(h.start+h.count-1)%len(h.transitions)].To
+}
+
+// workloadTransitions returns the last state transitions of the workload observed
+// by the cache, from the oldest to the newest.
+func (c *Cache) workloadTransitions(w *kueue.Workload) []Transition {
+	c.RLock()
+	defer c.RUnlock()
+	h, found := c.workloadHistories[workload.Key(w)]
+	if !found {
+		return nil
+	}
+	return h.list()
+}
+
+// recordTransition records the transition of the workload to the state, if it
+// differs from the last recorded one.
+func (c *Cache) recordTransition(w *kueue.Workload, to WorkloadState, reason string) {
+	if c.workloadHistorySize <= 0 {
+		return
+	}
+	k := workload.Key(w)
+	h, found := c.workloadHistories[k]
+	if !found {
+		if to == WorkloadStatePending {
+			return
+		}
+		h = &workloadHistory{transitions: make([]Transition, c.workloadHistorySize)}
+		c.workloadHistories[k] = h
+	}
+	if h.last() == to {
+		return
+	}
+	t := Transition{
+		Time:   c.clock.Now(),
+		From:   h.last(),
+		To:     to,
+		Reason: reason,
+	}
+	h.add(t)
+	c.recordAudit(w, t)
+}
+
+// recordObservedState records the transition of the workload to the state
+// derived from its status.
+func (c *Cache) recordObservedState(w *kueue.Workload) {
+	if cond := apimeta.FindStatusCondition(w.Status.Conditions, kueue.WorkloadEvicted); cond != nil && cond.Status == metav1.ConditionTrue {
+		c.recordTransition(w, WorkloadStateEvicted, cond.Reason)
+		return
+	}
+	if workload.HasQuotaReservation(w) {
+		c.recordTransition(w, WorkloadStateAdmitted, kueue.WorkloadQuotaReserved)
+		return
+	}
+	c.recordTransition(w, WorkloadStatePending, "QuotaReleased")
+}
+
+func (c *Cache) forgetHistory(w *kueue.Workload) {
+	delete(c.workloadHistories, workload.Key(w))
+}
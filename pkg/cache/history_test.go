@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestWorkloadHistory(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	admission := utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "1").Obj()
+	pending := utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, "1").Obj()
+	admitted := pending.DeepCopy()
+	admitted.Status.Admission = admission
+	admitted.Status.Conditions = []metav1.Condition{{
+		Type:   kueue.WorkloadQuotaReserved,
+		Status: metav1.ConditionTrue,
+	}}
+	evicted := admitted.DeepCopy()
+	evicted.Status.Conditions = append(evicted.Status.Conditions, metav1.Condition{
+		Type:   kueue.WorkloadEvicted,
+		Status: metav1.ConditionTrue,
+		Reason: kueue.WorkloadEvictedByPreemption,
+	})
+	requeued := pending.DeepCopy()
+
+	cases := map[string]struct {
+		size int
+		want []Transition
+	}{
+		"full history": {
+			size: 10,
+			want: []Transition{
+				{Time: now, From: WorkloadStatePending, To: WorkloadStateAssumed, Reason: "Assumed"},
+				{Time: now.Add(time.Second), From: WorkloadStateAssumed, To: WorkloadStateAdmitted, Reason: kueue.WorkloadQuotaReserved},
+				{Time: now.Add(2 * time.Second), From: WorkloadStateAdmitted, To: WorkloadStateEvicted, Reason: kueue.WorkloadEvictedByPreemption},
+				{Time: now.Add(3 * time.Second), From: WorkloadStateEvicted, To: WorkloadStatePending, Reason: "QuotaReleased"},
+			},
+		},
+		"capped history": {
+			size: 2,
+			want: []Transition{
+				{Time: now.Add(2 * time.Second), From: WorkloadStateAdmitted, To: WorkloadStateEvicted, Reason: kueue.WorkloadEvictedByPreemption},
+				{Time: now.Add(3 * time.Second), From: WorkloadStateEvicted, To: WorkloadStatePending, Reason: "QuotaReleased"},
+			},
+		},
+		"disabled": {},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeClock := testingclock.NewFakeClock(now)
+			cache := New(utiltesting.NewFakeClient(), WithClock(fakeClock), WithWorkloadHistorySize(tc.size))
+			cq := utiltesting.MakeClusterQueue("cq").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+
+			if err := cache.AssumeWorkload(admitted); err != nil {
+				t.Fatalf("Failed assuming the workload: %v", err)
+			}
+			fakeClock.Step(time.Second)
+			if !cache.AddOrUpdateWorkload(admitted) {
+				t.Fatal("Failed adding the workload")
+			}
+			fakeClock.Step(time.Second)
+			if err := cache.UpdateWorkload(admitted, evicted); err != nil {
+				t.Fatalf("Failed evicting the workload: %v", err)
+			}
+			fakeClock.Step(time.Second)
+			if err := cache.UpdateWorkload(evicted, requeued); err != nil {
+				t.Fatalf("Failed requeueing the workload: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.want, cache.workloadTransitions(pending)); diff != "" {
+				t.Errorf("Unexpected history (-want,+got):\n%s", diff)
+			}
+
+			cache.ClearWorkloadState(requeued)
+			if got := cache.workloadTransitions(pending); got != nil {
+				t.Errorf("Unexpected history after the workload was deleted: %v", got)
+			}
+		})
+	}
+}
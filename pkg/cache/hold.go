@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+)
+
+// reserve holds the amount of resources in the ClusterQueue on behalf of the
+// holder, which doesn't need to be a Workload. The hold is accounted in the
+// usage of the ClusterQueue like an admitted workload, so it's respected when
+// admitting workloads. Each resource is held in the first flavor that has
+// enough unused nominal quota. The returned function releases the hold.
+func (c *Cache) reserve(cqName string, amount Resources, holder string) (func(), error) {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
+	c.Lock()
+	defer c.Unlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil, errCqNotFound
+	}
+	if _, found := cq.holds[holder]; found {
+		return nil, fmt.Errorf("holder %q already has a reservation in ClusterQueue %q", holder, cqName)
+	}
+	hold, err := cq.holdFor(amount)
+	if err != nil {
+		return nil, err
+	}
+	cq.addHold(holder, hold)
+	changed = append(changed, cqName)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			var released []string
+			defer func() { c.notifyUsageChanged(released...) }()
+			c.Lock()
+			defer c.Unlock()
+			if cq, ok := c.clusterQueues[cqName]; ok {
+				cq.deleteHold(holder)
+				released = append(released, cqName)
+			}
+		})
+	}, nil
+}
+
+// holdFor returns the flavors in which to hold each resource of the amount,
+// the first one with enough unused nominal quota.
+func (c *ClusterQueue) holdFor(amount Resources) (FlavorResourceQuantities, error) {
+	hold := make(FlavorResourceQuantities)
+	for rName, val := range amount {
+		fName, found := c.flavorWithUnusedQuota(rName, val)
+		if !found {
+			return nil, fmt.Errorf("not enough unused quota for %s in ClusterQueue %q", rName, c.Name)
+		}
+		if hold[fName] == nil {
+			hold[fName] = make(map[corev1.ResourceName]int64)
+		}
+		hold[fName][rName] = val
+	}
+	return hold, nil
+}
+
+// flavorWithUnusedQuota returns the first flavor with at least val of unused
+// nominal quota for the resource.
+func (c *ClusterQueue) flavorWithUnusedQuota(rName corev1.ResourceName, val int64) (kueue.ResourceFlavorReference, bool) {
+	rg := c.RGByResource[rName]
+	if rg == nil {
+		return "", false
+	}
+	for _, flvQuotas := range rg.Flavors {
+		if quota, found := flvQuotas.Resources[rName]; found && quota.Nominal-c.Usage[flvQuotas.Name][rName] >= val {
+			return flvQuotas.Name, true
+		}
+	}
+	return "", false
+}
+
+func (c *ClusterQueue) addHold(holder string, hold FlavorResourceQuantities) {
+	if c.holds == nil {
+		c.holds = make(map[string]FlavorResourceQuantities)
+	}
+	c.holds[holder] = hold
+	c.updateHoldUsage(hold, 1)
+}
+
+func (c *ClusterQueue) deleteHold(holder string) {
+	hold, found := c.holds[holder]
+	if !found {
+		return
+	}
+	c.updateHoldUsage(hold, -1)
+	delete(c.holds, holder)
+	// Releasing the hold frees quota.
+	c.AllocatableResourceGeneration++
+}
+
+func (c *ClusterQueue) updateHoldUsage(hold FlavorResourceQuantities, m int64) {
+	for fName, resources := range hold {
+		for rName, val := range resources {
+			if _, exists := c.Usage[fName][rName]; exists {
+				c.Usage[fName][rName] += val * m
+			}
+		}
+	}
+	c.syncCohortCapacity()
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestReserve(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(
+			*utiltesting.MakeFlavorQuotas("on-demand").Resource(corev1.ResourceCPU, "10").Obj(),
+			*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "4").Obj(),
+		).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		Request(corev1.ResourceCPU, "3").
+		ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "on-demand", "3").Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(wl) {
+		t.Fatalf("Failed adding workload")
+	}
+	wantUsage := func(onDemand, spot int64) FlavorResourceQuantities {
+		return FlavorResourceQuantities{
+			"on-demand": {corev1.ResourceCPU: onDemand},
+			"spot":      {corev1.ResourceCPU: spot},
+		}
+	}
+
+	releaseA, err := cache.reserve("cq", Resources{corev1.ResourceCPU: 6_000}, "batch-a")
+	if err != nil {
+		t.Fatalf("Failed reserving for batch-a: %v", err)
+	}
+	if diff := cmp.Diff(wantUsage(9_000, 0), cache.Snapshot().ClusterQueues["cq"].Usage); diff != "" {
+		t.Errorf("Unexpected usage after reserving for batch-a (-want,+got):\n%s", diff)
+	}
+
+	if _, err := cache.reserve("cq", Resources{corev1.ResourceCPU: 1_000}, "batch-a"); err == nil {
+		t.Error("Reserving twice for the same holder succeeded")
+	}
+	if _, err := cache.reserve("cq", Resources{corev1.ResourceCPU: 5_000}, "batch-b"); err == nil {
+		t.Error("Reserving more than the unused quota succeeded")
+	}
+	if _, err := cache.reserve("unknown", Resources{corev1.ResourceCPU: 1_000}, "batch-b"); err == nil {
+		t.Error("Reserving in an unknown ClusterQueue succeeded")
+	}
+
+	releaseB, err := cache.reserve("cq", Resources{corev1.ResourceCPU: 2_000}, "batch-b")
+	if err != nil {
+		t.Fatalf("Failed reserving for batch-b: %v", err)
+	}
+	if diff := cmp.Diff(wantUsage(9_000, 2_000), cache.Snapshot().ClusterQueues["cq"].Usage); diff != "" {
+		t.Errorf("Unexpected usage after reserving for batch-b (-want,+got):\n%s", diff)
+	}
+
+	generation := cache.clusterQueues["cq"].AllocatableResourceGeneration
+	releaseA()
+	releaseA()
+	if diff := cmp.Diff(wantUsage(3_000, 2_000), cache.Snapshot().ClusterQueues["cq"].Usage); diff != "" {
+		t.Errorf("Unexpected usage after releasing batch-a (-want,+got):\n%s", diff)
+	}
+	if got := cache.clusterQueues["cq"].AllocatableResourceGeneration; got != generation+1 {
+		t.Errorf("Unexpected allocatable resource generation, got %d, want %d", got, generation+1)
+	}
+
+	if _, err := cache.reserve("cq", Resources{corev1.ResourceCPU: 5_000}, "batch-c"); err != nil {
+		t.Errorf("Failed reserving after releasing batch-a: %v", err)
+	}
+	releaseB()
+	if diff := cmp.Diff(wantUsage(8_000, 0), cache.Snapshot().ClusterQueues["cq"].Usage); diff != "" {
+		t.Errorf("Unexpected usage after releasing batch-b (-want,+got):\n%s", diff)
+	}
+}
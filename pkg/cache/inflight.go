@@ -0,0 +1,34 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "errors"
+
+var errTooManyInflightAssumed = errors.New("too many workloads assumed and not committed")
+
+// inflightAssumedCount returns the number of workloads assumed in the
+// ClusterQueue whose admission isn't committed yet, that is, not observed
+// through AddOrUpdateWorkload or UpdateWorkload nor forgotten.
+func (c *Cache) inflightAssumedCount(cqName string) int {
+	count := 0
+	for _, assumedCQ := range c.assumedWorkloads {
+		if assumedCQ == cqName {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestInflightAssumedWorkloads(t *testing.T) {
+	type operation struct {
+		// op is one of "assume", "commit" or "forget".
+		op       string
+		workload string
+		cqName   string
+		wantErr  error
+	}
+	cases := map[string]struct {
+		maxInflight int
+		operations  []operation
+		wantCount   map[string]int
+	}{
+		"no maximum": {
+			operations: []operation{
+				{op: "assume", workload: "a1", cqName: "a"},
+				{op: "assume", workload: "a2", cqName: "a"},
+				{op: "assume", workload: "a3", cqName: "a"},
+			},
+			wantCount: map[string]int{"a": 3, "b": 0},
+		},
+		"maximum reached": {
+			maxInflight: 2,
+			operations: []operation{
+				{op: "assume", workload: "a1", cqName: "a"},
+				{op: "assume", workload: "a2", cqName: "a"},
+				{op: "assume", workload: "a3", cqName: "a", wantErr: errTooManyInflightAssumed},
+			},
+			wantCount: map[string]int{"a": 2, "b": 0},
+		},
+		"recovers after a commit": {
+			maxInflight: 2,
+			operations: []operation{
+				{op: "assume", workload: "a1", cqName: "a"},
+				{op: "assume", workload: "a2", cqName: "a"},
+				{op: "assume", workload: "a3", cqName: "a", wantErr: errTooManyInflightAssumed},
+				{op: "commit", workload: "a1", cqName: "a"},
+				{op: "assume", workload: "a3", cqName: "a"},
+			},
+			wantCount: map[string]int{"a": 2, "b": 0},
+		},
+		"recovers after forgetting a workload": {
+			maxInflight: 1,
+			operations: []operation{
+				{op: "assume", workload: "a1", cqName: "a"},
+				{op: "assume", workload: "a2", cqName: "a", wantErr: errTooManyInflightAssumed},
+				{op: "forget", workload: "a1", cqName: "a"},
+				{op: "assume", workload: "a2", cqName: "a"},
+			},
+			wantCount: map[string]int{"a": 1, "b": 0},
+		},
+		"maximum per ClusterQueue": {
+			maxInflight: 1,
+			operations: []operation{
+				{op: "assume", workload: "a1", cqName: "a"},
+				{op: "assume", workload: "b1", cqName: "b"},
+				{op: "assume", workload: "b2", cqName: "b", wantErr: errTooManyInflightAssumed},
+			},
+			wantCount: map[string]int{"a": 1, "b": 1},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient(), WithMaxInflightAssumedWorkloads(tc.maxInflight))
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			for _, cqName := range []string{"a", "b"} {
+				cq := utiltesting.MakeClusterQueue(cqName).
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+					Obj()
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			workloads := make(map[string]*kueue.Workload)
+			for _, op := range tc.operations {
+				w, found := workloads[op.workload]
+				if !found {
+					w = utiltesting.MakeWorkload(op.workload, "ns").
+						Request(corev1.ResourceCPU, "1").
+						ReserveQuota(utiltesting.MakeAdmission(op.cqName).Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+						Obj()
+					workloads[op.workload] = w
+				}
+				var err error
+				switch op.op {
+				case "assume":
+					err = cache.AssumeWorkload(w)
+				case "commit":
+					if !cache.AddOrUpdateWorkload(w) {
+						t.Fatalf("Failed committing workload %s", op.workload)
+					}
+				case "forget":
+					err = cache.ForgetWorkload(w)
+				}
+				if !errors.Is(err, op.wantErr) {
+					t.Errorf("Unexpected error on %s of workload %s: %v, want %v", op.op, op.workload, err, op.wantErr)
+				}
+			}
+			gotCount := make(map[string]int)
+			for _, cqName := range []string{"a", "b"} {
+				gotCount[cqName] = cache.inflightAssumedCount(cqName)
+			}
+			if diff := cmp.Diff(tc.wantCount, gotCount); diff != "" {
+				t.Errorf("Unexpected inflight assumed workloads (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/metrics"
+)
+
+// MetricsRecorder records the metrics of the cache. The cache calls it while
+// holding its lock, so it must not call the cache.
+type MetricsRecorder interface {
+	// RecordAdmissionWait records how long a workload waited to be admitted
+	// in the ClusterQueue since it was queued.
+	RecordAdmissionWait(cqName string, wait time.Duration)
+	// SetActiveWorkloads sets the number of workloads that reserve quota in
+	// the ClusterQueue and, out of them, the admitted ones.
+	SetActiveWorkloads(cqName string, reserving, admitted int)
+	// SetClusterQueueStatus sets the status of the ClusterQueue.
+	SetClusterQueueStatus(cqName string, status metrics.ClusterQueueStatus)
+	// SetSoftQuotaExceeded sets whether the usage of the ClusterQueue exceeds
+	// its soft quota for the resource in the flavor.
+	SetSoftQuotaExceeded(cqName string, fName kueue.ResourceFlavorReference, rName corev1.ResourceName, exceeded bool)
+	// ClearSoftQuotaExceeded clears the soft quotas of the ClusterQueue.
+	ClearSoftQuotaExceeded(cqName string)
+	// SetCohortBorrowedResource sets how much of the resource in the flavor
+	// the members of the cohort borrow above their nominal quota.
+	SetCohortBorrowedResource(cohortName string, fName kueue.ResourceFlavorReference, rName corev1.ResourceName, borrowed float64)
+	// ClearCohortBorrowedResources clears the borrowed resources of the
+	// cohort.
+	ClearCohortBorrowedResources(cohortName string)
+	// ClearClusterQueue clears all the metrics of the ClusterQueue.
+	ClearClusterQueue(cqName string)
+}
+
+// prometheusRecorder is the default MetricsRecorder, which reports the
+// metrics to the Prometheus collectors of the metrics package.
+type prometheusRecorder struct{}
+
+var _ MetricsRecorder = prometheusRecorder{}
+
+func (prometheusRecorder) RecordAdmissionWait(cqName string, wait time.Duration) {
+	metrics.ReportAdmissionWaitDuration(cqName, wait)
+}
+
+func (prometheusRecorder) SetActiveWorkloads(cqName string, reserving, admitted int) {
+	metrics.AdmittedActiveWorkloads.WithLabelValues(cqName).Set(float64(admitted))
+	metrics.ReservingActiveWorkloads.WithLabelValues(cqName).Set(float64(reserving))
+}
+
+func (prometheusRecorder) SetClusterQueueStatus(cqName string, status metrics.ClusterQueueStatus) {
+	metrics.ReportClusterQueueStatus(cqName, status)
+}
+
+func (prometheusRecorder) SetSoftQuotaExceeded(cqName string, fName kueue.ResourceFlavorReference, rName corev1.ResourceName, exceeded bool) {
+	metrics.ReportClusterQueueSoftQuotaExceeded(cqName, string(fName), string(rName), exceeded)
+}
+
+func (prometheusRecorder) ClearSoftQuotaExceeded(cqName string) {
+	metrics.ClearClusterQueueSoftQuotaExceeded(cqName)
+}
+
+func (prometheusRecorder) SetCohortBorrowedResource(cohortName string, fName kueue.ResourceFlavorReference, rName corev1.ResourceName, borrowed float64) {
+	metrics.ReportCohortBorrowedResources(cohortName, string(fName), string(rName), borrowed)
+}
+
+func (prometheusRecorder) ClearCohortBorrowedResources(cohortName string) {
+	metrics.ClearCohortBorrowedResources(cohortName)
+}
+
+func (prometheusRecorder) ClearClusterQueue(cqName string) {
+	metrics.ClearCacheMetrics(cqName)
+}
+
+// recorder returns the MetricsRecorder of the ClusterQueue, which is the one
+// of the cache, or the default one for ClusterQueues built outside the cache.
+func (c *ClusterQueue) recorder() MetricsRecorder {
+	if c.metrics == nil {
+		return prometheusRecorder{}
+	}
+	return c.metrics
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	"sigs.k8s.io/kueue/pkg/metrics"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+// fakeMetricsRecorder records the calls to a MetricsRecorder as strings.
+type fakeMetricsRecorder struct {
+	calls []string
+}
+
+func (r *fakeMetricsRecorder) record(format string, args ...any) {
+	r.calls = append(r.calls, fmt.Sprintf(format, args...))
+}
+
+func (r *fakeMetricsRecorder) RecordAdmissionWait(cqName string, wait time.Duration) {
+	r.record("RecordAdmissionWait(%s, %v)", cqName, wait)
+}
+
+func (r *fakeMetricsRecorder) SetActiveWorkloads(cqName string, reserving, admitted int) {
+	r.record("SetActiveWorkloads(%s, %d, %d)", cqName, reserving, admitted)
+}
+
+func (r *fakeMetricsRecorder) SetClusterQueueStatus(cqName string, status metrics.ClusterQueueStatus) {
+	r.record("SetClusterQueueStatus(%s, %s)", cqName, status)
+}
+
+func (r *fakeMetricsRecorder) SetSoftQuotaExceeded(cqName string, fName kueue.ResourceFlavorReference, rName corev1.ResourceName, exceeded bool) {
+	r.record("SetSoftQuotaExceeded(%s, %s, %s, %t)", cqName, fName, rName, exceeded)
+}
+
+func (r *fakeMetricsRecorder) ClearSoftQuotaExceeded(cqName string) {
+	r.record("ClearSoftQuotaExceeded(%s)", cqName)
+}
+
+func (r *fakeMetricsRecorder) SetCohortBorrowedResource(cohortName string, fName kueue.ResourceFlavorReference, rName corev1.ResourceName, borrowed float64) {
+	r.record("SetCohortBorrowedResource(%s, %s, %s, %v)", cohortName, fName, rName, borrowed)
+}
+
+func (r *fakeMetricsRecorder) ClearCohortBorrowedResources(cohortName string) {
+	r.record("ClearCohortBorrowedResources(%s)", cohortName)
+}
+
+func (r *fakeMetricsRecorder) ClearClusterQueue(cqName string) {
+	r.record("ClearClusterQueue(%s)", cqName)
+}
+
+func (r *fakeMetricsRecorder) takeCalls() []string {
+	calls := r.calls
+	r.calls = nil
+	return calls
+}
+
+func TestMetricsRecorder(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	fakeClock := testingclock.NewFakeClock(now)
+	recorder := &fakeMetricsRecorder{}
+	cache := New(utiltesting.NewFakeClient(), WithClock(fakeClock), WithMetricsRecorder(recorder))
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	for _, name := range []string{"a", "b"} {
+		cq := utiltesting.MakeClusterQueue(name).
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+			Cohort("one").
+			Obj()
+		cq.Annotations = map[string]string{constants.SoftQuotasAnnotation: "default:cpu=2"}
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	recorder.takeCalls()
+
+	wlWrapper := utiltesting.MakeWorkload("wl", "ns").
+		Creation(now).
+		Request(corev1.ResourceCPU, "5").
+		ReserveQuotaAt(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "5").Obj(), now)
+	reserved := wlWrapper.Clone().Obj()
+	if err := cache.AssumeWorkload(reserved); err != nil {
+		t.Fatalf("Failed assuming workload: %v", err)
+	}
+	wantCalls := []string{
+		"SetActiveWorkloads(a, 1, 0)",
+		"SetCohortBorrowedResource(one, default, cpu, 1)",
+		"ClearSoftQuotaExceeded(a)",
+		"SetSoftQuotaExceeded(a, default, cpu, true)",
+	}
+	if diff := cmp.Diff(wantCalls, recorder.takeCalls()); diff != "" {
+		t.Errorf("Unexpected calls after the quota reservation (-want,+got):\n%s", diff)
+	}
+
+	fakeClock.Step(time.Minute)
+	admitted := wlWrapper.Admitted(true).Obj()
+	if err := cache.UpdateWorkload(reserved, admitted); err != nil {
+		t.Fatalf("Failed updating workload: %v", err)
+	}
+	wantCalls = []string{
+		"SetActiveWorkloads(a, 0, 0)",
+		"RecordAdmissionWait(a, 1m0s)",
+		"SetActiveWorkloads(a, 1, 1)",
+		"SetCohortBorrowedResource(one, default, cpu, 1)",
+		"ClearSoftQuotaExceeded(a)",
+		"SetSoftQuotaExceeded(a, default, cpu, true)",
+	}
+	if diff := cmp.Diff(wantCalls, recorder.takeCalls()); diff != "" {
+		t.Errorf("Unexpected calls after the admission (-want,+got):\n%s", diff)
+	}
+
+	if err := cache.DeleteWorkload(admitted); err != nil {
+		t.Fatalf("Failed deleting workload: %v", err)
+	}
+	wantCalls = []string{
+		"SetActiveWorkloads(a, 0, 0)",
+		"SetCohortBorrowedResource(one, default, cpu, 0)",
+		"ClearSoftQuotaExceeded(a)",
+		"SetSoftQuotaExceeded(a, default, cpu, false)",
+	}
+	if diff := cmp.Diff(wantCalls, recorder.takeCalls()); diff != "" {
+		t.Errorf("Unexpected calls after the eviction (-want,+got):\n%s", diff)
+	}
+
+	cache.DeleteClusterQueue(utiltesting.MakeClusterQueue("a").Obj())
+	wantCalls = []string{
+		"ClearCohortBorrowedResources(one)",
+		"SetCohortBorrowedResource(one, default, cpu, 0)",
+		"ClearClusterQueue(a)",
+	}
+	if diff := cmp.Diff(wantCalls, recorder.takeCalls()); diff != "" {
+		t.Errorf("Unexpected calls after deleting the ClusterQueue (-want,+got):\n%s", diff)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+// namespaceUsage returns, keyed by namespace, the usage of the workloads of
+// each namespace with quota reserved in the ClusterQueue, added up across
+// flavors. Returns nil if the ClusterQueue doesn't exist.
+func (c *Cache) namespaceUsage(cqName string) map[string]Resources {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	return cq.namespaceUsage()
+}
+
+// NamespaceShares returns, keyed by namespace, the share of the ClusterQueue
+// used by the workloads of each namespace: the highest, among the resources,
+// fraction of the nominal quota of the ClusterQueue that the namespace uses.
+// The namespaces with the lowest shares are the most under-served ones.
+// Returns nil if the ClusterQueue doesn't exist.
+func (c *Cache) NamespaceShares(cqName string) map[string]float64 {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	nominal := make(Resources)
+	for _, rg := range cq.ResourceGroups {
+		for _, flvQuotas := range rg.Flavors {
+			for rName, rQuota := range flvQuotas.Resources {
+				nominal[rName] += rQuota.Nominal
+			}
+		}
+	}
+	usage := cq.namespaceUsage()
+	shares := make(map[string]float64, len(usage))
+	for ns, nsUsage := range usage {
+		var share float64
+		for rName, val := range nsUsage {
+			if nominal[rName] > 0 {
+				share = max(share, float64(val)/float64(nominal[rName]))
+			}
+		}
+		shares[ns] = share
+	}
+	return shares
+}
+
+func (c *ClusterQueue) namespaceUsage() map[string]Resources {
+	usage := make(map[string]Resources)
+	for _, wi := range c.Workloads {
+		ns := wi.Obj.Namespace
+		if usage[ns] == nil {
+			usage[ns] = make(Resources)
+		}
+		for _, psr := range wi.TotalRequests {
+			for rName, val := range psr.Requests {
+				usage[ns][rName] += val
+			}
+		}
+	}
+	return usage
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestNamespaceUsage(t *testing.T) {
+	cases := map[string]struct {
+		cqName     string
+		workloads  []*kueue.Workload
+		wantUsage  map[string]Resources
+		wantShares map[string]float64
+	}{
+		"unknown ClusterQueue": {
+			cqName: "other",
+		},
+		"no workloads": {
+			cqName:     "cq",
+			wantUsage:  map[string]Resources{},
+			wantShares: map[string]float64{},
+		},
+		"one namespace uses most of the capacity": {
+			cqName: "cq",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("a", "busy").
+					ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "on-demand", "4").Obj()).
+					Obj(),
+				utiltesting.MakeWorkload("b", "busy").
+					ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "spot", "2").Obj()).
+					Obj(),
+				utiltesting.MakeWorkload("c", "idle").
+					ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "spot", "1").Obj()).
+					Obj(),
+				utiltesting.MakeWorkload("other", "idle").
+					ReserveQuota(utiltesting.MakeAdmission("other-cq").Assignment(corev1.ResourceCPU, "spot", "1").Obj()).
+					Obj(),
+			},
+			wantUsage: map[string]Resources{
+				"busy": {corev1.ResourceCPU: 6_000},
+				"idle": {corev1.ResourceCPU: 1_000},
+			},
+			wantShares: map[string]float64{
+				"busy": 0.75,
+				"idle": 0.125,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+			clusterQueues := []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("cq").
+					ResourceGroup(
+						*utiltesting.MakeFlavorQuotas("on-demand").Resource(corev1.ResourceCPU, "4").Obj(),
+						*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "4").Obj(),
+					).
+					Obj(),
+				utiltesting.MakeClusterQueue("other-cq").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "4").Obj()).
+					Obj(),
+			}
+			for _, cq := range clusterQueues {
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			for _, wl := range tc.workloads {
+				if !cache.AddOrUpdateWorkload(wl) {
+					t.Fatalf("Failed adding workload %q", wl.Name)
+				}
+			}
+			if diff := cmp.Diff(tc.wantUsage, cache.namespaceUsage(tc.cqName)); diff != "" {
+				t.Errorf("Unexpected usage (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantShares, cache.NamespaceShares(tc.cqName)); diff != "" {
+				t.Errorf("Unexpected shares (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
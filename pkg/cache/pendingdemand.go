@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// totalPendingDemand returns, per resource, the requests of the workloads of
+// the ClusterQueue that aren't admitted yet, such as the input of a cluster
+// autoscaler. It's the sum of the flavored and unflavored demand returned by
+// pendingDemandByFlavor. Returns nil if the ClusterQueue doesn't exist.
+func (c *Cache) totalPendingDemand(cqName string) Resources {
+	flavored, unflavored := c.pendingDemandByFlavor(cqName)
+	if flavored == nil {
+		return nil
+	}
+	demand := maps.Clone(unflavored)
+	for _, resources := range flavored {
+		for rName, val := range resources {
+			demand[rName] += val
+		}
+	}
+	return demand
+}
+
+// pendingDemandByFlavor returns the requests of the workloads of the
+// ClusterQueue that aren't admitted yet. The flavored demand, per flavor and
+// resource, is the one of the workloads holding a quota reservation, whose
+// flavors are known, that wait for their admission checks. The unflavored
+// demand, per resource, is the one of the workloads waiting for a quota
+// reservation in the queues, as reported by SetPendingDemand.
+// Returns nil if the ClusterQueue doesn't exist.
+func (c *Cache) pendingDemandByFlavor(cqName string) (FlavorResourceQuantities, Resources) {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil, nil
+	}
+	flavored := make(FlavorResourceQuantities)
+	for _, wi := range cq.Workloads {
+		if workload.IsAdmitted(wi.Obj) {
+			continue
+		}
+		for _, psr := range wi.TotalRequests {
+			for rName, val := range psr.Requests {
+				fName := psr.Flavors[rName]
+				if flavored[fName] == nil {
+					flavored[fName] = make(map[corev1.ResourceName]int64)
+				}
+				flavored[fName][rName] += val
+			}
+		}
+	}
+	unflavored := maps.Clone(c.pendingDemand[cqName])
+	if unflavored == nil {
+		unflavored = make(Resources)
+	}
+	return flavored, unflavored
+}
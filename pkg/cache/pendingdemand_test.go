@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+func TestPendingDemand(t *testing.T) {
+	cases := map[string]struct {
+		cqName         string
+		workloads      []*kueue.Workload
+		reported       Resources
+		wantDemand     Resources
+		wantFlavored   FlavorResourceQuantities
+		wantUnflavored Resources
+	}{
+		"unknown ClusterQueue": {
+			cqName: "other",
+		},
+		"no pending workloads": {
+			cqName: "cq",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("admitted", "ns").
+					ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "on-demand", "4").Obj()).
+					Admitted(true).
+					Obj(),
+			},
+			wantDemand:     Resources{},
+			wantFlavored:   FlavorResourceQuantities{},
+			wantUnflavored: Resources{},
+		},
+		"demand of several pending workloads": {
+			cqName: "cq",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("admitted", "ns").
+					ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "on-demand", "4").Obj()).
+					Admitted(true).
+					Obj(),
+				utiltesting.MakeWorkload("a", "ns").
+					ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "on-demand", "2").Obj()).
+					Obj(),
+				utiltesting.MakeWorkload("b", "ns").
+					ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "on-demand", "1").Obj()).
+					Obj(),
+				utiltesting.MakeWorkload("c", "ns").
+					ReserveQuota(utiltesting.MakeAdmission("cq").
+						Assignment(corev1.ResourceCPU, "spot", "3").
+						Assignment(corev1.ResourceMemory, "spot", "1Gi").
+						Obj()).
+					Obj(),
+			},
+			reported: Resources{corev1.ResourceCPU: 5_000},
+			wantDemand: Resources{
+				corev1.ResourceCPU:    11_000,
+				corev1.ResourceMemory: 1024 * 1024 * 1024,
+			},
+			wantFlavored: FlavorResourceQuantities{
+				"on-demand": {corev1.ResourceCPU: 3_000},
+				"spot": {
+					corev1.ResourceCPU:    3_000,
+					corev1.ResourceMemory: 1024 * 1024 * 1024,
+				},
+			},
+			wantUnflavored: Resources{corev1.ResourceCPU: 5_000},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+			cq := utiltesting.MakeClusterQueue("cq").
+				ResourceGroup(
+					*utiltesting.MakeFlavorQuotas("on-demand").
+						Resource(corev1.ResourceCPU, "10").
+						Resource(corev1.ResourceMemory, "10Gi").
+						Obj(),
+					*utiltesting.MakeFlavorQuotas("spot").
+						Resource(corev1.ResourceCPU, "10").
+						Resource(corev1.ResourceMemory, "10Gi").
+						Obj(),
+				).
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			for _, wl := range tc.workloads {
+				if !cache.AddOrUpdateWorkload(wl) {
+					t.Fatalf("Failed adding workload %q", wl.Name)
+				}
+			}
+			cache.SetPendingDemand("cq", workload.Requests(tc.reported))
+			if diff := cmp.Diff(tc.wantDemand, cache.totalPendingDemand(tc.cqName)); diff != "" {
+				t.Errorf("Unexpected demand (-want,+got):\n%s", diff)
+			}
+			gotFlavored, gotUnflavored := cache.pendingDemandByFlavor(tc.cqName)
+			if diff := cmp.Diff(tc.wantFlavored, gotFlavored); diff != "" {
+				t.Errorf("Unexpected flavored demand (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantUnflavored, gotUnflavored); diff != "" {
+				t.Errorf("Unexpected unflavored demand (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+)
+
+var errInvalidQuotaPercentage = errors.New("invalid nominal quota percentage")
+
+// quotaPercentages holds, per flavor and resource, the fraction of the total
+// nominal quota of the cohort that is the nominal quota of a ClusterQueue.
+type quotaPercentages map[kueue.ResourceFlavorReference]map[corev1.ResourceName]float64
+
+// parseQuotaPercentage parses a percentage between 0% and 100%, such as "25%"
+// or "12.5%", into a fraction.
+func parseQuotaPercentage(value string) (float64, error) {
+	number, found := strings.CutSuffix(strings.TrimSpace(value), "%")
+	if !found {
+		return 0, fmt.Errorf("%w: %q doesn't end with %%", errInvalidQuotaPercentage, value)
+	}
+	pct, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q: %v", errInvalidQuotaPercentage, value, err)
+	}
+	if pct < 0 || pct > 100 {
+		return 0, fmt.Errorf("%w: %q is not between 0%% and 100%%", errInvalidQuotaPercentage, value)
+	}
+	return pct / 100, nil
+}
+
+// nominalQuotaPercentages returns the nominal quotas of the ClusterQueue
+// declared as percentages in the NominalQuotaPercentagesAnnotation.
+func nominalQuotaPercentages(cq *kueue.ClusterQueue) (quotaPercentages, error) {
+	value, found := cq.Annotations[constants.NominalQuotaPercentagesAnnotation]
+	if !found {
+		return nil, nil
+	}
+	percentages := make(quotaPercentages)
+	for _, entry := range strings.Split(value, ",") {
+		key, pctValue, found := strings.Cut(strings.TrimSpace(entry), "=")
+		fName, rName, keyFound := strings.Cut(key, ":")
+		if !found || !keyFound || fName == "" || rName == "" {
+			return nil, fmt.Errorf("%w: %q in annotation %s, expected <flavor>:<resource>=<percentage>%%", errInvalidQuotaPercentage, entry, constants.NominalQuotaPercentagesAnnotation)
+		}
+		pct, err := parseQuotaPercentage(pctValue)
+		if err != nil {
+			return nil, err
+		}
+		flvPercentages := percentages[kueue.ResourceFlavorReference(fName)]
+		if flvPercentages == nil {
+			flvPercentages = make(map[corev1.ResourceName]float64)
+			percentages[kueue.ResourceFlavorReference(fName)] = flvPercentages
+		}
+		flvPercentages[corev1.ResourceName(rName)] = pct
+	}
+	return percentages, nil
+}
+
+// recomputePercentageQuotas recomputes the nominal quotas declared as
+// percentages by the ClusterQueues whose primary cohort is the given one,
+// from the total absolute nominal quota of the members of the cohort. It's
+// called whenever the members of the cohort or their quotas change.
+func (c *Cache) recomputePercentageQuotas(cohort *Cohort) {
+	total := make(FlavorResourceQuantities)
+	for cq := range cohort.Members {
+		for _, rg := range cq.ResourceGroups {
+			for _, flvQuotas := range rg.Flavors {
+				for rName, rQuota := range flvQuotas.Resources {
+					if _, isPercentage := cq.nominalQuotaPercentages[flvQuotas.Name][rName]; isPercentage {
+						continue
+					}
+					if total[flvQuotas.Name] == nil {
+						total[flvQuotas.Name] = make(map[corev1.ResourceName]int64)
+					}
+					total[flvQuotas.Name][rName] += rQuota.Nominal
+				}
+			}
+		}
+	}
+	for cq := range cohort.Members {
+		if cq.Cohort == cohort && len(cq.nominalQuotaPercentages) > 0 {
+			cq.applyQuotaPercentages(total)
+		}
+	}
+}
+
+// applyQuotaPercentages sets the nominal quotas declared as percentages to
+// their share of the total nominal quota of the cohort.
+func (c *ClusterQueue) applyQuotaPercentages(total FlavorResourceQuantities) {
+	// The resource groups are shared with the snapshots, so they are copied
+	// instead of modified in place.
+	rgs := slices.Clone(c.ResourceGroups)
+	changed := false
+	for i := range rgs {
+		rg := &rgs[i]
+		rg.Flavors = slices.Clone(rg.Flavors)
+		for j := range rg.Flavors {
+			flvQuotas := &rg.Flavors[j]
+			percentages := c.nominalQuotaPercentages[flvQuotas.Name]
+			if len(percentages) == 0 {
+				continue
+			}
+			flvQuotas.Resources = maps.Clone(flvQuotas.Resources)
+			for rName, pct := range percentages {
+				rQuota, found := flvQuotas.Resources[rName]
+				if !found {
+					continue
+				}
+				nominal := int64(float64(total[flvQuotas.Name][rName]) * pct)
+				if nominal == rQuota.Nominal {
+					continue
+				}
+				scaled := *rQuota
+				scaled.Nominal = nominal
+				flvQuotas.Resources[rName] = &scaled
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return
+	}
+	c.ResourceGroups = rgs
+	c.AllocatableResourceGeneration++
+	c.UpdateRGByResource()
+	c.updateGuaranteedQuota()
+	c.syncCohortCapacity()
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestParseQuotaPercentage(t *testing.T) {
+	cases := map[string]struct {
+		value   string
+		want    float64
+		wantErr error
+	}{
+		"integer": {
+			value: "25%",
+			want:  0.25,
+		},
+		"decimal": {
+			value: " 12.5% ",
+			want:  0.125,
+		},
+		"missing percent sign": {
+			value:   "25",
+			wantErr: errInvalidQuotaPercentage,
+		},
+		"not a number": {
+			value:   "a%",
+			wantErr: errInvalidQuotaPercentage,
+		},
+		"above 100": {
+			value:   "150%",
+			wantErr: errInvalidQuotaPercentage,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseQuotaPercentage(tc.value)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Unexpected error: %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Unexpected fraction %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPercentageQuotas(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	makeCQ := func(name, nominal string) *kueue.ClusterQueue {
+		return utiltesting.MakeClusterQueue(name).
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, nominal).Obj()).
+			Cohort("one").
+			Obj()
+	}
+	pct := makeCQ("pct", "0")
+	pct.Annotations = map[string]string{constants.NominalQuotaPercentagesAnnotation: "default:cpu=50%"}
+	nominal := func() int64 {
+		t.Helper()
+		cq := cache.Snapshot().ClusterQueues["pct"]
+		return cq.ResourceGroups[0].Flavors[0].Resources[corev1.ResourceCPU].Nominal
+	}
+
+	for _, cq := range []*kueue.ClusterQueue{makeCQ("a", "10"), pct} {
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue %q: %v", cq.Name, err)
+		}
+	}
+	if got := nominal(); got != 5_000 {
+		t.Errorf("Unexpected nominal quota with one member, got %d, want 5000", got)
+	}
+
+	b := makeCQ("b", "10")
+	if err := cache.AddClusterQueue(context.Background(), b); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	if got := nominal(); got != 10_000 {
+		t.Errorf("Unexpected nominal quota after a member joined, got %d, want 10000", got)
+	}
+
+	b = makeCQ("b", "30")
+	if err := cache.UpdateClusterQueue(b); err != nil {
+		t.Fatalf("Failed updating ClusterQueue: %v", err)
+	}
+	if got := nominal(); got != 20_000 {
+		t.Errorf("Unexpected nominal quota after a member grew, got %d, want 20000", got)
+	}
+
+	cache.DeleteClusterQueue(b)
+	if got := nominal(); got != 5_000 {
+		t.Errorf("Unexpected nominal quota after a member left, got %d, want 5000", got)
+	}
+
+	invalid := makeCQ("invalid", "0")
+	invalid.Annotations = map[string]string{constants.NominalQuotaPercentagesAnnotation: "cpu=50%"}
+	if err := cache.AddClusterQueue(context.Background(), invalid); !errors.Is(err, errInvalidQuotaPercentage) {
+		t.Errorf("Unexpected error adding a ClusterQueue with an invalid annotation: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"maps"
+	"sort"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// cohortPreemptionCandidates returns the workloads in the other ClusterQueues
+// of the cohort whose removal returns the needed resources to the
+// ClusterQueue. Only workloads from ClusterQueues that are borrowing are
+// considered, and only to the extent that their removal reduces the borrowing.
+// The candidates are picked by lowest effective priority first and, for equal
+// priority, shortest termination grace period and most recently reserved
+// first. ExpectedDrainTime returns how long the candidates may take to drain.
+// If the ClusterQueue would borrow with the needed resources, the candidates
+// are restricted by the borrowWithinCohort policy of its preemption: none with
+// the Never policy, and only the ones whose effective priority doesn't exceed
+// the maxPriorityThreshold, if set, with the LowerPriority policy.
+// Returns nil if the needed resources can't be reclaimed.
+func (c *Cache) cohortPreemptionCandidates(cqName string, needed Resources) []*workload.Info {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok || cq.Cohort == nil || len(needed) == 0 {
+		return nil
+	}
+	var threshold *int32
+	if cq.wouldBorrow(needed) {
+		borrowWithinCohort := cq.Preemption.BorrowWithinCohort
+		if borrowWithinCohort == nil || borrowWithinCohort.Policy != kueue.BorrowWithinCohortPolicyLowerPriority {
+			return nil
+		}
+		threshold = borrowWithinCohort.MaxPriorityThreshold
+	}
+
+	peers := make(map[string]*ClusterQueue)
+	usage := make(map[string]FlavorResourceQuantities)
+	var candidates []*workload.Info
+	for peer := range cq.Cohort.Members {
+		if peer == cq || !peer.Active() {
+			continue
+		}
+		peerUsage := make(FlavorResourceQuantities, len(peer.Usage))
+		for fName, rUsage := range peer.Usage {
+			peerUsage[fName] = maps.Clone(rUsage)
+		}
+		if !hasAny(peer.borrowing(peerUsage), needed) {
+			continue
+		}
+		peers[peer.Name] = peer
+		usage[peer.Name] = peerUsage
+		for _, wi := range peer.Workloads {
+			if threshold != nil && peer.EffectivePriority(wi.Obj) > *threshold {
+				continue
+			}
+			candidates = append(candidates, wi)
+		}
+	}
+	sortPreemptionCandidates(candidates, peers)
+
+	remaining := maps.Clone(needed)
+	var targets []*workload.Info
+	for _, wi := range candidates {
+		peer := peers[wi.ClusterQueue]
+		before := peer.borrowing(usage[peer.Name])
+		updateUsage(wi, usage[peer.Name], -1)
+		after := peer.borrowing(usage[peer.Name])
+		reclaimed := false
+		for rName, val := range remaining {
+			if freed := before[rName] - after[rName]; freed > 0 && val > 0 {
+				remaining[rName] = val - freed
+				reclaimed = true
+			}
+		}
+		if !reclaimed {
+			updateUsage(wi, usage[peer.Name], 1)
+			continue
+		}
+		targets = append(targets, wi)
+		if !hasAny(remaining, needed) {
+			return targets
+		}
+	}
+	return nil
+}
+
+// ExpectedDrainTime returns how long the workloads may take to drain once
+// evicted together: the longest of their termination grace periods, as their
+// pods terminate in parallel.
+func ExpectedDrainTime(victims []*workload.Info) time.Duration {
+	var longest time.Duration
+	for _, wi := range victims {
+		longest = max(longest, wi.TerminationGracePeriod)
+	}
+	return longest
+}
+
+// sortPreemptionCandidates sorts the candidates by lowest effective priority
+// first and, for equal priority, by shortest termination grace period, as they
+// drain faster, and then most recently reserved first.
+func sortPreemptionCandidates(candidates []*workload.Info, cqs map[string]*ClusterQueue) {
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if pa, pb := cqs[a.ClusterQueue].EffectivePriority(a.Obj), cqs[b.ClusterQueue].EffectivePriority(b.Obj); pa != pb {
+			return pa < pb
+		}
+		if a.TerminationGracePeriod != b.TerminationGracePeriod {
+			return a.TerminationGracePeriod < b.TerminationGracePeriod
+		}
+		if ta, tb := quotaReservationTime(a.Obj), quotaReservationTime(b.Obj); !ta.Equal(tb) {
+			return ta.After(tb)
+		}
+		// Arbitrary comparison for deterministic sorting.
+		return a.Obj.UID < b.Obj.UID
+	})
+}
+
+// borrowing returns, per resource, the usage above the nominal quota in all
+// the flavors of the ClusterQueue.
+func (c *ClusterQueue) borrowing(usage FlavorResourceQuantities) Resources {
+	borrowed := make(Resources)
+	for _, rg := range c.ResourceGroups {
+		for _, flvQuotas := range rg.Flavors {
+			for rName, rQuota := range flvQuotas.Resources {
+				if val := usage[flvQuotas.Name][rName] - rQuota.Nominal; val > 0 {
+					borrowed[rName] += val
+				}
+			}
+		}
+	}
+	return borrowed
+}
+
+// wouldBorrow returns whether the ClusterQueue would use more than its
+// nominal quota of any resource with the needed resources on top of its usage.
+func (c *ClusterQueue) wouldBorrow(needed Resources) bool {
+	idle := c.idleNominal()
+	for rName, val := range needed {
+		if val > idle[rName] {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAny returns whether any of the resources in the filter has a positive
+// value in the resources.
+func hasAny(resources, filter Resources) bool {
+	for rName := range filter {
+		if resources[rName] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// quotaReservationTime returns the time at which the workload got its quota
+// reserved, or its creation time if unknown.
+func quotaReservationTime(wl *kueue.Workload) time.Time {
+	if cond := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadQuotaReserved); cond != nil {
+		return cond.LastTransitionTime.Time
+	}
+	return wl.CreationTimestamp.Time
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+func TestCohortPreemptionCandidates(t *testing.T) {
+	now := time.Now()
+	peerWorkloads := func(extra ...*kueue.Workload) []*kueue.Workload {
+		return append([]*kueue.Workload{
+			utiltesting.MakeWorkload("low", "ns").Priority(1).
+				ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "2").Obj(), now).
+				Obj(),
+			utiltesting.MakeWorkload("mid", "ns").Priority(5).
+				ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "2").Obj(), now).
+				Obj(),
+			utiltesting.MakeWorkload("high", "ns").Priority(10).
+				ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "2").Obj(), now).
+				Obj(),
+		}, extra...)
+	}
+	// own leaves 1 CPU of the nominal quota of cq unused.
+	own := utiltesting.MakeWorkload("own", "ns").
+		ReserveQuotaAt(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "5").Obj(), now).
+		Obj()
+	cases := map[string]struct {
+		borrowWithinCohort *kueue.BorrowWithinCohort
+		workloads          []*kueue.Workload
+		needed             Resources
+		want               []string
+	}{
+		"lowest priority borrowing workloads first": {
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("low", "ns").Priority(1).
+					ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "2").Obj(), now).
+					Obj(),
+				utiltesting.MakeWorkload("high", "ns").Priority(10).
+					ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "3").Obj(), now).
+					Obj(),
+				utiltesting.MakeWorkload("mid", "ns").Priority(5).
+					ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "3").Obj(), now).
+					Obj(),
+			},
+			needed: Resources{corev1.ResourceCPU: 4_000},
+			want:   []string{"ns/low", "ns/mid"},
+		},
+		"most recently reserved first for equal priority": {
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("old", "ns").
+					ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "3").Obj(), now.Add(-time.Minute)).
+					Obj(),
+				utiltesting.MakeWorkload("new", "ns").
+					ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "3").Obj(), now).
+					Obj(),
+			},
+			needed: Resources{corev1.ResourceCPU: 1_000},
+			want:   []string{"ns/new"},
+		},
+		"shortest termination grace period first for equal priority": {
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("fast", "ns").
+					PodSets(*utiltesting.MakePodSet("main", 1).TerminationGracePeriod(5).Obj()).
+					ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "3").Obj(), now.Add(-time.Minute)).
+					Obj(),
+				utiltesting.MakeWorkload("slow", "ns").
+					PodSets(*utiltesting.MakePodSet("main", 1).TerminationGracePeriod(300).Obj()).
+					ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "3").Obj(), now).
+					Obj(),
+			},
+			needed: Resources{corev1.ResourceCPU: 1_000},
+			want:   []string{"ns/fast"},
+		},
+		"only the borrowed quota is reclaimable": {
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("a", "ns").
+					ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "2").Obj(), now).
+					Obj(),
+				utiltesting.MakeWorkload("b", "ns").
+					ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "2").Obj(), now).
+					Obj(),
+			},
+			needed: Resources{corev1.ResourceCPU: 3_000},
+		},
+		"peer within its nominal quota": {
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("a", "ns").
+					ReserveQuotaAt(utiltesting.MakeAdmission("peer").Assignment(corev1.ResourceCPU, "default", "2").Obj(), now).
+					Obj(),
+			},
+			needed: Resources{corev1.ResourceCPU: 1_000},
+		},
+		"borrowing without borrowWithinCohort": {
+			workloads: peerWorkloads(own),
+			needed:    Resources{corev1.ResourceCPU: 2_000},
+		},
+		"borrowing with the Never policy": {
+			borrowWithinCohort: &kueue.BorrowWithinCohort{Policy: kueue.BorrowWithinCohortPolicyNever},
+			workloads:          peerWorkloads(own),
+			needed:             Resources{corev1.ResourceCPU: 2_000},
+		},
+		"borrowing with the LowerPriority policy without threshold": {
+			borrowWithinCohort: &kueue.BorrowWithinCohort{Policy: kueue.BorrowWithinCohortPolicyLowerPriority},
+			workloads:          peerWorkloads(own),
+			needed:             Resources{corev1.ResourceCPU: 3_000},
+			want:               []string{"ns/low", "ns/mid"},
+		},
+		"borrowing preempts the workloads below the threshold": {
+			borrowWithinCohort: &kueue.BorrowWithinCohort{
+				Policy:               kueue.BorrowWithinCohortPolicyLowerPriority,
+				MaxPriorityThreshold: ptr.To[int32](5),
+			},
+			workloads: peerWorkloads(own),
+			needed:    Resources{corev1.ResourceCPU: 3_000},
+			want:      []string{"ns/low", "ns/mid"},
+		},
+		"borrowing doesn't preempt the workloads above the threshold": {
+			borrowWithinCohort: &kueue.BorrowWithinCohort{
+				Policy:               kueue.BorrowWithinCohortPolicyLowerPriority,
+				MaxPriorityThreshold: ptr.To[int32](1),
+			},
+			workloads: peerWorkloads(own),
+			needed:    Resources{corev1.ResourceCPU: 3_000},
+		},
+		"borrowing preempts enough workloads below the threshold": {
+			borrowWithinCohort: &kueue.BorrowWithinCohort{
+				Policy:               kueue.BorrowWithinCohortPolicyLowerPriority,
+				MaxPriorityThreshold: ptr.To[int32](1),
+			},
+			workloads: peerWorkloads(own),
+			needed:    Resources{corev1.ResourceCPU: 2_000},
+			want:      []string{"ns/low"},
+		},
+		"threshold ignored when not borrowing": {
+			borrowWithinCohort: &kueue.BorrowWithinCohort{
+				Policy:               kueue.BorrowWithinCohortPolicyLowerPriority,
+				MaxPriorityThreshold: ptr.To[int32](1),
+			},
+			workloads: peerWorkloads(),
+			needed:    Resources{corev1.ResourceCPU: 3_000},
+			want:      []string{"ns/low", "ns/mid"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			clusterQueues := []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("cq").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "6").Obj()).
+					Cohort("one").
+					Preemption(kueue.ClusterQueuePreemption{BorrowWithinCohort: tc.borrowWithinCohort}).
+					Obj(),
+				utiltesting.MakeClusterQueue("peer").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "2").Obj()).
+					Cohort("one").
+					Obj(),
+			}
+			for _, cq := range clusterQueues {
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			for _, wl := range tc.workloads {
+				if !cache.AddOrUpdateWorkload(wl) {
+					t.Fatalf("Failed adding workload %q", wl.Name)
+				}
+			}
+			var got []string
+			for _, wi := range cache.cohortPreemptionCandidates("cq", tc.needed) {
+				got = append(got, workload.Key(wi.Obj))
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected candidates (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExpectedDrainTime(t *testing.T) {
+	cases := map[string]struct {
+		victims []*workload.Info
+		want    time.Duration
+	}{
+		"no victims": {},
+		"longest grace period": {
+			victims: []*workload.Info{
+				{TerminationGracePeriod: 30 * time.Second},
+				{TerminationGracePeriod: 2 * time.Minute},
+				{TerminationGracePeriod: 5 * time.Second},
+			},
+			want: 2 * time.Minute,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := ExpectedDrainTime(tc.victims); got != tc.want {
+				t.Errorf("Unexpected drain time, got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
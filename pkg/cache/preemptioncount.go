@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// maxPreemptionPriorityBoost caps the number of preemptions of a workload
+// that raise its effective priority, so that a workload preempted many times
+// doesn't overtake workloads of much higher priority.
+const maxPreemptionPriorityBoost = 10
+
+// RequeuePreempted records that the workload was preempted and is being
+// requeued to its ClusterQueue. Every preemption raises the effective
+// priority of the workload in the ClusterQueue by one, up to
+// maxPreemptionPriorityBoost, which moves it ahead of the workloads of the
+// same priority when it's requeued and makes it less likely to be preempted
+// again. The count is kept until the workload finishes or is deleted.
+func (c *Cache) RequeuePreempted(w *kueue.Workload) {
+	c.Lock()
+	defer c.Unlock()
+	cq := c.clusterQueueForRequeue(w)
+	if cq == nil {
+		return
+	}
+	if cq.PreemptionCounts == nil {
+		cq.PreemptionCounts = make(map[string]int32)
+	}
+	cq.PreemptionCounts[workload.Key(w)]++
+}
+
+// preemptionCount returns how many times the workload was preempted, as
+// recorded by RequeuePreempted.
+func (c *Cache) preemptionCount(w *kueue.Workload) int32 {
+	c.RLock()
+	defer c.RUnlock()
+	cq := c.clusterQueueForRequeue(w)
+	if cq == nil {
+		return 0
+	}
+	return cq.PreemptionCounts[workload.Key(w)]
+}
+
+func (c *Cache) forgetPreemptions(w *kueue.Workload) {
+	if cq := c.clusterQueueForRequeue(w); cq != nil {
+		delete(cq.PreemptionCounts, workload.Key(w))
+	}
+}
+
+// clusterQueueForRequeue returns the ClusterQueue where the workload has, or
+// had, its quota reservation or, otherwise, the ClusterQueue of its
+// LocalQueue.
+func (c *Cache) clusterQueueForRequeue(w *kueue.Workload) *ClusterQueue {
+	if w.Status.Admission != nil {
+		return c.clusterQueues[string(w.Status.Admission.ClusterQueue)]
+	}
+	qKey := workload.QueueKey(w)
+	for _, cq := range c.clusterQueues {
+		if _, found := cq.localQueues[qKey]; found {
+			return cq
+		}
+	}
+	return nil
+}
+
+// preemptionPriorityBoost returns how much the preemptions of the workload
+// raise its effective priority in the ClusterQueue.
+func (c *ClusterQueue) preemptionPriorityBoost(wl *kueue.Workload) int64 {
+	return int64(min(c.PreemptionCounts[workload.Key(wl)], maxPreemptionPriorityBoost))
+}
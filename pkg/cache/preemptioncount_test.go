@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestRequeuePreempted(t *testing.T) {
+	cases := map[string]struct {
+		preemptions  int
+		wantCount    int32
+		wantPriority int32
+	}{
+		"never preempted": {
+			wantPriority: 5,
+		},
+		"preempted twice": {
+			preemptions:  2,
+			wantCount:    2,
+			wantPriority: 7,
+		},
+		"boost is capped": {
+			preemptions:  maxPreemptionPriorityBoost + 5,
+			wantCount:    maxPreemptionPriorityBoost + 5,
+			wantPriority: 5 + maxPreemptionPriorityBoost,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			cq := utiltesting.MakeClusterQueue("cq").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			wl := utiltesting.MakeWorkload("wl", "ns").
+				Priority(5).
+				ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+				Obj()
+			for range tc.preemptions {
+				cache.RequeuePreempted(wl)
+			}
+			if got := cache.preemptionCount(wl); got != tc.wantCount {
+				t.Errorf("Unexpected preemption count, got %d, want %d", got, tc.wantCount)
+			}
+			if got := cache.EffectivePriority(wl, "cq"); got != tc.wantPriority {
+				t.Errorf("Unexpected effective priority, got %d, want %d", got, tc.wantPriority)
+			}
+			snapshot := cache.Snapshot()
+			if got := snapshot.ClusterQueues["cq"].EffectivePriority(wl); got != tc.wantPriority {
+				t.Errorf("Unexpected effective priority in the snapshot, got %d, want %d", got, tc.wantPriority)
+			}
+		})
+	}
+}
+
+func TestRequeuePreemptedOrdering(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	if err := cache.AddLocalQueue(utiltesting.MakeLocalQueue("lq", "ns").ClusterQueue("cq").Obj()); err != nil {
+		t.Fatalf("Failed adding LocalQueue: %v", err)
+	}
+	preempted := utiltesting.MakeWorkload("preempted", "ns").Queue("lq").Obj()
+	peer := utiltesting.MakeWorkload("peer", "ns").Queue("lq").Obj()
+	// The workload is requeued after losing its quota reservation.
+	cache.RequeuePreempted(preempted)
+	cache.RequeuePreempted(preempted)
+	if got, peerPriority := cache.EffectivePriority(preempted, "cq"), cache.EffectivePriority(peer, "cq"); got <= peerPriority {
+		t.Errorf("The twice preempted workload doesn't outrank its peer, got priorities %d and %d", got, peerPriority)
+	}
+
+	cache.AddOrUpdateWorkload(utiltesting.MakeWorkload("preempted", "ns").Queue("lq").Finished().Obj())
+	if got := cache.preemptionCount(preempted); got != 0 {
+		t.Errorf("Unexpected preemption count after the workload finished: %d", got)
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// QuantityToInt64 converts the quantity of the resource to the scale in which
+// the cache holds quotas and usage: milli-units for cpu and units for the rest.
+// Controllers formatting quantities for the cache should use it and
+// Int64ToQuantity, so that they interpret quantities as the cache does.
+func QuantityToInt64(name corev1.ResourceName, q resource.Quantity) int64 {
+	return workload.ResourceValue(name, q)
+}
+
+// Int64ToQuantity is the inverse of QuantityToInt64.
+func Int64ToQuantity(name corev1.ResourceName, v int64) resource.Quantity {
+	return workload.ResourceQuantity(name, v)
+}
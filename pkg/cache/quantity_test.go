@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestQuantityRoundTrip(t *testing.T) {
+	cases := map[string]struct {
+		name     corev1.ResourceName
+		quantity string
+		want     int64
+	}{
+		"cpu": {
+			name:     corev1.ResourceCPU,
+			quantity: "1500m",
+			want:     1_500,
+		},
+		"whole cpus": {
+			name:     corev1.ResourceCPU,
+			quantity: "4",
+			want:     4_000,
+		},
+		"memory": {
+			name:     corev1.ResourceMemory,
+			quantity: "2Gi",
+			want:     2 * utiltesting.Gi,
+		},
+		"gpu": {
+			name:     "nvidia.com/gpu",
+			quantity: "8",
+			want:     8,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			q := resource.MustParse(tc.quantity)
+			got := QuantityToInt64(tc.name, q)
+			if got != tc.want {
+				t.Errorf("Unexpected value, got %d, want %d", got, tc.want)
+			}
+			if back := Int64ToQuantity(tc.name, got); back.Cmp(q) != 0 {
+				t.Errorf("Unexpected quantity after the round trip, got %s, want %s", back.String(), q.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"math"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+var errNoQueueFits = errors.New("the workload doesn't fit in any ClusterQueue of the group")
+
+// admitToBestQueue returns the ClusterQueue of the group, a set of
+// ClusterQueues that present a single admission surface, where the workload
+// fits with the most headroom. The workload fits in a ClusterQueue if, for
+// each of its PodSets, every resource group has a flavor that
+// explainUnschedulable wouldn't reject. The headroom is the smallest fraction,
+// across the chosen flavors and resources, of the available quota left after
+// admitting the workload. Ties are broken by the order in the group.
+// The workload is not admitted; the caller routes it to the chosen
+// ClusterQueue.
+func (c *Cache) admitToBestQueue(w *kueue.Workload, group []string) (string, error) {
+	snap := c.Snapshot()
+	totalRequests := workload.NewInfo(w).TotalRequests
+	chosen := ""
+	chosenHeadroom := -1.0
+	for _, cqName := range group {
+		cq, ok := snap.ClusterQueues[cqName]
+		if !ok {
+			continue
+		}
+		headroom, fits := workloadHeadroom(&snap, cq, w, totalRequests)
+		if fits && headroom > chosenHeadroom {
+			chosen, chosenHeadroom = cqName, headroom
+		}
+	}
+	if chosen == "" {
+		return "", errNoQueueFits
+	}
+	return chosen, nil
+}
+
+// workloadHeadroom returns the headroom left in the ClusterQueue of the
+// snapshot after admitting the workload, and whether the workload fits.
+func workloadHeadroom(snap *Snapshot, cq *ClusterQueue, w *kueue.Workload, totalRequests []workload.PodSetResources) (float64, bool) {
+	headroom := 1.0
+	excluded := workload.ExcludedFlavors(w)
+	for _, psr := range totalRequests {
+		ps := podSetByName(w, psr.Name)
+		if ps == nil {
+			continue
+		}
+		for rName, val := range psr.Requests {
+			rg := cq.RGByResource[rName]
+			if rg == nil {
+				if val > 0 {
+					return 0, false
+				}
+				continue
+			}
+			var flvQuotas *FlavorQuotas
+			for i := range rg.Flavors {
+				if rejectFlavor(snap, cq, rg.Flavors[i], ps, psr.Requests, excluded) == "" {
+					flvQuotas = &rg.Flavors[i]
+					break
+				}
+			}
+			if flvQuotas == nil {
+				return 0, false
+			}
+			if val <= 0 {
+				continue
+			}
+			available := availableQuota(cq, flvQuotas.Name, rName, flvQuotas.Resources[rName])
+			headroom = math.Min(headroom, float64(available-val)/float64(available))
+		}
+	}
+	return headroom, true
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestAdmitToBestQueue(t *testing.T) {
+	cases := map[string]struct {
+		nominal map[string]string
+		group   []string
+		want    string
+		wantErr error
+	}{
+		"only one queue has room": {
+			nominal: map[string]string{"a": "1", "b": "4", "c": "2"},
+			group:   []string{"a", "b", "c"},
+			want:    "b",
+		},
+		"most headroom": {
+			nominal: map[string]string{"a": "8", "b": "4", "c": "6"},
+			group:   []string{"a", "b", "c"},
+			want:    "a",
+		},
+		"no queue has room": {
+			nominal: map[string]string{"a": "1", "b": "2", "c": "2"},
+			group:   []string{"a", "b", "c"},
+			wantErr: errNoQueueFits,
+		},
+		"unknown queues are skipped": {
+			nominal: map[string]string{"a": "4"},
+			group:   []string{"unknown", "a"},
+			want:    "a",
+		},
+		"empty group": {
+			wantErr: errNoQueueFits,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			for cqName, nominal := range tc.nominal {
+				cq := utiltesting.MakeClusterQueue(cqName).
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, nominal).Obj()).
+					Obj()
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+				// Each queue already uses 1 CPU.
+				wl := utiltesting.MakeWorkload("running-"+cqName, "ns").
+					ReserveQuota(utiltesting.MakeAdmission(cqName).Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+					Obj()
+				if !cache.AddOrUpdateWorkload(wl) {
+					t.Fatalf("Failed adding workload %q", wl.Name)
+				}
+			}
+
+			wl := utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, "2").Obj()
+			got, err := cache.admitToBestQueue(wl, tc.group)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Unexpected error: %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Unexpected ClusterQueue %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"maps"
+
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+var errSingleMemberCohort = errors.New("cohort has a single member")
+
+// Recommendation is a suggestion to preempt a workload that borrows quota in
+// a cohort, so that the quota returns to a starved ClusterQueue.
+type Recommendation struct {
+	// Workload is the workload to preempt.
+	Workload *workload.Info
+	// Beneficiary is the name of the ClusterQueue that gets the quota back.
+	Beneficiary string
+	// Resources is the borrowed quota returned by the preemption.
+	Resources Resources
+}
+
+// rebalanceRecommendations returns the borrowing workloads in the cohort that
+// should be preempted so that the starved ClusterQueues get back their nominal
+// quota. A ClusterQueue is starved when it has pending demand, as reported by
+// SetPendingDemand, for nominal quota that it doesn't use. The starved
+// ClusterQueues take turns to pick, one at a time, the next workload in
+// preemption order whose removal reduces the borrowing of the resources they
+// need, so that no ClusterQueue is served at the expense of the others.
+// The recommendations are advisory, the caller decides whether to act on them.
+func (c *Cache) rebalanceRecommendations(cohortName string) []Recommendation {
+	c.RLock()
+	defer c.RUnlock()
+	cohort, ok := c.cohorts[cohortName]
+	if !ok {
+		return nil
+	}
+
+	cqs := make(map[string]*ClusterQueue)
+	usage := make(map[string]FlavorResourceQuantities)
+	needed := make(map[string]Resources)
+	var starved []string
+	var candidates []*workload.Info
+	for _, cq := range cohort.SortedMembers() {
+		if !cq.Active() {
+			continue
+		}
+		cqs[cq.Name] = cq
+		cqUsage := make(FlavorResourceQuantities, len(cq.Usage))
+		for fName, rUsage := range cq.Usage {
+			cqUsage[fName] = maps.Clone(rUsage)
+		}
+		usage[cq.Name] = cqUsage
+		for _, wi := range cq.Workloads {
+			candidates = append(candidates, wi)
+		}
+		cqNeeded := make(Resources)
+		for rName, idle := range cq.idleNominal() {
+			if val := min(idle, c.pendingDemand[cq.Name][rName]); val > 0 {
+				cqNeeded[rName] = val
+			}
+		}
+		if len(cqNeeded) > 0 {
+			needed[cq.Name] = cqNeeded
+			starved = append(starved, cq.Name)
+		}
+	}
+	sortPreemptionCandidates(candidates, cqs)
+
+	var recommendations []Recommendation
+	picked := make(map[*workload.Info]bool)
+	for len(starved) > 0 {
+		remaining := starved[:0]
+		for _, beneficiary := range starved {
+			rec := pickRebalanceVictim(beneficiary, needed[beneficiary], candidates, picked, cqs, usage)
+			if rec == nil {
+				continue
+			}
+			recommendations = append(recommendations, *rec)
+			if hasAny(needed[beneficiary], needed[beneficiary]) {
+				remaining = append(remaining, beneficiary)
+			}
+		}
+		starved = remaining
+	}
+	return recommendations
+}
+
+// mostStarvedQueue returns the name of the active ClusterQueue in the cohort
+// with the largest unmet guaranteed demand: the pending demand, as reported by
+// SetPendingDemand, that fits in its unused nominal quota but can't be
+// admitted because the other members of the cohort borrowed that quota. The
+// unmet demand is compared as a fraction of the nominal quota of the
+// ClusterQueue, taking the most starved resource of each ClusterQueue; ties
+// are broken by name. Returns an empty name if no ClusterQueue is starved.
+func (c *Cache) mostStarvedQueue(cohortName string) (string, error) {
+	c.RLock()
+	defer c.RUnlock()
+	cohort, ok := c.cohorts[cohortName]
+	if !ok {
+		return "", errCohortNotFound
+	}
+	if len(cohort.Members) < 2 {
+		return "", errSingleMemberCohort
+	}
+	members := cohort.SortedMembers()
+	borrowed := make(map[string]Resources, len(members))
+	for _, cq := range members {
+		borrowed[cq.Name] = cq.borrowing(cq.Usage)
+	}
+
+	var starved string
+	var starvedRatio float64
+	for _, cq := range members {
+		if !cq.Active() {
+			continue
+		}
+		nominal := make(Resources)
+		for _, rg := range cq.ResourceGroups {
+			for _, flvQuotas := range rg.Flavors {
+				for rName, rQuota := range flvQuotas.Resources {
+					nominal[rName] += rQuota.Nominal
+				}
+			}
+		}
+		for rName, idle := range cq.idleNominal() {
+			var peersBorrowed int64
+			for _, peer := range members {
+				if peer != cq {
+					peersBorrowed += borrowed[peer.Name][rName]
+				}
+			}
+			unmet := min(idle, c.pendingDemand[cq.Name][rName], peersBorrowed)
+			if unmet <= 0 || nominal[rName] == 0 {
+				continue
+			}
+			if ratio := float64(unmet) / float64(nominal[rName]); ratio > starvedRatio {
+				starved, starvedRatio = cq.Name, ratio
+			}
+		}
+	}
+	return starved, nil
+}
+
+// pickRebalanceVictim returns the first candidate, not picked yet and outside
+// of the beneficiary, whose removal reduces the borrowing of its ClusterQueue
+// in the needed resources. The usage of the victim's ClusterQueue and the
+// needed resources are updated accordingly.
+// Returns nil if there is no such candidate.
+func pickRebalanceVictim(beneficiary string, needed Resources, candidates []*workload.Info, picked map[*workload.Info]bool, cqs map[string]*ClusterQueue, usage map[string]FlavorResourceQuantities) *Recommendation {
+	for _, wi := range candidates {
+		if picked[wi] || wi.ClusterQueue == beneficiary {
+			continue
+		}
+		cq := cqs[wi.ClusterQueue]
+		before := cq.borrowing(usage[cq.Name])
+		updateUsage(wi, usage[cq.Name], -1)
+		after := cq.borrowing(usage[cq.Name])
+		returned := make(Resources)
+		for rName, val := range needed {
+			if freed := before[rName] - after[rName]; freed > 0 && val > 0 {
+				returned[rName] = min(freed, val)
+				needed[rName] = val - returned[rName]
+			}
+		}
+		if len(returned) == 0 {
+			updateUsage(wi, usage[cq.Name], 1)
+			continue
+		}
+		picked[wi] = true
+		return &Recommendation{
+			Workload:    wi,
+			Beneficiary: beneficiary,
+			Resources:   returned,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+func TestRebalanceRecommendations(t *testing.T) {
+	now := time.Now()
+	type recommendation struct {
+		Workload    string
+		Beneficiary string
+		Resources   Resources
+	}
+	cases := map[string]struct {
+		pendingDemand map[string]Resources
+		want          []recommendation
+	}{
+		"no pending demand": {},
+		"starved queues take turns": {
+			pendingDemand: map[string]Resources{
+				"a": {corev1.ResourceCPU: 4_000},
+				"b": {corev1.ResourceCPU: 2_000},
+			},
+			want: []recommendation{
+				{Workload: "ns/p1", Beneficiary: "a", Resources: Resources{corev1.ResourceCPU: 3_000}},
+				{Workload: "ns/p2", Beneficiary: "b", Resources: Resources{corev1.ResourceCPU: 2_000}},
+				{Workload: "ns/p3", Beneficiary: "a", Resources: Resources{corev1.ResourceCPU: 1_000}},
+			},
+		},
+		"demand above the nominal quota": {
+			pendingDemand: map[string]Resources{
+				"a": {corev1.ResourceCPU: 10_000},
+			},
+			want: []recommendation{
+				{Workload: "ns/p1", Beneficiary: "a", Resources: Resources{corev1.ResourceCPU: 3_000}},
+				{Workload: "ns/p2", Beneficiary: "a", Resources: Resources{corev1.ResourceCPU: 3_000}},
+			},
+		},
+		"demand for a resource nobody borrows": {
+			pendingDemand: map[string]Resources{
+				"a": {corev1.ResourceMemory: 1_000},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			clusterQueues := []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("a").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "6").
+						Resource(corev1.ResourceMemory, "6").
+						Obj()).
+					Cohort("one").
+					Obj(),
+				utiltesting.MakeClusterQueue("b").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "6").
+						Resource(corev1.ResourceMemory, "6").
+						Obj()).
+					Cohort("one").
+					Obj(),
+				utiltesting.MakeClusterQueue("borrower").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "0").
+						Resource(corev1.ResourceMemory, "0").
+						Obj()).
+					Cohort("one").
+					Obj(),
+			}
+			for _, cq := range clusterQueues {
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			for i, name := range []string{"p1", "p2", "p3", "p4"} {
+				wl := utiltesting.MakeWorkload(name, "ns").Priority(int32(i)).
+					ReserveQuotaAt(utiltesting.MakeAdmission("borrower").Assignment(corev1.ResourceCPU, "default", "3").Obj(), now).
+					Obj()
+				if !cache.AddOrUpdateWorkload(wl) {
+					t.Fatalf("Failed adding workload %q", wl.Name)
+				}
+			}
+			for cqName, demand := range tc.pendingDemand {
+				cache.SetPendingDemand(cqName, workload.Requests(demand))
+			}
+
+			var got []recommendation
+			for _, rec := range cache.rebalanceRecommendations("one") {
+				got = append(got, recommendation{
+					Workload:    workload.Key(rec.Workload.Obj),
+					Beneficiary: rec.Beneficiary,
+					Resources:   rec.Resources,
+				})
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected recommendations (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMostStarvedQueue(t *testing.T) {
+	cases := map[string]struct {
+		cohort        string
+		pendingDemand map[string]Resources
+		want          string
+		wantErr       error
+	}{
+		"starved queue": {
+			cohort: "one",
+			pendingDemand: map[string]Resources{
+				"starved":  {corev1.ResourceCPU: 4_000},
+				"borrower": {corev1.ResourceCPU: 10_000},
+			},
+			want: "starved",
+		},
+		"demand fits in the unused quota of the cohort": {
+			cohort: "one",
+			pendingDemand: map[string]Resources{
+				"starved": {corev1.ResourceMemory: 4_000},
+			},
+		},
+		"no pending demand": {
+			cohort: "one",
+		},
+		"unknown cohort": {
+			cohort:  "unknown",
+			wantErr: errCohortNotFound,
+		},
+		"single member cohort": {
+			cohort:  "two",
+			wantErr: errSingleMemberCohort,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := New(utiltesting.NewFakeClient())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			clusterQueues := []*kueue.ClusterQueue{
+				utiltesting.MakeClusterQueue("starved").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "6").
+						Resource(corev1.ResourceMemory, "6").
+						Obj()).
+					Cohort("one").
+					Obj(),
+				utiltesting.MakeClusterQueue("borrower").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "2").
+						Resource(corev1.ResourceMemory, "2").
+						Obj()).
+					Cohort("one").
+					Obj(),
+				utiltesting.MakeClusterQueue("alone").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "2").
+						Obj()).
+					Cohort("two").
+					Obj(),
+			}
+			for _, cq := range clusterQueues {
+				if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			wl := utiltesting.MakeWorkload("big", "ns").
+				ReserveQuota(utiltesting.MakeAdmission("borrower").Assignment(corev1.ResourceCPU, "default", "8").Obj()).
+				Obj()
+			if !cache.AddOrUpdateWorkload(wl) {
+				t.Fatalf("Failed adding workload %q", wl.Name)
+			}
+			for cqName, demand := range tc.pendingDemand {
+				cache.SetPendingDemand(cqName, workload.Requests(demand))
+			}
+
+			got, err := cache.mostStarvedQueue(tc.cohort)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Unexpected error: %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Unexpected most starved queue %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+)
+
+var errFlavorRenameConflict = errors.New("the ClusterQueue already has a flavor with the new name")
+
+// renameFlavor moves the quotas, the usage and the flavor assignments of the
+// admitted workloads from the flavor oldName to the flavor newName in all the
+// ClusterQueues, for when a ResourceFlavor is replaced by one with another
+// name. Either all the ClusterQueues are updated or, if any of them already
+// has a flavor named newName, none of them.
+// The ClusterQueues and workloads are expected to be updated to refer to the
+// new flavor afterwards; until then, updating them reintroduces the old name.
+func (c *Cache) renameFlavor(oldName, newName kueue.ResourceFlavorReference) error {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
+	c.Lock()
+	defer c.Unlock()
+	if oldName == newName {
+		return nil
+	}
+	for _, cq := range c.clusterQueues {
+		if cq.hasFlavor(oldName) && cq.hasFlavor(newName) {
+			return fmt.Errorf("%w: ClusterQueue %s has flavors %s and %s", errFlavorRenameConflict, cq.Name, oldName, newName)
+		}
+	}
+	for _, cq := range c.clusterQueues {
+		if cq.renameFlavor(oldName, newName) {
+			cq.UpdateWithFlavors(c.resourceFlavors)
+			changed = append(changed, cq.Name)
+		}
+	}
+	return nil
+}
+
+func (c *ClusterQueue) hasFlavor(name kueue.ResourceFlavorReference) bool {
+	for _, rg := range c.ResourceGroups {
+		for _, flvQuotas := range rg.Flavors {
+			if flvQuotas.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// renameFlavor renames the flavor in the ClusterQueue and returns whether the
+// ClusterQueue had it.
+func (c *ClusterQueue) renameFlavor(oldName, newName kueue.ResourceFlavorReference) bool {
+	// The resource groups and the workload infos are shared with the
+	// snapshots, so they are copied instead of modified in place.
+	rgs := slices.Clone(c.ResourceGroups)
+	found := false
+	for i := range rgs {
+		rg := &rgs[i]
+		idx := slices.IndexFunc(rg.Flavors, func(flvQuotas FlavorQuotas) bool {
+			return flvQuotas.Name == oldName
+		})
+		if idx < 0 {
+			continue
+		}
+		rg.Flavors = slices.Clone(rg.Flavors)
+		rg.Flavors[idx].Name = newName
+		found = true
+	}
+	if !found {
+		return false
+	}
+	c.ResourceGroups = rgs
+	c.UpdateRGByResource()
+
+	renameFlavorKey(c.Usage, oldName, newName)
+	renameFlavorKey(c.AdmittedUsage, oldName, newName)
+	for _, hold := range c.holds {
+		renameFlavorKey(hold, oldName, newName)
+	}
+	for _, lq := range c.localQueues {
+		renameFlavorKey(lq.usage, oldName, newName)
+		renameFlavorKey(lq.admittedUsage, oldName, newName)
+	}
+	if percentages, found := c.nominalQuotaPercentages[oldName]; found {
+		c.nominalQuotaPercentages[newName] = percentages
+		delete(c.nominalQuotaPercentages, oldName)
+	}
+	for key, wi := range c.Workloads {
+		renamed := *wi
+		renamed.TotalRequests = slices.Clone(wi.TotalRequests)
+		for i := range renamed.TotalRequests {
+			psr := &renamed.TotalRequests[i]
+			psr.Flavors = maps.Clone(psr.Flavors)
+			for rName, fName := range psr.Flavors {
+				if fName == oldName {
+					psr.Flavors[rName] = newName
+				}
+			}
+		}
+		c.Workloads[key] = &renamed
+	}
+	c.AllocatableResourceGeneration++
+	c.updateGuaranteedQuota()
+	c.syncCohortCapacity()
+	return true
+}
+
+// renameFlavorKey moves the quantities of the flavor oldName to newName.
+func renameFlavorKey(q FlavorResourceQuantities, oldName, newName kueue.ResourceFlavorReference) {
+	if quantities, found := q[oldName]; found {
+		q[newName] = quantities
+		delete(q, oldName)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestRenameFlavor(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("preemptible").Obj())
+	clusterQueues := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("a").
+			ResourceGroup(
+				*utiltesting.MakeFlavorQuotas("on-demand").Resource(corev1.ResourceCPU, "2").Obj(),
+				*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "4", "2").Obj(),
+			).
+			Cohort("one").
+			Obj(),
+		utiltesting.MakeClusterQueue("b").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "6").Obj()).
+			Cohort("one").
+			Obj(),
+		utiltesting.MakeClusterQueue("conflict").
+			ResourceGroup(
+				*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "1").Obj(),
+				*utiltesting.MakeFlavorQuotas("preemptible").Resource(corev1.ResourceCPU, "1").Obj(),
+			).
+			Obj(),
+	}
+	for _, cq := range clusterQueues {
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "spot", "5").Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(wl) {
+		t.Fatalf("Failed adding workload %q", wl.Name)
+	}
+
+	if err := cache.renameFlavor("spot", "preemptible"); !errors.Is(err, errFlavorRenameConflict) {
+		t.Fatalf("Unexpected error renaming a flavor with a conflict: %v", err)
+	}
+	if got := cache.clusterQueues["a"].Usage["spot"][corev1.ResourceCPU]; got != 5_000 {
+		t.Errorf("Failed rename changed the usage of spot to %d, want 5000", got)
+	}
+
+	cache.DeleteClusterQueue(clusterQueues[2])
+	if err := cache.renameFlavor("spot", "preemptible"); err != nil {
+		t.Fatalf("Failed renaming flavor: %v", err)
+	}
+	a := cache.clusterQueues["a"]
+	wantUsage := FlavorResourceQuantities{
+		"on-demand":   {corev1.ResourceCPU: 0},
+		"preemptible": {corev1.ResourceCPU: 5_000},
+	}
+	if diff := cmp.Diff(wantUsage, a.Usage); diff != "" {
+		t.Errorf("Unexpected usage after renaming (-want,+got):\n%s", diff)
+	}
+	wantQuotas := []FlavorQuotas{
+		{
+			Name:      "on-demand",
+			Resources: map[corev1.ResourceName]*ResourceQuota{corev1.ResourceCPU: {Nominal: 2_000}},
+		},
+		{
+			Name:      "preemptible",
+			Resources: map[corev1.ResourceName]*ResourceQuota{corev1.ResourceCPU: {Nominal: 4_000, BorrowingLimit: ptr.To[int64](2_000)}},
+		},
+	}
+	if diff := cmp.Diff(wantQuotas, a.ResourceGroups[0].Flavors); diff != "" {
+		t.Errorf("Unexpected quotas after renaming (-want,+got):\n%s", diff)
+	}
+	if got := cache.clusterQueues["b"].ResourceGroups[0].Flavors[0].Name; got != "preemptible" {
+		t.Errorf("Unexpected flavor %q in ClusterQueue b, want preemptible", got)
+	}
+	if !a.Active() {
+		t.Error("ClusterQueue a is not active after renaming to an existing flavor")
+	}
+	if got, _ := cache.cohortFreeCapacity("one"); got[corev1.ResourceCPU] != 7_000 {
+		t.Errorf("Unexpected free capacity of the cohort %d, want 7000", got[corev1.ResourceCPU])
+	}
+
+	if err := cache.DeleteWorkload(wl); err != nil {
+		t.Fatalf("Failed deleting workload: %v", err)
+	}
+	if got := a.Usage["preemptible"][corev1.ResourceCPU]; got != 0 {
+		t.Errorf("Unexpected usage of preemptible after deleting the workload, got %d, want 0", got)
+	}
+}
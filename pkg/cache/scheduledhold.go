@@ -0,0 +1,177 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+)
+
+var errInvalidScheduledReservation = errors.New("invalid scheduled reservation")
+
+// scheduledHold is a reservation that becomes a hold in its ClusterQueue
+// during a time window.
+type scheduledHold struct {
+	holder string
+	cqName string
+	amount Resources
+	start  time.Time
+	end    time.Time
+	active bool
+}
+
+// scheduledHolder is the holder of the scheduled reservation of the
+// ClusterQueue.
+func scheduledHolder(cqName string) string {
+	return "scheduled-reservation/" + cqName
+}
+
+// parseScheduledReservation returns the reservation declared in the
+// ScheduledReservationAnnotation of the ClusterQueue, or nil if there is
+// none.
+func parseScheduledReservation(cq *kueue.ClusterQueue) (*scheduledHold, error) {
+	value, found := cq.Annotations[constants.ScheduledReservationAnnotation]
+	if !found {
+		return nil, nil
+	}
+	parts := strings.Split(value, ";")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: %q in annotation %s, expected <start>;<duration>;<resources>", errInvalidScheduledReservation, value, constants.ScheduledReservationAnnotation)
+	}
+	start, err := time.Parse(time.RFC3339, strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidScheduledReservation, err)
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidScheduledReservation, err)
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("%w: the duration must be positive, got %v", errInvalidScheduledReservation, duration)
+	}
+	amount, err := parseResourceQuantities(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidScheduledReservation, err)
+	}
+	for rName, v := range amount {
+		if v == 0 {
+			return nil, fmt.Errorf("%w: the amount of %s is 0", errInvalidScheduledReservation, rName)
+		}
+	}
+	return &scheduledHold{
+		holder: scheduledHolder(cq.Name),
+		cqName: cq.Name,
+		amount: amount,
+		start:  start,
+		end:    start.Add(duration),
+	}, nil
+}
+
+// syncScheduledHold replaces the scheduled reservation of the ClusterQueue
+// with the one declared in its annotation, if they differ, and activates it
+// if it's due. It returns the names of the ClusterQueues whose usage changed.
+// A reservation that expired isn't activated again while the annotation
+// stays the same.
+func (c *Cache) syncScheduledHold(cq *ClusterQueue) []string {
+	want := cq.scheduledReservation
+	var changed []string
+	if sh, found := c.scheduledHolds[scheduledHolder(cq.Name)]; found {
+		if want != nil && sh.start.Equal(want.start) && sh.end.Equal(want.end) && maps.Equal(sh.amount, want.amount) {
+			return nil
+		}
+		if sh.active {
+			cq.deleteHold(sh.holder)
+			changed = append(changed, cq.Name)
+		}
+		delete(c.scheduledHolds, sh.holder)
+	}
+	if want == nil {
+		return changed
+	}
+	sh := *want
+	sh.amount = maps.Clone(want.amount)
+	c.scheduledHolds[sh.holder] = &sh
+	// The reservation might be due already.
+	return append(changed, c.sweepScheduledHolds()...)
+}
+
+// SweepScheduledHolds activates the scheduled reservations that are due and
+// releases the ones that expired, according to the clock of the cache.
+func (c *Cache) SweepScheduledHolds() {
+	var changed []string
+	defer func() { c.notifyUsageChanged(changed...) }()
+	c.Lock()
+	defer c.Unlock()
+	changed = c.sweepScheduledHolds()
+}
+
+// RunScheduledHoldsSweeper calls SweepScheduledHolds every period until the
+// context is done.
+func (c *Cache) RunScheduledHoldsSweeper(ctx context.Context, period time.Duration) {
+	wait.UntilWithContext(ctx, func(context.Context) {
+		c.SweepScheduledHolds()
+	}, period)
+}
+
+// sweepScheduledHolds returns the names of the ClusterQueues whose usage
+// changed. The reservations are processed in the order they are due.
+func (c *Cache) sweepScheduledHolds() []string {
+	now := c.clock.Now()
+	holds := make([]*scheduledHold, 0, len(c.scheduledHolds))
+	for _, sh := range c.scheduledHolds {
+		holds = append(holds, sh)
+	}
+	sort.Slice(holds, func(i, j int) bool {
+		if !holds[i].start.Equal(holds[j].start) {
+			return holds[i].start.Before(holds[j].start)
+		}
+		return holds[i].holder < holds[j].holder
+	})
+	var changed []string
+	for _, sh := range holds {
+		cq, found := c.clusterQueues[sh.cqName]
+		if !now.Before(sh.end) {
+			if sh.active && found {
+				cq.deleteHold(sh.holder)
+				changed = append(changed, sh.cqName)
+			}
+			delete(c.scheduledHolds, sh.holder)
+			continue
+		}
+		if sh.active || !found || now.Before(sh.start) {
+			continue
+		}
+		hold, err := cq.holdFor(sh.amount)
+		if err != nil {
+			continue
+		}
+		cq.addHold(sh.holder, hold)
+		sh.active = true
+		changed = append(changed, sh.cqName)
+	}
+	return changed
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestParseScheduledReservation(t *testing.T) {
+	start := time.Date(2024, time.May, 1, 22, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		annotation string
+		want       *scheduledHold
+		wantErr    error
+	}{
+		"valid": {
+			annotation: "2024-05-01T22:00:00Z;4h;nvidia.com/gpu=8, cpu=500m",
+			want: &scheduledHold{
+				holder: "scheduled-reservation/cq",
+				cqName: "cq",
+				amount: Resources{"nvidia.com/gpu": 8, corev1.ResourceCPU: 500},
+				start:  start,
+				end:    start.Add(4 * time.Hour),
+			},
+		},
+		"missing duration": {
+			annotation: "2024-05-01T22:00:00Z;nvidia.com/gpu=8",
+			wantErr:    errInvalidScheduledReservation,
+		},
+		"invalid start": {
+			annotation: "tonight;4h;nvidia.com/gpu=8",
+			wantErr:    errInvalidScheduledReservation,
+		},
+		"zero duration": {
+			annotation: "2024-05-01T22:00:00Z;0s;nvidia.com/gpu=8",
+			wantErr:    errInvalidScheduledReservation,
+		},
+		"zero quantity": {
+			annotation: "2024-05-01T22:00:00Z;4h;nvidia.com/gpu=0",
+			wantErr:    errInvalidScheduledReservation,
+		},
+		"missing quantity": {
+			annotation: "2024-05-01T22:00:00Z;4h;nvidia.com/gpu",
+			wantErr:    errInvalidScheduledReservation,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cq := utiltesting.MakeClusterQueue("cq").Obj()
+			cq.Annotations = map[string]string{constants.ScheduledReservationAnnotation: tc.annotation}
+			got, err := parseScheduledReservation(cq)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Unexpected error, got %v, want %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(scheduledHold{})); diff != "" {
+				t.Errorf("Unexpected reservation (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestScheduledReservation(t *testing.T) {
+	now := time.Date(2024, time.May, 1, 21, 59, 0, 0, time.UTC)
+	fakeClock := testingclock.NewFakeClock(now)
+	cache := New(utiltesting.NewFakeClient(), WithClock(fakeClock))
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	cq.Annotations = map[string]string{constants.ScheduledReservationAnnotation: "2024-05-01T22:00:00Z;1h;cpu=4"}
+	var notified []string
+	cache.OnClusterQueueUsageChanged = func(cqName string) {
+		notified = append(notified, cqName)
+	}
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	wantUsage := func(cpu int64) FlavorResourceQuantities {
+		return FlavorResourceQuantities{"default": {corev1.ResourceCPU: cpu}}
+	}
+	checkUsage := func(step string, want FlavorResourceQuantities) {
+		t.Helper()
+		if diff := cmp.Diff(want, cache.Snapshot().ClusterQueues["cq"].Usage); diff != "" {
+			t.Errorf("Unexpected usage %s (-want,+got):\n%s", step, diff)
+		}
+	}
+	updateAnnotation := func(value string) {
+		t.Helper()
+		cq = cq.DeepCopy()
+		if value == "" {
+			delete(cq.Annotations, constants.ScheduledReservationAnnotation)
+		} else {
+			cq.Annotations[constants.ScheduledReservationAnnotation] = value
+		}
+		if err := cache.UpdateClusterQueue(cq); err != nil {
+			t.Fatalf("Failed updating ClusterQueue: %v", err)
+		}
+	}
+
+	checkUsage("before the reservation starts", wantUsage(0))
+	if len(notified) != 0 {
+		t.Errorf("Unexpected notifications before the reservation starts: %v", notified)
+	}
+
+	fakeClock.Step(time.Minute)
+	cache.SweepScheduledHolds()
+	checkUsage("after the reservation starts", wantUsage(4_000))
+	if diff := cmp.Diff([]string{"cq"}, notified); diff != "" {
+		t.Errorf("Unexpected notifications after the reservation starts (-want,+got):\n%s", diff)
+	}
+
+	updateAnnotation("2024-05-01T22:00:00Z;1h;cpu=4")
+	checkUsage("after an update that keeps the reservation", wantUsage(4_000))
+
+	updateAnnotation("2024-05-01T21:00:00Z;2h;cpu=6")
+	checkUsage("after replacing the reservation with a due one", wantUsage(6_000))
+
+	updateAnnotation("")
+	checkUsage("after removing the annotation", wantUsage(0))
+
+	updateAnnotation("2024-05-01T22:00:00Z;1h;cpu=4")
+	checkUsage("after adding the annotation back", wantUsage(4_000))
+
+	fakeClock.Step(time.Hour)
+	cache.SweepScheduledHolds()
+	checkUsage("after the reservation expires", wantUsage(0))
+
+	updateAnnotation("2024-05-01T22:00:00Z;1h;cpu=4")
+	checkUsage("after an update with the expired reservation", wantUsage(0))
+
+	cache.DeleteClusterQueue(cq)
+	if len(cache.scheduledHolds) != 0 {
+		t.Errorf("Unexpected scheduled reservations after deleting the ClusterQueue: %v", cache.scheduledHolds)
+	}
+}
+
+func TestScheduledReservationRetriesActivation(t *testing.T) {
+	now := time.Date(2024, time.May, 1, 21, 59, 0, 0, time.UTC)
+	fakeClock := testingclock.NewFakeClock(now)
+	cache := New(utiltesting.NewFakeClient(), WithClock(fakeClock))
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	release, err := cache.reserve("cq", Resources{corev1.ResourceCPU: 8_000}, "batch")
+	if err != nil {
+		t.Fatalf("Failed reserving: %v", err)
+	}
+	cq = cq.DeepCopy()
+	cq.Annotations = map[string]string{constants.ScheduledReservationAnnotation: "2024-05-01T22:00:00Z;1h;cpu=4"}
+	if err := cache.UpdateClusterQueue(cq); err != nil {
+		t.Fatalf("Failed updating ClusterQueue: %v", err)
+	}
+
+	fakeClock.Step(time.Minute)
+	cache.SweepScheduledHolds()
+	want := FlavorResourceQuantities{"default": {corev1.ResourceCPU: 8_000}}
+	if diff := cmp.Diff(want, cache.Snapshot().ClusterQueues["cq"].Usage); diff != "" {
+		t.Errorf("Unexpected usage without enough unused quota (-want,+got):\n%s", diff)
+	}
+
+	release()
+	fakeClock.Step(time.Minute)
+	cache.SweepScheduledHolds()
+	want = FlavorResourceQuantities{"default": {corev1.ResourceCPU: 4_000}}
+	if diff := cmp.Diff(want, cache.Snapshot().ClusterQueues["cq"].Usage); diff != "" {
+		t.Errorf("Unexpected usage after the quota is released (-want,+got):\n%s", diff)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	"sigs.k8s.io/kueue/pkg/util/limitrange"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// The weights of the components of the score of a flavor.
+const (
+	flavorCostWeight     = 1.0
+	flavorHeadroomWeight = 1.0
+	flavorAffinityWeight = 0.5
+)
+
+// FlavorScore is the score of a flavor for a resource of a PodSet, as
+// returned by scoreFlavors.
+type FlavorScore struct {
+	Name kueue.ResourceFlavorReference
+	// Score is the weighted sum of the cost, headroom and affinity scores of
+	// the flavor, each between 0 and 1. Higher is better.
+	Score float64
+	// Reason is why the PodSet can't be assigned the flavor, empty if it can.
+	Reason string
+}
+
+// scoreFlavors scores the flavors of the ClusterQueue for the resource of the
+// PodSet, as a weighted decision instead of the strict order of the flavors:
+//   - cost: cheaper flavors, by their kueue.x-k8s.io/cost annotation, score
+//     higher.
+//   - headroom: the fraction of the quota of the flavor, including what can be
+//     borrowed from the cohort, left after assigning the PodSet.
+//   - affinity: the fraction of the node selector of the PodSet that the node
+//     labels of the flavor match.
+//
+// The flavors that the PodSet can be assigned come first, sorted by
+// decreasing score, keeping the order of the ClusterQueue for equal scores.
+// Returns nil if the ClusterQueue isn't active or doesn't have the resource.
+func (c *Cache) scoreFlavors(podSet kueue.PodSet, rName corev1.ResourceName, cqName string) []FlavorScore {
+	snap := c.Snapshot()
+	cq, ok := snap.ClusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	rg := cq.RGByResource[rName]
+	if rg == nil {
+		return nil
+	}
+	requests := make(workload.Requests)
+	for name, q := range limitrange.TotalRequests(&podSet.Template.Spec) {
+		requests[name] = workload.ResourceValue(name, q) * int64(podSet.Count)
+	}
+	nodeSelector := podSet.Template.Spec.NodeSelector
+	scores := make([]FlavorScore, 0, len(rg.Flavors))
+	for _, flvQuotas := range rg.Flavors {
+		score := FlavorScore{
+			Name:   flvQuotas.Name,
+			Reason: rejectFlavor(&snap, cq, flvQuotas, &podSet, requests, nil),
+		}
+		if rf, found := snap.ResourceFlavors[flvQuotas.Name]; found {
+			score.Score += flavorCostWeight*flavorCostScore(rf) + flavorAffinityWeight*flavorAffinityScore(rf, nodeSelector)
+		}
+		if rQuota, found := flvQuotas.Resources[rName]; found {
+			available := availableQuota(cq, flvQuotas.Name, rName, rQuota)
+			if left := available - requests[rName]; left > 0 {
+				score.Score += flavorHeadroomWeight * float64(left) / float64(max(rQuota.Nominal, available))
+			}
+		}
+		scores = append(scores, score)
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		if fitsI, fitsJ := scores[i].Reason == "", scores[j].Reason == ""; fitsI != fitsJ {
+			return fitsI
+		}
+		return scores[i].Score > scores[j].Score
+	})
+	return scores
+}
+
+// flavorCostScore returns 1 for a flavor without cost, decreasing as its cost
+// increases. Invalid costs are ignored.
+func flavorCostScore(rf *kueue.ResourceFlavor) float64 {
+	cost, err := strconv.ParseInt(rf.Annotations[constants.ResourceFlavorCostAnnotation], 10, 64)
+	if err != nil || cost < 0 {
+		return 1
+	}
+	return 1 / float64(1+cost)
+}
+
+// flavorAffinityScore returns the fraction of the node selector that the node
+// labels of the flavor match, 0 if the node selector is empty.
+func flavorAffinityScore(rf *kueue.ResourceFlavor, nodeSelector map[string]string) float64 {
+	if len(nodeSelector) == 0 {
+		return 0
+	}
+	matched := 0
+	for k, v := range nodeSelector {
+		if label, found := rf.Spec.NodeLabels[k]; found && label == v {
+			matched++
+		}
+	}
+	return float64(matched) / float64(len(nodeSelector))
+}
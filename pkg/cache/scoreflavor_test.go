@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestScoreFlavors(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	onDemand := utiltesting.MakeResourceFlavor("on-demand").Label("zone", "a").Obj()
+	onDemand.Annotations = map[string]string{constants.ResourceFlavorCostAnnotation: "3"}
+	spot := utiltesting.MakeResourceFlavor("spot").Obj()
+	spot.Annotations = map[string]string{constants.ResourceFlavorCostAnnotation: "1"}
+	cache.AddOrUpdateResourceFlavor(onDemand)
+	cache.AddOrUpdateResourceFlavor(spot)
+	for _, name := range []string{"saturated", "idle"} {
+		cq := utiltesting.MakeClusterQueue(name).
+			ResourceGroup(
+				*utiltesting.MakeFlavorQuotas("on-demand").Resource(corev1.ResourceCPU, "10").Obj(),
+				*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "10").Obj(),
+			).
+			Obj()
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("saturated").Assignment(corev1.ResourceCPU, "on-demand", "10").Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(wl) {
+		t.Fatalf("Failed adding workload %q", wl.Name)
+	}
+
+	cases := map[string]struct {
+		cqName   string
+		podSet   *kueue.PodSet
+		resource corev1.ResourceName
+		want     []FlavorScore
+	}{
+		"cheaper flavor with headroom scores above a pricier saturated one": {
+			cqName:   "saturated",
+			podSet:   utiltesting.MakePodSet("main", 2).Request(corev1.ResourceCPU, "1").Obj(),
+			resource: corev1.ResourceCPU,
+			want: []FlavorScore{
+				// cost 1/2, headroom 8/10.
+				{Name: "spot", Score: 1.3},
+				// cost 1/4, no headroom.
+				{
+					Name:   "on-demand",
+					Score:  0.25,
+					Reason: "has insufficient quota for cpu (requested 2, available 0)",
+				},
+			},
+		},
+		"affinity outweighs the cost": {
+			cqName: "idle",
+			podSet: utiltesting.MakePodSet("main", 2).
+				Request(corev1.ResourceCPU, "1").
+				NodeSelector(map[string]string{"zone": "a"}).
+				Obj(),
+			resource: corev1.ResourceCPU,
+			want: []FlavorScore{
+				// cost 1/4, headroom 8/10, affinity 1.
+				{Name: "on-demand", Score: 1.55},
+				// cost 1/2, headroom 8/10, affinity 0.
+				{Name: "spot", Score: 1.3},
+			},
+		},
+		"resource not in the ClusterQueue": {
+			cqName:   "idle",
+			podSet:   utiltesting.MakePodSet("main", 1).Request(corev1.ResourceMemory, "1Gi").Obj(),
+			resource: corev1.ResourceMemory,
+		},
+		"unknown ClusterQueue": {
+			cqName:   "unknown",
+			podSet:   utiltesting.MakePodSet("main", 1).Request(corev1.ResourceCPU, "1").Obj(),
+			resource: corev1.ResourceCPU,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := cache.scoreFlavors(*tc.podSet, tc.resource, tc.cqName)
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+				t.Errorf("Unexpected scores (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -25,7 +25,6 @@ import (
 	"k8s.io/klog/v2"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
-	"sigs.k8s.io/kueue/pkg/features"
 	utilmaps "sigs.k8s.io/kueue/pkg/util/maps"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
@@ -34,6 +33,25 @@ type Snapshot struct {
 	ClusterQueues            map[string]*ClusterQueue
 	ResourceFlavors          map[kueue.ResourceFlavorReference]*kueue.ResourceFlavor
 	InactiveClusterQueueSets sets.Set[string]
+	// FlavorObservedCapacity is the physical capacity observed for the
+	// ResourceFlavors that reported it.
+	FlavorObservedCapacity map[kueue.ResourceFlavorReference]Resources
+	// FlavorUsage is the usage of each flavor by all the ClusterQueues,
+	// including the inactive ones, which count towards the observed capacity
+	// of the flavor.
+	FlavorUsage FlavorResourceQuantities
+	// DisabledFlavors are the ResourceFlavors that can't be assigned to new
+	// workloads.
+	DisabledFlavors sets.Set[kueue.ResourceFlavorReference]
+	// FlavorTopologyKeys are the topology keys of the ResourceFlavors that set
+	// one.
+	FlavorTopologyKeys map[kueue.ResourceFlavorReference]string
+	// FlavorTopologyDomains are the number of topology domains observed for
+	// the ResourceFlavors that reported it.
+	FlavorTopologyDomains map[kueue.ResourceFlavorReference]int
+	// FlavorShareWeights are the share weights of the ResourceFlavors that set
+	// one.
+	FlavorShareWeights map[kueue.ResourceFlavorReference]float64
 }
 
 // RemoveWorkload removes a workload from its corresponding ClusterQueue and
@@ -42,11 +60,12 @@ func (s *Snapshot) RemoveWorkload(wl *workload.Info) {
 	cq := s.ClusterQueues[wl.ClusterQueue]
 	delete(cq.Workloads, workload.Key(wl.Obj))
 	updateUsage(wl, cq.Usage, -1)
-	if cq.Cohort != nil {
-		if features.Enabled(features.LendingLimit) {
-			updateCohortUsage(wl, cq, -1)
+	updateUsage(wl, s.FlavorUsage, -1)
+	for _, cohort := range cq.Cohorts() {
+		if cq.tracksGuaranteedUsage() {
+			updateCohortUsage(wl, cq, cohort, -1)
 		} else {
-			updateUsage(wl, cq.Cohort.Usage, -1)
+			updateUsage(wl, cohort.Usage, -1)
 		}
 	}
 }
@@ -57,11 +76,12 @@ func (s *Snapshot) AddWorkload(wl *workload.Info) {
 	cq := s.ClusterQueues[wl.ClusterQueue]
 	cq.Workloads[workload.Key(wl.Obj)] = wl
 	updateUsage(wl, cq.Usage, 1)
-	if cq.Cohort != nil {
-		if features.Enabled(features.LendingLimit) {
-			updateCohortUsage(wl, cq, 1)
+	updateUsage(wl, s.FlavorUsage, 1)
+	for _, cohort := range cq.Cohorts() {
+		if cq.tracksGuaranteedUsage() {
+			updateCohortUsage(wl, cq, cohort, 1)
 		} else {
-			updateUsage(wl, cq.Cohort.Usage, 1)
+			updateUsage(wl, cohort.Usage, 1)
 		}
 	}
 }
@@ -95,23 +115,51 @@ func (s *Snapshot) Log(log logr.Logger) {
 func (c *Cache) Snapshot() Snapshot {
 	c.RLock()
 	defer c.RUnlock()
+	return c.snapshot()
+}
 
+// snapshot takes the snapshot of the cache, whose lock must be held.
+func (c *Cache) snapshot() Snapshot {
 	snap := Snapshot{
 		ClusterQueues:            make(map[string]*ClusterQueue, len(c.clusterQueues)),
 		ResourceFlavors:          make(map[kueue.ResourceFlavorReference]*kueue.ResourceFlavor, len(c.resourceFlavors)),
 		InactiveClusterQueueSets: sets.New[string](),
+		FlavorObservedCapacity:   make(map[kueue.ResourceFlavorReference]Resources, len(c.flavorCapacity)),
+		DisabledFlavors:          c.disabledFlavors.Clone(),
+		FlavorTopologyKeys:       maps.Clone(c.flavorTopologyKeys),
+		FlavorTopologyDomains:    maps.Clone(c.flavorTopologyDomains),
+		FlavorShareWeights:       maps.Clone(c.flavorShareWeights),
+		FlavorUsage:              make(FlavorResourceQuantities),
 	}
 	for _, cq := range c.clusterQueues {
+		for fName, rUsage := range cq.Usage {
+			if snap.FlavorUsage[fName] == nil {
+				snap.FlavorUsage[fName] = make(map[corev1.ResourceName]int64, len(rUsage))
+			}
+			for rName, val := range rUsage {
+				snap.FlavorUsage[fName][rName] += val
+			}
+		}
 		if !cq.Active() {
 			snap.InactiveClusterQueueSets.Insert(cq.Name)
 			continue
 		}
-		snap.ClusterQueues[cq.Name] = cq.snapshot()
+		cqCopy := cq.snapshot()
+		if c.clock.Now().Before(cq.borrowBoostUntil) {
+			// The boost is replaced, never modified, in the cache.
+			cqCopy.BorrowBoost = cq.BorrowBoost
+		}
+		snap.ClusterQueues[cq.Name] = cqCopy
 	}
 	for name, rf := range c.resourceFlavors {
 		// Shallow copy is enough
 		snap.ResourceFlavors[name] = rf
 	}
+	for name, capacity := range c.flavorCapacity {
+		// The capacity is replaced, never modified, in the cache.
+		snap.FlavorObservedCapacity[name] = capacity
+	}
+	cohortCopies := make(map[string]*Cohort, len(c.cohorts))
 	for _, cohort := range c.cohorts {
 		cohortCopy := newCohort(cohort.Name, cohort.Members.Len())
 		cohortCopy.AllocatableResourceGeneration = 0
@@ -119,11 +167,21 @@ func (c *Cache) Snapshot() Snapshot {
 			if cq.Active() {
 				cqCopy := snap.ClusterQueues[cq.Name]
 				cqCopy.accumulateResources(cohortCopy)
-				cqCopy.Cohort = cohortCopy
 				cohortCopy.Members.Insert(cqCopy)
 				cohortCopy.AllocatableResourceGeneration += cqCopy.AllocatableResourceGeneration
 			}
 		}
+		cohortCopies[cohort.Name] = cohortCopy
+	}
+	for _, cq := range c.clusterQueues {
+		cqCopy, found := snap.ClusterQueues[cq.Name]
+		if !found || cq.Cohort == nil {
+			continue
+		}
+		cqCopy.Cohort = cohortCopies[cq.Cohort.Name]
+		for _, cohort := range cq.SecondaryCohorts {
+			cqCopy.SecondaryCohorts = append(cqCopy.SecondaryCohorts, cohortCopies[cohort.Name])
+		}
 	}
 	return snap
 }
@@ -143,14 +201,18 @@ func (c *ClusterQueue) snapshot() *ClusterQueue {
 		NamespaceSelector:             c.NamespaceSelector,
 		Status:                        c.Status,
 		AdmissionChecks:               c.AdmissionChecks.Clone(),
+		ResourceSubstitutes:           c.ResourceSubstitutes, // Shallow copy is enough.
+		PriorityOffset:                c.PriorityOffset,
+		PreemptionCounts:              maps.Clone(c.PreemptionCounts),
+		BorrowingDisabled:             c.BorrowingDisabled,
+		BorrowingForbidden:            c.BorrowingForbidden, // Shallow copy is enough.
+		Headroom:                      c.Headroom,           // Shallow copy is enough.
+		GuaranteedQuota:               c.GuaranteedQuota,    // Shallow copy is enough.
+		OvercommitQuota:               c.OvercommitQuota,    // Shallow copy is enough.
 	}
 	for fName, rUsage := range c.Usage {
 		cc.Usage[fName] = maps.Clone(rUsage)
 	}
-	if features.Enabled(features.LendingLimit) {
-		cc.GuaranteedQuota = c.GuaranteedQuota
-	}
-
 	return cc
 }
 
@@ -170,11 +232,9 @@ func (c *ClusterQueue) accumulateResources(cohort *Cohort) {
 				// the sum of cq.NominalQuota and other cqs' LendingLimit (if not nil).
 				// If LendingLimit is not nil, we should count the lendingLimit as the requestable
 				// resource because we can't borrow more quota than lendingLimit.
-				if features.Enabled(features.LendingLimit) && rQuota.LendingLimit != nil {
-					res[rName] += *rQuota.LendingLimit
-				} else {
-					res[rName] += rQuota.Nominal
-				}
+				// The headroom of the cq is never requestable either, so we count the
+				// nominal quota above the guaranteed quota.
+				res[rName] += rQuota.Nominal - c.guaranteedQuota(flvQuotas.Name, rName)
 			}
 		}
 	}
@@ -189,13 +249,9 @@ func (c *ClusterQueue) accumulateResources(cohort *Cohort) {
 		}
 		for res, val := range resUsages {
 			// Similar to cohort.RequestableResources, we accumulate the usage above the guaranteed resources,
-			// here we should remove the guaranteed quota as well for that part can not be borrowed.
-			val -= c.guaranteedQuota(fName, res)
-			// if val < 0, it means the cq is not using any quota belongs to LendingLimit
-			if val < 0 {
-				val = 0
-			}
-			used[res] += val
+			// here we should remove the guaranteed and the overcommitted quota as well for that part can not
+			// be borrowed.
+			used[res] += c.usageInCohort(fName, res, val)
 		}
 	}
 }
@@ -29,6 +29,7 @@ import (
 	"k8s.io/utils/ptr"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
 	"sigs.k8s.io/kueue/pkg/features"
 	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
 	"sigs.k8s.io/kueue/pkg/workload"
@@ -36,9 +37,10 @@ import (
 
 var snapCmpOpts = []cmp.Option{
 	cmpopts.EquateEmpty(),
-	cmpopts.IgnoreUnexported(ClusterQueue{}),
+	cmpopts.IgnoreUnexported(ClusterQueue{}, Cohort{}),
 	cmpopts.IgnoreFields(ClusterQueue{}, "RGByResource"),
 	cmpopts.IgnoreFields(Cohort{}, "Members"), // avoid recursion.
+	cmpopts.IgnoreFields(Snapshot{}, "FlavorUsage"),
 	cmpopts.IgnoreFields(metav1.Condition{}, "LastTransitionTime"),
 }
 
@@ -1307,3 +1309,183 @@ func TestSnapshotAddRemoveWorkloadWithLendingLimit(t *testing.T) {
 		})
 	}
 }
+
+func TestSnapshotLendingLimitKeepsReserve(t *testing.T) {
+	defer features.SetFeatureGateDuringTest(t, features.LendingLimit, true)()
+	ctx := context.Background()
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	clusterQueues := []*kueue.ClusterQueue{
+		// The lender keeps 30% of its nominal quota as a reserve.
+		utiltesting.MakeClusterQueue("lender").
+			Cohort("cohort").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10", "", "7").Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("borrower").
+			Cohort("cohort").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "0").Obj()).
+			Obj(),
+	}
+	for _, cq := range clusterQueues {
+		if err := cache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Couldn't add ClusterQueue to cache: %v", err)
+		}
+	}
+
+	snapshot := cache.Snapshot()
+	borrower := snapshot.ClusterQueues["borrower"]
+	if got := borrower.RequestableCohortQuota("default", corev1.ResourceCPU); got != 7_000 {
+		t.Errorf("Unexpected requestable cohort quota for the borrower, got %d, want 7000", got)
+	}
+	if !borrower.FitInCohort(FlavorResourceQuantities{"default": {corev1.ResourceCPU: 7_000}}) {
+		t.Error("Expected the borrower to fit the quota lent by the lender")
+	}
+	if borrower.FitInCohort(FlavorResourceQuantities{"default": {corev1.ResourceCPU: 8_000}}) {
+		t.Error("Expected the borrower not to fit in the lender's reserve")
+	}
+
+	lender := snapshot.ClusterQueues["lender"]
+	if got := lender.RequestableCohortQuota("default", corev1.ResourceCPU); got != 10_000 {
+		t.Errorf("Unexpected requestable cohort quota for the lender, got %d, want 10000", got)
+	}
+}
+
+func TestSnapshotFlavorObservedCapacity(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	generation := cache.clusterQueues["cq"].AllocatableResourceGeneration
+
+	capacity := Resources{corev1.ResourceCPU: 6_000}
+	cache.SetFlavorObservedCapacity("default", capacity)
+	cache.SetFlavorObservedCapacity("unused", Resources{corev1.ResourceCPU: 1_000})
+	capacity[corev1.ResourceCPU] = 0
+	snapshot := cache.Snapshot()
+	wantCapacity := map[kueue.ResourceFlavorReference]Resources{
+		"default": {corev1.ResourceCPU: 6_000},
+		"unused":  {corev1.ResourceCPU: 1_000},
+	}
+	if diff := cmp.Diff(wantCapacity, snapshot.FlavorObservedCapacity); diff != "" {
+		t.Errorf("Unexpected observed capacity (-want,+got):\n%s", diff)
+	}
+	if got := snapshot.ClusterQueues["cq"].AllocatableResourceGeneration; got != generation+1 {
+		t.Errorf("Unexpected allocatable resource generation, got %d, want %d", got, generation+1)
+	}
+
+	cache.SetFlavorObservedCapacity("unused", nil)
+	snapshot = cache.Snapshot()
+	delete(wantCapacity, "unused")
+	if diff := cmp.Diff(wantCapacity, snapshot.FlavorObservedCapacity); diff != "" {
+		t.Errorf("Unexpected observed capacity after removal (-want,+got):\n%s", diff)
+	}
+}
+
+func TestSnapshotFlavorUsage(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cqs := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("active").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("inactive").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("missing").Resource(corev1.ResourceMemory, "10Gi").Obj()).
+			Obj(),
+	}
+	for _, cq := range cqs {
+		if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue %q: %v", cq.Name, err)
+		}
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("active-wl", "ns").
+			Request(corev1.ResourceCPU, "2").
+			ReserveQuota(utiltesting.MakeAdmission("active").Assignment(corev1.ResourceCPU, "default", "2").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("inactive-wl", "ns").
+			Request(corev1.ResourceCPU, "3").
+			ReserveQuota(utiltesting.MakeAdmission("inactive").Assignment(corev1.ResourceCPU, "default", "3").Obj()).
+			Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %q", wl.Name)
+		}
+	}
+
+	snapshot := cache.Snapshot()
+	if _, found := snapshot.ClusterQueues["inactive"]; found {
+		t.Fatalf("ClusterQueue \"inactive\" is unexpectedly active")
+	}
+	want := FlavorResourceQuantities{
+		"default": {corev1.ResourceCPU: 5_000},
+		"missing": {corev1.ResourceMemory: 0},
+	}
+	if diff := cmp.Diff(want, snapshot.FlavorUsage); diff != "" {
+		t.Errorf("Unexpected flavor usage (-want,+got):\n%s", diff)
+	}
+
+	wl := workload.NewInfo(workloads[0])
+	snapshot.RemoveWorkload(wl)
+	want["default"][corev1.ResourceCPU] = 3_000
+	if diff := cmp.Diff(want, snapshot.FlavorUsage); diff != "" {
+		t.Errorf("Unexpected flavor usage after removing the workload (-want,+got):\n%s", diff)
+	}
+	snapshot.AddWorkload(wl)
+	want["default"][corev1.ResourceCPU] = 5_000
+	if diff := cmp.Diff(want, snapshot.FlavorUsage); diff != "" {
+		t.Errorf("Unexpected flavor usage after adding the workload back (-want,+got):\n%s", diff)
+	}
+}
+
+func TestSnapshotDisabledFlavors(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	flavor := utiltesting.MakeResourceFlavor("default").Obj()
+	cache.AddOrUpdateResourceFlavor(flavor)
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		Request(corev1.ResourceCPU, "2").
+		ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "2").Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(wl) {
+		t.Fatalf("Failed adding workload")
+	}
+	generation := cache.clusterQueues["cq"].AllocatableResourceGeneration
+	wantUsage := FlavorResourceQuantities{"default": {corev1.ResourceCPU: 2_000}}
+
+	disabled := flavor.DeepCopy()
+	disabled.Annotations = map[string]string{constants.ResourceFlavorDisabledAnnotation: "true"}
+	cache.AddOrUpdateResourceFlavor(disabled)
+	snapshot := cache.Snapshot()
+	if diff := cmp.Diff(sets.New[kueue.ResourceFlavorReference]("default"), snapshot.DisabledFlavors); diff != "" {
+		t.Errorf("Unexpected disabled flavors (-want,+got):\n%s", diff)
+	}
+	if diff := cmp.Diff(wantUsage, snapshot.ClusterQueues["cq"].Usage); diff != "" {
+		t.Errorf("Unexpected usage in disabled flavor (-want,+got):\n%s", diff)
+	}
+	if got := snapshot.ClusterQueues["cq"].AllocatableResourceGeneration; got != generation+1 {
+		t.Errorf("Unexpected allocatable resource generation, got %d, want %d", got, generation+1)
+	}
+
+	cache.AddOrUpdateResourceFlavor(flavor)
+	snapshot = cache.Snapshot()
+	if snapshot.DisabledFlavors.Len() != 0 {
+		t.Errorf("Unexpected disabled flavors after enabling: %v", sets.List(snapshot.DisabledFlavors))
+	}
+	if diff := cmp.Diff(wantUsage, snapshot.ClusterQueues["cq"].Usage); diff != "" {
+		t.Errorf("Unexpected usage after enabling (-want,+got):\n%s", diff)
+	}
+	if got := snapshot.ClusterQueues["cq"].AllocatableResourceGeneration; got != generation+2 {
+		t.Errorf("Unexpected allocatable resource generation, got %d, want %d", got, generation+2)
+	}
+}
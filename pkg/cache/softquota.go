@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+var errInvalidSoftQuota = errors.New("invalid soft quota")
+
+// parseSoftQuotas returns the soft quotas of the ClusterQueue declared in the
+// SoftQuotasAnnotation.
+func parseSoftQuotas(cq *kueue.ClusterQueue) (FlavorResourceQuantities, error) {
+	value, found := cq.Annotations[constants.SoftQuotasAnnotation]
+	if !found {
+		return nil, nil
+	}
+	softQuotas := make(FlavorResourceQuantities)
+	for _, entry := range strings.Split(value, ",") {
+		key, quantity, found := strings.Cut(strings.TrimSpace(entry), "=")
+		fName, rName, keyFound := strings.Cut(key, ":")
+		if !found || !keyFound || fName == "" || rName == "" {
+			return nil, fmt.Errorf("%w: %q in annotation %s, expected <flavor>:<resource>=<quantity>", errInvalidSoftQuota, entry, constants.SoftQuotasAnnotation)
+		}
+		q, err := resource.ParseQuantity(quantity)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", errInvalidSoftQuota, entry, err)
+		}
+		flvQuotas := softQuotas[kueue.ResourceFlavorReference(fName)]
+		if flvQuotas == nil {
+			flvQuotas = make(map[corev1.ResourceName]int64)
+			softQuotas[kueue.ResourceFlavorReference(fName)] = flvQuotas
+		}
+		flvQuotas[corev1.ResourceName(rName)] = workload.ResourceValue(corev1.ResourceName(rName), q)
+	}
+	return softQuotas, nil
+}
+
+// softQuotaExceeded returns, for each resource and flavor with a soft quota in
+// the ClusterQueue, whether the usage exceeds it. Exceeding a soft quota
+// doesn't prevent admission; it's a warning that the ClusterQueue is getting
+// close to its quota. Returns nil if the ClusterQueue doesn't exist or doesn't
+// have soft quotas.
+func (c *Cache) softQuotaExceeded(cqName string) map[corev1.ResourceName]map[kueue.ResourceFlavorReference]bool {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil
+	}
+	return cq.softQuotaExceeded()
+}
+
+func (c *ClusterQueue) softQuotaExceeded() map[corev1.ResourceName]map[kueue.ResourceFlavorReference]bool {
+	if len(c.softQuotas) == 0 {
+		return nil
+	}
+	exceeded := make(map[corev1.ResourceName]map[kueue.ResourceFlavorReference]bool)
+	for fName, resources := range c.softQuotas {
+		for rName, softQuota := range resources {
+			if exceeded[rName] == nil {
+				exceeded[rName] = make(map[kueue.ResourceFlavorReference]bool)
+			}
+			exceeded[rName][fName] = c.Usage[fName][rName] > softQuota
+		}
+	}
+	return exceeded
+}
+
+func (c *Cache) reportSoftQuotas(cqNames ...string) {
+	c.RLock()
+	defer c.RUnlock()
+	for _, name := range cqNames {
+		if cq, ok := c.clusterQueues[name]; ok {
+			cq.reportSoftQuotas()
+		}
+	}
+}
+
+// reportSoftQuotas reports, from scratch, whether the usage of the
+// ClusterQueue exceeds its soft quotas.
+func (c *ClusterQueue) reportSoftQuotas() {
+	recorder := c.recorder()
+	recorder.ClearSoftQuotaExceeded(c.Name)
+	for rName, flavors := range c.softQuotaExceeded() {
+		for fName, exceeded := range flavors {
+			recorder.SetSoftQuotaExceeded(c.Name, fName, rName, exceeded)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	"sigs.k8s.io/kueue/pkg/metrics"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	testingmetrics "sigs.k8s.io/kueue/pkg/util/testing/metrics"
+)
+
+func TestParseSoftQuotas(t *testing.T) {
+	cases := map[string]struct {
+		value   string
+		want    FlavorResourceQuantities
+		wantErr error
+	}{
+		"valid": {
+			value: "default:cpu=8, default:memory=1Gi,spot:cpu=500m",
+			want: FlavorResourceQuantities{
+				"default": {corev1.ResourceCPU: 8_000, corev1.ResourceMemory: utiltesting.Gi},
+				"spot":    {corev1.ResourceCPU: 500},
+			},
+		},
+		"missing flavor": {
+			value:   "cpu=8",
+			wantErr: errInvalidSoftQuota,
+		},
+		"invalid quantity": {
+			value:   "default:cpu=lots",
+			wantErr: errInvalidSoftQuota,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cq := utiltesting.MakeClusterQueue("cq").Obj()
+			cq.Annotations = map[string]string{constants.SoftQuotasAnnotation: tc.value}
+			got, err := parseSoftQuotas(cq)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Unexpected error: %v, want %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected soft quotas (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSoftQuotaExceeded(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+			Resource(corev1.ResourceCPU, "10").
+			Resource(corev1.ResourceMemory, "10Gi").
+			Obj()).
+		Obj()
+	cq.Annotations = map[string]string{constants.SoftQuotasAnnotation: "default:cpu=6,default:memory=8Gi"}
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	wantMetrics := func(step string, cpu, memory float64) {
+		t.Helper()
+		want := []testingmetrics.GaugeDataPoint{
+			{
+				Labels: map[string]string{"cluster_queue": "cq", "flavor": "default", "resource": "cpu"},
+				Value:  cpu,
+			},
+			{
+				Labels: map[string]string{"cluster_queue": "cq", "flavor": "default", "resource": "memory"},
+				Value:  memory,
+			},
+		}
+		got := testingmetrics.CollectFilteredGaugeVec(metrics.ClusterQueueSoftQuotaExceeded, map[string]string{"cluster_queue": "cq"})
+		if diff := cmp.Diff(want, got, cmpopts.SortSlices(func(a, b testingmetrics.GaugeDataPoint) bool { return a.Less(&b) })); diff != "" {
+			t.Errorf("Unexpected metrics %s (-want,+got):\n%s", step, diff)
+		}
+	}
+	wantMetrics("without workloads", 0, 0)
+
+	// The usage is above the soft quota for cpu and below the hard quota.
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		ReserveQuota(utiltesting.MakeAdmission("cq").
+			Assignment(corev1.ResourceCPU, "default", "8").
+			Assignment(corev1.ResourceMemory, "default", "4Gi").
+			Obj()).
+		Obj()
+	if !cache.AddOrUpdateWorkload(wl) {
+		t.Fatalf("Failed adding workload %q", wl.Name)
+	}
+	want := map[corev1.ResourceName]map[kueue.ResourceFlavorReference]bool{
+		corev1.ResourceCPU:    {"default": true},
+		corev1.ResourceMemory: {"default": false},
+	}
+	if diff := cmp.Diff(want, cache.softQuotaExceeded("cq")); diff != "" {
+		t.Errorf("Unexpected soft quotas exceeded (-want,+got):\n%s", diff)
+	}
+	// Exceeding the soft quota doesn't affect admission.
+	if !cache.ClusterQueueActive("cq") {
+		t.Error("ClusterQueue is not active")
+	}
+	wantMetrics("after admitting a workload", 1, 0)
+
+	if err := cache.DeleteWorkload(wl); err != nil {
+		t.Fatalf("Failed deleting workload: %v", err)
+	}
+	wantMetrics("after deleting the workload", 0, 0)
+
+	if got := cache.softQuotaExceeded("unknown"); got != nil {
+		t.Errorf("Unexpected soft quotas exceeded for an unknown ClusterQueue: %v", got)
+	}
+
+	cache.DeleteClusterQueue(cq)
+	if got := testingmetrics.CollectFilteredGaugeVec(metrics.ClusterQueueSoftQuotaExceeded, map[string]string{"cluster_queue": "cq"}); len(got) != 0 {
+		t.Errorf("Unexpected metrics after deleting the ClusterQueue: %v", got)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/features"
+	"sigs.k8s.io/kueue/pkg/util/limitrange"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// divisibleResources are the resources whose request can be split across
+// flavors.
+var divisibleResources = sets.New(corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage)
+
+// splitAssignment returns, keyed by flavor name, how much of the total request
+// of the PodSet for the resource to take from each flavor of the ClusterQueue,
+// so that the request fits in the unused nominal quota of the flavors. The
+// flavors are taken in order, each one until its unused quota is exhausted,
+// skipping the disabled ones and the ones whose node labels contradict the
+// node selector of the PodSet.
+// Returns false if the FlavorSplitting feature is disabled, the resource is
+// not divisible, the ClusterQueue doesn't exist or the request doesn't fit.
+func (c *Cache) splitAssignment(podSet kueue.PodSet, rName corev1.ResourceName, cqName string) (map[string]int64, bool) {
+	if !features.Enabled(features.FlavorSplitting) || !divisibleResources.Has(rName) {
+		return nil, false
+	}
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[cqName]
+	if !ok {
+		return nil, false
+	}
+	rg := cq.RGByResource[rName]
+	if rg == nil {
+		return nil, false
+	}
+	perPod, found := limitrange.TotalRequests(&podSet.Template.Spec)[rName]
+	if !found {
+		return nil, false
+	}
+	remaining := workload.ResourceValue(rName, perPod) * int64(podSet.Count)
+	split := make(map[string]int64)
+	for _, flvQuotas := range rg.Flavors {
+		if remaining == 0 {
+			break
+		}
+		quota, found := flvQuotas.Resources[rName]
+		if !found || c.disabledFlavors.Has(flvQuotas.Name) {
+			continue
+		}
+		if rf, found := c.resourceFlavors[flvQuotas.Name]; !found || !flavorMatchesNodeSelector(rf, podSet.Template.Spec.NodeSelector) {
+			continue
+		}
+		unused := quota.Nominal - cq.Usage[flvQuotas.Name][rName]
+		if unused <= 0 {
+			continue
+		}
+		take := min(remaining, unused)
+		split[string(flvQuotas.Name)] = take
+		remaining -= take
+	}
+	if remaining > 0 {
+		return nil, false
+	}
+	return split, true
+}
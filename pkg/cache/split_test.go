@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	"sigs.k8s.io/kueue/pkg/features"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestSplitAssignment(t *testing.T) {
+	cases := map[string]struct {
+		disableFeature bool
+		disabledFlavor bool
+		podSet         *kueue.PodSet
+		resource       corev1.ResourceName
+		wantSplit      map[string]int64
+		wantOK         bool
+	}{
+		"fits in the first flavor": {
+			podSet:    utiltesting.MakePodSet("main", 4).Request(corev1.ResourceCPU, "1").Obj(),
+			resource:  corev1.ResourceCPU,
+			wantSplit: map[string]int64{"default": 4_000},
+			wantOK:    true,
+		},
+		"split across flavors": {
+			podSet:    utiltesting.MakePodSet("main", 10).Request(corev1.ResourceCPU, "1").Obj(),
+			resource:  corev1.ResourceCPU,
+			wantSplit: map[string]int64{"default": 6_000, "spare": 4_000},
+			wantOK:    true,
+		},
+		"skips the disabled flavor": {
+			disabledFlavor: true,
+			podSet:         utiltesting.MakePodSet("main", 4).Request(corev1.ResourceCPU, "1").Obj(),
+			resource:       corev1.ResourceCPU,
+			wantSplit:      map[string]int64{"spare": 4_000},
+			wantOK:         true,
+		},
+		"doesn't fit in all the flavors": {
+			podSet:   utiltesting.MakePodSet("main", 20).Request(corev1.ResourceCPU, "1").Obj(),
+			resource: corev1.ResourceCPU,
+		},
+		"resource not divisible": {
+			podSet:   utiltesting.MakePodSet("main", 4).Request("example.com/gpu", "1").Obj(),
+			resource: "example.com/gpu",
+		},
+		"feature disabled": {
+			disableFeature: true,
+			podSet:         utiltesting.MakePodSet("main", 10).Request(corev1.ResourceCPU, "1").Obj(),
+			resource:       corev1.ResourceCPU,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			defer features.SetFeatureGateDuringTest(t, features.FlavorSplitting, !tc.disableFeature)()
+			cache := New(utiltesting.NewFakeClient())
+			defaultFlavor := utiltesting.MakeResourceFlavor("default").Obj()
+			if tc.disabledFlavor {
+				defaultFlavor.Annotations = map[string]string{constants.ResourceFlavorDisabledAnnotation: "true"}
+			}
+			cache.AddOrUpdateResourceFlavor(defaultFlavor)
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spare").Obj())
+			cq := utiltesting.MakeClusterQueue("cq").
+				ResourceGroup(
+					*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "8").Resource("example.com/gpu", "2").Obj(),
+					*utiltesting.MakeFlavorQuotas("spare").Resource(corev1.ResourceCPU, "5").Resource("example.com/gpu", "2").Obj(),
+				).
+				Obj()
+			if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			wl := utiltesting.MakeWorkload("wl", "ns").
+				Request(corev1.ResourceCPU, "2").
+				ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "2").Obj()).
+				Obj()
+			if !cache.AddOrUpdateWorkload(wl) {
+				t.Fatalf("Failed adding workload")
+			}
+
+			gotSplit, gotOK := cache.splitAssignment(*tc.podSet, tc.resource, "cq")
+			if gotOK != tc.wantOK {
+				t.Errorf("Unexpected result, got %t, want %t", gotOK, tc.wantOK)
+			}
+			if diff := cmp.Diff(tc.wantSplit, gotSplit); diff != "" {
+				t.Errorf("Unexpected split (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
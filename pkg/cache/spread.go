@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+)
+
+// flavorTopologyKeyFromAnnotation returns the topology key of the
+// ResourceFlavor, declared in the ResourceFlavorTopologyKeyAnnotation, or an
+// empty string if it's not set. Keys that are not valid label keys are
+// ignored.
+func flavorTopologyKeyFromAnnotation(rf *kueue.ResourceFlavor) string {
+	key := rf.Annotations[constants.ResourceFlavorTopologyKeyAnnotation]
+	if key == "" || len(validation.IsQualifiedName(key)) > 0 {
+		return ""
+	}
+	return key
+}
+
+// flavorTopologyKey returns the topology key of the ResourceFlavor, or an
+// empty string if it's not set.
+func (c *Cache) flavorTopologyKey(flavorName string) string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.flavorTopologyKeys[kueue.ResourceFlavorReference(flavorName)]
+}
+
+// SetFlavorTopologyDomains records the number of topology domains, as
+// identified by the topology key of the ResourceFlavor, observed among the
+// nodes of the ResourceFlavor. A count of 0 or less unsets it.
+func (c *Cache) SetFlavorTopologyDomains(flavorName string, domains int) {
+	c.Lock()
+	defer c.Unlock()
+	fName := kueue.ResourceFlavorReference(flavorName)
+	if domains <= 0 {
+		delete(c.flavorTopologyDomains, fName)
+	} else {
+		c.flavorTopologyDomains[fName] = domains
+	}
+}
+
+// flavorSatisfiesSpread returns whether the nodes of the ResourceFlavor span
+// enough topology domains for the spread constraints of a PodSet, so that a
+// spread-constrained workload is not admitted to a flavor whose nodes are all
+// in the same zone.
+// Only the constraints that can't be ignored by the scheduler are checked,
+// requiring their minDomains, 1 by default. The domains of a constraint are
+// known if the nodeLabels of the ResourceFlavor pin its topology key to a
+// single domain or if it's the topology key of the ResourceFlavor, for which
+// the given domains observed are used. The constraints whose domains are
+// unknown are assumed to be satisfied.
+func flavorSatisfiesSpread(rf *kueue.ResourceFlavor, topologyKey string, domains int, constraints []corev1.TopologySpreadConstraint) bool {
+	var nodeLabels map[string]string
+	if rf != nil {
+		nodeLabels = rf.Spec.NodeLabels
+	}
+	for _, constraint := range constraints {
+		if constraint.WhenUnsatisfiable != corev1.DoNotSchedule {
+			continue
+		}
+		_, pinned := nodeLabels[constraint.TopologyKey]
+		var available int
+		switch {
+		case pinned:
+			available = 1
+		case constraint.TopologyKey == topologyKey && domains > 0:
+			available = domains
+		default:
+			continue
+		}
+		if available < int(ptr.Deref(constraint.MinDomains, 1)) {
+			return false
+		}
+	}
+	return true
+}
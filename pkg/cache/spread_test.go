@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+const zoneKey = "topology.kubernetes.io/zone"
+
+func zoneSpread(minDomains int32, whenUnsatisfiable corev1.UnsatisfiableConstraintAction) corev1.TopologySpreadConstraint {
+	return corev1.TopologySpreadConstraint{
+		MaxSkew:           1,
+		TopologyKey:       zoneKey,
+		WhenUnsatisfiable: whenUnsatisfiable,
+		MinDomains:        ptr.To(minDomains),
+	}
+}
+
+func TestFlavorSatisfiesSpread(t *testing.T) {
+	cases := map[string]struct {
+		flavor      string
+		constraints []corev1.TopologySpreadConstraint
+		want        bool
+	}{
+		"no constraints": {
+			flavor: "single-zone",
+			want:   true,
+		},
+		"3-zone spread rejects a single-zone flavor": {
+			flavor:      "single-zone",
+			constraints: []corev1.TopologySpreadConstraint{zoneSpread(3, corev1.DoNotSchedule)},
+		},
+		"3-zone spread fits a flavor spanning 3 zones": {
+			flavor:      "multi-zone",
+			constraints: []corev1.TopologySpreadConstraint{zoneSpread(3, corev1.DoNotSchedule)},
+			want:        true,
+		},
+		"4-zone spread rejects a flavor spanning 3 zones": {
+			flavor:      "multi-zone",
+			constraints: []corev1.TopologySpreadConstraint{zoneSpread(4, corev1.DoNotSchedule)},
+		},
+		"spread that can be ignored": {
+			flavor:      "single-zone",
+			constraints: []corev1.TopologySpreadConstraint{zoneSpread(3, corev1.ScheduleAnyway)},
+			want:        true,
+		},
+		"spread without minDomains fits a single-zone flavor": {
+			flavor: "single-zone",
+			constraints: []corev1.TopologySpreadConstraint{{
+				MaxSkew:           1,
+				TopologyKey:       zoneKey,
+				WhenUnsatisfiable: corev1.DoNotSchedule,
+			}},
+			want: true,
+		},
+		"unknown domains": {
+			flavor:      "unknown-zones",
+			constraints: []corev1.TopologySpreadConstraint{zoneSpread(3, corev1.DoNotSchedule)},
+			want:        true,
+		},
+	}
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("single-zone").Label(zoneKey, "a").Obj())
+	multiZone := utiltesting.MakeResourceFlavor("multi-zone").Obj()
+	multiZone.Annotations = map[string]string{constants.ResourceFlavorTopologyKeyAnnotation: zoneKey}
+	cache.AddOrUpdateResourceFlavor(multiZone)
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("unknown-zones").Obj())
+	cache.SetFlavorTopologyDomains("multi-zone", 3)
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fName := kueue.ResourceFlavorReference(tc.flavor)
+			got := flavorSatisfiesSpread(cache.resourceFlavors[fName], cache.flavorTopologyKeys[fName], cache.flavorTopologyDomains[fName], tc.constraints)
+			if got != tc.want {
+				t.Errorf("Unexpected result, got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExplainUnschedulableSpread(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("single-zone").Label(zoneKey, "a").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(*utiltesting.MakeFlavorQuotas("single-zone").Resource(corev1.ResourceCPU, "10").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		PodSets(*utiltesting.MakePodSet("main", 3).
+			Request(corev1.ResourceCPU, "1").
+			TopologySpreadConstraints(zoneSpread(3, corev1.DoNotSchedule)).
+			Obj()).
+		Obj()
+	want := "PodSet main: 0/1 flavors are available for cpu: " +
+		"flavor single-zone doesn't have enough topology domains for the spread constraints"
+	if got := cache.explainUnschedulable(wl, "cq"); got != want {
+		t.Errorf("Unexpected explanation\ngot:  %q\nwant: %q", got, want)
+	}
+}
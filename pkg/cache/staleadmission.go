@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// staleAdmissions returns the workloads holding quota in the flavor that have
+// a PodSet, assigned the flavor, whose node selector contradicts the current
+// node labels of the flavor, such as after the region label of the flavor
+// changed. The pods of such PodSets can no longer be scheduled in the flavor,
+// so the caller should evict the workloads for them to be admitted again.
+// The workloads are sorted by key. The returned infos are copies that the
+// caller can modify. Returns nil if the flavor doesn't exist.
+func (c *Cache) staleAdmissions(flavorName kueue.ResourceFlavorReference) []*workload.Info {
+	c.RLock()
+	defer c.RUnlock()
+	rf, found := c.resourceFlavors[flavorName]
+	if !found {
+		return nil
+	}
+	var infos []*workload.Info
+	for _, cq := range c.clusterQueues {
+		for _, wi := range cq.Workloads {
+			if admissionIsStale(wi, rf) {
+				infos = append(infos, cloneInfo(wi))
+			}
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return workload.Key(infos[i].Obj) < workload.Key(infos[j].Obj)
+	})
+	return infos
+}
+
+// admissionIsStale returns whether a PodSet of the workload assigned the
+// flavor has a node selector that contradicts the node labels of the flavor.
+func admissionIsStale(wi *workload.Info, rf *kueue.ResourceFlavor) bool {
+	fName := kueue.ResourceFlavorReference(rf.Name)
+	for _, psr := range wi.TotalRequests {
+		assigned := false
+		for _, flavor := range psr.Flavors {
+			if flavor == fName {
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			continue
+		}
+		if ps := podSetByName(wi.Obj, psr.Name); ps != nil && !flavorMatchesNodeSelector(rf, ps.Template.Spec.NodeSelector) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+func TestStaleAdmissions(t *testing.T) {
+	cache := New(utiltesting.NewFakeClient())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("regional").Label("region", "us-east").Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		ResourceGroup(
+			*utiltesting.MakeFlavorQuotas("regional").Resource(corev1.ResourceCPU, "10").Obj(),
+			*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "10").Obj(),
+		).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	east := map[string]string{"region": "us-east"}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("east", "ns").
+			NodeSelector(east).
+			ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "regional", "1").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("unconstrained", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "regional", "1").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("other-flavor", "ns").
+			NodeSelector(east).
+			ReserveQuota(utiltesting.MakeAdmission("cq").Assignment(corev1.ResourceCPU, "default", "1").Obj()).
+			Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %q", wl.Name)
+		}
+	}
+	staleKeys := func() []string {
+		var keys []string
+		for _, wi := range cache.staleAdmissions("regional") {
+			keys = append(keys, workload.Key(wi.Obj))
+		}
+		return keys
+	}
+
+	if got := staleKeys(); len(got) != 0 {
+		t.Errorf("Unexpected stale admissions before changing the flavor: %v", got)
+	}
+
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("regional").Label("region", "us-west").Obj())
+	if diff := cmp.Diff([]string{"ns/east"}, staleKeys()); diff != "" {
+		t.Errorf("Unexpected stale admissions after changing the region (-want,+got):\n%s", diff)
+	}
+
+	if got := cache.staleAdmissions("unknown"); got != nil {
+		t.Errorf("Unexpected stale admissions for an unknown flavor: %v", got)
+	}
+}
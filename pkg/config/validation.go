@@ -42,6 +42,7 @@ var (
 	namespaceSelectorPath      = podOptionsPath.Child("namespaceSelector")
 	waitForPodsReadyPath       = field.NewPath("waitForPodsReady")
 	requeuingStrategyPath      = waitForPodsReadyPath.Child("requeuingStrategy")
+	resourceSubstitutionsPath  = field.NewPath("resources", "substitutions")
 )
 
 func validate(c *configapi.Configuration) field.ErrorList {
@@ -54,6 +55,8 @@ func validate(c *configapi.Configuration) field.ErrorList {
 	// Validate PodNamespaceSelector for the pod framework
 	allErrs = append(allErrs, validateIntegrations(c)...)
 
+	allErrs = append(allErrs, validateResourceSubstitutions(c)...)
+
 	return allErrs
 }
 
@@ -145,3 +148,29 @@ func validatePodIntegrationOptions(c *configapi.Configuration) field.ErrorList {
 
 	return allErrs
 }
+
+func validateResourceSubstitutions(c *configapi.Configuration) field.ErrorList {
+	var allErrs field.ErrorList
+	if c.Resources == nil {
+		return allErrs
+	}
+	seen := make(map[corev1.ResourceName]struct{}, len(c.Resources.Substitutions))
+	for i, substitution := range c.Resources.Substitutions {
+		path := resourceSubstitutionsPath.Index(i)
+		if substitution.Resource == "" {
+			allErrs = append(allErrs, field.Required(path.Child("resource"), ""))
+		} else if _, found := seen[substitution.Resource]; found {
+			allErrs = append(allErrs, field.Duplicate(path.Child("resource"), substitution.Resource))
+		}
+		seen[substitution.Resource] = struct{}{}
+		if len(substitution.Substitutes) == 0 {
+			allErrs = append(allErrs, field.Required(path.Child("substitutes"), ""))
+		}
+		for j, substitute := range substitution.Substitutes {
+			if substitute == substitution.Resource {
+				allErrs = append(allErrs, field.Invalid(path.Child("substitutes").Index(j), substitute, "must not be the substituted resource"))
+			}
+		}
+	}
+	return allErrs
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/utils/ptr"
@@ -271,6 +272,48 @@ func TestValidate(t *testing.T) {
 				},
 			},
 		},
+		"valid resources.substitutions": {
+			cfg: &configapi.Configuration{
+				Integrations: defaultIntegrations,
+				Resources: &configapi.Resources{
+					Substitutions: []configapi.ResourceSubstitution{{
+						Resource:    corev1.ResourceCPU,
+						Substitutes: []corev1.ResourceName{"example.com/fast-cpu"},
+					}},
+				},
+			},
+			wantErr: nil,
+		},
+		"invalid resources.substitutions": {
+			cfg: &configapi.Configuration{
+				Integrations: defaultIntegrations,
+				Resources: &configapi.Resources{
+					Substitutions: []configapi.ResourceSubstitution{
+						{
+							Resource:    corev1.ResourceCPU,
+							Substitutes: []corev1.ResourceName{corev1.ResourceCPU},
+						},
+						{
+							Resource: corev1.ResourceCPU,
+						},
+					},
+				},
+			},
+			wantErr: field.ErrorList{
+				&field.Error{
+					Type:  field.ErrorTypeInvalid,
+					Field: "resources.substitutions[0].substitutes[0]",
+				},
+				&field.Error{
+					Type:  field.ErrorTypeDuplicate,
+					Field: "resources.substitutions[1].resource",
+				},
+				&field.Error{
+					Type:  field.ErrorTypeRequired,
+					Field: "resources.substitutions[1].substitutes",
+				},
+			},
+		},
 	}
 
 	for name, tc := range testCases {
@@ -27,6 +27,11 @@ const (
 	AdmissionName          = KueueName + "-admission"
 	ReclaimablePodsMgr     = KueueName + "-reclaimable-pods"
 
+	// ProvisioningRequestControllerName is the controller name of the
+	// AdmissionChecks handled by the ProvisioningRequest admission check
+	// controller.
+	ProvisioningRequestControllerName = "kueue.x-k8s.io/provisioning-request"
+
 	// UpdatesBatchPeriod is the batch period to hold workload updates
 	// before syncing a Queue and ClusterQueue objects.
 	UpdatesBatchPeriod = time.Second
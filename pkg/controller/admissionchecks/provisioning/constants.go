@@ -16,9 +16,11 @@ limitations under the License.
 
 package provisioning
 
+import "sigs.k8s.io/kueue/pkg/constants"
+
 const (
 	ConfigKind            = "ProvisioningRequestConfig"
-	ControllerName        = "kueue.x-k8s.io/provisioning-request"
+	ControllerName        = constants.ProvisioningRequestControllerName
 	ConsumesAnnotationKey = "cluster-autoscaler.kubernetes.io/consume-provisioning-request"
 
 	CheckInactiveMessage = "the check is not active"
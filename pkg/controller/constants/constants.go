@@ -37,6 +37,126 @@ const (
 	// This label is always mutable because it might be useful for the preemption.
 	WorkloadPriorityClassLabel = "kueue.x-k8s.io/priority-class"
 
+	// ResourceFlavorDisabledAnnotation is the annotation key in the ResourceFlavor
+	// that, when set to "true", stops the flavor from being assigned to new
+	// workloads. The usage of the workloads already admitted in the flavor is
+	// still accounted for.
+	ResourceFlavorDisabledAnnotation = "kueue.x-k8s.io/disabled"
+
+	// ResourceFlavorProvisioningClassAnnotation is the annotation key in the
+	// ResourceFlavor that holds the class of the provisioner, for example a
+	// cloud provider, that brings up its capacity on demand. Workloads assigned
+	// to such a flavor are only admitted once a provisioning admission check of
+	// their ClusterQueue is ready.
+	ResourceFlavorProvisioningClassAnnotation = "kueue.x-k8s.io/provisioning-class"
+
+	// ResourceFlavorCostAnnotation is the annotation key in the ResourceFlavor
+	// that holds its cost relative to the other flavors, as a non-negative
+	// integer. Cheaper flavors score higher when scoring flavors.
+	ResourceFlavorCostAnnotation = "kueue.x-k8s.io/cost"
+
+	// ResourceFlavorShareWeightAnnotation is the annotation key in the
+	// ResourceFlavor that holds the weight of its usage in the fair sharing
+	// computations, as a non-negative number. Defaults to 1.
+	ResourceFlavorShareWeightAnnotation = "kueue.x-k8s.io/share-weight"
+
+	// ResourceFlavorTopologyKeyAnnotation is the annotation key in the
+	// ResourceFlavor that holds the node label key, such as
+	// topology.kubernetes.io/zone, that identifies the topology domains of the
+	// nodes in the flavor.
+	ResourceFlavorTopologyKeyAnnotation = "kueue.x-k8s.io/topology-key"
+
+	// ResourceFlavorFractionalResourcesAnnotation is the annotation key in the
+	// ResourceFlavor that declares the resources that are shared by fractions,
+	// such as GPUs shared with MPS or time-slicing. Its value is a comma
+	// separated list of <resource>=<granularity> pairs, for example
+	// "nvidia.com/gpu=0.25". Fractional requests are rounded up to the
+	// granularity.
+	ResourceFlavorFractionalResourcesAnnotation = "kueue.x-k8s.io/fractional-resources"
+
+	// PinnedFlavorsAnnotation is the annotation key in the workload that pins
+	// resources to a flavor. Its value is a comma separated list of
+	// <resource>=<flavor> pairs, for example "cpu=on-demand,nvidia.com/gpu=a100".
+	// A pinned resource is only assigned its pinned flavor.
+	PinnedFlavorsAnnotation = "kueue.x-k8s.io/pinned-flavors"
+
+	// ExcludedFlavorsAnnotation is the annotation key in the workload that
+	// holds a comma separated list of flavors that the workload must not be
+	// assigned, for example "spot,preemptible".
+	ExcludedFlavorsAnnotation = "kueue.x-k8s.io/excluded-flavors"
+
+	// EstimatedDurationAnnotation is the annotation key in the workload that
+	// holds how long it's expected to run, as a duration such as "30m". It's
+	// used to backfill short workloads into capacity that is only free for a
+	// limited time.
+	EstimatedDurationAnnotation = "kueue.x-k8s.io/estimated-duration"
+
+	// SecondaryCohortsAnnotation is the annotation key in the ClusterQueue that
+	// lists, separated by commas, the cohorts that it lends its unused quota
+	// to, besides its cohort.
+	SecondaryCohortsAnnotation = "kueue.x-k8s.io/secondary-cohorts"
+
+	// NominalQuotaPercentagesAnnotation is the annotation key in the
+	// ClusterQueue that declares nominal quotas as a percentage of the total
+	// nominal quota of its cohort, instead of absolute values. Its value is a
+	// comma separated list of <flavor>:<resource>=<percentage>% entries, for
+	// example "on-demand:cpu=25%". The total of the cohort only includes the
+	// absolute nominal quotas of its members.
+	NominalQuotaPercentagesAnnotation = "kueue.x-k8s.io/nominal-quota-percentages"
+
+	// SoftQuotasAnnotation is the annotation key in the ClusterQueue that
+	// declares soft quotas: usage thresholds, below the quotas that admission
+	// enforces, whose excess is only reported. Its value is a comma separated
+	// list of <flavor>:<resource>=<quantity> entries, for example
+	// "on-demand:cpu=8".
+	SoftQuotasAnnotation = "kueue.x-k8s.io/soft-quotas"
+
+	// BorrowingForbiddenResourcesAnnotation is the annotation key in the
+	// ClusterQueue that lists, separated by commas, the resources that it
+	// can't borrow from its cohorts in any flavor, as if their borrowing
+	// limits were 0.
+	BorrowingForbiddenResourcesAnnotation = "kueue.x-k8s.io/borrowing-forbidden-resources"
+
+	// HeadroomAnnotation is the annotation key in the ClusterQueue that
+	// declares, per resource, the amount of the nominal quota of each flavor
+	// that it never lends to its cohorts. Its value is a comma separated list
+	// of <resource>=<quantity> entries, for example "cpu=4,memory=8Gi".
+	HeadroomAnnotation = "kueue.x-k8s.io/headroom"
+
+	// BorrowBoostAnnotation is the annotation key in the ClusterQueue that
+	// declares, per resource, an amount that it can borrow beyond the
+	// borrowing limit of its flavors until a deadline, for example during a
+	// migration. Its value is <until>;<resources>, where until is an RFC 3339
+	// time and resources is a comma separated list of <resource>=<quantity>
+	// entries, for example "2024-05-02T06:00:00Z;cpu=16".
+	BorrowBoostAnnotation = "kueue.x-k8s.io/borrow-boost"
+
+	// PriorityOffsetAnnotation is the annotation key in the ClusterQueue that
+	// declares an integer offset added to the priority of its workloads when
+	// they are compared with the workloads of other ClusterQueues, for example
+	// "-100".
+	PriorityOffsetAnnotation = "kueue.x-k8s.io/priority-offset"
+
+	// ScheduledReservationAnnotation is the annotation key in the ClusterQueue
+	// that declares an amount of resources held in the ClusterQueue during a
+	// time window, as if they were used by an admitted workload. Its value is
+	// <start>;<duration>;<resources>, where start is an RFC 3339 time,
+	// duration is a Go duration and resources is a comma separated list of
+	// <resource>=<quantity> entries, for example
+	// "2024-05-01T22:00:00Z;4h;nvidia.com/gpu=8".
+	ScheduledReservationAnnotation = "kueue.x-k8s.io/scheduled-reservation"
+
+	// BorrowingConfigMapName is the name of the ConfigMap, in the namespace of
+	// Kueue, that pauses borrowing in all the ClusterQueues at runtime, for
+	// example during an incident. It overrides the pauseBorrowing field of the
+	// configuration while it exists.
+	BorrowingConfigMapName = "kueue-borrowing"
+
+	// BorrowingPausedKey is the key in the data of the BorrowingConfigMapName
+	// ConfigMap that, when set to "true", pauses borrowing in all the
+	// ClusterQueues.
+	BorrowingPausedKey = "paused"
+
 	// ProvReqAnnotationPrefix is the prefix for annotations that should be pass to ProvisioningRequest as Parameters.
 	ProvReqAnnotationPrefix = "provreq.kueue.x-k8s.io/"
 )
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	"sigs.k8s.io/kueue/pkg/queue"
+)
+
+// BorrowingReconciler pauses or resumes borrowing in all the ClusterQueues
+// according to the BorrowingConfigMapName ConfigMap in the namespace of
+// Kueue. While the ConfigMap doesn't exist, borrowing is paused as set in the
+// configuration.
+type BorrowingReconciler struct {
+	log           logr.Logger
+	qManager      *queue.Manager
+	cache         *cache.Cache
+	client        client.Client
+	namespace     string
+	defaultPaused bool
+}
+
+func NewBorrowingReconciler(
+	client client.Client,
+	qMgr *queue.Manager,
+	cache *cache.Cache,
+	namespace string,
+	defaultPaused bool,
+) *BorrowingReconciler {
+	return &BorrowingReconciler{
+		log:           ctrl.Log.WithName("borrowing-reconciler"),
+		cache:         cache,
+		client:        client,
+		qManager:      qMgr,
+		namespace:     namespace,
+		defaultPaused: defaultPaused,
+	}
+}
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+func (r *BorrowingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	paused := r.defaultPaused
+	var cm corev1.ConfigMap
+	if err := r.client.Get(ctx, req.NamespacedName, &cm); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+	} else {
+		paused = cm.Data[constants.BorrowingPausedKey] == "true"
+	}
+
+	wasPaused := r.cache.SetBorrowingPaused(paused)
+	if paused == wasPaused {
+		return ctrl.Result{}, nil
+	}
+	log.V(2).Info("Borrowing state changed", "paused", paused)
+	// The workloads that needed to borrow might fit now.
+	if !paused {
+		r.qManager.QueueInadmissibleWorkloads(ctx, sets.New(r.qManager.GetClusterQueueNames()...))
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *BorrowingReconciler) isBorrowingConfigMap(obj client.Object) bool {
+	return obj.GetNamespace() == r.namespace && obj.GetName() == constants.BorrowingConfigMapName
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BorrowingReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("borrowing").
+		For(&corev1.ConfigMap{}, builder.WithPredicates(predicate.NewPredicateFuncs(r.isBorrowingConfigMap))).
+		WithOptions(controller.Options{NeedLeaderElection: ptr.To(false)}).
+		Complete(r)
+}
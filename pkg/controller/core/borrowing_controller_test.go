@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	"sigs.k8s.io/kueue/pkg/queue"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestBorrowingReconcile(t *testing.T) {
+	configMap := func(paused string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.BorrowingConfigMapName,
+				Namespace: "kueue-system",
+			},
+			Data: map[string]string{constants.BorrowingPausedKey: paused},
+		}
+	}
+
+	cases := map[string]struct {
+		configMap     *corev1.ConfigMap
+		defaultPaused bool
+		wasPaused     bool
+		wantPaused    bool
+	}{
+		"paused": {
+			configMap:  configMap("true"),
+			wantPaused: true,
+		},
+		"resumed": {
+			configMap: configMap("false"),
+			wasPaused: true,
+		},
+		"resumed despite the configuration": {
+			configMap:     configMap("false"),
+			defaultPaused: true,
+			wasPaused:     true,
+		},
+		"invalid value": {
+			configMap: configMap("yes"),
+			wasPaused: true,
+		},
+		"deleted ConfigMap falls back to the configuration": {
+			defaultPaused: true,
+			wantPaused:    true,
+		},
+		"deleted ConfigMap resumes borrowing": {
+			wasPaused: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var objs []client.Object
+			if tc.configMap != nil {
+				objs = append(objs, tc.configMap)
+			}
+			cl := utiltesting.NewFakeClient(objs...)
+			cqCache := cache.New(cl, cache.WithBorrowingPaused(tc.wasPaused))
+			qManager := queue.NewManager(cl, cqCache)
+
+			reconciler := NewBorrowingReconciler(cl, qManager, cqCache, "kueue-system", tc.defaultPaused)
+			req := ctrl.Request{NamespacedName: types.NamespacedName{
+				Name:      constants.BorrowingConfigMapName,
+				Namespace: "kueue-system",
+			}}
+			if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// SetBorrowingPaused returns the state left by the reconciler.
+			if got := cqCache.SetBorrowingPaused(tc.wantPaused); got != tc.wantPaused {
+				t.Errorf("Unexpected borrowing paused %t, want %t", got, tc.wantPaused)
+			}
+		})
+	}
+}
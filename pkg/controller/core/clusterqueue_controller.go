@@ -273,7 +273,9 @@ func (r *ClusterQueueReconciler) Delete(e event.DeleteEvent) bool {
 	defer r.notifyWatchers(cq, nil)
 
 	r.log.V(2).Info("ClusterQueue delete event", "clusterQueue", klog.KObj(cq))
-	r.cache.DeleteClusterQueue(cq)
+	if wlKeys := r.cache.DeleteClusterQueue(cq); len(wlKeys) > 0 {
+		r.log.V(2).Info("Deleted ClusterQueue still had workloads holding quota", "clusterQueue", klog.KObj(cq), "workloads", wlKeys)
+	}
 	r.qManager.DeleteClusterQueue(cq)
 	r.qManager.DeleteSnapshot(cq)
 
@@ -636,9 +638,9 @@ func (r *ClusterQueueReconciler) updateCqStatusIfChanged(
 ) error {
 	oldStatus := cq.Status.DeepCopy()
 	pendingWorkloads := r.qManager.Pending(cq)
-	stats, err := r.cache.Usage(cq)
+	stats, err := r.cache.ClusterQueueStatus(cq.Name)
 	if err != nil {
-		r.log.Error(err, "Failed getting usage from cache")
+		r.log.Error(err, "Failed getting status from cache")
 		// This is likely because the cluster queue was recently removed,
 		// but we didn't process that event yet.
 		return err
@@ -25,6 +25,7 @@ import (
 	"sigs.k8s.io/kueue/pkg/cache"
 	"sigs.k8s.io/kueue/pkg/config"
 	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/features"
 	"sigs.k8s.io/kueue/pkg/queue"
 )
 
@@ -45,6 +46,7 @@ func SetupControllers(mgr ctrl.Manager, qManager *queue.Manager, cc *cache.Cache
 	if err := qRec.SetupWithManager(mgr, cfg); err != nil {
 		return "LocalQueue", err
 	}
+	cc.OnClusterQueueUsageChanged = qRec.NotifyClusterQueueUsageChange
 
 	cqRec := NewClusterQueueReconciler(
 		mgr.GetClient(),
@@ -70,6 +72,14 @@ func SetupControllers(mgr ctrl.Manager, qManager *queue.Manager, cc *cache.Cache
 		WithRequeuingBackoffLimitCount(requeuingBackoffLimitCount(cfg))).SetupWithManager(mgr, cfg); err != nil {
 		return "Workload", err
 	}
+	if err := NewBorrowingReconciler(mgr.GetClient(), qManager, cc, *cfg.Namespace, cfg.PauseBorrowing).SetupWithManager(mgr); err != nil {
+		return "Borrowing", err
+	}
+	if features.Enabled(features.FlavorNodeObservation) {
+		if err := NewNodeReconciler(mgr.GetClient(), qManager, cc).SetupWithManager(mgr); err != nil {
+			return "Node", err
+		}
+	}
 	return "", nil
 }
 
@@ -18,6 +18,7 @@ package core
 
 import (
 	"context"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -25,6 +26,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
@@ -55,6 +57,36 @@ type LocalQueueReconciler struct {
 	queues     *queue.Manager
 	cache      *cache.Cache
 	wlUpdateCh chan event.GenericEvent
+	cqUsage    *cqUsageChanges
+}
+
+// cqUsageChanges coalesces the ClusterQueues whose usage changed until the
+// controller handles them, so that the cache never blocks when reporting a
+// change. A single event in the channel stands for all the pending
+// ClusterQueues.
+type cqUsageChanges struct {
+	sync.Mutex
+	cqNames sets.Set[string]
+	ch      chan event.GenericEvent
+}
+
+func (c *cqUsageChanges) add(cqName string) {
+	c.Lock()
+	c.cqNames.Insert(cqName)
+	c.Unlock()
+	select {
+	case c.ch <- event.GenericEvent{Object: &kueue.ClusterQueue{ObjectMeta: metav1.ObjectMeta{Name: cqName}}}:
+	default:
+		// The pending events take care of this ClusterQueue.
+	}
+}
+
+func (c *cqUsageChanges) take() []string {
+	c.Lock()
+	defer c.Unlock()
+	cqNames := sets.List(c.cqNames)
+	c.cqNames = sets.New[string]()
+	return cqNames
 }
 
 func NewLocalQueueReconciler(client client.Client, queues *queue.Manager, cache *cache.Cache) *LocalQueueReconciler {
@@ -64,6 +96,10 @@ func NewLocalQueueReconciler(client client.Client, queues *queue.Manager, cache
 		cache:      cache,
 		client:     client,
 		wlUpdateCh: make(chan event.GenericEvent, updateChBuffer),
+		cqUsage: &cqUsageChanges{
+			cqNames: sets.New[string](),
+			ch:      make(chan event.GenericEvent, updateChBuffer),
+		},
 	}
 }
 
@@ -80,6 +116,12 @@ func (r *LocalQueueReconciler) NotifyWorkloadUpdate(oldWl, newWl *kueue.Workload
 	}
 }
 
+// NotifyClusterQueueUsageChange signals the controller to reconcile the
+// LocalQueues pointing to the ClusterQueue, whose usage changed.
+func (r *LocalQueueReconciler) NotifyClusterQueueUsageChange(cqName string) {
+	r.cqUsage.add(cqName)
+}
+
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;watch;update;patch
 // +kubebuilder:rbac:groups=kueue.x-k8s.io,resources=localqueues,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kueue.x-k8s.io,resources=localqueues/status,verbs=get;update;patch
@@ -160,6 +202,10 @@ func (r *LocalQueueReconciler) Update(e event.UpdateEvent) bool {
 }
 
 func (r *LocalQueueReconciler) Generic(e event.GenericEvent) bool {
+	if cq, isCq := e.Object.(*kueue.ClusterQueue); isCq {
+		r.log.V(3).Info("Got ClusterQueue usage event", "clusterQueue", klog.KObj(cq))
+		return true
+	}
 	r.log.V(3).Info("Got Workload event", "workload", klog.KObj(e.Object))
 	return true
 }
@@ -193,10 +239,13 @@ func (h *qWorkloadHandler) Generic(_ context.Context, e event.GenericEvent, q wo
 	q.AddAfter(req, constants.UpdatesBatchPeriod)
 }
 
-// qCQHandler signals the controller to reconcile the Queue associated
-// to the workload in the event.
+// qCQHandler signals the controller to reconcile the Queues associated
+// to the ClusterQueue in the event.
+// The Generic events come from a channel Source, when the usage of some
+// ClusterQueues changes.
 type qCQHandler struct {
-	client client.Client
+	client  client.Client
+	cqUsage *cqUsageChanges
 }
 
 func (h *qCQHandler) Create(ctx context.Context, e event.CreateEvent, wq workqueue.RateLimitingInterface) {
@@ -232,7 +281,10 @@ func (h *qCQHandler) Delete(ctx context.Context, e event.DeleteEvent, wq workque
 	h.addLocalQueueToWorkQueue(ctx, cq, wq)
 }
 
-func (h *qCQHandler) Generic(context.Context, event.GenericEvent, workqueue.RateLimitingInterface) {
+func (h *qCQHandler) Generic(ctx context.Context, _ event.GenericEvent, wq workqueue.RateLimitingInterface) {
+	for _, cqName := range h.cqUsage.take() {
+		h.addLocalQueueToWorkQueue(ctx, &kueue.ClusterQueue{ObjectMeta: metav1.ObjectMeta{Name: cqName}}, wq)
+	}
 }
 
 func (h *qCQHandler) addLocalQueueToWorkQueue(ctx context.Context, cq *kueue.ClusterQueue, wq workqueue.RateLimitingInterface) {
@@ -253,12 +305,14 @@ func (h *qCQHandler) addLocalQueueToWorkQueue(ctx context.Context, cq *kueue.Clu
 // SetupWithManager sets up the controller with the Manager.
 func (r *LocalQueueReconciler) SetupWithManager(mgr ctrl.Manager, cfg *config.Configuration) error {
 	queueCQHandler := qCQHandler{
-		client: r.client,
+		client:  r.client,
+		cqUsage: r.cqUsage,
 	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kueue.LocalQueue{}).
 		WithOptions(controller.Options{NeedLeaderElection: ptr.To(false)}).
 		WatchesRawSource(&source.Channel{Source: r.wlUpdateCh}, &qWorkloadHandler{}).
+		WatchesRawSource(&source.Channel{Source: r.cqUsage.ch}, &queueCQHandler).
 		Watches(&kueue.ClusterQueue{}, &queueCQHandler).
 		WithEventFilter(r).
 		Complete(WithLeadingManager(mgr, r, &kueue.LocalQueue{}, cfg))
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/queue"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestNotifyClusterQueueUsageChange(t *testing.T) {
+	cl := utiltesting.NewFakeClient(
+		utiltesting.MakeLocalQueue("lq-a", "ns").ClusterQueue("cq-a").Obj(),
+		utiltesting.MakeLocalQueue("lq-b", "ns").ClusterQueue("cq-b").Obj(),
+		utiltesting.MakeLocalQueue("lq-c", "ns").ClusterQueue("cq-c").Obj(),
+	)
+	cqCache := cache.New(cl)
+	reconciler := NewLocalQueueReconciler(cl, queue.NewManager(cl, cqCache), cqCache)
+
+	// More changes than the channel holds don't block the caller.
+	for i := 0; i < 2*updateChBuffer; i++ {
+		reconciler.NotifyClusterQueueUsageChange(fmt.Sprintf("cq-%c", 'a'+i%3))
+	}
+	if got := len(reconciler.cqUsage.ch); got != updateChBuffer {
+		t.Errorf("Unexpected number of events in the channel, got %d, want %d", got, updateChBuffer)
+	}
+
+	handler := qCQHandler{client: cl, cqUsage: reconciler.cqUsage}
+	wq := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer wq.ShutDown()
+	handler.Generic(context.Background(), <-reconciler.cqUsage.ch, wq)
+
+	var got []reconcile.Request
+	for wq.Len() > 0 {
+		item, _ := wq.Get()
+		got = append(got, item.(reconcile.Request))
+		wq.Done(item)
+	}
+	want := []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "lq-a"}},
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "lq-b"}},
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "lq-c"}},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.SortSlices(func(a, b reconcile.Request) bool {
+		return a.Name < b.Name
+	})); diff != "" {
+		t.Errorf("Unexpected reconcile requests (-want,+got):\n%s", diff)
+	}
+
+	// The remaining events find no pending ClusterQueues.
+	handler.Generic(context.Background(), event.GenericEvent{}, wq)
+	if wq.Len() != 0 {
+		t.Errorf("Unexpected reconcile requests for ClusterQueues already handled: %d", wq.Len())
+	}
+}
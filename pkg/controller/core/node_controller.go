@@ -0,0 +1,176 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	"sigs.k8s.io/kueue/pkg/queue"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// NodeReconciler observes the nodes matching the labels of each
+// ResourceFlavor and records in the cache the capacity they provide and the
+// number of topology domains they span.
+type NodeReconciler struct {
+	log      logr.Logger
+	qManager *queue.Manager
+	cache    *cache.Cache
+	client   client.Client
+}
+
+func NewNodeReconciler(
+	client client.Client,
+	qMgr *queue.Manager,
+	cache *cache.Cache,
+) *NodeReconciler {
+	return &NodeReconciler{
+		log:      ctrl.Log.WithName("node-reconciler"),
+		cache:    cache,
+		client:   client,
+		qManager: qMgr,
+	}
+}
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kueue.x-k8s.io,resources=resourceflavors,verbs=get;list;watch
+
+func (r *NodeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx).WithValues("resourceFlavor", klog.KRef("", req.Name))
+	ctx = ctrl.LoggerInto(ctx, log)
+	log.V(2).Info("Reconciling the nodes of ResourceFlavor")
+
+	var flavor kueue.ResourceFlavor
+	if err := r.client.Get(ctx, req.NamespacedName, &flavor); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		r.cache.SetFlavorObservedCapacity(req.Name, nil)
+		r.cache.SetFlavorTopologyDomains(req.Name, 0)
+		return ctrl.Result{}, nil
+	}
+
+	var nodes corev1.NodeList
+	if err := r.client.List(ctx, &nodes, client.MatchingLabels(flavor.Spec.NodeLabels)); err != nil {
+		return ctrl.Result{}, err
+	}
+	capacity := make(cache.Resources)
+	topologyKey := flavor.Annotations[constants.ResourceFlavorTopologyKeyAnnotation]
+	domains := sets.New[string]()
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.Spec.Unschedulable {
+			continue
+		}
+		for name, q := range node.Status.Allocatable {
+			capacity[name] += workload.ResourceValue(name, q)
+		}
+		if domain, found := node.Labels[topologyKey]; topologyKey != "" && found {
+			domains.Insert(domain)
+		}
+	}
+	log.V(3).Info("Observed the nodes of ResourceFlavor", "nodes", len(nodes.Items), "domains", domains.Len())
+	r.cache.SetFlavorObservedCapacity(flavor.Name, capacity)
+	r.cache.SetFlavorTopologyDomains(flavor.Name, domains.Len())
+
+	// The workloads that didn't fit the previous capacity might fit now.
+	if cqNames := r.cache.ClusterQueuesUsingFlavor(flavor.Name); len(cqNames) > 0 {
+		r.qManager.QueueInadmissibleWorkloads(ctx, sets.New(cqNames...))
+	}
+	return ctrl.Result{}, nil
+}
+
+// nodeHandler enqueues the ResourceFlavors whose labels match the node in
+// the event, before or after an update.
+type nodeHandler struct {
+	client client.Client
+}
+
+func (h *nodeHandler) Create(ctx context.Context, e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.enqueueFlavors(ctx, q, e.Object)
+}
+
+func (h *nodeHandler) Update(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	oldNode, match := e.ObjectOld.(*corev1.Node)
+	if !match {
+		return
+	}
+	newNode, match := e.ObjectNew.(*corev1.Node)
+	if !match {
+		return
+	}
+	// Skip the heartbeats and the other status updates that don't change
+	// the observed capacity or domains.
+	if equality.Semantic.DeepEqual(oldNode.Labels, newNode.Labels) &&
+		oldNode.Spec.Unschedulable == newNode.Spec.Unschedulable &&
+		equality.Semantic.DeepEqual(oldNode.Status.Allocatable, newNode.Status.Allocatable) {
+		return
+	}
+	h.enqueueFlavors(ctx, q, oldNode, newNode)
+}
+
+func (h *nodeHandler) Delete(ctx context.Context, e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.enqueueFlavors(ctx, q, e.Object)
+}
+
+func (h *nodeHandler) Generic(context.Context, event.GenericEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *nodeHandler) enqueueFlavors(ctx context.Context, q workqueue.RateLimitingInterface, nodes ...client.Object) {
+	var flavors kueue.ResourceFlavorList
+	if err := h.client.List(ctx, &flavors); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "Listing ResourceFlavors")
+		return
+	}
+	for _, rf := range flavors.Items {
+		selector := labels.SelectorFromSet(rf.Spec.NodeLabels)
+		for _, node := range nodes {
+			if selector.Matches(labels.Set(node.GetLabels())) {
+				q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Name: rf.Name}})
+				break
+			}
+		}
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("resourceflavor-nodes").
+		For(&kueue.ResourceFlavor{}).
+		Watches(&corev1.Node{}, &nodeHandler{client: r.client}).
+		WithOptions(controller.Options{NeedLeaderElection: ptr.To(false)}).
+		Complete(r)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/controller/constants"
+	"sigs.k8s.io/kueue/pkg/queue"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestNodeReconcile(t *testing.T) {
+	const zoneKey = "topology.kubernetes.io/zone"
+	node := func(name, zone, cpu string, unschedulable bool) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{"instance-type": "spot", zoneKey: zone},
+			},
+			Spec: corev1.NodeSpec{Unschedulable: unschedulable},
+			Status: corev1.NodeStatus{
+				Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+			},
+		}
+	}
+	spot := utiltesting.MakeResourceFlavor("spot").Label("instance-type", "spot").Obj()
+	spot.Annotations = map[string]string{constants.ResourceFlavorTopologyKeyAnnotation: zoneKey}
+
+	cases := map[string]struct {
+		flavor       *kueue.ResourceFlavor
+		nodes        []client.Object
+		wantCapacity map[kueue.ResourceFlavorReference]cache.Resources
+		wantDomains  map[kueue.ResourceFlavorReference]int
+	}{
+		"nodes of the flavor": {
+			flavor: spot,
+			nodes: []client.Object{
+				node("a", "zone-a", "4", false),
+				node("b", "zone-b", "2", false),
+				node("c", "zone-b", "500m", false),
+			},
+			wantCapacity: map[kueue.ResourceFlavorReference]cache.Resources{
+				"spot": {corev1.ResourceCPU: 6_500},
+			},
+			wantDomains: map[kueue.ResourceFlavorReference]int{"spot": 2},
+		},
+		"unschedulable nodes are skipped": {
+			flavor: spot,
+			nodes: []client.Object{
+				node("a", "zone-a", "4", false),
+				node("b", "zone-b", "2", true),
+			},
+			wantCapacity: map[kueue.ResourceFlavorReference]cache.Resources{
+				"spot": {corev1.ResourceCPU: 4_000},
+			},
+			wantDomains: map[kueue.ResourceFlavorReference]int{"spot": 1},
+		},
+		"nodes not matching the flavor labels": {
+			flavor: utiltesting.MakeResourceFlavor("spot").Label("instance-type", "on-demand").Obj(),
+			nodes: []client.Object{
+				node("a", "zone-a", "4", false),
+			},
+			wantCapacity: map[kueue.ResourceFlavorReference]cache.Resources{},
+			wantDomains:  map[kueue.ResourceFlavorReference]int{},
+		},
+		"flavor not found": {
+			nodes: []client.Object{
+				node("a", "zone-a", "4", false),
+			},
+			wantCapacity: map[kueue.ResourceFlavorReference]cache.Resources{},
+			wantDomains:  map[kueue.ResourceFlavorReference]int{},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			objs := tc.nodes
+			if tc.flavor != nil {
+				objs = append(objs, tc.flavor)
+			}
+			cl := utiltesting.NewFakeClient(objs...)
+			cqCache := cache.New(cl)
+			qManager := queue.NewManager(cl, cqCache)
+			// A previous observation is cleared when the flavor is gone.
+			cqCache.SetFlavorObservedCapacity("spot", cache.Resources{corev1.ResourceCPU: 1_000})
+			cqCache.SetFlavorTopologyDomains("spot", 3)
+
+			reconciler := NewNodeReconciler(cl, qManager, cqCache)
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "spot"}}
+			if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			snapshot := cqCache.Snapshot()
+			if diff := cmp.Diff(tc.wantCapacity, snapshot.FlavorObservedCapacity); diff != "" {
+				t.Errorf("Unexpected observed capacity (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantDomains, snapshot.FlavorTopologyDomains); diff != "" {
+				t.Errorf("Unexpected topology domains (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
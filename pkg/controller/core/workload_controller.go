@@ -472,6 +472,7 @@ func (r *WorkloadReconciler) Delete(e event.DeleteEvent) bool {
 	// Even if the state is unknown, the last cached state tells us whether the
 	// workload was in the queues and should be cleared from them.
 	r.queues.DeleteWorkload(wl)
+	r.cache.ClearWorkloadState(wl)
 
 	return true
 }
@@ -506,6 +507,10 @@ func (r *WorkloadReconciler) Update(e event.UpdateEvent) bool {
 	}
 	log.V(2).Info("Workload update event")
 
+	if active != ptr.Deref(oldWl.Spec.Active, true) {
+		r.cache.SetWorkloadActive(wl, active)
+	}
+
 	wlCopy := wl.DeepCopy()
 	// We do not handle old workload here as it will be deleted or replaced by new one anyway.
 	workload.AdjustResources(ctrl.LoggerInto(ctx, log), r.client, wlCopy)
@@ -539,6 +544,9 @@ func (r *WorkloadReconciler) Update(e event.UpdateEvent) bool {
 			log.V(2).Info("ClusterQueue for workload didn't exist; ignored for now")
 		}
 	case prevStatus == admitted && status == pending:
+		if cond := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadEvicted); cond != nil && cond.Status == metav1.ConditionTrue && cond.Reason == kueue.WorkloadEvictedByPreemption {
+			r.cache.RequeuePreempted(oldWl)
+		}
 		// trigger the move of associated inadmissibleWorkloads, if there are any.
 		r.queues.QueueAssociatedInadmissibleWorkloadsAfter(ctx, wl, func() {
 			// Delete the workload from cache while holding the queues lock
@@ -18,6 +18,7 @@ package debugger
 
 import (
 	"context"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -62,3 +63,23 @@ func (d *Dumper) DumpAll(ctx context.Context) {
 	d.queues.LogDump(log)
 	log.Info("Ended dump")
 }
+
+// CacheDumpPath is the path, in the metrics server, of the CacheHandler. The
+// handler is only registered with the CacheDebugHandler feature gate.
+const CacheDumpPath = "/debug/kueue/cache"
+
+// CacheHandler serves the state of the cache as JSON.
+// Cache must be set before the handler serves requests.
+type CacheHandler struct {
+	Cache *cache.Cache
+}
+
+func (h *CacheHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	state, err := h.Cache.DumpState()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(state)
+}
@@ -83,6 +83,27 @@ const (
 	//
 	// Enables lending limit.
 	LendingLimit featuregate.Feature = "LendingLimit"
+
+	// owner: @AdrianoKF
+	// alpha: v0.6
+	//
+	// Enables splitting the request of a divisible resource, such as cpu,
+	// across multiple flavors.
+	FlavorSplitting featuregate.Feature = "FlavorSplitting"
+
+	// owner: @AdrianoKF
+	// alpha: v0.6
+	//
+	// Enables observing the capacity and the topology domains of the nodes
+	// of each ResourceFlavor.
+	FlavorNodeObservation featuregate.Feature = "FlavorNodeObservation"
+
+	// owner: @AdrianoKF
+	// alpha: v0.6
+	//
+	// Enables serving the state of the cache as JSON in the
+	// /debug/kueue/cache path of the metrics server.
+	CacheDebugHandler featuregate.Feature = "CacheDebugHandler"
 )
 
 func init() {
@@ -104,6 +125,9 @@ var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
 	PrioritySortingWithinCohort: {Default: true, PreRelease: featuregate.Beta},
 	MultiKueue:                  {Default: false, PreRelease: featuregate.Alpha},
 	LendingLimit:                {Default: false, PreRelease: featuregate.Alpha},
+	FlavorSplitting:             {Default: false, PreRelease: featuregate.Alpha},
+	FlavorNodeObservation:       {Default: false, PreRelease: featuregate.Alpha},
+	CacheDebugHandler:           {Default: false, PreRelease: featuregate.Alpha},
 }
 
 func SetFeatureGateDuringTest(tb testing.TB, f featuregate.Feature, value bool) func() {
@@ -107,6 +107,16 @@ The label 'result' can have the following values:
 		}, []string{"cluster_queue"},
 	)
 
+	AdmissionWaitDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: constants.KueueName,
+			Name:      "admission_wait_duration_seconds",
+			Help:      "The time between a Workload was queued and its transition to admitted, as observed by the cache, per 'cluster_queue'",
+			// 0.1s to ~3.6h
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 18),
+		}, []string{"cluster_queue"},
+	)
+
 	// Metrics tied to the cache.
 
 	ReservingActiveWorkloads = prometheus.NewGaugeVec(
@@ -174,6 +184,22 @@ For a ClusterQueue, the metric only reports a value of 1 for one of the statuses
 			Help:      `Reports the cluster_queue's resource lending limit within all the flavors`,
 		}, []string{"cohort", "cluster_queue", "flavor", "resource"},
 	)
+
+	ClusterQueueSoftQuotaExceeded = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: constants.KueueName,
+			Name:      "cluster_queue_soft_quota_exceeded",
+			Help:      `Reports 1 if the usage of the cluster_queue exceeds its soft quota for the resource in the flavor, and 0 otherwise`,
+		}, []string{"cluster_queue", "flavor", "resource"},
+	)
+
+	CohortBorrowedResources = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: constants.KueueName,
+			Name:      "cohort_borrowed_resources",
+			Help:      `Reports the total amount of resources that the cluster_queues in the cohort borrow above their nominal quota within all the flavors`,
+		}, []string{"cohort", "flavor", "resource"},
+	)
 )
 
 func AdmissionAttempt(result AdmissionResult, duration time.Duration) {
@@ -186,6 +212,10 @@ func AdmittedWorkload(cqName kueue.ClusterQueueReference, waitTime time.Duration
 	admissionWaitTime.WithLabelValues(string(cqName)).Observe(waitTime.Seconds())
 }
 
+func ReportAdmissionWaitDuration(cqName string, waitTime time.Duration) {
+	AdmissionWaitDuration.WithLabelValues(cqName).Observe(waitTime.Seconds())
+}
+
 func ReportPendingWorkloads(cqName string, active, inadmissible int) {
 	PendingWorkloads.WithLabelValues(cqName, PendingStatusActive).Set(float64(active))
 	PendingWorkloads.WithLabelValues(cqName, PendingStatusInadmissible).Set(float64(inadmissible))
@@ -211,9 +241,11 @@ func ReportClusterQueueStatus(cqName string, cqStatus ClusterQueueStatus) {
 func ClearCacheMetrics(cqName string) {
 	ReservingActiveWorkloads.DeleteLabelValues(cqName)
 	AdmittedActiveWorkloads.DeleteLabelValues(cqName)
+	AdmissionWaitDuration.DeleteLabelValues(cqName)
 	for _, status := range CQStatuses {
 		ClusterQueueByStatus.DeleteLabelValues(cqName, string(status))
 	}
+	ClearClusterQueueSoftQuotaExceeded(cqName)
 }
 
 func ReportClusterQueueQuotas(cohort, queue, flavor, resource string, nominal, borrowing, lending float64) {
@@ -288,6 +320,26 @@ func ClearClusterQueueResourceReservations(cqName, flavor, resource string) {
 	ClusterQueueResourceReservations.DeletePartialMatch(lbls)
 }
 
+func ReportClusterQueueSoftQuotaExceeded(cqName, flavor, resource string, exceeded bool) {
+	var v float64
+	if exceeded {
+		v = 1
+	}
+	ClusterQueueSoftQuotaExceeded.WithLabelValues(cqName, flavor, resource).Set(v)
+}
+
+func ClearClusterQueueSoftQuotaExceeded(cqName string) {
+	ClusterQueueSoftQuotaExceeded.DeletePartialMatch(prometheus.Labels{"cluster_queue": cqName})
+}
+
+func ReportCohortBorrowedResources(cohort, flavor, resource string, borrowed float64) {
+	CohortBorrowedResources.WithLabelValues(cohort, flavor, resource).Set(borrowed)
+}
+
+func ClearCohortBorrowedResources(cohort string) {
+	CohortBorrowedResources.DeletePartialMatch(prometheus.Labels{"cohort": cohort})
+}
+
 func Register() {
 	metrics.Registry.MustRegister(
 		AdmissionAttemptsTotal,
@@ -297,10 +349,13 @@ func Register() {
 		AdmittedActiveWorkloads,
 		AdmittedWorkloadsTotal,
 		admissionWaitTime,
+		AdmissionWaitDuration,
 		ClusterQueueResourceUsage,
 		ClusterQueueResourceReservations,
 		ClusterQueueResourceNominalQuota,
 		ClusterQueueResourceBorrowingLimit,
 		ClusterQueueResourceLendingLimit,
+		ClusterQueueSoftQuotaExceeded,
+		CohortBorrowedResources,
 	)
 }
@@ -137,3 +137,16 @@ func TestReportAndCleanupClusterQueueUsage(t *testing.T) {
 	expectFilteredMetricsCount(t, ClusterQueueResourceUsage, 1, "cluster_queue", "queue")
 	expectFilteredMetricsCount(t, ClusterQueueResourceUsage, 0, "cluster_queue", "queue", "flavor", "flavor", "resource", "res2")
 }
+
+func TestReportAndCleanupCohortBorrowedResources(t *testing.T) {
+	ReportCohortBorrowedResources("cohort", "flavor", "res", 5)
+	ReportCohortBorrowedResources("cohort", "flavor2", "res", 0)
+	ReportCohortBorrowedResources("cohort2", "flavor", "res", 1)
+
+	expectFilteredMetricsCount(t, CohortBorrowedResources, 2, "cohort", "cohort")
+
+	ClearCohortBorrowedResources("cohort")
+
+	expectFilteredMetricsCount(t, CohortBorrowedResources, 0, "cohort", "cohort")
+	expectFilteredMetricsCount(t, CohortBorrowedResources, 1, "cohort", "cohort2")
+}
@@ -40,5 +40,5 @@ func newClusterQueueBestEffortFIFO(cq *kueue.ClusterQueue, wo workload.Ordering)
 }
 
 func (cq *ClusterQueueBestEffortFIFO) RequeueIfNotPresent(wInfo *workload.Info, reason RequeueReason) bool {
-	return cq.requeueIfNotPresent(wInfo, reason == RequeueReasonFailedAfterNomination || reason == RequeueReasonPendingPreemption)
+	return cq.requeueIfNotPresent(wInfo, reason == RequeueReasonFailedAfterNomination || reason == RequeueReasonPendingPreemption || reason == RequeueReasonBackoffExpired)
 }
@@ -18,6 +18,7 @@ package queue
 
 import (
 	"context"
+	"maps"
 	"sort"
 	"sync"
 
@@ -46,6 +47,11 @@ type clusterQueueBase struct {
 	// inadmissibleWorkloads are workloads that have been tried at least once and couldn't be admitted.
 	inadmissibleWorkloads map[string]*workload.Info
 
+	// demand is the total requests of the workloads in heap and
+	// inadmissibleWorkloads. It's kept up to date by the methods that add
+	// workloads to them or remove workloads from them.
+	demand workload.Requests
+
 	// popCycle identifies the last call to Pop. It's incremented when calling Pop.
 	// popCycle and queueInadmissibleCycle are used to track when there is a requeuing
 	// of inadmissible workloads while a workload is being scheduled.
@@ -73,6 +79,7 @@ func newClusterQueueImpl(
 	return &clusterQueueBase{
 		heap:                   heap.New(workloadKey, lessFunc),
 		inadmissibleWorkloads:  make(map[string]*workload.Info),
+		demand:                 make(workload.Requests),
 		queueInadmissibleCycle: -1,
 		lessFunc:               lessFunc,
 		rwm:                    sync.RWMutex{},
@@ -102,7 +109,7 @@ func (c *clusterQueueBase) AddFromLocalQueue(q *LocalQueue) bool {
 	defer c.rwm.Unlock()
 	added := false
 	for _, info := range q.items {
-		if c.heap.PushIfNotPresent(info) {
+		if c.pushIfNotPresent(info) {
 			added = true
 		}
 	}
@@ -122,17 +129,19 @@ func (c *clusterQueueBase) PushOrUpdate(wInfo *workload.Info) {
 			equality.Semantic.DeepEqual(oldInfo.Obj.Status.ReclaimablePods, wInfo.Obj.Status.ReclaimablePods) &&
 			equality.Semantic.DeepEqual(apimeta.FindStatusCondition(oldInfo.Obj.Status.Conditions, kueue.WorkloadEvicted),
 				apimeta.FindStatusCondition(wInfo.Obj.Status.Conditions, kueue.WorkloadEvicted)) {
-			c.inadmissibleWorkloads[key] = wInfo
+			c.setInadmissible(key, wInfo)
 			return
 		}
 		// otherwise move or update in place in the queue.
-		delete(c.inadmissibleWorkloads, key)
+		c.deleteInadmissible(key)
 	}
 	if c.heap.GetByKey(key) == nil && !c.backoffWaitingTimeExpired(wInfo) {
-		c.inadmissibleWorkloads[key] = wInfo
+		c.setInadmissible(key, wInfo)
 		return
 	}
+	c.addDemand(c.heap.GetByKey(key), -1)
 	c.heap.PushOrUpdate(wInfo)
+	c.addDemand(wInfo, 1)
 }
 
 // backoffWaitingTimeExpired returns true if the current time is after the requeueAt.
@@ -151,8 +160,8 @@ func (c *clusterQueueBase) backoffWaitingTimeExpired(wInfo *workload.Info) bool
 
 func (c *clusterQueueBase) Delete(w *kueue.Workload) {
 	key := workload.Key(w)
-	delete(c.inadmissibleWorkloads, key)
-	c.heap.Delete(key)
+	c.deleteInadmissible(key)
+	c.deleteFromHeap(key)
 }
 
 func (c *clusterQueueBase) DeleteFromLocalQueue(q *LocalQueue) {
@@ -160,9 +169,7 @@ func (c *clusterQueueBase) DeleteFromLocalQueue(q *LocalQueue) {
 	defer c.rwm.Unlock()
 	for _, w := range q.items {
 		key := workload.Key(w.Obj)
-		if wl := c.inadmissibleWorkloads[key]; wl != nil {
-			delete(c.inadmissibleWorkloads, key)
-		}
+		c.deleteInadmissible(key)
 	}
 	for _, w := range q.items {
 		c.Delete(w.Obj)
@@ -184,9 +191,9 @@ func (c *clusterQueueBase) requeueIfNotPresent(wInfo *workload.Info, immediate b
 		inadmissibleWl := c.inadmissibleWorkloads[key]
 		if inadmissibleWl != nil {
 			wInfo = inadmissibleWl
-			delete(c.inadmissibleWorkloads, key)
+			c.deleteInadmissible(key)
 		}
-		return c.heap.PushIfNotPresent(wInfo)
+		return c.pushIfNotPresent(wInfo)
 	}
 
 	if c.inadmissibleWorkloads[key] != nil {
@@ -197,7 +204,7 @@ func (c *clusterQueueBase) requeueIfNotPresent(wInfo *workload.Info, immediate b
 		return false
 	}
 
-	c.inadmissibleWorkloads[key] = wInfo
+	c.setInadmissible(key, wInfo)
 
 	return true
 }
@@ -220,7 +227,8 @@ func (c *clusterQueueBase) QueueInadmissibleWorkloads(ctx context.Context, clien
 		if err != nil || !c.namespaceSelector.Matches(labels.Set(ns.Labels)) || !c.backoffWaitingTimeExpired(wInfo) {
 			inadmissibleWorkloads[key] = wInfo
 		} else {
-			moved = c.heap.PushIfNotPresent(wInfo) || moved
+			c.addDemand(wInfo, -1)
+			moved = c.pushIfNotPresent(wInfo) || moved
 		}
 	}
 
@@ -250,7 +258,76 @@ func (c *clusterQueueBase) Pop() *workload.Info {
 		return nil
 	}
 
-	return c.heap.Pop()
+	wInfo := c.heap.Pop()
+	c.addDemand(wInfo, -1)
+	return wInfo
+}
+
+func (c *clusterQueueBase) PopFromNamespaces(shares map[string]float64) *workload.Info {
+	c.rwm.Lock()
+	defer c.rwm.Unlock()
+	c.popCycle++
+	var head *workload.Info
+	for _, info := range c.heap.List() {
+		if head == nil {
+			head = info
+			continue
+		}
+		share, headShare := shares[info.Obj.Namespace], shares[head.Obj.Namespace]
+		if share < headShare || (share == headShare && c.lessFunc(info, head)) {
+			head = info
+		}
+	}
+	if head != nil {
+		c.deleteFromHeap(workloadKey(head))
+	}
+	return head
+}
+
+func (c *clusterQueueBase) PendingDemand() workload.Requests {
+	c.rwm.RLock()
+	defer c.rwm.RUnlock()
+	return maps.Clone(c.demand)
+}
+
+func (c *clusterQueueBase) pushIfNotPresent(wInfo *workload.Info) bool {
+	if !c.heap.PushIfNotPresent(wInfo) {
+		return false
+	}
+	c.addDemand(wInfo, 1)
+	return true
+}
+
+func (c *clusterQueueBase) deleteFromHeap(key string) {
+	c.addDemand(c.heap.GetByKey(key), -1)
+	c.heap.Delete(key)
+}
+
+func (c *clusterQueueBase) setInadmissible(key string, wInfo *workload.Info) {
+	c.addDemand(c.inadmissibleWorkloads[key], -1)
+	c.inadmissibleWorkloads[key] = wInfo
+	c.addDemand(wInfo, 1)
+}
+
+func (c *clusterQueueBase) deleteInadmissible(key string) {
+	c.addDemand(c.inadmissibleWorkloads[key], -1)
+	delete(c.inadmissibleWorkloads, key)
+}
+
+// addDemand adds the requests of the workload, if any, to the demand of the
+// ClusterQueue, or subtracts them if sign is -1.
+func (c *clusterQueueBase) addDemand(wInfo *workload.Info, sign int64) {
+	if wInfo == nil {
+		return
+	}
+	for _, psr := range wInfo.TotalRequests {
+		for rName, val := range psr.Requests {
+			c.demand[rName] += sign * val
+			if c.demand[rName] == 0 {
+				delete(c.demand, rName)
+			}
+		}
+	}
 }
 
 func (c *clusterQueueBase) Dump() ([]string, bool) {
@@ -287,6 +364,20 @@ func (c *clusterQueueBase) Snapshot() []*workload.Info {
 	return elements
 }
 
+func (c *clusterQueueBase) SnapshotOrdered() []*workload.Info {
+	c.rwm.RLock()
+	defer c.rwm.RUnlock()
+	elements := c.heap.Ordered()
+	inadmissible := make([]*workload.Info, 0, len(c.inadmissibleWorkloads))
+	for _, e := range c.inadmissibleWorkloads {
+		inadmissible = append(inadmissible, e)
+	}
+	sort.Slice(inadmissible, func(i, j int) bool {
+		return c.lessFunc(inadmissible[i], inadmissible[j])
+	})
+	return append(elements, inadmissible...)
+}
+
 func (c *clusterQueueBase) Info(key string) *workload.Info {
 	c.rwm.RLock()
 	defer c.rwm.RUnlock()
@@ -18,6 +18,7 @@ package queue
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -137,6 +138,52 @@ func Test_Pop(t *testing.T) {
 	}
 }
 
+func Test_PopFromNamespaces(t *testing.T) {
+	now := time.Now()
+	cq := newClusterQueueImpl(defaultQueueOrderingFunc, testingclock.NewFakeClock(now))
+	if cq.PopFromNamespaces(nil) != nil {
+		t.Error("ClusterQueue should be empty")
+	}
+	cq.PushOrUpdate(workload.NewInfo(utiltesting.MakeWorkload("busy-1", "busy").Creation(now).Obj()))
+	cq.PushOrUpdate(workload.NewInfo(utiltesting.MakeWorkload("idle-1", "idle").Creation(now.Add(time.Second)).Obj()))
+	cq.PushOrUpdate(workload.NewInfo(utiltesting.MakeWorkload("idle-2", "idle").Creation(now.Add(2 * time.Second)).Obj()))
+	shares := map[string]float64{"busy": 0.8}
+	var got []string
+	for wl := cq.PopFromNamespaces(shares); wl != nil; wl = cq.PopFromNamespaces(shares) {
+		got = append(got, workload.Key(wl.Obj))
+	}
+	if diff := cmp.Diff([]string{"idle/idle-1", "idle/idle-2", "busy/busy-1"}, got); diff != "" {
+		t.Errorf("Unexpected order (-want,+got):\n%s", diff)
+	}
+}
+
+func Test_SnapshotOrdered(t *testing.T) {
+	now := time.Now()
+	cq := newClusterQueueImpl(defaultQueueOrderingFunc, testingclock.NewFakeClock(now))
+	for i, prio := range []int32{1, 5, 1, 3, 5, 3} {
+		cq.PushOrUpdate(workload.NewInfo(utiltesting.MakeWorkload(fmt.Sprintf("workload-%d", i), defaultNamespace).
+			Priority(prio).
+			Creation(now).
+			Obj()))
+	}
+	inadmissible := workload.NewInfo(utiltesting.MakeWorkload("inadmissible", defaultNamespace).Priority(10).Creation(now).Obj())
+	cq.requeueIfNotPresent(inadmissible, false)
+
+	snapshot := cq.SnapshotOrdered()
+	var gotKeys []string
+	for _, wl := range snapshot {
+		gotKeys = append(gotKeys, workload.Key(wl.Obj))
+	}
+	var wantKeys []string
+	for wl := cq.Pop(); wl != nil; wl = cq.Pop() {
+		wantKeys = append(wantKeys, workload.Key(wl.Obj))
+	}
+	wantKeys = append(wantKeys, workload.Key(inadmissible.Obj))
+	if diff := cmp.Diff(wantKeys, gotKeys); diff != "" {
+		t.Errorf("Unexpected order (-want,+got):\n%s", diff)
+	}
+}
+
 func Test_Delete(t *testing.T) {
 	cq := newClusterQueueImpl(defaultQueueOrderingFunc, testingclock.NewFakeClock(time.Now()))
 	wl1 := utiltesting.MakeWorkload("workload-1", defaultNamespace).Obj()
@@ -437,6 +484,72 @@ func TestQueueInadmissibleWorkloadsDuringScheduling(t *testing.T) {
 	}
 }
 
+func TestPendingDemand(t *testing.T) {
+	cq := newClusterQueueImpl(defaultQueueOrderingFunc, testingclock.NewFakeClock(time.Now()))
+	cq.namespaceSelector = labels.Everything()
+	cl := utiltesting.NewFakeClient(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: defaultNamespace}})
+	ctx := context.Background()
+	small := utiltesting.MakeWorkload("small", defaultNamespace).Request(corev1.ResourceCPU, "1").Obj()
+	big := utiltesting.MakeWorkload("big", defaultNamespace).Request(corev1.ResourceCPU, "4").Obj()
+
+	steps := []struct {
+		name string
+		do   func()
+		want workload.Requests
+	}{
+		{
+			name: "pushed",
+			do: func() {
+				cq.PushOrUpdate(workload.NewInfo(small))
+				cq.PushOrUpdate(workload.NewInfo(big))
+			},
+			want: workload.Requests{corev1.ResourceCPU: 5_000},
+		},
+		{
+			name: "updated",
+			do: func() {
+				cq.PushOrUpdate(workload.NewInfo(utiltesting.MakeWorkload("small", defaultNamespace).Request(corev1.ResourceCPU, "2").Obj()))
+			},
+			want: workload.Requests{corev1.ResourceCPU: 6_000},
+		},
+		{
+			name: "popped",
+			do: func() {
+				head := cq.Pop()
+				cq.requeueIfNotPresent(head, false)
+			},
+			want: workload.Requests{corev1.ResourceCPU: 6_000},
+		},
+		{
+			name: "inadmissible requeued",
+			do: func() {
+				cq.QueueInadmissibleWorkloads(ctx, cl)
+			},
+			want: workload.Requests{corev1.ResourceCPU: 6_000},
+		},
+		{
+			name: "deleted",
+			do: func() {
+				cq.Delete(big)
+			},
+			want: workload.Requests{corev1.ResourceCPU: 2_000},
+		},
+		{
+			name: "all popped",
+			do: func() {
+				cq.Pop()
+			},
+			want: workload.Requests{},
+		},
+	}
+	for _, step := range steps {
+		step.do()
+		if diff := cmp.Diff(step.want, cq.PendingDemand()); diff != "" {
+			t.Errorf("Unexpected demand after the workloads were %s (-want,+got):\n%s", step.name, diff)
+		}
+	}
+}
+
 func TestBackoffWaitingTimeExpired(t *testing.T) {
 	now := time.Now()
 	minuteLater := now.Add(time.Minute)
@@ -33,6 +33,9 @@ const (
 	RequeueReasonNamespaceMismatch     RequeueReason = "NamespaceMismatch"
 	RequeueReasonGeneric               RequeueReason = ""
 	RequeueReasonPendingPreemption     RequeueReason = "PendingPreemption"
+	// RequeueReasonBackoffExpired is used when the admission backoff of the
+	// workload elapsed, so it has to be retried right away.
+	RequeueReasonBackoffExpired RequeueReason = "BackoffExpired"
 )
 
 // ClusterQueue is an interface for a cluster queue to store workloads waiting
@@ -59,6 +62,11 @@ type ClusterQueue interface {
 	// Pop removes the head of the queue and returns it. It returns nil if the
 	// queue is empty.
 	Pop() *workload.Info
+	// PopFromNamespaces removes and returns the first workload of the
+	// namespace with the lowest share, among the namespaces with workloads in
+	// the queue. Namespaces missing from the shares have a share of 0. It
+	// returns nil if the queue is empty.
+	PopFromNamespaces(shares map[string]float64) *workload.Info
 
 	// RequeueIfNotPresent inserts a workload that was not
 	// admitted back into the ClusterQueue. If the boolean is true,
@@ -85,6 +93,9 @@ type ClusterQueue interface {
 	// workloads that were already tried and are waiting for cluster conditions
 	// to change to potentially become admissible.
 	PendingInadmissible() int
+	// PendingDemand returns the total requests of the pending workloads,
+	// including the inadmissible ones.
+	PendingDemand() workload.Requests
 
 	// Dump produces a dump of the current workloads in the heap of
 	// this ClusterQueue. It returns false if the queue is empty,
@@ -94,6 +105,10 @@ type ClusterQueue interface {
 	// Snapshot returns a copy of the current workloads in the heap of
 	// this ClusterQueue.
 	Snapshot() []*workload.Info
+	// SnapshotOrdered returns a copy of the current workloads in the heap of
+	// this ClusterQueue in the order in which Pop would return them, followed
+	// by the inadmissible workloads, which are only popped after being requeued.
+	SnapshotOrdered() []*workload.Info
 	// Info returns workload.Info for the workload key.
 	// Users of this method should not modify the returned object.
 	Info(string) *workload.Info
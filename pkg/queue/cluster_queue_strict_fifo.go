@@ -20,7 +20,6 @@ import (
 	"k8s.io/utils/clock"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
-	utilpriority "sigs.k8s.io/kueue/pkg/util/priority"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
 
@@ -49,20 +48,9 @@ func newClusterQueueStrictFIFO(cq *kueue.ClusterQueue, wo workload.Ordering) (Cl
 // queueOrderingFunc returns a function used by the clusterQueue heap algorithm
 // to sort workloads. The function sorts workloads based on their priority.
 // When priorities are equal, it uses the workload's creation or eviction
-// time.
+// time, and then its namespace and name. See workload.Ordering.Less.
 func queueOrderingFunc(wo workload.Ordering) func(a, b *workload.Info) bool {
-	return func(a, b *workload.Info) bool {
-		p1 := utilpriority.Priority(a.Obj)
-		p2 := utilpriority.Priority(b.Obj)
-
-		if p1 != p2 {
-			return p1 > p2
-		}
-
-		tA := wo.GetQueueOrderTimestamp(a.Obj)
-		tB := wo.GetQueueOrderTimestamp(b.Obj)
-		return !tB.Before(tA)
-	}
+	return wo.Less
 }
 
 // RequeueIfNotPresent requeues if the workload is not present.
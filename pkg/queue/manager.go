@@ -21,10 +21,13 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -43,6 +46,9 @@ var (
 
 type options struct {
 	podsReadyRequeuingTimestamp config.RequeuingTimestamp
+	namespaceFairness           NamespaceFairness
+	pendingDemandRecorder       PendingDemandRecorder
+	clock                       clock.WithDelayedExecution
 }
 
 // Option configures the manager.
@@ -50,6 +56,7 @@ type Option func(*options)
 
 var defaultOptions = options{
 	podsReadyRequeuingTimestamp: config.EvictionTimestamp,
+	clock:                       clock.RealClock{},
 }
 
 // WithPodsReadyRequeuingTimestamp sets the timestamp that is used for ordering
@@ -60,18 +67,54 @@ func WithPodsReadyRequeuingTimestamp(ts config.RequeuingTimestamp) Option {
 	}
 }
 
+// WithNamespaceFairness makes the heads of the ClusterQueues be the first
+// pending workloads of the namespaces using the lowest share of each
+// ClusterQueue, according to the NamespaceFairness, so that a single namespace
+// can't monopolize the capacity of a ClusterQueue.
+func WithNamespaceFairness(f NamespaceFairness) Option {
+	return func(o *options) {
+		o.namespaceFairness = f
+	}
+}
+
+// WithPendingDemandRecorder reports the total requests of the pending
+// workloads of each ClusterQueue to the PendingDemandRecorder whenever they
+// change.
+func WithPendingDemandRecorder(r PendingDemandRecorder) Option {
+	return func(o *options) {
+		o.pendingDemandRecorder = r
+	}
+}
+
+// WithClock sets the clock used by the manager to delay requeues.
+func WithClock(c clock.WithDelayedExecution) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
 type Manager struct {
 	sync.RWMutex
 	cond sync.Cond
 
-	client        client.Client
-	statusChecker StatusChecker
-	clusterQueues map[string]ClusterQueue
-	localQueues   map[string]*LocalQueue
+	client            client.Client
+	statusChecker     StatusChecker
+	namespaceFairness NamespaceFairness
+	pendingDemand     PendingDemandRecorder
+	clock             clock.WithDelayedExecution
+	clusterQueues     map[string]ClusterQueue
+	localQueues       map[string]*LocalQueue
 
 	snapshotsMutex sync.RWMutex
 	snapshots      map[string][]kueue.ClusterQueuePendingWorkload
 
+	// demandUpdates holds the last pending demand of the ClusterQueues that
+	// changed since publishPendingDemand last reported them to pendingDemand.
+	demandMutex   sync.Mutex
+	demandUpdates map[string]workload.Requests
+	// publishMutex keeps the reports to pendingDemand in order.
+	publishMutex sync.Mutex
+
 	// Key is cohort's name. Value is a set of associated ClusterQueue names.
 	cohorts map[string]sets.Set[string]
 
@@ -84,13 +127,17 @@ func NewManager(client client.Client, checker StatusChecker, opts ...Option) *Ma
 		opt(&options)
 	}
 	m := &Manager{
-		client:         client,
-		statusChecker:  checker,
-		localQueues:    make(map[string]*LocalQueue),
-		clusterQueues:  make(map[string]ClusterQueue),
-		cohorts:        make(map[string]sets.Set[string]),
-		snapshotsMutex: sync.RWMutex{},
-		snapshots:      make(map[string][]kueue.ClusterQueuePendingWorkload, 0),
+		client:            client,
+		statusChecker:     checker,
+		namespaceFairness: options.namespaceFairness,
+		pendingDemand:     options.pendingDemandRecorder,
+		clock:             options.clock,
+		localQueues:       make(map[string]*LocalQueue),
+		clusterQueues:     make(map[string]ClusterQueue),
+		cohorts:           make(map[string]sets.Set[string]),
+		snapshotsMutex:    sync.RWMutex{},
+		snapshots:         make(map[string][]kueue.ClusterQueuePendingWorkload, 0),
+		demandUpdates:     make(map[string]workload.Requests),
 		workloadOrdering: workload.Ordering{
 			PodsReadyRequeuingTimestamp: options.podsReadyRequeuingTimestamp,
 		},
@@ -100,6 +147,7 @@ func NewManager(client client.Client, checker StatusChecker, opts ...Option) *Ma
 }
 
 func (m *Manager) AddClusterQueue(ctx context.Context, cq *kueue.ClusterQueue) error {
+	defer m.publishPendingDemand()
 	m.Lock()
 	defer m.Unlock()
 
@@ -142,6 +190,7 @@ func (m *Manager) AddClusterQueue(ctx context.Context, cq *kueue.ClusterQueue) e
 }
 
 func (m *Manager) UpdateClusterQueue(ctx context.Context, cq *kueue.ClusterQueue, specUpdated bool) error {
+	defer m.publishPendingDemand()
 	m.Lock()
 	defer m.Unlock()
 	cqImpl, ok := m.clusterQueues[cq.Name]
@@ -184,6 +233,7 @@ func (m *Manager) DeleteClusterQueue(cq *kueue.ClusterQueue) {
 }
 
 func (m *Manager) AddLocalQueue(ctx context.Context, q *kueue.LocalQueue) error {
+	defer m.publishPendingDemand()
 	m.Lock()
 	defer m.Unlock()
 
@@ -209,12 +259,14 @@ func (m *Manager) AddLocalQueue(ctx context.Context, q *kueue.LocalQueue) error
 	}
 	cq := m.clusterQueues[qImpl.ClusterQueue]
 	if cq != nil && cq.AddFromLocalQueue(qImpl) {
+		m.reportPendingWorkloads(qImpl.ClusterQueue, cq)
 		m.Broadcast()
 	}
 	return nil
 }
 
 func (m *Manager) UpdateLocalQueue(q *kueue.LocalQueue) error {
+	defer m.publishPendingDemand()
 	m.Lock()
 	defer m.Unlock()
 	qImpl, ok := m.localQueues[Key(q)]
@@ -225,9 +277,11 @@ func (m *Manager) UpdateLocalQueue(q *kueue.LocalQueue) error {
 		oldCQ := m.clusterQueues[qImpl.ClusterQueue]
 		if oldCQ != nil {
 			oldCQ.DeleteFromLocalQueue(qImpl)
+			m.reportPendingWorkloads(qImpl.ClusterQueue, oldCQ)
 		}
 		newCQ := m.clusterQueues[string(q.Spec.ClusterQueue)]
 		if newCQ != nil && newCQ.AddFromLocalQueue(qImpl) {
+			m.reportPendingWorkloads(string(q.Spec.ClusterQueue), newCQ)
 			m.Broadcast()
 		}
 	}
@@ -236,6 +290,7 @@ func (m *Manager) UpdateLocalQueue(q *kueue.LocalQueue) error {
 }
 
 func (m *Manager) DeleteLocalQueue(q *kueue.LocalQueue) {
+	defer m.publishPendingDemand()
 	m.Lock()
 	defer m.Unlock()
 	key := Key(q)
@@ -246,6 +301,7 @@ func (m *Manager) DeleteLocalQueue(q *kueue.LocalQueue) {
 	cq := m.clusterQueues[qImpl.ClusterQueue]
 	if cq != nil {
 		cq.DeleteFromLocalQueue(qImpl)
+		m.reportPendingWorkloads(qImpl.ClusterQueue, cq)
 	}
 	delete(m.localQueues, key)
 }
@@ -292,6 +348,7 @@ func (m *Manager) ClusterQueueForWorkload(wl *kueue.Workload) (string, bool) {
 // AddOrUpdateWorkload adds or updates workload to the corresponding queue.
 // Returns whether the queue existed.
 func (m *Manager) AddOrUpdateWorkload(w *kueue.Workload) bool {
+	defer m.publishPendingDemand()
 	m.Lock()
 	defer m.Unlock()
 	return m.addOrUpdateWorkload(w)
@@ -319,6 +376,7 @@ func (m *Manager) addOrUpdateWorkload(w *kueue.Workload) bool {
 // workload still exist in the client cache and not admitted. It won't
 // requeue if the workload is already in the queue (possible if the workload was updated).
 func (m *Manager) RequeueWorkload(ctx context.Context, info *workload.Info, reason RequeueReason) bool {
+	defer m.publishPendingDemand()
 	m.Lock()
 	defer m.Unlock()
 
@@ -349,10 +407,21 @@ func (m *Manager) RequeueWorkload(ctx context.Context, info *workload.Info, reas
 	return added
 }
 
+// RequeueWorkloadAfter requeues the workload, as RequeueWorkload does, once
+// the delay elapses, so that a workload that can't be admitted before then,
+// such as one in admission backoff, is neither retried in the meantime nor
+// left waiting for an unrelated event.
+func (m *Manager) RequeueWorkloadAfter(ctx context.Context, info *workload.Info, delay time.Duration) {
+	m.clock.AfterFunc(delay, func() {
+		m.RequeueWorkload(ctx, info, RequeueReasonBackoffExpired)
+	})
+}
+
 func (m *Manager) DeleteWorkload(w *kueue.Workload) {
 	m.Lock()
 	m.deleteWorkloadFromQueueAndClusterQueue(w, workload.QueueKey(w))
 	m.Unlock()
+	m.publishPendingDemand()
 }
 
 func (m *Manager) deleteWorkloadFromQueueAndClusterQueue(w *kueue.Workload, qKey string) {
@@ -449,6 +518,7 @@ func (m *Manager) queueAllInadmissibleWorkloadsInCohort(ctx context.Context, cq
 // UpdateWorkload updates the workload to the corresponding queue or adds it if
 // it didn't exist. Returns whether the queue existed.
 func (m *Manager) UpdateWorkload(oldW, w *kueue.Workload) bool {
+	defer m.publishPendingDemand()
 	m.Lock()
 	defer m.Unlock()
 	if oldW.Spec.QueueName != w.Spec.QueueName {
@@ -468,6 +538,7 @@ func (m *Manager) CleanUpOnContext(ctx context.Context) {
 // Heads returns the heads of the queues, along with their associated ClusterQueue.
 // It blocks if the queues empty until they have elements or the context terminates.
 func (m *Manager) Heads(ctx context.Context) []workload.Info {
+	defer m.publishPendingDemand()
 	m.Lock()
 	defer m.Unlock()
 	log := ctrl.LoggerFrom(ctx)
@@ -493,7 +564,12 @@ func (m *Manager) heads() []workload.Info {
 		if m.statusChecker != nil && !m.statusChecker.ClusterQueueActive(cqName) {
 			continue
 		}
-		wl := cq.Pop()
+		var wl *workload.Info
+		if m.namespaceFairness != nil {
+			wl = cq.PopFromNamespaces(m.namespaceFairness.NamespaceShares(cqName))
+		} else {
+			wl = cq.Pop()
+		}
 		if wl == nil {
 			continue
 		}
@@ -543,6 +619,29 @@ func (m *Manager) reportPendingWorkloads(cqName string, cq ClusterQueue) {
 		active = 0
 	}
 	metrics.ReportPendingWorkloads(cqName, active, inadmissible)
+	if m.pendingDemand != nil {
+		m.demandMutex.Lock()
+		m.demandUpdates[cqName] = cq.PendingDemand()
+		m.demandMutex.Unlock()
+	}
+}
+
+// publishPendingDemand reports the pending demand of the ClusterQueues that
+// changed to the PendingDemandRecorder. It must be called without holding the
+// lock of the manager, so that the recorder can take its own locks.
+func (m *Manager) publishPendingDemand() {
+	if m.pendingDemand == nil {
+		return
+	}
+	m.publishMutex.Lock()
+	defer m.publishMutex.Unlock()
+	m.demandMutex.Lock()
+	updates := m.demandUpdates
+	m.demandUpdates = make(map[string]workload.Requests)
+	m.demandMutex.Unlock()
+	for cqName, demand := range updates {
+		m.pendingDemand.SetPendingDemand(cqName, demand)
+	}
 }
 
 func (m *Manager) GetClusterQueueNames() []string {
@@ -569,6 +668,39 @@ func (m *Manager) PendingWorkloadsInfo(cqName string) []*workload.Info {
 	return cq.Snapshot()
 }
 
+// LocalQueuePendingCount returns the number of pending workloads, including
+// the inadmissible ones, submitted to the LocalQueue namespace/queueName.
+func (m *Manager) LocalQueuePendingCount(namespace, queueName string) int {
+	m.RLock()
+	q, found := m.localQueues[fmt.Sprintf("%s/%s", namespace, queueName)]
+	m.RUnlock()
+	if !found {
+		return 0
+	}
+	count := 0
+	for _, wInfo := range m.PendingWorkloadsInfo(q.ClusterQueue) {
+		if wInfo.Obj.Namespace == namespace && wInfo.Obj.Spec.QueueName == queueName {
+			count++
+		}
+	}
+	return count
+}
+
+// PendingWorkloadsOrdered returns the pending workloads of the ClusterQueue in
+// the order in which they are going to be tried for admission.
+func (m *Manager) PendingWorkloadsOrdered(cqName string) []*workload.Info {
+	cq := m.getClusterQueue(cqName)
+	if cq == nil {
+		return nil
+	}
+	return cq.SnapshotOrdered()
+}
+
+// QueuedAt returns the timestamp used to order the workload in its queue.
+func (m *Manager) QueuedAt(w *kueue.Workload) metav1.Time {
+	return *m.workloadOrdering.GetQueueOrderTimestamp(w)
+}
+
 func (m *Manager) ClusterQueueFromLocalQueue(lqName string) (string, error) {
 	if lq, ok := m.localQueues[lqName]; ok {
 		return lq.ClusterQueue, nil
@@ -1119,6 +1119,55 @@ func (c *fakeStatusChecker) ClusterQueueActive(name string) bool {
 	return strings.Contains(name, "active-")
 }
 
+// fakePendingDemandRecorder records the last demand of each ClusterQueue and
+// whether it was ever called while the lock of the manager was held.
+type fakePendingDemandRecorder struct {
+	manager      *Manager
+	demand       map[string]workload.Requests
+	calledLocked bool
+}
+
+func (f *fakePendingDemandRecorder) SetPendingDemand(cqName string, demand workload.Requests) {
+	if f.manager.TryLock() {
+		f.manager.Unlock()
+	} else {
+		f.calledLocked = true
+	}
+	f.demand[cqName] = demand
+}
+
+type fakeNamespaceFairness map[string]float64
+
+func (f fakeNamespaceFairness) NamespaceShares(string) map[string]float64 {
+	return f
+}
+
+func TestHeadsWithNamespaceFairness(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), headsTimeout)
+	defer cancel()
+	now := time.Now().Truncate(time.Second)
+	manager := NewManager(utiltesting.NewFakeClient(), nil, WithNamespaceFairness(fakeNamespaceFairness{"busy": 0.9, "idle": 0.1}))
+	if err := manager.AddClusterQueue(ctx, utiltesting.MakeClusterQueue("cq").Obj()); err != nil {
+		t.Fatalf("Failed adding clusterQueue: %v", err)
+	}
+	for _, ns := range []string{"busy", "idle"} {
+		if err := manager.AddLocalQueue(ctx, utiltesting.MakeLocalQueue("foo", ns).ClusterQueue("cq").Obj()); err != nil {
+			t.Fatalf("Failed adding queue: %v", err)
+		}
+	}
+	manager.AddOrUpdateWorkload(utiltesting.MakeWorkload("a", "busy").Creation(now).Queue("foo").Obj())
+	manager.AddOrUpdateWorkload(utiltesting.MakeWorkload("b", "idle").Creation(now.Add(time.Hour)).Queue("foo").Obj())
+
+	go manager.CleanUpOnContext(ctx)
+	var got []string
+	for _, h := range manager.Heads(ctx) {
+		got = append(got, workload.Key(h.Obj))
+	}
+	if diff := cmp.Diff([]string{"idle/b"}, got); diff != "" {
+		t.Errorf("Unexpected heads (-want,+got):\n%s", diff)
+	}
+}
+
 func TestGetPendingWorkloadsInfo(t *testing.T) {
 	now := time.Now().Truncate(time.Second)
 
@@ -1175,6 +1224,7 @@ func TestGetPendingWorkloadsInfo(t *testing.T) {
 							QueueName: "foo",
 						},
 					},
+					TerminationGracePeriod: 30 * time.Second,
 				},
 				{
 					Obj: &kueue.Workload{
@@ -1186,6 +1236,7 @@ func TestGetPendingWorkloadsInfo(t *testing.T) {
 							QueueName: "foo",
 						},
 					},
+					TerminationGracePeriod: 30 * time.Second,
 				},
 			},
 		},
@@ -1204,3 +1255,143 @@ func TestGetPendingWorkloadsInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestLocalQueuePendingCount(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	ctx := context.Background()
+	cq := utiltesting.MakeClusterQueue("cq").Obj()
+	queues := []*kueue.LocalQueue{
+		utiltesting.MakeLocalQueue("lq", "ns1").ClusterQueue("cq").Obj(),
+		utiltesting.MakeLocalQueue("lq", "ns2").ClusterQueue("cq").Obj(),
+		utiltesting.MakeLocalQueue("other", "ns1").ClusterQueue("cq").Obj(),
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("a", "ns1").Queue("lq").Creation(now).Obj(),
+		utiltesting.MakeWorkload("b", "ns1").Queue("lq").Creation(now.Add(time.Second)).Obj(),
+		utiltesting.MakeWorkload("c", "ns1").Queue("other").Creation(now.Add(time.Second)).Obj(),
+		utiltesting.MakeWorkload("a", "ns2").Queue("lq").Creation(now.Add(time.Second)).Obj(),
+		utiltesting.MakeWorkload("reserved", "ns1").Queue("lq").Creation(now.Add(time.Second)).
+			ReserveQuota(utiltesting.MakeAdmission("cq").Obj()).Obj(),
+	}
+	cl := utiltesting.NewFakeClient()
+	for _, w := range workloads {
+		if err := cl.Create(ctx, w); err != nil {
+			t.Fatalf("Failed adding workload %s to the client: %v", workload.Key(w), err)
+		}
+	}
+	manager := NewManager(cl, nil)
+	if err := manager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Failed adding clusterQueue %s: %v", cq.Name, err)
+	}
+	for _, q := range queues {
+		if err := manager.AddLocalQueue(ctx, q); err != nil {
+			t.Fatalf("Failed adding queue %s: %v", q.Name, err)
+		}
+	}
+	// Make ns1/a inadmissible.
+	heads := manager.Heads(ctx)
+	if len(heads) != 1 || workload.Key(heads[0].Obj) != "ns1/a" {
+		t.Fatalf("Unexpected heads: %v", heads)
+	}
+	manager.RequeueWorkload(ctx, &heads[0], RequeueReasonGeneric)
+	if diff := cmp.Diff(map[string][]string{"cq": {"ns1/a"}}, manager.DumpInadmissible()); diff != "" {
+		t.Fatalf("Unexpected inadmissible workloads (-want,+got):\n%s", diff)
+	}
+
+	cases := map[string]struct {
+		namespace string
+		queueName string
+		want      int
+	}{
+		"queue in first namespace": {
+			namespace: "ns1",
+			queueName: "lq",
+			want:      2,
+		},
+		"queue with the same name in second namespace": {
+			namespace: "ns2",
+			queueName: "lq",
+			want:      1,
+		},
+		"other queue": {
+			namespace: "ns1",
+			queueName: "other",
+			want:      1,
+		},
+		"unknown queue": {
+			namespace: "ns2",
+			queueName: "other",
+			want:      0,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := manager.LocalQueuePendingCount(tc.namespace, tc.queueName); got != tc.want {
+				t.Errorf("Unexpected pending count, got %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPendingDemandRecorder(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	ctx := context.Background()
+	cq := utiltesting.MakeClusterQueue("cq").Obj()
+	q := utiltesting.MakeLocalQueue("lq", "ns").ClusterQueue("cq").Obj()
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("a", "ns").Queue("lq").Creation(now).
+			Request(corev1.ResourceCPU, "1").Obj(),
+		utiltesting.MakeWorkload("b", "ns").Queue("lq").Creation(now.Add(time.Second)).
+			PodSets(*utiltesting.MakePodSet("main", 2).Request(corev1.ResourceCPU, "2").Request(corev1.ResourceMemory, "1Gi").Obj()).Obj(),
+	}
+	cl := utiltesting.NewFakeClient()
+	for _, w := range workloads {
+		if err := cl.Create(ctx, w); err != nil {
+			t.Fatalf("Failed adding workload %s to the client: %v", workload.Key(w), err)
+		}
+	}
+	recorder := &fakePendingDemandRecorder{demand: make(map[string]workload.Requests)}
+	manager := NewManager(cl, nil, WithPendingDemandRecorder(recorder))
+	recorder.manager = manager
+	if err := manager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Failed adding clusterQueue %s: %v", cq.Name, err)
+	}
+	if err := manager.AddLocalQueue(ctx, q); err != nil {
+		t.Fatalf("Failed adding queue %s: %v", q.Name, err)
+	}
+	wantDemand := map[string]workload.Requests{
+		"cq": {corev1.ResourceCPU: 5_000, corev1.ResourceMemory: 2 * utiltesting.Gi},
+	}
+	if diff := cmp.Diff(wantDemand, recorder.demand); diff != "" {
+		t.Errorf("Unexpected demand after adding the workloads (-want,+got):\n%s", diff)
+	}
+
+	// The inadmissible workloads are still pending.
+	heads := manager.Heads(ctx)
+	if len(heads) != 1 || workload.Key(heads[0].Obj) != "ns/a" {
+		t.Fatalf("Unexpected heads: %v", heads)
+	}
+	manager.RequeueWorkload(ctx, &heads[0], RequeueReasonGeneric)
+	if diff := cmp.Diff(wantDemand, recorder.demand); diff != "" {
+		t.Errorf("Unexpected demand after requeueing a workload (-want,+got):\n%s", diff)
+	}
+
+	manager.DeleteWorkload(workloads[1])
+	wantDemand = map[string]workload.Requests{
+		"cq": {corev1.ResourceCPU: 1_000},
+	}
+	if diff := cmp.Diff(wantDemand, recorder.demand); diff != "" {
+		t.Errorf("Unexpected demand after deleting a workload (-want,+got):\n%s", diff)
+	}
+
+	manager.DeleteWorkload(workloads[0])
+	wantDemand = map[string]workload.Requests{
+		"cq": {},
+	}
+	if diff := cmp.Diff(wantDemand, recorder.demand); diff != "" {
+		t.Errorf("Unexpected demand after deleting all the workloads (-want,+got):\n%s", diff)
+	}
+	if recorder.calledLocked {
+		t.Error("The demand was reported while holding the lock of the manager")
+	}
+}
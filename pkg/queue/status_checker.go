@@ -16,8 +16,25 @@ limitations under the License.
 
 package queue
 
+import "sigs.k8s.io/kueue/pkg/workload"
+
 // StatusChecker checks status of clusterQueue.
 type StatusChecker interface {
 	// ClusterQueueActive returns whether the clusterQueue is active.
 	ClusterQueueActive(name string) bool
 }
+
+// NamespaceFairness reports how much of a clusterQueue each namespace uses.
+type NamespaceFairness interface {
+	// NamespaceShares returns, keyed by namespace, the share of the
+	// clusterQueue used by the workloads of each namespace.
+	NamespaceShares(cqName string) map[string]float64
+}
+
+// PendingDemandRecorder receives the total requests of the pending workloads
+// of each clusterQueue.
+type PendingDemandRecorder interface {
+	// SetPendingDemand records the total requests of the pending workloads of
+	// the clusterQueue.
+	SetPendingDemand(cqName string, demand workload.Requests)
+}
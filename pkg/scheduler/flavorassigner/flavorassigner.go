@@ -19,6 +19,8 @@ package flavorassigner
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"sort"
 	"strings"
 
@@ -38,6 +40,11 @@ import (
 	"sigs.k8s.io/kueue/pkg/workload"
 )
 
+var (
+	errUnknownPinnedFlavor      = errors.New("unknown pinned flavor")
+	errIncompatiblePinnedFlavor = errors.New("incompatible pinned flavor")
+)
+
 type Assignment struct {
 	PodSets   []PodSetAssignment
 	Borrowing bool
@@ -177,15 +184,32 @@ func (psa *PodSetAssignment) RepresentativeMode() FlavorAssignmentMode {
 
 type ResourceAssignment map[corev1.ResourceName]*FlavorAssignment
 
+// toAPI returns the admission of the podSet. The flavor and usage of a
+// substituted resource are recorded under the substitute, which is the
+// resource whose quota is consumed.
 func (psa *PodSetAssignment) toAPI() kueue.PodSetAssignment {
 	flavors := make(map[corev1.ResourceName]kueue.ResourceFlavorReference, len(psa.Flavors))
+	usage := psa.Requests
+	copied := false
 	for res, flvAssignment := range psa.Flavors {
-		flavors[res] = flvAssignment.Name
+		if flvAssignment.substitute == "" {
+			flavors[res] = flvAssignment.Name
+			continue
+		}
+		flavors[flvAssignment.substitute] = flvAssignment.Name
+		if q, found := psa.Requests[res]; found {
+			if !copied {
+				usage = psa.Requests.DeepCopy()
+				copied = true
+			}
+			delete(usage, res)
+			usage[flvAssignment.substitute] = q
+		}
 	}
 	return kueue.PodSetAssignment{
 		Name:          psa.Name,
 		Flavors:       flavors,
-		ResourceUsage: psa.Requests,
+		ResourceUsage: usage,
 		Count:         ptr.To(psa.Count),
 	}
 }
@@ -225,19 +249,32 @@ type FlavorAssignment struct {
 	Mode           FlavorAssignmentMode
 	TriedFlavorIdx int
 	borrow         bool
+	// substitute is the resource whose quota is consumed, when the quota of the
+	// requested resource is exhausted.
+	substitute corev1.ResourceName
 }
 
 type FlavorAssigner struct {
 	wl              *workload.Info
 	cq              *cache.ClusterQueue
 	resourceFlavors map[kueue.ResourceFlavorReference]*kueue.ResourceFlavor
+	// observedCapacity is the physical capacity of the flavors that reported it.
+	observedCapacity map[kueue.ResourceFlavorReference]cache.Resources
+	// flavorUsage is the usage of the flavors by all the ClusterQueues, which
+	// counts towards their observed capacity.
+	flavorUsage cache.FlavorResourceQuantities
+	// disabledFlavors are the flavors that can't be assigned.
+	disabledFlavors sets.Set[kueue.ResourceFlavorReference]
 }
 
-func New(wl *workload.Info, cq *cache.ClusterQueue, resourceFlavors map[kueue.ResourceFlavorReference]*kueue.ResourceFlavor) *FlavorAssigner {
+func New(wl *workload.Info, cq *cache.ClusterQueue, resourceFlavors map[kueue.ResourceFlavorReference]*kueue.ResourceFlavor, observedCapacity map[kueue.ResourceFlavorReference]cache.Resources, flavorUsage cache.FlavorResourceQuantities, disabledFlavors sets.Set[kueue.ResourceFlavorReference]) *FlavorAssigner {
 	return &FlavorAssigner{
-		wl:              wl,
-		cq:              cq,
-		resourceFlavors: resourceFlavors,
+		wl:               wl,
+		cq:               cq,
+		resourceFlavors:  resourceFlavors,
+		observedCapacity: observedCapacity,
+		flavorUsage:      flavorUsage,
+		disabledFlavors:  disabledFlavors,
 	}
 }
 
@@ -312,6 +349,11 @@ func (a *FlavorAssigner) assignFlavors(log logr.Logger, requests []workload.PodS
 				continue
 			}
 			flavors, status := a.findFlavorForPodSetResource(log, i, podSet.Requests, resName, assignment.Usage)
+			if !status.IsError() && !flavors.fits() {
+				if subFlavors, subStatus := a.findSubstituteFlavors(log, i, podSet.Requests, resName, assignment.Usage); subFlavors != nil {
+					flavors, status = subFlavors, subStatus
+				}
+			}
 			if status.IsError() || len(flavors) == 0 {
 				psAssignment.Flavors = nil
 				psAssignment.Status = status
@@ -349,12 +391,104 @@ func (a *Assignment) append(requests workload.Requests, psAssignment *PodSetAssi
 		if a.Usage[flvAssignment.Name] == nil {
 			a.Usage[flvAssignment.Name] = make(map[corev1.ResourceName]int64)
 		}
-		a.Usage[flvAssignment.Name][resource] += requests[resource]
+		if flvAssignment.substitute != "" {
+			a.Usage[flvAssignment.Name][flvAssignment.substitute] += substituteRequest(requests, resource, flvAssignment.substitute)
+		} else {
+			a.Usage[flvAssignment.Name][resource] += requests[resource]
+		}
 		flavorIdx[resource] = flvAssignment.TriedFlavorIdx
 	}
 	a.LastState.LastTriedFlavorIdx = append(a.LastState.LastTriedFlavorIdx, flavorIdx)
 }
 
+// fits returns whether all the resources fit in their assigned flavors.
+func (ra ResourceAssignment) fits() bool {
+	for _, fa := range ra {
+		if fa.Mode != Fit {
+			return false
+		}
+	}
+	return len(ra) > 0
+}
+
+// findSubstituteFlavors looks for a flavor that fits the requests of the
+// resource group of resName, replacing one of its resources with the quota of
+// its substitutes, in order. The other resources in the resource group are
+// assigned without the replaced resource.
+// Returns nil if no substitute fits.
+func (a *FlavorAssigner) findSubstituteFlavors(
+	log logr.Logger,
+	psId int,
+	requests workload.Requests,
+	resName corev1.ResourceName,
+	assignmentUsage cache.FlavorResourceQuantities,
+) (ResourceAssignment, *Status) {
+	resourceGroup, found := a.cq.RGByResource[resName]
+	if !found {
+		return nil, nil
+	}
+	groupRequests := filterRequestedResources(requests, resourceGroup.CoveredResources)
+	groupResources := make([]corev1.ResourceName, 0, len(groupRequests))
+	for rName := range groupRequests {
+		groupResources = append(groupResources, rName)
+	}
+	slices.Sort(groupResources)
+	for _, rName := range groupResources {
+		if assignments, status := a.substituteResource(log, psId, requests, rName, assignmentUsage); assignments != nil {
+			return assignments, status
+		}
+	}
+	return nil, nil
+}
+
+// substituteResource looks for a flavor that fits the request of resName
+// using the quota of its substitutes, in order.
+func (a *FlavorAssigner) substituteResource(
+	log logr.Logger,
+	psId int,
+	requests workload.Requests,
+	resName corev1.ResourceName,
+	assignmentUsage cache.FlavorResourceQuantities,
+) (ResourceAssignment, *Status) {
+	for _, subName := range a.cq.ResourceSubstitutes[resName] {
+		if _, found := requests[subName]; found {
+			// The substitute is requested by the podSet itself.
+			continue
+		}
+		if _, found := a.cq.RGByResource[subName]; !found {
+			continue
+		}
+		subFlavors, status := a.findFlavorForPodSetResource(log, psId, workload.Requests{subName: substituteRequest(requests, resName, subName)}, subName, assignmentUsage)
+		if status.IsError() || !subFlavors.fits() {
+			continue
+		}
+		subAssignment := subFlavors[subName]
+		subAssignment.substitute = subName
+		subAssignment.TriedFlavorIdx = -1
+		assignments := ResourceAssignment{resName: subAssignment}
+
+		rest := maps.Clone(requests)
+		delete(rest, resName)
+		for rName := range filterRequestedResources(rest, a.cq.RGByResource[resName].CoveredResources) {
+			flavors, restStatus := a.findFlavorForPodSetResource(log, psId, rest, rName, assignmentUsage)
+			if restStatus.IsError() || len(flavors) == 0 {
+				return nil, nil
+			}
+			maps.Copy(assignments, flavors)
+			status = restStatus
+			break
+		}
+		return assignments, status
+	}
+	return nil, nil
+}
+
+// substituteRequest returns the request of resName expressed in the units of
+// the substitute subName.
+func substituteRequest(requests workload.Requests, resName, subName corev1.ResourceName) int64 {
+	return workload.ResourceValue(subName, workload.ResourceQuantity(resName, requests[resName]))
+}
+
 // findFlavorForPodSetResource finds the flavor which can satisfy the podSet request
 // for all resources in the same group as resName.
 // Returns the chosen flavor, along with the information about resources that need to be borrowed.
@@ -385,7 +519,17 @@ func (a *FlavorAssigner) findFlavorForPodSetResource(
 	selector := flavorSelector(podSpec, resourceGroup.LabelKeys)
 	assignedFlavorIdx := -1
 	idx := a.wl.LastAssignment.NextFlavorToTryForPodSetResource(psId, resName)
-	for ; idx < len(resourceGroup.Flavors); idx++ {
+	endIdx := len(resourceGroup.Flavors)
+	pinnedIdx, err := pinnedFlavorIdx(a.wl.Obj, resourceGroup, requests)
+	if err != nil {
+		status.err = err
+		return nil, status
+	}
+	if pinnedIdx >= 0 {
+		idx, endIdx = pinnedIdx, pinnedIdx+1
+	}
+	excluded := workload.ExcludedFlavors(a.wl.Obj)
+	for ; idx < endIdx; idx++ {
 		flvQuotas := resourceGroup.Flavors[idx]
 		flavor, exist := a.resourceFlavors[flvQuotas.Name]
 		if !exist {
@@ -393,6 +537,14 @@ func (a *FlavorAssigner) findFlavorForPodSetResource(
 			status.append(fmt.Sprintf("flavor %s not found", flvQuotas.Name))
 			continue
 		}
+		if a.disabledFlavors.Has(flvQuotas.Name) {
+			status.append(fmt.Sprintf("flavor %s is disabled", flvQuotas.Name))
+			continue
+		}
+		if excluded.Has(flvQuotas.Name) {
+			status.append(fmt.Sprintf("flavor %s is excluded by the workload", flvQuotas.Name))
+			continue
+		}
 		taint, untolerated := corev1helpers.FindMatchingUntoleratedTaint(flavor.Spec.NodeTaints, podSpec.Tolerations, func(t *corev1.Taint) bool {
 			return t.Effect == corev1.TaintEffectNoSchedule || t.Effect == corev1.TaintEffectNoExecute
 		})
@@ -459,6 +611,11 @@ func (a *FlavorAssigner) findFlavorForPodSetResource(
 		}
 	}
 
+	if pinnedIdx >= 0 && assignedFlavorIdx == -1 {
+		status.err = fmt.Errorf("%w: %s", errIncompatiblePinnedFlavor, status.Message())
+		return nil, status
+	}
+
 	if features.Enabled(features.FlavorFungibility) {
 		for _, assignment := range bestAssignment {
 			if assignedFlavorIdx == len(resourceGroup.Flavors)-1 {
@@ -475,6 +632,36 @@ func (a *FlavorAssigner) findFlavorForPodSetResource(
 	return bestAssignment, status
 }
 
+// pinnedFlavorIdx returns the index, in the resource group, of the flavor
+// that the requested resources are pinned to, or -1 if none of them is pinned.
+func pinnedFlavorIdx(wl *kueue.Workload, resourceGroup *cache.ResourceGroup, requests workload.Requests) (int, error) {
+	pinned, err := workload.PinnedFlavors(wl)
+	if err != nil || len(pinned) == 0 {
+		return -1, err
+	}
+	var pinnedFlavor kueue.ResourceFlavorReference
+	var pinnedResource corev1.ResourceName
+	for _, rName := range sets.List(sets.KeySet(requests)) {
+		flavor, found := pinned[rName]
+		if !found {
+			continue
+		}
+		if pinnedFlavor != "" && flavor != pinnedFlavor {
+			return -1, fmt.Errorf("resources %s and %s are pinned to different flavors in the same resource group", pinnedResource, rName)
+		}
+		pinnedFlavor, pinnedResource = flavor, rName
+	}
+	if pinnedFlavor == "" {
+		return -1, nil
+	}
+	for i, flvQuotas := range resourceGroup.Flavors {
+		if flvQuotas.Name == pinnedFlavor {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("%w: flavor %s pinned for resource %s is not defined in the ClusterQueue", errUnknownPinnedFlavor, pinnedFlavor, pinnedResource)
+}
+
 func shouldTryNextFlavor(representativeMode FlavorAssignmentMode, flavorFungibility kueue.FlavorFungibility, needsBorrowing bool) bool {
 	policyPreempt := flavorFungibility.WhenCanPreempt
 	policyBorrow := flavorFungibility.WhenCanBorrow
@@ -551,14 +738,17 @@ func (a *FlavorAssigner) fitsResourceQuota(fName kueue.ResourceFlavorReference,
 	var status Status
 	var borrow bool
 	used := a.cq.Usage[fName][rName]
+	// The overcommitted quota is only available to this ClusterQueue.
+	nominal := rQuota.OvercommittedNominal()
 	mode := NoFit
-	if val <= rQuota.Nominal {
+	if val <= nominal {
 		// The request can be satisfied by the nominal quota, assuming quota is
 		// reclaimed from the cohort or assuming all active workloads in the
 		// ClusterQueue are preempted.
 		mode = Preempt
 	}
-	cohortAvailable := rQuota.Nominal
+	borrowingLimit := a.cq.BoostedBorrowingLimit(rName, rQuota.BorrowingLimit)
+	cohortAvailable := nominal
 	if a.cq.Cohort != nil {
 		cohortAvailable = a.cq.RequestableCohortQuota(fName, rName)
 	}
@@ -566,15 +756,19 @@ func (a *FlavorAssigner) fitsResourceQuota(fName kueue.ResourceFlavorReference,
 	if a.cq.Preemption.BorrowWithinCohort != nil && a.cq.Preemption.BorrowWithinCohort.Policy != kueue.BorrowWithinCohortPolicyNever {
 		// when preemption with borrowing is enabled, we can succeed to admit the
 		// workload if preemption is used.
-		if (rQuota.BorrowingLimit == nil || val <= rQuota.Nominal+*rQuota.BorrowingLimit) && val <= cohortAvailable {
+		if (borrowingLimit == nil || val <= nominal+*borrowingLimit) && val <= cohortAvailable {
 			mode = Preempt
-			borrow = val > rQuota.Nominal
+			borrow = val > nominal
 		}
 	}
-	if rQuota.BorrowingLimit != nil && used+val > rQuota.Nominal+*rQuota.BorrowingLimit {
+	if borrowingLimit != nil && used+val > nominal+*borrowingLimit {
 		status.append(fmt.Sprintf("borrowing limit for %s in flavor %s exceeded", rName, fName))
 		return mode, borrow, &status
 	}
+	if capacity, found := a.observedCapacity[fName][rName]; found && used+val > nominal && a.flavorUsage[fName][rName]+val > capacity {
+		status.append(fmt.Sprintf("borrowing for %s in flavor %s exceeds the observed capacity", rName, fName))
+		return mode, borrow, &status
+	}
 
 	cohortUsed := used
 	if a.cq.Cohort != nil {
@@ -583,7 +777,7 @@ func (a *FlavorAssigner) fitsResourceQuota(fName kueue.ResourceFlavorReference,
 
 	lack := cohortUsed + val - cohortAvailable
 	if lack <= 0 {
-		return Fit, used+val > rQuota.Nominal, nil
+		return Fit, used+val > nominal, nil
 	}
 
 	lackQuantity := workload.ResourceQuantity(rName, lack)
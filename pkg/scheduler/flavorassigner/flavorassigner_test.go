@@ -17,6 +17,8 @@ limitations under the License.
 package flavorassigner
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -64,6 +66,11 @@ func TestAssignFlavors(t *testing.T) {
 		wantRepMode        FlavorAssignmentMode
 		wantAssignment     Assignment
 		enableLendingLimit bool
+		observedCapacity   map[kueue.ResourceFlavorReference]cache.Resources
+		flavorUsage        cache.FlavorResourceQuantities
+		disabledFlavors    sets.Set[kueue.ResourceFlavorReference]
+		// wantPodSetAssignments is the expected admission, checked when set.
+		wantPodSetAssignments []kueue.PodSetAssignment
 	}{
 		"single flavor, fits": {
 			wlPods: []kueue.PodSet{
@@ -192,6 +199,207 @@ func TestAssignFlavors(t *testing.T) {
 				},
 			},
 		},
+		"single flavor, overcommit ratio 1.0, doesn't fit": {
+			wlPods: []kueue.PodSet{
+				*utiltesting.MakePodSet("main", 1).
+					Request(corev1.ResourceCPU, "2").
+					Obj(),
+			},
+			clusterQueue: cache.ClusterQueue{
+				ResourceGroups: []cache.ResourceGroup{{
+					CoveredResources: sets.New(corev1.ResourceCPU),
+					Flavors: []cache.FlavorQuotas{{
+						Name: "default",
+						Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+							corev1.ResourceCPU: {Nominal: 4000, OvercommitRatio: ptr.To(1.0)},
+						},
+					}},
+				}},
+				Usage: cache.FlavorResourceQuantities{
+					"default": {corev1.ResourceCPU: 3_000},
+				},
+			},
+			wantRepMode: Preempt,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "default", Mode: Preempt},
+					},
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("2000m"),
+					},
+					Status: &Status{
+						reasons: []string{"insufficient unused quota for cpu in flavor default, 1 more needed"},
+					},
+					Count: 1,
+				}},
+				Usage: cache.FlavorResourceQuantities{
+					"default": {
+						corev1.ResourceCPU: 2000,
+					},
+				},
+			},
+		},
+		"single flavor, overcommit ratio 2.0, fits": {
+			wlPods: []kueue.PodSet{
+				*utiltesting.MakePodSet("main", 1).
+					Request(corev1.ResourceCPU, "5").
+					Obj(),
+			},
+			clusterQueue: cache.ClusterQueue{
+				ResourceGroups: []cache.ResourceGroup{{
+					CoveredResources: sets.New(corev1.ResourceCPU),
+					Flavors: []cache.FlavorQuotas{{
+						Name: "default",
+						Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+							corev1.ResourceCPU: {Nominal: 4000, OvercommitRatio: ptr.To(2.0)},
+						},
+					}},
+				}},
+				Usage: cache.FlavorResourceQuantities{
+					"default": {corev1.ResourceCPU: 3_000},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "default", Mode: Fit},
+					},
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("5000m"),
+					},
+					Count: 1,
+				}},
+				Usage: cache.FlavorResourceQuantities{
+					"default": {
+						corev1.ResourceCPU: 5000,
+					},
+				},
+			},
+		},
+		"resource exhausted, falls back to substitute resource": {
+			wlPods: []kueue.PodSet{
+				*utiltesting.MakePodSet("main", 1).
+					Request(corev1.ResourceCPU, "3").
+					Request(corev1.ResourceMemory, "1Mi").
+					Obj(),
+			},
+			clusterQueue: cache.ClusterQueue{
+				ResourceGroups: []cache.ResourceGroup{
+					{
+						CoveredResources: sets.New(corev1.ResourceCPU, corev1.ResourceMemory),
+						Flavors: []cache.FlavorQuotas{{
+							Name: "one",
+							Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+								corev1.ResourceCPU:    {Nominal: 2000},
+								corev1.ResourceMemory: {Nominal: utiltesting.Mi},
+							},
+						}},
+					},
+					{
+						CoveredResources: sets.New[corev1.ResourceName]("example.com/fast-cpu"),
+						Flavors: []cache.FlavorQuotas{{
+							Name: "two",
+							Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+								"example.com/fast-cpu": {Nominal: 4},
+							},
+						}},
+					},
+				},
+				Usage: cache.FlavorResourceQuantities{
+					"one": {corev1.ResourceCPU: 0, corev1.ResourceMemory: 0},
+					"two": {"example.com/fast-cpu": 0},
+				},
+				ResourceSubstitutes: map[corev1.ResourceName][]corev1.ResourceName{
+					corev1.ResourceCPU: {"example.com/fast-cpu"},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU:    {Name: "two", Mode: Fit},
+						corev1.ResourceMemory: {Name: "one", Mode: Fit},
+					},
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("3000m"),
+						corev1.ResourceMemory: resource.MustParse("1Mi"),
+					},
+					Count: 1,
+				}},
+				Usage: cache.FlavorResourceQuantities{
+					"one": {corev1.ResourceMemory: utiltesting.Mi},
+					"two": {"example.com/fast-cpu": 3},
+				},
+			},
+			wantPodSetAssignments: []kueue.PodSetAssignment{{
+				Name: "main",
+				Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{
+					"example.com/fast-cpu": "two",
+					corev1.ResourceMemory:  "one",
+				},
+				ResourceUsage: corev1.ResourceList{
+					"example.com/fast-cpu": resource.MustParse("3"),
+					corev1.ResourceMemory:  resource.MustParse("1Mi"),
+				},
+				Count: ptr.To[int32](1),
+			}},
+		},
+		"flavor covers the resource and its substitute, records the substitute": {
+			wlPods: []kueue.PodSet{
+				*utiltesting.MakePodSet("main", 1).
+					Request(corev1.ResourceCPU, "3").
+					Obj(),
+			},
+			clusterQueue: cache.ClusterQueue{
+				ResourceGroups: []cache.ResourceGroup{{
+					CoveredResources: sets.New[corev1.ResourceName](corev1.ResourceCPU, "example.com/fast-cpu"),
+					Flavors: []cache.FlavorQuotas{{
+						Name: "one",
+						Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+							corev1.ResourceCPU:     {Nominal: 2000},
+							"example.com/fast-cpu": {Nominal: 4},
+						},
+					}},
+				}},
+				Usage: cache.FlavorResourceQuantities{
+					"one": {corev1.ResourceCPU: 0, "example.com/fast-cpu": 0},
+				},
+				ResourceSubstitutes: map[corev1.ResourceName][]corev1.ResourceName{
+					corev1.ResourceCPU: {"example.com/fast-cpu"},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: Fit},
+					},
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("3000m"),
+					},
+					Count: 1,
+				}},
+				Usage: cache.FlavorResourceQuantities{
+					"one": {"example.com/fast-cpu": 3},
+				},
+			},
+			wantPodSetAssignments: []kueue.PodSetAssignment{{
+				Name: "main",
+				Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{
+					"example.com/fast-cpu": "one",
+				},
+				ResourceUsage: corev1.ResourceList{
+					"example.com/fast-cpu": resource.MustParse("3"),
+				},
+				Count: ptr.To[int32](1),
+			}},
+		},
 		"multiple resource groups, fits": {
 			wlPods: []kueue.PodSet{
 				*utiltesting.MakePodSet("main", 1).
@@ -625,6 +833,53 @@ func TestAssignFlavors(t *testing.T) {
 				},
 			},
 		},
+		"multiple flavors, skip disabled flavor": {
+			wlPods: []kueue.PodSet{
+				*utiltesting.MakePodSet("main", 1).
+					Request(corev1.ResourceCPU, "3").
+					Obj(),
+			},
+			clusterQueue: cache.ClusterQueue{
+				ResourceGroups: []cache.ResourceGroup{
+					{
+						CoveredResources: sets.New(corev1.ResourceCPU),
+						Flavors: []cache.FlavorQuotas{
+							{
+								Name: "one",
+								Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+									corev1.ResourceCPU: {Nominal: 4000},
+								},
+							},
+							{
+								Name: "two",
+								Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+									corev1.ResourceCPU: {Nominal: 4000},
+								},
+							},
+						},
+					},
+				},
+			},
+			disabledFlavors: sets.New[kueue.ResourceFlavorReference]("one"),
+			wantRepMode:     Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "two", Mode: Fit},
+					},
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("3000m"),
+					},
+					Count: 1,
+				}},
+				Usage: cache.FlavorResourceQuantities{
+					"two": map[corev1.ResourceName]int64{
+						corev1.ResourceCPU: 3000,
+					},
+				},
+			},
+		},
 		"multiple flavors, fits a node selector": {
 			wlPods: []kueue.PodSet{
 				{
@@ -1150,6 +1405,158 @@ func TestAssignFlavors(t *testing.T) {
 				},
 			},
 		},
+		"borrowing past observed capacity": {
+			wlPods: []kueue.PodSet{
+				*utiltesting.MakePodSet("main", 1).
+					Request(corev1.ResourceCPU, "4").
+					Obj(),
+			},
+			clusterQueue: cache.ClusterQueue{
+				ResourceGroups: []cache.ResourceGroup{{
+					CoveredResources: sets.New(corev1.ResourceCPU),
+					Flavors: []cache.FlavorQuotas{{
+						Name: "one",
+						Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+							corev1.ResourceCPU: {Nominal: 2000},
+						},
+					}},
+				}},
+				Usage: cache.FlavorResourceQuantities{},
+				Cohort: &cache.Cohort{
+					Members: sets.New(&cache.ClusterQueue{
+						Usage: cache.FlavorResourceQuantities{
+							"one": {corev1.ResourceCPU: 3_000},
+						},
+					}),
+					RequestableResources: cache.FlavorResourceQuantities{
+						"one": {corev1.ResourceCPU: 10_000},
+					},
+					Usage: cache.FlavorResourceQuantities{
+						"one": {corev1.ResourceCPU: 3_000},
+					},
+				},
+			},
+			observedCapacity: map[kueue.ResourceFlavorReference]cache.Resources{
+				"one": {corev1.ResourceCPU: 6_000},
+			},
+			flavorUsage: cache.FlavorResourceQuantities{
+				"one": {corev1.ResourceCPU: 3_000},
+			},
+			wantRepMode: NoFit,
+			wantAssignment: Assignment{
+				Usage: cache.FlavorResourceQuantities{},
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Status: &Status{
+						reasons: []string{"borrowing for cpu in flavor one exceeds the observed capacity"},
+					},
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("4000m"),
+					},
+					Count: 1,
+				}},
+			},
+		},
+		"borrowing past observed capacity used outside the cohort": {
+			wlPods: []kueue.PodSet{
+				*utiltesting.MakePodSet("main", 1).
+					Request(corev1.ResourceCPU, "4").
+					Obj(),
+			},
+			clusterQueue: cache.ClusterQueue{
+				ResourceGroups: []cache.ResourceGroup{{
+					CoveredResources: sets.New(corev1.ResourceCPU),
+					Flavors: []cache.FlavorQuotas{{
+						Name: "one",
+						Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+							corev1.ResourceCPU: {Nominal: 2000},
+						},
+					}},
+				}},
+				Usage: cache.FlavorResourceQuantities{},
+				Cohort: &cache.Cohort{
+					RequestableResources: cache.FlavorResourceQuantities{
+						"one": {corev1.ResourceCPU: 10_000},
+					},
+					Usage: cache.FlavorResourceQuantities{},
+				},
+			},
+			observedCapacity: map[kueue.ResourceFlavorReference]cache.Resources{
+				"one": {corev1.ResourceCPU: 6_000},
+			},
+			flavorUsage: cache.FlavorResourceQuantities{
+				"one": {corev1.ResourceCPU: 3_000},
+			},
+			wantRepMode: NoFit,
+			wantAssignment: Assignment{
+				Usage: cache.FlavorResourceQuantities{},
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Status: &Status{
+						reasons: []string{"borrowing for cpu in flavor one exceeds the observed capacity"},
+					},
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("4000m"),
+					},
+					Count: 1,
+				}},
+			},
+		},
+		"borrowing within observed capacity": {
+			wlPods: []kueue.PodSet{
+				*utiltesting.MakePodSet("main", 1).
+					Request(corev1.ResourceCPU, "4").
+					Obj(),
+			},
+			clusterQueue: cache.ClusterQueue{
+				ResourceGroups: []cache.ResourceGroup{{
+					CoveredResources: sets.New(corev1.ResourceCPU),
+					Flavors: []cache.FlavorQuotas{{
+						Name: "one",
+						Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+							corev1.ResourceCPU: {Nominal: 2000},
+						},
+					}},
+				}},
+				Usage: cache.FlavorResourceQuantities{},
+				Cohort: &cache.Cohort{
+					Members: sets.New(&cache.ClusterQueue{
+						Usage: cache.FlavorResourceQuantities{
+							"one": {corev1.ResourceCPU: 3_000},
+						},
+					}),
+					RequestableResources: cache.FlavorResourceQuantities{
+						"one": {corev1.ResourceCPU: 10_000},
+					},
+					Usage: cache.FlavorResourceQuantities{
+						"one": {corev1.ResourceCPU: 3_000},
+					},
+				},
+			},
+			observedCapacity: map[kueue.ResourceFlavorReference]cache.Resources{
+				"one": {corev1.ResourceCPU: 8_000},
+			},
+			flavorUsage: cache.FlavorResourceQuantities{
+				"one": {corev1.ResourceCPU: 3_000},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				Borrowing: true,
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: Fit},
+					},
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("4000m"),
+					},
+					Count: 1,
+				}},
+				Usage: cache.FlavorResourceQuantities{
+					"one": {corev1.ResourceCPU: 4000},
+				},
+			},
+		},
 		"past min, but can preempt in ClusterQueue": {
 			wlPods: []kueue.PodSet{
 				*utiltesting.MakePodSet("main", 1).
@@ -2286,7 +2693,7 @@ func TestAssignFlavors(t *testing.T) {
 			}
 			tc.clusterQueue.UpdateWithFlavors(resourceFlavors)
 			tc.clusterQueue.UpdateRGByResource()
-			flvAssigner := New(wlInfo, &tc.clusterQueue, resourceFlavors)
+			flvAssigner := New(wlInfo, &tc.clusterQueue, resourceFlavors, tc.observedCapacity, tc.flavorUsage, tc.disabledFlavors)
 			assignment := flvAssigner.Assign(log, nil)
 			if repMode := assignment.RepresentativeMode(); repMode != tc.wantRepMode {
 				t.Errorf("e.assignFlavors(_).RepresentativeMode()=%s, want %s", repMode, tc.wantRepMode)
@@ -2295,6 +2702,202 @@ func TestAssignFlavors(t *testing.T) {
 			if diff := cmp.Diff(tc.wantAssignment, assignment, cmpopts.IgnoreUnexported(Assignment{}, FlavorAssignment{}), cmpopts.IgnoreFields(Assignment{}, "LastState"), cmpopts.IgnoreFields(FlavorAssignment{}, "TriedFlavorIdx")); diff != "" {
 				t.Errorf("Unexpected assignment (-want,+got):\n%s", diff)
 			}
+			if tc.wantPodSetAssignments != nil {
+				if diff := cmp.Diff(tc.wantPodSetAssignments, assignment.ToAPI()); diff != "" {
+					t.Errorf("Unexpected admission (-want,+got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestAssignFlavorsPinned(t *testing.T) {
+	resourceFlavors := map[kueue.ResourceFlavorReference]*kueue.ResourceFlavor{
+		"one": utiltesting.MakeResourceFlavor("one").Obj(),
+		"two": utiltesting.MakeResourceFlavor("two").Obj(),
+		"tainted": utiltesting.MakeResourceFlavor("tainted").
+			Taint(corev1.Taint{
+				Key:    "instance",
+				Value:  "spot",
+				Effect: corev1.TaintEffectNoSchedule,
+			}).Obj(),
+	}
+	cases := map[string]struct {
+		pin         string
+		wantFlavor  kueue.ResourceFlavorReference
+		wantRepMode FlavorAssignmentMode
+		wantErr     error
+	}{
+		"pinned to a flavor after one that fits": {
+			pin:         "cpu=two",
+			wantFlavor:  "two",
+			wantRepMode: Fit,
+		},
+		"pinned to an unknown flavor": {
+			pin:         "cpu=three",
+			wantRepMode: NoFit,
+			wantErr:     errUnknownPinnedFlavor,
+		},
+		"pinned to an incompatible flavor": {
+			pin:         "cpu=tainted",
+			wantRepMode: NoFit,
+			wantErr:     errIncompatiblePinnedFlavor,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			log := testr.NewWithOptions(t, testr.Options{
+				Verbosity: 2,
+			})
+			wlInfo := workload.NewInfo(utiltesting.MakeWorkload("wl", "ns").
+				Annotations(map[string]string{"kueue.x-k8s.io/pinned-flavors": tc.pin}).
+				Request(corev1.ResourceCPU, "1").
+				Obj())
+			clusterQueue := cache.ClusterQueue{
+				ResourceGroups: []cache.ResourceGroup{{
+					CoveredResources: sets.New(corev1.ResourceCPU),
+					Flavors: []cache.FlavorQuotas{
+						{
+							Name: "one",
+							Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+								corev1.ResourceCPU: {Nominal: 4000},
+							},
+						},
+						{
+							Name: "tainted",
+							Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+								corev1.ResourceCPU: {Nominal: 4000},
+							},
+						},
+						{
+							Name: "two",
+							Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+								corev1.ResourceCPU: {Nominal: 4000},
+							},
+						},
+					},
+				}},
+				FlavorFungibility: kueue.FlavorFungibility{
+					WhenCanBorrow:  kueue.Borrow,
+					WhenCanPreempt: kueue.TryNextFlavor,
+				},
+			}
+			clusterQueue.UpdateWithFlavors(resourceFlavors)
+			clusterQueue.UpdateRGByResource()
+			assignment := New(wlInfo, &clusterQueue, resourceFlavors, nil, nil, nil).Assign(log, nil)
+			if repMode := assignment.RepresentativeMode(); repMode != tc.wantRepMode {
+				t.Errorf("e.assignFlavors(_).RepresentativeMode()=%s, want %s", repMode, tc.wantRepMode)
+			}
+			psAssignment := assignment.PodSets[0]
+			var gotErr error
+			if psAssignment.Status != nil {
+				gotErr = psAssignment.Status.err
+			}
+			if !errors.Is(gotErr, tc.wantErr) {
+				t.Errorf("Unexpected error, got %v, want %v", gotErr, tc.wantErr)
+			}
+			if tc.wantFlavor != "" {
+				if got := psAssignment.Flavors[corev1.ResourceCPU]; got == nil || got.Name != tc.wantFlavor {
+					t.Errorf("Unexpected flavor assigned, got %v, want %s", got, tc.wantFlavor)
+				}
+			}
+		})
+	}
+}
+
+func TestAssignFlavorsExcluded(t *testing.T) {
+	resourceFlavors := map[kueue.ResourceFlavorReference]*kueue.ResourceFlavor{
+		"spot":      utiltesting.MakeResourceFlavor("spot").Obj(),
+		"on-demand": utiltesting.MakeResourceFlavor("on-demand").Obj(),
+	}
+	cases := map[string]struct {
+		exclude     string
+		cpu         string
+		wantFlavor  kueue.ResourceFlavorReference
+		wantRepMode FlavorAssignmentMode
+		wantReasons []string
+	}{
+		"no exclusion": {
+			cpu:         "1",
+			wantFlavor:  "spot",
+			wantRepMode: Fit,
+		},
+		"spot excluded": {
+			exclude:     "spot",
+			cpu:         "1",
+			wantFlavor:  "on-demand",
+			wantRepMode: Fit,
+		},
+		"on-demand doesn't fit after excluding spot": {
+			exclude:     "spot",
+			cpu:         "6",
+			wantRepMode: NoFit,
+			wantReasons: []string{
+				"flavor spot is excluded by the workload",
+				"insufficient quota for cpu in flavor on-demand in ClusterQueue",
+			},
+		},
+		"all flavors excluded": {
+			exclude:     "spot, on-demand",
+			cpu:         "1",
+			wantRepMode: NoFit,
+			wantReasons: []string{
+				"flavor spot is excluded by the workload",
+				"flavor on-demand is excluded by the workload",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			log := testr.NewWithOptions(t, testr.Options{
+				Verbosity: 2,
+			})
+			wl := utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, tc.cpu)
+			if tc.exclude != "" {
+				wl.Annotations(map[string]string{"kueue.x-k8s.io/excluded-flavors": tc.exclude})
+			}
+			clusterQueue := cache.ClusterQueue{
+				ResourceGroups: []cache.ResourceGroup{{
+					CoveredResources: sets.New(corev1.ResourceCPU),
+					Flavors: []cache.FlavorQuotas{
+						{
+							Name: "spot",
+							Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+								corev1.ResourceCPU: {Nominal: 10_000},
+							},
+						},
+						{
+							Name: "on-demand",
+							Resources: map[corev1.ResourceName]*cache.ResourceQuota{
+								corev1.ResourceCPU: {Nominal: 4_000},
+							},
+						},
+					},
+				}},
+				FlavorFungibility: kueue.FlavorFungibility{
+					WhenCanBorrow:  kueue.Borrow,
+					WhenCanPreempt: kueue.TryNextFlavor,
+				},
+			}
+			clusterQueue.UpdateWithFlavors(resourceFlavors)
+			clusterQueue.UpdateRGByResource()
+			assignment := New(workload.NewInfo(wl.Obj()), &clusterQueue, resourceFlavors, nil, nil, nil).Assign(log, nil)
+			if repMode := assignment.RepresentativeMode(); repMode != tc.wantRepMode {
+				t.Errorf("e.assignFlavors(_).RepresentativeMode()=%s, want %s", repMode, tc.wantRepMode)
+			}
+			psAssignment := assignment.PodSets[0]
+			if tc.wantFlavor != "" {
+				if got := psAssignment.Flavors[corev1.ResourceCPU]; got == nil || got.Name != tc.wantFlavor {
+					t.Errorf("Unexpected flavor assigned, got %v, want %s", got, tc.wantFlavor)
+				}
+			}
+			var gotReasons []string
+			if psAssignment.Status != nil {
+				gotReasons = psAssignment.Status.reasons
+			}
+			if diff := cmp.Diff(tc.wantReasons, gotReasons); diff != "" {
+				t.Errorf("Unexpected reasons (-want,+got):\n%s", diff)
+			}
 		})
 	}
 }
@@ -2369,3 +2972,81 @@ func TestLastAssignmentOutdated(t *testing.T) {
 		})
 	}
 }
+
+func TestAssignFlavorsSecondaryCohorts(t *testing.T) {
+	cases := map[string]struct {
+		primaryUsage   string
+		secondaryUsage string
+		wantRepMode    FlavorAssignmentMode
+		wantBorrow     bool
+	}{
+		"borrows from the primary cohort": {
+			primaryUsage:   "0",
+			secondaryUsage: "0",
+			wantRepMode:    Fit,
+			wantBorrow:     true,
+		},
+		"primary cohort saturated, doesn't borrow from the secondary cohort": {
+			primaryUsage:   "4",
+			secondaryUsage: "0",
+			wantRepMode:    NoFit,
+		},
+		"both cohorts saturated": {
+			primaryUsage:   "4",
+			secondaryUsage: "4",
+			wantRepMode:    NoFit,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			log := testr.NewWithOptions(t, testr.Options{
+				Verbosity: 2,
+			})
+			cqCache := cache.New(utiltesting.NewFakeClient())
+			cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			borrower := utiltesting.MakeClusterQueue("borrower").
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "0").Obj()).
+				Cohort("primary").
+				Obj()
+			borrower.Annotations = map[string]string{"kueue.x-k8s.io/secondary-cohorts": "secondary"}
+			clusterQueues := []*kueue.ClusterQueue{
+				borrower,
+				utiltesting.MakeClusterQueue("primary-lender").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+					Cohort("primary").
+					Obj(),
+				utiltesting.MakeClusterQueue("secondary-lender").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "4").Obj()).
+					Cohort("secondary").
+					Obj(),
+			}
+			for _, cq := range clusterQueues {
+				if err := cqCache.AddClusterQueue(context.Background(), cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			usage := map[string]string{
+				"primary-lender":   tc.primaryUsage,
+				"secondary-lender": tc.secondaryUsage,
+			}
+			for cqName, cpu := range usage {
+				wl := utiltesting.MakeWorkload("wl-"+cqName, "ns").
+					ReserveQuota(utiltesting.MakeAdmission(cqName).Assignment(corev1.ResourceCPU, "default", cpu).Obj()).
+					Obj()
+				if !cqCache.AddOrUpdateWorkload(wl) {
+					t.Fatalf("Failed adding workload %q", wl.Name)
+				}
+			}
+			snapshot := cqCache.Snapshot()
+
+			wlInfo := workload.NewInfo(utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, "3").Obj())
+			assignment := New(wlInfo, snapshot.ClusterQueues["borrower"], snapshot.ResourceFlavors, nil, nil, nil).Assign(log, nil)
+			if repMode := assignment.RepresentativeMode(); repMode != tc.wantRepMode {
+				t.Errorf("e.assignFlavors(_).RepresentativeMode()=%s, want %s", repMode, tc.wantRepMode)
+			}
+			if assignment.Borrowing != tc.wantBorrow {
+				t.Errorf("e.assignFlavors(_).Borrowing=%t, want %t", assignment.Borrowing, tc.wantBorrow)
+			}
+		})
+	}
+}
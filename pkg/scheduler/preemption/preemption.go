@@ -86,7 +86,7 @@ func (p *Preemptor) GetTargets(wl workload.Info, assignment flavorassigner.Assig
 	if len(candidates) == 0 {
 		return nil
 	}
-	sort.Slice(candidates, candidatesOrdering(candidates, cq.Name, time.Now()))
+	sort.Slice(candidates, candidatesOrdering(candidates, snapshot, cq.Name, time.Now()))
 
 	sameQueueCandidates := candidatesOnlyFromQueue(candidates, wl.ClusterQueue)
 
@@ -109,7 +109,7 @@ func (p *Preemptor) GetTargets(wl workload.Info, assignment flavorassigner.Assig
 	// requeued.
 	borrowWithinCohort := cq.Preemption.BorrowWithinCohort
 	if borrowWithinCohort != nil && borrowWithinCohort.Policy != kueue.BorrowWithinCohortPolicyNever {
-		allowBorrowingBelowPriority := ptr.To(priority.Priority(wl.Obj))
+		allowBorrowingBelowPriority := ptr.To(cq.EffectivePriority(wl.Obj))
 		if borrowWithinCohort.MaxPriorityThreshold != nil && *borrowWithinCohort.MaxPriorityThreshold < *allowBorrowingBelowPriority {
 			allowBorrowingBelowPriority = ptr.To(*borrowWithinCohort.MaxPriorityThreshold + 1)
 		}
@@ -181,7 +181,7 @@ func minimalPreemptions(wl *workload.Info, assignment flavorassigner.Assignment,
 		if cq != candCQ && !cqIsBorrowing(candCQ, resPerFlv) {
 			continue
 		}
-		if cq != candCQ && allowBorrowingBelowPriority != nil && priority.Priority(candWl.Obj) >= *allowBorrowingBelowPriority {
+		if cq != candCQ && allowBorrowingBelowPriority != nil && candCQ.EffectivePriority(candWl.Obj) >= *allowBorrowingBelowPriority {
 			// We set allowBorrowing=false if there is a candidate with priority
 			// exceeding allowBorrowingBelowPriority added to targets.
 			//
@@ -279,7 +279,7 @@ func findCandidates(wl *kueue.Workload, wo workload.Ordering, cq *cache.ClusterQ
 	}
 
 	if cq.Cohort != nil && cq.Preemption.ReclaimWithinCohort != kueue.PreemptionPolicyNever {
-		for cohortCQ := range cq.Cohort.Members {
+		for _, cohortCQ := range cq.Cohort.SortedMembers() {
 			if cq == cohortCQ || !cqIsBorrowing(cohortCQ, resPerFlv) {
 				// Can't reclaim quota from itself or ClusterQueues that are not borrowing.
 				continue
@@ -289,7 +289,7 @@ func findCandidates(wl *kueue.Workload, wo workload.Ordering, cq *cache.ClusterQ
 				onlyLowerPrio = false
 			}
 			for _, candidateWl := range cohortCQ.Workloads {
-				if onlyLowerPrio && priority.Priority(candidateWl.Obj) >= priority.Priority(wl) {
+				if onlyLowerPrio && cohortCQ.EffectivePriority(candidateWl.Obj) >= cq.EffectivePriority(wl) {
 					continue
 				}
 				if !workloadUsesResources(candidateWl, resPerFlv) {
@@ -366,10 +366,10 @@ func workloadFits(wlReq cache.FlavorResourceQuantities, cq *cache.ClusterQueue,
 						return false
 					}
 				} else {
-					// When resource.BorrowingLimit == nil there is no borrowing
+					// When the borrowing limit is nil there is no borrowing
 					// limit, so we can skip the check.
-					if resource.BorrowingLimit != nil {
-						if cqResUsage[rName]+rReq > resource.Nominal+*resource.BorrowingLimit {
+					if limit := cq.BoostedBorrowingLimit(rName, resource.BorrowingLimit); limit != nil {
+						if cqResUsage[rName]+rReq > resource.Nominal+*limit {
 							return false
 						}
 					}
@@ -392,9 +392,9 @@ func workloadFits(wlReq cache.FlavorResourceQuantities, cq *cache.ClusterQueue,
 // 0. Workloads already marked for preemption first.
 // 1. Workloads from other ClusterQueues in the cohort before the ones in the
 // same ClusterQueue as the preemptor.
-// 2. Workloads with lower priority first.
+// 2. Workloads with lower effective priority first.
 // 3. Workloads admitted more recently first.
-func candidatesOrdering(candidates []*workload.Info, cq string, now time.Time) func(int, int) bool {
+func candidatesOrdering(candidates []*workload.Info, snapshot *cache.Snapshot, cq string, now time.Time) func(int, int) bool {
 	return func(i, j int) bool {
 		a := candidates[i]
 		b := candidates[j]
@@ -408,8 +408,8 @@ func candidatesOrdering(candidates []*workload.Info, cq string, now time.Time) f
 		if aInCQ != bInCQ {
 			return !aInCQ
 		}
-		pa := priority.Priority(a.Obj)
-		pb := priority.Priority(b.Obj)
+		pa := effectivePriority(snapshot, a)
+		pb := effectivePriority(snapshot, b)
 		if pa != pb {
 			return pa < pb
 		}
@@ -423,6 +423,15 @@ func candidatesOrdering(candidates []*workload.Info, cq string, now time.Time) f
 	}
 }
 
+// effectivePriority returns the priority of the workload including the
+// priority offset of its ClusterQueue in the snapshot.
+func effectivePriority(snapshot *cache.Snapshot, wi *workload.Info) int32 {
+	if cq := snapshot.ClusterQueues[wi.ClusterQueue]; cq != nil {
+		return cq.EffectivePriority(wi.Obj)
+	}
+	return priority.Priority(wi.Obj)
+}
+
 func quotaReservationTime(wl *kueue.Workload, now time.Time) time.Time {
 	cond := meta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadQuotaReserved)
 	if cond == nil || cond.Status != metav1.ConditionTrue {
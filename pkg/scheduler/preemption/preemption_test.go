@@ -43,7 +43,7 @@ import (
 
 var snapCmpOpts = []cmp.Option{
 	cmpopts.EquateEmpty(),
-	cmpopts.IgnoreUnexported(cache.ClusterQueue{}),
+	cmpopts.IgnoreUnexported(cache.ClusterQueue{}, cache.Cohort{}),
 	cmpopts.IgnoreFields(cache.Cohort{}, "AllocatableResourceGeneration"),
 	cmpopts.IgnoreFields(cache.ClusterQueue{}, "AllocatableResourceGeneration"),
 	cmp.Transformer("Cohort.Members", func(s sets.Set[*cache.ClusterQueue]) sets.Set[string] {
@@ -1156,7 +1156,7 @@ func TestCandidatesOrdering(t *testing.T) {
 			}).
 			Obj()),
 	}
-	sort.Slice(candidates, candidatesOrdering(candidates, "self", now))
+	sort.Slice(candidates, candidatesOrdering(candidates, &cache.Snapshot{}, "self", now))
 	gotNames := make([]string, len(candidates))
 	for i, c := range candidates {
 		gotNames[i] = workload.Key(c.Obj)
@@ -1167,6 +1167,35 @@ func TestCandidatesOrdering(t *testing.T) {
 	}
 }
 
+func TestCandidatesOrderingWithPriorityOffset(t *testing.T) {
+	now := time.Now()
+	candidates := []*workload.Info{
+		workload.NewInfo(utiltesting.MakeWorkload("boosted", "").
+			ReserveQuotaAt(utiltesting.MakeAdmission("boosted").Obj(), now).
+			Priority(10).
+			Obj()),
+		workload.NewInfo(utiltesting.MakeWorkload("penalized", "").
+			ReserveQuotaAt(utiltesting.MakeAdmission("penalized").Obj(), now).
+			Priority(10).
+			Obj()),
+	}
+	snapshot := &cache.Snapshot{
+		ClusterQueues: map[string]*cache.ClusterQueue{
+			"boosted":   {Name: "boosted", PriorityOffset: 5},
+			"penalized": {Name: "penalized", PriorityOffset: -5},
+		},
+	}
+	sort.Slice(candidates, candidatesOrdering(candidates, snapshot, "self", now))
+	gotNames := make([]string, len(candidates))
+	for i, c := range candidates {
+		gotNames[i] = workload.Key(c.Obj)
+	}
+	wantCandidates := []string{"/penalized", "/boosted"}
+	if diff := cmp.Diff(wantCandidates, gotNames); diff != "" {
+		t.Errorf("Sorted with wrong order (-want,+got):\n%s", diff)
+	}
+}
+
 func singlePodSetAssignment(assignments flavorassigner.ResourceAssignment) flavorassigner.Assignment {
 	return flavorassigner.Assignment{
 		PodSets: []flavorassigner.PodSetAssignment{{
@@ -190,10 +190,13 @@ func (s *Scheduler) schedule(ctx context.Context) {
 	// 3. Calculate requirements (resource flavors, borrowing) for admitting workloads.
 	entries := s.nominate(ctx, headWorkloads, snapshot)
 
-	// 4. Sort entries based on borrowing, priorities (if enabled) and timestamps.
+	// 4. Sort entries based on borrowing, priorities (if enabled), fair sharing
+	// and timestamps.
 	sort.Sort(entryOrdering{
 		entries:          entries,
 		workloadOrdering: s.workloadOrdering,
+		clusterQueues:    snapshot.ClusterQueues,
+		shares:           borrowingShares(entries, &snapshot),
 	})
 
 	// 5. Admit entries, ensuring that no more than one workload gets
@@ -209,6 +212,10 @@ func (s *Scheduler) schedule(ctx context.Context) {
 		if mode == flavorassigner.NoFit {
 			continue
 		}
+		if flv := s.flavorRequiringProvisioning(e); flv != "" && s.cache.ProvisioningChecks(e.ClusterQueue).Len() == 0 {
+			e.inadmissibleMsg = fmt.Sprintf("Flavor %s requires provisioning, but the ClusterQueue has no active provisioning admission check", flv)
+			continue
+		}
 
 		cq := snapshot.ClusterQueues[e.ClusterQueue]
 		if cq.Cohort != nil {
@@ -305,10 +312,12 @@ type entry struct {
 	// workload.Info holds the workload from the API as well as resource usage
 	// and flavors assigned.
 	workload.Info
-	assignment        flavorassigner.Assignment
-	status            entryStatus
-	inadmissibleMsg   string
-	requeueReason     queue.RequeueReason
+	assignment      flavorassigner.Assignment
+	status          entryStatus
+	inadmissibleMsg string
+	requeueReason   queue.RequeueReason
+	// requeueAfter is how long the workload has to wait before it's requeued.
+	requeueAfter      time.Duration
 	preemptionTargets []*workload.Info
 }
 
@@ -325,8 +334,13 @@ func (s *Scheduler) nominate(ctx context.Context, workloads []workload.Info, sna
 		if s.cache.IsAssumedOrAdmittedWorkload(w) {
 			log.Info("Workload skipped from admission because it's already assumed or admitted", "workload", klog.KObj(w.Obj))
 			continue
+		} else if !s.cache.IsWorkloadActive(w.Obj) {
+			e.inadmissibleMsg = "The workload is deactivated"
 		} else if workload.HasRetryOrRejectedChecks(w.Obj) {
 			e.inadmissibleMsg = "The workload has failed admission checks"
+		} else if backoff := s.cache.RequeueAfter(w.Obj); backoff > 0 {
+			e.inadmissibleMsg = fmt.Sprintf("Waiting %s before retrying admission after previous failures", backoff.Round(time.Second))
+			e.requeueAfter = backoff
 		} else if snap.InactiveClusterQueueSets.Has(w.ClusterQueue) {
 			e.inadmissibleMsg = fmt.Sprintf("ClusterQueue %s is inactive", w.ClusterQueue)
 		} else if cq == nil {
@@ -350,6 +364,24 @@ func (s *Scheduler) nominate(ctx context.Context, workloads []workload.Info, sna
 	return entries
 }
 
+// flavorRequiringProvisioning returns the first flavor, in alphabetical order,
+// of the assignment whose capacity needs to be provisioned, or an empty string
+// if none does. Such workloads are only admitted once a provisioning admission
+// check is ready.
+func (s *Scheduler) flavorRequiringProvisioning(e *entry) kueue.ResourceFlavorReference {
+	flavors := make([]kueue.ResourceFlavorReference, 0, len(e.assignment.Usage))
+	for flv := range e.assignment.Usage {
+		flavors = append(flavors, flv)
+	}
+	sort.Slice(flavors, func(i, j int) bool { return flavors[i] < flavors[j] })
+	for _, flv := range flavors {
+		if s.cache.RequiresProvisioning(string(flv)) {
+			return flv
+		}
+	}
+	return ""
+}
+
 // resourcesToReserve calculates how much of the available resources in cq/cohort assignment should be reserved.
 func resourcesToReserve(e *entry, cq *cache.ClusterQueue) cache.FlavorResourceQuantities {
 	if e.assignment.RepresentativeMode() != flavorassigner.Preempt {
@@ -389,7 +421,7 @@ type partialAssignment struct {
 
 func (s *Scheduler) getAssignments(log logr.Logger, wl *workload.Info, snap *cache.Snapshot) (flavorassigner.Assignment, []*workload.Info) {
 	cq := snap.ClusterQueues[wl.ClusterQueue]
-	flvAssigner := flavorassigner.New(wl, cq, snap.ResourceFlavors)
+	flvAssigner := flavorassigner.New(wl, cq, snap.ResourceFlavors, snap.FlavorObservedCapacity, snap.FlavorUsage, snap.DisabledFlavors)
 	fullAssignment := flvAssigner.Assign(log, nil)
 	var faPreemtionTargets []*workload.Info
 
@@ -534,6 +566,7 @@ func (s *Scheduler) admit(ctx context.Context, e *entry, mustHaveChecks sets.Set
 		}
 
 		log.Error(err, errCouldNotAdmitWL)
+		s.cache.RecordAdmissionFailure(newWorkload)
 		s.requeueAndUpdate(log, ctx, *e)
 	})
 
@@ -547,6 +580,25 @@ func (s *Scheduler) applyAdmissionWithSSA(ctx context.Context, w *kueue.Workload
 type entryOrdering struct {
 	entries          []entry
 	workloadOrdering workload.Ordering
+	// clusterQueues are used to obtain the priority offsets of the entries'
+	// ClusterQueues.
+	clusterQueues map[string]*cache.ClusterQueue
+	// shares are the dominant resource shares of the ClusterQueues of the
+	// borrowing entries.
+	shares map[string]float64
+}
+
+// borrowingShares returns the dominant resource shares of the ClusterQueues
+// of the entries that need to borrow.
+func borrowingShares(entries []entry, snapshot *cache.Snapshot) map[string]float64 {
+	shares := make(map[string]float64)
+	for i := range entries {
+		e := &entries[i]
+		if _, found := shares[e.ClusterQueue]; !found && e.assignment.Borrows() {
+			shares[e.ClusterQueue] = snapshot.DominantResourceShare(e.ClusterQueue)
+		}
+	}
+	return shares
 }
 
 func (e entryOrdering) Len() int {
@@ -559,8 +611,9 @@ func (e entryOrdering) Swap(i, j int) {
 
 // Less is the ordering criteria:
 // 1. request under nominal quota before borrowing.
-// 2. higher priority first.
-// 3. FIFO on eviction or creation timestamp.
+// 2. higher effective priority first.
+// 3. when borrowing, lower dominant resource share in the cohort first.
+// 4. FIFO on eviction or creation timestamp.
 func (e entryOrdering) Less(i, j int) bool {
 	a := e.entries[i]
 	b := e.entries[j]
@@ -574,26 +627,49 @@ func (e entryOrdering) Less(i, j int) bool {
 
 	// 2. Higher priority first if not disabled.
 	if features.Enabled(features.PrioritySortingWithinCohort) {
-		p1 := priority.Priority(a.Obj)
-		p2 := priority.Priority(b.Obj)
+		p1 := e.effectivePriority(&a)
+		p2 := e.effectivePriority(&b)
 		if p1 != p2 {
 			return p1 > p2
 		}
 	}
 
-	// 3. FIFO.
+	// 3. Fair sharing among the borrowing ClusterQueues. The entries of
+	// different cohorts don't compete for quota, so comparing their shares
+	// only keeps the ordering consistent.
+	if aBorrows {
+		if aShare, bShare := e.shares[a.ClusterQueue], e.shares[b.ClusterQueue]; aShare != bShare {
+			return aShare < bShare
+		}
+	}
+
+	// 4. FIFO.
 	aComparisonTimestamp := e.workloadOrdering.GetQueueOrderTimestamp(a.Obj)
 	bComparisonTimestamp := e.workloadOrdering.GetQueueOrderTimestamp(b.Obj)
 	return aComparisonTimestamp.Before(bComparisonTimestamp)
 }
 
+// effectivePriority returns the priority of the entry including the priority
+// offset of its ClusterQueue.
+func (e entryOrdering) effectivePriority(en *entry) int32 {
+	if cq := e.clusterQueues[en.ClusterQueue]; cq != nil {
+		return cq.EffectivePriority(en.Obj)
+	}
+	return priority.Priority(en.Obj)
+}
+
 func (s *Scheduler) requeueAndUpdate(log logr.Logger, ctx context.Context, e entry) {
 	if e.status != notNominated && e.requeueReason == queue.RequeueReasonGeneric {
 		// Failed after nomination is the only reason why a workload would be requeued downstream.
 		e.requeueReason = queue.RequeueReasonFailedAfterNomination
 	}
-	added := s.queues.RequeueWorkload(ctx, &e.Info, e.requeueReason)
-	log.V(2).Info("Workload re-queued", "workload", klog.KObj(e.Obj), "clusterQueue", klog.KRef("", e.ClusterQueue), "queue", klog.KRef(e.Obj.Namespace, e.Obj.Spec.QueueName), "requeueReason", e.requeueReason, "added", added)
+	if e.requeueAfter > 0 {
+		s.queues.RequeueWorkloadAfter(ctx, &e.Info, e.requeueAfter)
+		log.V(2).Info("Workload re-queue delayed", "workload", klog.KObj(e.Obj), "clusterQueue", klog.KRef("", e.ClusterQueue), "queue", klog.KRef(e.Obj.Namespace, e.Obj.Spec.QueueName), "requeueAfter", e.requeueAfter)
+	} else {
+		added := s.queues.RequeueWorkload(ctx, &e.Info, e.requeueReason)
+		log.V(2).Info("Workload re-queued", "workload", klog.KObj(e.Obj), "clusterQueue", klog.KRef("", e.ClusterQueue), "queue", klog.KRef(e.Obj.Namespace, e.Obj.Spec.QueueName), "requeueReason", e.requeueReason, "added", added)
+	}
 
 	if e.status == notNominated || e.status == skipped {
 		if workload.UnsetQuotaReservationWithCondition(e.Obj, "Pending", e.inadmissibleMsg) {
@@ -34,6 +34,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
+	testingclock "k8s.io/utils/clock/testing"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
@@ -42,6 +43,7 @@ import (
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
 	"sigs.k8s.io/kueue/pkg/cache"
 	"sigs.k8s.io/kueue/pkg/constants"
+	controllerconsts "sigs.k8s.io/kueue/pkg/controller/constants"
 	"sigs.k8s.io/kueue/pkg/features"
 	"sigs.k8s.io/kueue/pkg/queue"
 	"sigs.k8s.io/kueue/pkg/scheduler/flavorassigner"
@@ -65,6 +67,10 @@ func TestSchedule(t *testing.T) {
 		{ObjectMeta: metav1.ObjectMeta{Name: "on-demand"}},
 		{ObjectMeta: metav1.ObjectMeta{Name: "spot"}},
 		{ObjectMeta: metav1.ObjectMeta{Name: "model-a"}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:        "burst",
+			Annotations: map[string]string{controllerconsts.ResourceFlavorProvisioningClassAnnotation: "queued-provisioning"},
+		}},
 	}
 	clusterQueues := []kueue.ClusterQueue{
 		*utiltesting.MakeClusterQueue("sales").
@@ -770,6 +776,95 @@ func TestSchedule(t *testing.T) {
 				"eng-alpha/use-all": *utiltesting.MakeAdmission("other-alpha").Assignment(corev1.ResourceCPU, "on-demand", "100").Obj(),
 			},
 		},
+		"doesn't borrow from a secondary cohort when the cohort is saturated": {
+			additionalClusterQueues: []kueue.ClusterQueue{
+				*utiltesting.MakeClusterQueue("borrower").
+					Cohort("primary").
+					Annotations(map[string]string{controllerconsts.SecondaryCohortsAnnotation: "secondary"}).
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "0").Obj()).
+					Obj(),
+				*utiltesting.MakeClusterQueue("primary-lender").
+					Cohort("primary").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "4").Obj()).
+					Obj(),
+				*utiltesting.MakeClusterQueue("secondary-lender").
+					Cohort("secondary").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "4").Obj()).
+					Obj(),
+			},
+			additionalLocalQueues: []kueue.LocalQueue{
+				*utiltesting.MakeLocalQueue("borrower", "sales").ClusterQueue("borrower").Obj(),
+			},
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("new", "sales").
+					Queue("borrower").
+					Request(corev1.ResourceCPU, "3").
+					Obj(),
+				*utiltesting.MakeWorkload("use-all", "sales").
+					Request(corev1.ResourceCPU, "4").
+					ReserveQuota(utiltesting.MakeAdmission("primary-lender").Assignment(corev1.ResourceCPU, "default", "4").Obj()).
+					Obj(),
+			},
+			wantInadmissibleLeft: map[string][]string{
+				"borrower": {"sales/new"},
+			},
+			wantAssignments: map[string]kueue.Admission{
+				"sales/use-all": *utiltesting.MakeAdmission("primary-lender").Assignment(corev1.ResourceCPU, "default", "4").Obj(),
+			},
+		},
+		"lends to the members of a secondary cohort": {
+			additionalClusterQueues: []kueue.ClusterQueue{
+				*utiltesting.MakeClusterQueue("borrower").
+					Cohort("secondary").
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "0").Obj()).
+					Obj(),
+				*utiltesting.MakeClusterQueue("lender").
+					Cohort("primary").
+					Annotations(map[string]string{controllerconsts.SecondaryCohortsAnnotation: "secondary"}).
+					ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+						Resource(corev1.ResourceCPU, "4").Obj()).
+					Obj(),
+			},
+			additionalLocalQueues: []kueue.LocalQueue{
+				*utiltesting.MakeLocalQueue("borrower", "sales").ClusterQueue("borrower").Obj(),
+			},
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("new", "sales").
+					Queue("borrower").
+					Request(corev1.ResourceCPU, "3").
+					Obj(),
+			},
+			wantAssignments: map[string]kueue.Admission{
+				"sales/new": *utiltesting.MakeAdmission("borrower").Assignment(corev1.ResourceCPU, "default", "3").Obj(),
+			},
+			wantScheduled: []string{"sales/new"},
+		},
+		"workload assigned to a flavor requiring provisioning without a provisioning check": {
+			additionalClusterQueues: []kueue.ClusterQueue{
+				*utiltesting.MakeClusterQueue("burst-cq").
+					ResourceGroup(
+						*utiltesting.MakeFlavorQuotas("burst").
+							Resource(corev1.ResourceCPU, "50").Obj(),
+					).
+					Obj(),
+			},
+			additionalLocalQueues: []kueue.LocalQueue{
+				*utiltesting.MakeLocalQueue("burst", "sales").ClusterQueue("burst-cq").Obj(),
+			},
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("new", "sales").
+					Queue("burst").
+					Request(corev1.ResourceCPU, "1").
+					Obj(),
+			},
+			wantInadmissibleLeft: map[string][]string{
+				"burst-cq": {"sales/new"},
+			},
+		},
 		"cannot borrow resource not listed in clusterQueue": {
 			workloads: []kueue.Workload{
 				*utiltesting.MakeWorkload("new", "eng-alpha").
@@ -1348,7 +1443,9 @@ func TestSchedule(t *testing.T) {
 			wantScheduled: []string{
 				"eng-beta/b",
 			},
-			wantInadmissibleLeft: map[string][]string{
+			// cq_b doesn't borrow yet, so its workload goes first and the
+			// workload of cq_a is requeued because the cohort was used.
+			wantLeft: map[string][]string{
 				"cq_a": {"eng-alpha/a"},
 			},
 		},
@@ -1636,6 +1733,87 @@ func TestEntryOrdering(t *testing.T) {
 	}
 }
 
+func TestEntryOrderingFairSharing(t *testing.T) {
+	ctx, _ := utiltesting.ContextWithLog(t)
+	now := time.Now()
+	spot := utiltesting.MakeResourceFlavor("spot").Obj()
+	spot.Annotations = map[string]string{controllerconsts.ResourceFlavorShareWeightAnnotation: "0"}
+	cqCache := cache.New(utiltesting.NewFakeClient())
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cqCache.AddOrUpdateResourceFlavor(spot)
+	for _, cq := range []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("a").
+			Cohort("one").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "2").Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("b").
+			Cohort("one").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "2").Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("c").
+			Cohort("one").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("spot").Resource(corev1.ResourceCPU, "2").Obj()).
+			Obj(),
+	} {
+		if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Inserting clusterQueue %s in cache: %v", cq.Name, err)
+		}
+	}
+	for _, wl := range []*kueue.Workload{
+		utiltesting.MakeWorkload("admitted_a", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("a").Assignment(corev1.ResourceCPU, "default", "4").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("admitted_b", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("b").Assignment(corev1.ResourceCPU, "default", "3").Obj()).
+			Obj(),
+		utiltesting.MakeWorkload("admitted_c", "ns").
+			ReserveQuota(utiltesting.MakeAdmission("c").Assignment(corev1.ResourceCPU, "spot", "6").Obj()).
+			Obj(),
+	} {
+		if !cqCache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %q", wl.Name)
+		}
+	}
+	newEntry := func(name, cqName string, created time.Time, borrowing bool) entry {
+		return entry{
+			Info: workload.Info{
+				Obj: &kueue.Workload{ObjectMeta: metav1.ObjectMeta{
+					Name:              name,
+					CreationTimestamp: metav1.NewTime(created),
+				}},
+				ClusterQueue: cqName,
+			},
+			assignment: flavorassigner.Assignment{
+				Borrowing: borrowing,
+			},
+		}
+	}
+	entries := []entry{
+		newEntry("borrowing_a", "a", now, true),
+		newEntry("borrowing_b", "b", now.Add(2*time.Second), true),
+		newEntry("borrowing_c", "c", now.Add(3*time.Second), true),
+		newEntry("not_borrowing_a", "a", now.Add(4*time.Second), false),
+	}
+
+	snapshot := cqCache.Snapshot()
+	sort.Sort(entryOrdering{
+		entries:          entries,
+		workloadOrdering: workload.Ordering{PodsReadyRequeuingTimestamp: config.EvictionTimestamp},
+		clusterQueues:    snapshot.ClusterQueues,
+		shares:           borrowingShares(entries, &snapshot),
+	})
+	order := make([]string, len(entries))
+	for i, e := range entries {
+		order[i] = e.Obj.Name
+	}
+	// The usage of spot doesn't count towards the share of c, and b borrows
+	// less than a.
+	wantOrder := []string{"not_borrowing_a", "borrowing_c", "borrowing_b", "borrowing_a"}
+	if diff := cmp.Diff(wantOrder, order); diff != "" {
+		t.Errorf("Unexpected order (-want,+got):\n%s", diff)
+	}
+}
+
 func TestLastSchedulingContext(t *testing.T) {
 	resourceFlavors := []*kueue.ResourceFlavor{
 		{ObjectMeta: metav1.ObjectMeta{Name: "on-demand"}},
@@ -2193,6 +2371,161 @@ func TestRequeueAndUpdate(t *testing.T) {
 	}
 }
 
+func TestScheduleAfterAdmissionBackoff(t *testing.T) {
+	rf := utiltesting.MakeResourceFlavor("default").Obj()
+	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue("cq").Obj()
+	w1 := utiltesting.MakeWorkload("w1", "ns1").Queue(q1.Name).Request(corev1.ResourceCPU, "1").Obj()
+
+	cases := map[string]kueue.QueueingStrategy{
+		"best effort fifo": kueue.BestEffortFIFO,
+		"strict fifo":      kueue.StrictFIFO,
+	}
+	for name, strategy := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx, _ := utiltesting.ContextWithLog(t)
+			cq := utiltesting.MakeClusterQueue("cq").
+				QueueingStrategy(strategy).
+				ResourceGroup(*utiltesting.MakeFlavorQuotas("default").
+					Resource(corev1.ResourceCPU, "2").Obj()).
+				Obj()
+			cl := utiltesting.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+				SubResourcePatch: utiltesting.TreatSSAAsStrategicMerge,
+			}).
+				WithObjects(w1, q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).
+				WithStatusSubresource(w1).
+				Build()
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			cqCache := cache.New(cl, cache.WithClock(fakeClock))
+			qManager := queue.NewManager(cl, cqCache, queue.WithClock(fakeClock))
+			cqCache.AddOrUpdateResourceFlavor(rf)
+			if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Inserting clusterQueue %s in cache: %v", cq.Name, err)
+			}
+			if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
+			}
+			if err := qManager.AddLocalQueue(ctx, q1); err != nil {
+				t.Fatalf("Inserting queue %s/%s in manager: %v", q1.Namespace, q1.Name, err)
+			}
+			cqCache.RecordAdmissionFailure(w1)
+
+			scheduler := New(qManager, cqCache, cl, &utiltesting.EventRecorder{})
+			var gotScheduled []string
+			var mu sync.Mutex
+			scheduler.applyAdmission = func(ctx context.Context, w *kueue.Workload) error {
+				mu.Lock()
+				gotScheduled = append(gotScheduled, workload.Key(w))
+				mu.Unlock()
+				return nil
+			}
+			wg := sync.WaitGroup{}
+			scheduler.setAdmissionRoutineWrapper(routine.NewWrapper(
+				func() { wg.Add(1) },
+				func() { wg.Done() },
+			))
+
+			ctx, cancel := context.WithTimeout(ctx, queueingTimeout)
+			go qManager.CleanUpOnContext(ctx)
+			defer cancel()
+
+			scheduler.schedule(ctx)
+			wg.Wait()
+			if len(gotScheduled) != 0 {
+				t.Errorf("Workloads scheduled during the backoff: %v", gotScheduled)
+			}
+			if dump := qManager.Dump(); dump != nil {
+				t.Errorf("Unexpected elements in the queue during the backoff: %v", dump)
+			}
+			if dump := qManager.DumpInadmissible(); dump != nil {
+				t.Errorf("Unexpected inadmissible elements during the backoff: %v", dump)
+			}
+
+			fakeClock.Step(2 * time.Second)
+			scheduler.schedule(ctx)
+			wg.Wait()
+			if diff := cmp.Diff([]string{workload.Key(w1)}, gotScheduled); diff != "" {
+				t.Errorf("Unexpected scheduled workloads after the backoff (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestScheduleWithBorrowingPaused(t *testing.T) {
+	ctx, _ := utiltesting.ContextWithLog(t)
+	rf := utiltesting.MakeResourceFlavor("default").Obj()
+	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue("a").Obj()
+	w1 := utiltesting.MakeWorkload("w1", "ns1").Queue(q1.Name).Request(corev1.ResourceCPU, "3").Obj()
+	cqs := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("a").
+			Cohort("one").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "2").Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("b").
+			Cohort("one").
+			ResourceGroup(*utiltesting.MakeFlavorQuotas("default").Resource(corev1.ResourceCPU, "2").Obj()).
+			Obj(),
+	}
+	cl := utiltesting.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		SubResourcePatch: utiltesting.TreatSSAAsStrategicMerge,
+	}).
+		WithObjects(w1, q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).
+		WithStatusSubresource(w1).
+		Build()
+	cqCache := cache.New(cl, cache.WithBorrowingPaused(true))
+	qManager := queue.NewManager(cl, cqCache)
+	cqCache.AddOrUpdateResourceFlavor(rf)
+	for _, cq := range cqs {
+		if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Inserting clusterQueue %s in cache: %v", cq.Name, err)
+		}
+		if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
+		}
+	}
+	if err := qManager.AddLocalQueue(ctx, q1); err != nil {
+		t.Fatalf("Inserting queue %s/%s in manager: %v", q1.Namespace, q1.Name, err)
+	}
+
+	scheduler := New(qManager, cqCache, cl, &utiltesting.EventRecorder{})
+	var gotScheduled []string
+	var mu sync.Mutex
+	scheduler.applyAdmission = func(ctx context.Context, w *kueue.Workload) error {
+		mu.Lock()
+		gotScheduled = append(gotScheduled, workload.Key(w))
+		mu.Unlock()
+		return nil
+	}
+	wg := sync.WaitGroup{}
+	scheduler.setAdmissionRoutineWrapper(routine.NewWrapper(
+		func() { wg.Add(1) },
+		func() { wg.Done() },
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, queueingTimeout)
+	go qManager.CleanUpOnContext(ctx)
+	defer cancel()
+
+	scheduler.schedule(ctx)
+	wg.Wait()
+	if len(gotScheduled) != 0 {
+		t.Errorf("Workloads scheduled while borrowing is paused: %v", gotScheduled)
+	}
+	wantInadmissible := map[string][]string{"a": {workload.Key(w1)}}
+	if diff := cmp.Diff(wantInadmissible, qManager.DumpInadmissible()); diff != "" {
+		t.Errorf("Unexpected inadmissible workloads while borrowing is paused (-want,+got):\n%s", diff)
+	}
+
+	if !cqCache.SetBorrowingPaused(false) {
+		t.Fatal("Borrowing wasn't paused")
+	}
+	qManager.QueueInadmissibleWorkloads(ctx, sets.New(qManager.GetClusterQueueNames()...))
+	scheduler.schedule(ctx)
+	wg.Wait()
+	if diff := cmp.Diff([]string{workload.Key(w1)}, gotScheduled); diff != "" {
+		t.Errorf("Unexpected scheduled workloads after resuming borrowing (-want,+got):\n%s", diff)
+	}
+}
+
 func TestResourcesToReserve(t *testing.T) {
 	resourceFlavors := []*kueue.ResourceFlavor{
 		{ObjectMeta: metav1.ObjectMeta{Name: "on-demand"}},
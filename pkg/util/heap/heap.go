@@ -170,6 +170,25 @@ func (h *Heap[T]) List() []*T {
 	return list
 }
 
+// Ordered returns a list of all the items in the order in which Pop would
+// return them, without modifying the heap.
+func (h *Heap[T]) Ordered() []*T {
+	clone := data[T]{
+		items:    make(map[string]*heapItem[T], len(h.data.items)),
+		keys:     append([]string(nil), h.data.keys...),
+		keyFunc:  h.data.keyFunc,
+		lessFunc: h.data.lessFunc,
+	}
+	for key, item := range h.data.items {
+		clone.items[key] = &heapItem[T]{obj: item.obj, index: item.index}
+	}
+	list := make([]*T, 0, h.Len())
+	for clone.Len() > 0 {
+		list = append(list, heap.Pop(&clone).(*T))
+	}
+	return list
+}
+
 // New returns a Heap which can be used to queue up items to process.
 func New[T any](keyFn keyFunc[T], lessFn lessFunc[T]) Heap[T] {
 	return Heap[T]{
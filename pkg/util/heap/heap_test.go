@@ -20,6 +20,7 @@ limitations under the License.
 package heap
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -261,3 +262,22 @@ func TestHeap_List(t *testing.T) {
 		}
 	}
 }
+
+// TestHeap_Ordered tests that Heap.Ordered returns the items in the order in
+// which they are popped, including ties, without modifying the heap.
+func TestHeap_Ordered(t *testing.T) {
+	h := New(testHeapObjectKeyFunc, compareInts)
+	for i, val := range []int{5, 3, 5, 1, 3, 8, 1} {
+		h.PushOrUpdate(mkHeapObj(fmt.Sprintf("obj-%d", i), val))
+	}
+
+	ordered := h.Ordered()
+	if h.Len() != len(ordered) {
+		t.Fatalf("Heap was modified, got %d items, want %d", h.Len(), len(ordered))
+	}
+	for i, want := range ordered {
+		if got := h.Pop(); got.name != want.name {
+			t.Errorf("Unexpected item at position %d, got %q, want %q", i, got.name, want.name)
+		}
+	}
+}
@@ -416,6 +416,16 @@ func (p *PodSetWrapper) PodOverHead(resources corev1.ResourceList) *PodSetWrappe
 	return p
 }
 
+func (p *PodSetWrapper) TerminationGracePeriod(seconds int64) *PodSetWrapper {
+	p.Template.Spec.TerminationGracePeriodSeconds = ptr.To(seconds)
+	return p
+}
+
+func (p *PodSetWrapper) TopologySpreadConstraints(constraints ...corev1.TopologySpreadConstraint) *PodSetWrapper {
+	p.Template.Spec.TopologySpreadConstraints = constraints
+	return p
+}
+
 // AdmissionWrapper wraps an Admission
 type AdmissionWrapper struct{ kueue.Admission }
 
@@ -525,6 +535,12 @@ func (c *ClusterQueueWrapper) Obj() *kueue.ClusterQueue {
 	return &c.ClusterQueue
 }
 
+// Annotations sets the annotations of the ClusterQueue.
+func (c *ClusterQueueWrapper) Annotations(annotations map[string]string) *ClusterQueueWrapper {
+	c.ObjectMeta.Annotations = annotations
+	return c
+}
+
 // Cohort sets the borrowing cohort.
 func (c *ClusterQueueWrapper) Cohort(cohort string) *ClusterQueueWrapper {
 	c.Spec.Cohort = cohort
@@ -638,6 +654,17 @@ func (f *FlavorQuotasWrapper) Resource(name corev1.ResourceName, qs ...string) *
 	return f
 }
 
+// OvercommitRatio sets the overcommit ratio of the resource, which must be
+// added before with Resource.
+func (f *FlavorQuotasWrapper) OvercommitRatio(name corev1.ResourceName, ratio float64) *FlavorQuotasWrapper {
+	for i := range f.Resources {
+		if f.Resources[i].Name == name {
+			f.Resources[i].OvercommitRatio = ptr.To(ratio)
+		}
+	}
+	return f
+}
+
 // ResourceFlavorWrapper wraps a ResourceFlavor.
 type ResourceFlavorWrapper struct{ kueue.ResourceFlavor }
 
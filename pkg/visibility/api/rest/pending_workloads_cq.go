@@ -66,7 +66,7 @@ func (m *pendingWorkloadsInCqREST) Get(ctx context.Context, name string, opts ru
 	offset := pendingWorkloadOpts.Offset
 
 	wls := make([]v1alpha1.PendingWorkload, 0, limit)
-	pendingWorkloadsInfo := m.queueMgr.PendingWorkloadsInfo(name)
+	pendingWorkloadsInfo := m.queueMgr.PendingWorkloadsOrdered(name)
 	if pendingWorkloadsInfo == nil {
 		return nil, errors.NewNotFound(v1alpha1.Resource("clusterqueue"), name)
 	}
@@ -82,7 +82,7 @@ func (m *pendingWorkloadsInCqREST) Get(ctx context.Context, name string, opts ru
 
 		if index >= int(offset) {
 			// Add a workload to results
-			wls = append(wls, *newPendingWorkload(wlInfo, positionInLocalQueue, index))
+			wls = append(wls, *newPendingWorkload(wlInfo, positionInLocalQueue, index, m.queueMgr.QueuedAt(wlInfo.Obj)))
 		}
 	}
 	return &v1alpha1.PendingWorkloadsSummary{Items: wls}, nil
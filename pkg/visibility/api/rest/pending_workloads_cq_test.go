@@ -94,6 +94,7 @@ func TestPendingWorkloadsInCQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 0,
 						PositionInLocalQueue:   0,
+						QueuedAt:               v1.NewTime(now),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -105,6 +106,7 @@ func TestPendingWorkloadsInCQ(t *testing.T) {
 						Priority:               lowPrio,
 						PositionInClusterQueue: 1,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now),
 					}},
 			},
 		},
@@ -138,6 +140,7 @@ func TestPendingWorkloadsInCQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 0,
 						PositionInLocalQueue:   0,
+						QueuedAt:               v1.NewTime(now),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -149,6 +152,7 @@ func TestPendingWorkloadsInCQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 1,
 						PositionInLocalQueue:   0,
+						QueuedAt:               v1.NewTime(now.Add(time.Second)),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -160,6 +164,7 @@ func TestPendingWorkloadsInCQ(t *testing.T) {
 						Priority:               lowPrio,
 						PositionInClusterQueue: 2,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -171,6 +176,7 @@ func TestPendingWorkloadsInCQ(t *testing.T) {
 						Priority:               lowPrio,
 						PositionInClusterQueue: 3,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now.Add(time.Second)),
 					}},
 			},
 		},
@@ -204,6 +210,7 @@ func TestPendingWorkloadsInCQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 0,
 						PositionInLocalQueue:   0,
+						QueuedAt:               v1.NewTime(now),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -215,6 +222,7 @@ func TestPendingWorkloadsInCQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 1,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now.Add(time.Second)),
 					}},
 			},
 		},
@@ -249,6 +257,7 @@ func TestPendingWorkloadsInCQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 1,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now.Add(time.Second)),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -260,6 +269,7 @@ func TestPendingWorkloadsInCQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 2,
 						PositionInLocalQueue:   2,
+						QueuedAt:               v1.NewTime(now.Add(time.Second * 2)),
 					}},
 			},
 		},
@@ -294,6 +304,7 @@ func TestPendingWorkloadsInCQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 1,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now.Add(time.Second)),
 					}},
 			},
 		},
@@ -74,7 +74,7 @@ func (m *pendingWorkloadsInLqREST) Get(ctx context.Context, name string, opts ru
 
 	wls := make([]v1alpha1.PendingWorkload, 0, limit)
 	skippedWls := 0
-	for index, wlInfo := range m.queueMgr.PendingWorkloadsInfo(cqName) {
+	for index, wlInfo := range m.queueMgr.PendingWorkloadsOrdered(cqName) {
 		if len(wls) >= int(limit) {
 			break
 		}
@@ -83,7 +83,7 @@ func (m *pendingWorkloadsInLqREST) Get(ctx context.Context, name string, opts ru
 				skippedWls++
 			} else {
 				// Add a workload to results
-				wls = append(wls, *newPendingWorkload(wlInfo, int32(len(wls)+int(offset)), index))
+				wls = append(wls, *newPendingWorkload(wlInfo, int32(len(wls)+int(offset)), index, m.queueMgr.QueuedAt(wlInfo.Obj)))
 			}
 		}
 	}
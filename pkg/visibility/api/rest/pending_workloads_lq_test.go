@@ -95,6 +95,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 0,
 						PositionInLocalQueue:   0,
+						QueuedAt:               v1.NewTime(now),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -106,6 +107,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               lowPrio,
 						PositionInClusterQueue: 1,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now),
 					}},
 			},
 		},
@@ -140,6 +142,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 0,
 						PositionInLocalQueue:   0,
+						QueuedAt:               v1.NewTime(now),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -151,6 +154,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               lowPrio,
 						PositionInClusterQueue: 2,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now),
 					}},
 			},
 		},
@@ -185,6 +189,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 1,
 						PositionInLocalQueue:   0,
+						QueuedAt:               v1.NewTime(now.Add(time.Second)),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -196,6 +201,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               lowPrio,
 						PositionInClusterQueue: 3,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now.Add(time.Second)),
 					},
 				},
 			},
@@ -232,6 +238,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 0,
 						PositionInLocalQueue:   0,
+						QueuedAt:               v1.NewTime(now),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -243,6 +250,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               lowPrio,
 						PositionInClusterQueue: 1,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now),
 					},
 				},
 			},
@@ -279,6 +287,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 0,
 						PositionInLocalQueue:   0,
+						QueuedAt:               v1.NewTime(now.Add(time.Second)),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -290,6 +299,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               lowPrio,
 						PositionInClusterQueue: 1,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now.Add(time.Second)),
 					},
 				},
 			},
@@ -325,6 +335,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 0,
 						PositionInLocalQueue:   0,
+						QueuedAt:               v1.NewTime(now),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -336,6 +347,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 1,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now.Add(time.Second)),
 					},
 				},
 			},
@@ -372,6 +384,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 1,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now.Add(time.Second)),
 					},
 					{
 						ObjectMeta: v1.ObjectMeta{
@@ -383,6 +396,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 2,
 						PositionInLocalQueue:   2,
+						QueuedAt:               v1.NewTime(now.Add(time.Second * 2)),
 					},
 				},
 			},
@@ -419,6 +433,7 @@ func TestPendingWorkloadsInLQ(t *testing.T) {
 						Priority:               highPrio,
 						PositionInClusterQueue: 1,
 						PositionInLocalQueue:   1,
+						QueuedAt:               v1.NewTime(now.Add(time.Second)),
 					},
 				},
 			},
@@ -21,7 +21,7 @@ import (
 	"sigs.k8s.io/kueue/pkg/workload"
 )
 
-func newPendingWorkload(wlInfo *workload.Info, positionInLq int32, positionInCq int) *v1alpha1.PendingWorkload {
+func newPendingWorkload(wlInfo *workload.Info, positionInLq int32, positionInCq int, queuedAt metav1.Time) *v1alpha1.PendingWorkload {
 	ownerReferences := make([]metav1.OwnerReference, 0, len(wlInfo.Obj.OwnerReferences))
 	for _, ref := range wlInfo.Obj.OwnerReferences {
 		ownerReferences = append(ownerReferences, metav1.OwnerReference{
@@ -42,5 +42,6 @@ func newPendingWorkload(wlInfo *workload.Info, positionInLq int32, positionInCq
 		Priority:               *wlInfo.Obj.Spec.Priority,
 		LocalQueueName:         wlInfo.Obj.Spec.QueueName,
 		PositionInLocalQueue:   positionInLq,
+		QueuedAt:               queuedAt,
 	}
 }
@@ -38,8 +38,9 @@ import (
 )
 
 const (
-	limitIsEmptyErrorMsg string = `must be nil when cohort is empty`
-	lendingLimitErrorMsg string = `must be less than or equal to the nominalQuota`
+	limitIsEmptyErrorMsg    string = `must be nil when cohort is empty`
+	lendingLimitErrorMsg    string = `must be less than or equal to the nominalQuota`
+	overcommitRatioErrorMsg string = `must be greater than or equal to 1`
 )
 
 type ClusterQueueWebhook struct{}
@@ -205,6 +206,9 @@ func validateFlavorQuotas(flavorQuotas kueue.FlavorQuotas, coveredResources []co
 			allErrs = append(allErrs, validateLimit(*rq.LendingLimit, cohort, lendingLimitPath)...)
 			allErrs = append(allErrs, validateLendingLimit(*rq.LendingLimit, rq.NominalQuota, lendingLimitPath)...)
 		}
+		if rq.OvercommitRatio != nil && *rq.OvercommitRatio < 1 {
+			allErrs = append(allErrs, field.Invalid(path.Child("overcommitRatio"), *rq.OvercommitRatio, overcommitRatioErrorMsg))
+		}
 	}
 	return allErrs
 }
@@ -179,6 +179,23 @@ func TestValidateClusterQueue(t *testing.T) {
 			},
 			enableLendingLimit: true,
 		},
+		{
+			name: "flavor quota with overcommitRatio",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				ResourceGroup(
+					*testingutil.MakeFlavorQuotas("x86").Resource("cpu", "1").OvercommitRatio("cpu", 1.5).Obj()).
+				Obj(),
+		},
+		{
+			name: "flavor quota with overcommitRatio less than 1",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				ResourceGroup(
+					*testingutil.MakeFlavorQuotas("x86").Resource("cpu", "1").OvercommitRatio("cpu", 0.5).Obj()).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(resourceGroupsPath.Index(0).Child("flavors").Index(0).Child("resources").Index(0).Child("overcommitRatio"), 0.5, overcommitRatioErrorMsg),
+			},
+		},
 		{
 			name: "empty queueing strategy is supported",
 			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
@@ -18,14 +18,18 @@ package workload
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"maps"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -33,9 +37,11 @@ import (
 	config "sigs.k8s.io/kueue/apis/config/v1beta1"
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
 	"sigs.k8s.io/kueue/pkg/constants"
+	controllerconsts "sigs.k8s.io/kueue/pkg/controller/constants"
 	"sigs.k8s.io/kueue/pkg/features"
 	"sigs.k8s.io/kueue/pkg/util/api"
 	"sigs.k8s.io/kueue/pkg/util/limitrange"
+	"sigs.k8s.io/kueue/pkg/util/priority"
 )
 
 var (
@@ -100,6 +106,13 @@ type Info struct {
 	// already admitted.
 	ClusterQueue   string
 	LastAssignment *AssignmentClusterQueueState
+	// EstimatedDuration is how long the workload is expected to run, as set in
+	// the EstimatedDurationAnnotation, or zero if unknown.
+	EstimatedDuration time.Duration
+	// TerminationGracePeriod is the longest termination grace period of the
+	// pods of the workload, which is how long it may take to drain once
+	// evicted. Pods that don't set it get the default of Kubernetes.
+	TerminationGracePeriod time.Duration
 }
 
 type PodSetResources struct {
@@ -126,7 +139,9 @@ func (psr *PodSetResources) ScaledTo(newCount int32) *PodSetResources {
 
 func NewInfo(w *kueue.Workload) *Info {
 	info := &Info{
-		Obj: w,
+		Obj:                    w,
+		EstimatedDuration:      estimatedDuration(w),
+		TerminationGracePeriod: terminationGracePeriod(w),
 	}
 	if w.Status.Admission != nil {
 		info.ClusterQueue = string(w.Status.Admission.ClusterQueue)
@@ -139,6 +154,33 @@ func NewInfo(w *kueue.Workload) *Info {
 
 func (i *Info) Update(wl *kueue.Workload) {
 	i.Obj = wl
+	i.EstimatedDuration = estimatedDuration(wl)
+	i.TerminationGracePeriod = terminationGracePeriod(wl)
+}
+
+// estimatedDuration returns the duration set in the EstimatedDurationAnnotation
+// of the workload, or zero if it's missing, invalid or not positive.
+func estimatedDuration(w *kueue.Workload) time.Duration {
+	value, found := w.Annotations[controllerconsts.EstimatedDurationAnnotation]
+	if !found {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// terminationGracePeriod returns the longest termination grace period of the
+// PodSets of the workload.
+func terminationGracePeriod(w *kueue.Workload) time.Duration {
+	var longest time.Duration
+	for i := range w.Spec.PodSets {
+		seconds := ptr.Deref(w.Spec.PodSets[i].Template.Spec.TerminationGracePeriodSeconds, corev1.DefaultTerminationGracePeriodSeconds)
+		longest = max(longest, time.Duration(seconds)*time.Second)
+	}
+	return longest
 }
 
 func (i *Info) CanBePartiallyAdmitted() bool {
@@ -163,6 +205,22 @@ func QueueKey(w *kueue.Workload) string {
 	return fmt.Sprintf("%s/%s", w.Namespace, w.Spec.QueueName)
 }
 
+// Fingerprint returns a hash of the shape of the PodSets of the workload:
+// their names, counts and the requests of their pods. Workloads with the same
+// shape get the same fingerprint, regardless of their names.
+func Fingerprint(w *kueue.Workload) string {
+	h := sha256.New()
+	for _, ps := range w.Spec.PodSets {
+		fmt.Fprintf(h, "%s:%d:%d", ps.Name, ps.Count, ptr.Deref(ps.MinCount, ps.Count))
+		requests := newRequests(limitrange.TotalRequests(&ps.Template.Spec))
+		for _, rName := range sets.List(sets.KeySet(requests)) {
+			fmt.Fprintf(h, ",%s=%d", rName, requests[rName])
+		}
+		h.Write([]byte{';'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 func reclaimableCounts(wl *kueue.Workload) map[string]int32 {
 	ret := make(map[string]int32, len(wl.Status.ReclaimablePods))
 	for i := range wl.Status.ReclaimablePods {
@@ -227,7 +285,9 @@ func totalRequestsFromAdmission(wl *kueue.Workload) []PodSetResources {
 			Requests: newRequests(psa.ResourceUsage),
 		}
 
-		if count := currentCounts[psa.Name]; count != setRes.Count {
+		// Only the reclaimable pods reduce the usage; a partially admitted PodSet
+		// keeps the usage of its admitted count.
+		if count := currentCounts[psa.Name]; count < setRes.Count {
 			setRes.Requests.scaleDown(int64(setRes.Count))
 			setRes.Requests.scaleUp(int64(count))
 			setRes.Count = count
@@ -261,7 +321,10 @@ func (r Requests) ToResourceList() corev1.ResourceList {
 }
 
 // ResourceValue returns the integer value for the resource name.
-// It's milli-units for CPU and absolute units for everything else.
+// It's milli-units for CPU and absolute units for everything else: bytes for
+// memory and ephemeral-storage, and counts for extended resources.
+// The quotas and the usage in the cache are both converted with it, so they
+// are always in the same scale.
 func ResourceValue(name corev1.ResourceName, q resource.Quantity) int64 {
 	if name == corev1.ResourceCPU {
 		return q.MilliValue()
@@ -436,6 +499,30 @@ func (o Ordering) GetQueueOrderTimestamp(w *kueue.Workload) *metav1.Time {
 	return &w.CreationTimestamp
 }
 
+// Less returns whether the workload a goes before b in the queueing order:
+// higher priority first, then earlier queue order timestamp, then the
+// namespace and name of the workloads, so that the order is total.
+func (o Ordering) Less(a, b *Info) bool {
+	if pA, pB := priority.Priority(a.Obj), priority.Priority(b.Obj); pA != pB {
+		return pA > pB
+	}
+	tA := o.GetQueueOrderTimestamp(a.Obj)
+	tB := o.GetQueueOrderTimestamp(b.Obj)
+	if !tA.Equal(tB) {
+		return tA.Before(tB)
+	}
+	if a.Obj.Namespace != b.Obj.Namespace {
+		return a.Obj.Namespace < b.Obj.Namespace
+	}
+	return a.Obj.Name < b.Obj.Name
+}
+
+// Less returns whether the workload a goes before b in the queueing order,
+// using the creation timestamps of the workloads. See Ordering.Less.
+func Less(a, b *Info) bool {
+	return Ordering{}.Less(a, b)
+}
+
 // HasQuotaReservation checks if workload is admitted based on conditions
 func HasQuotaReservation(w *kueue.Workload) bool {
 	return apimeta.IsStatusConditionTrue(w.Status.Conditions, kueue.WorkloadQuotaReserved)
@@ -478,6 +565,56 @@ func IsAdmitted(w *kueue.Workload) bool {
 	return apimeta.IsStatusConditionTrue(w.Status.Conditions, kueue.WorkloadAdmitted)
 }
 
+// FlavorForPodSetResource returns the flavor assigned to the resource in the
+// podSet of the workload, and whether the assignment exists.
+func FlavorForPodSetResource(w *kueue.Workload, podSetName string, r corev1.ResourceName) (string, bool) {
+	if w == nil || w.Status.Admission == nil {
+		return "", false
+	}
+	for _, psa := range w.Status.Admission.PodSetAssignments {
+		if psa.Name != podSetName {
+			continue
+		}
+		flavor, found := psa.Flavors[r]
+		return string(flavor), found
+	}
+	return "", false
+}
+
+// PinnedFlavors returns the flavors that the resources of the workload are
+// pinned to, as set in the PinnedFlavorsAnnotation.
+func PinnedFlavors(w *kueue.Workload) (map[corev1.ResourceName]kueue.ResourceFlavorReference, error) {
+	value, found := w.Annotations[controllerconsts.PinnedFlavorsAnnotation]
+	if !found {
+		return nil, nil
+	}
+	pinned := make(map[corev1.ResourceName]kueue.ResourceFlavorReference)
+	for _, pin := range strings.Split(value, ",") {
+		rName, flavor, found := strings.Cut(strings.TrimSpace(pin), "=")
+		if !found || rName == "" || flavor == "" {
+			return nil, fmt.Errorf("invalid flavor pin %q in annotation %s, expected <resource>=<flavor>", pin, controllerconsts.PinnedFlavorsAnnotation)
+		}
+		pinned[corev1.ResourceName(rName)] = kueue.ResourceFlavorReference(flavor)
+	}
+	return pinned, nil
+}
+
+// ExcludedFlavors returns the flavors that the workload must not be assigned,
+// as set in the ExcludedFlavorsAnnotation.
+func ExcludedFlavors(w *kueue.Workload) sets.Set[kueue.ResourceFlavorReference] {
+	value, found := w.Annotations[controllerconsts.ExcludedFlavorsAnnotation]
+	if !found {
+		return nil
+	}
+	excluded := sets.New[kueue.ResourceFlavorReference]()
+	for _, flavor := range strings.Split(value, ",") {
+		if flavor = strings.TrimSpace(flavor); flavor != "" {
+			excluded.Insert(kueue.ResourceFlavorReference(flavor))
+		}
+	}
+	return excluded
+}
+
 // IsFinished returns true if the workload is finished.
 func IsFinished(w *kueue.Workload) bool {
 	return apimeta.IsStatusConditionTrue(w.Status.Conditions, kueue.WorkloadFinished)
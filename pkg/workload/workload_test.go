@@ -31,6 +31,7 @@ import (
 
 	config "sigs.k8s.io/kueue/apis/config/v1beta1"
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1beta1"
+	controllerconsts "sigs.k8s.io/kueue/pkg/controller/constants"
 	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
 )
 
@@ -55,6 +56,7 @@ func TestNewInfo(t *testing.T) {
 						Count: 1,
 					},
 				},
+				TerminationGracePeriod: 30 * time.Second,
 			},
 		},
 		"pending with reclaim": {
@@ -83,6 +85,7 @@ func TestNewInfo(t *testing.T) {
 						Count: 3,
 					},
 				},
+				TerminationGracePeriod: 30 * time.Second,
 			},
 		},
 		"admitted": {
@@ -147,6 +150,7 @@ func TestNewInfo(t *testing.T) {
 						Count: 3,
 					},
 				},
+				TerminationGracePeriod: 30 * time.Second,
 			},
 		},
 		"admitted with reclaim": {
@@ -186,6 +190,102 @@ func TestNewInfo(t *testing.T) {
 						Count: 3,
 					},
 				},
+				TerminationGracePeriod: 30 * time.Second,
+			},
+		},
+		"partially admitted": {
+			workload: *utiltesting.MakeWorkload("", "").
+				PodSets(
+					*utiltesting.MakePodSet("main", 5).
+						SetMinimumCount(2).
+						Request(corev1.ResourceCPU, "10m").
+						Obj(),
+				).
+				ReserveQuota(
+					utiltesting.MakeAdmission("").
+						Assignment(corev1.ResourceCPU, "f1", "20m").
+						AssignmentPodCount(2).
+						Obj(),
+				).
+				Obj(),
+			wantInfo: Info{
+				TotalRequests: []PodSetResources{
+					{
+						Name: "main",
+						Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{
+							corev1.ResourceCPU: "f1",
+						},
+						Requests: Requests{
+							corev1.ResourceCPU: 2 * 10,
+						},
+						Count: 2,
+					},
+				},
+				TerminationGracePeriod: 30 * time.Second,
+			},
+		},
+		"with estimated duration": {
+			workload: *utiltesting.MakeWorkload("", "").
+				Annotations(map[string]string{controllerconsts.EstimatedDurationAnnotation: "30m"}).
+				Request(corev1.ResourceCPU, "10m").
+				Obj(),
+			wantInfo: Info{
+				TotalRequests: []PodSetResources{
+					{
+						Name: "main",
+						Requests: Requests{
+							corev1.ResourceCPU: 10,
+						},
+						Count: 1,
+					},
+				},
+				EstimatedDuration:      30 * time.Minute,
+				TerminationGracePeriod: 30 * time.Second,
+			},
+		},
+		"with termination grace periods": {
+			workload: *utiltesting.MakeWorkload("", "").
+				PodSets(
+					*utiltesting.MakePodSet("driver", 1).Request(corev1.ResourceCPU, "10m").TerminationGracePeriod(10).Obj(),
+					*utiltesting.MakePodSet("workers", 1).Request(corev1.ResourceCPU, "10m").TerminationGracePeriod(120).Obj(),
+				).
+				Obj(),
+			wantInfo: Info{
+				TotalRequests: []PodSetResources{
+					{
+						Name: "driver",
+						Requests: Requests{
+							corev1.ResourceCPU: 10,
+						},
+						Count: 1,
+					},
+					{
+						Name: "workers",
+						Requests: Requests{
+							corev1.ResourceCPU: 10,
+						},
+						Count: 1,
+					},
+				},
+				TerminationGracePeriod: 2 * time.Minute,
+			},
+		},
+		"with invalid estimated duration": {
+			workload: *utiltesting.MakeWorkload("", "").
+				Annotations(map[string]string{controllerconsts.EstimatedDurationAnnotation: "soon"}).
+				Request(corev1.ResourceCPU, "10m").
+				Obj(),
+			wantInfo: Info{
+				TotalRequests: []PodSetResources{
+					{
+						Name: "main",
+						Requests: Requests{
+							corev1.ResourceCPU: 10,
+						},
+						Count: 1,
+					},
+				},
+				TerminationGracePeriod: 30 * time.Second,
 			},
 		},
 	}
@@ -352,6 +452,49 @@ func TestGetQueueOrderTimestamp(t *testing.T) {
 	}
 }
 
+func TestLess(t *testing.T) {
+	now := time.Now()
+	cases := map[string]struct {
+		a, b *kueue.Workload
+		want bool
+	}{
+		"higher priority first": {
+			a:    utiltesting.MakeWorkload("a", "ns").Priority(2).Creation(now.Add(time.Second)).Obj(),
+			b:    utiltesting.MakeWorkload("b", "ns").Priority(1).Creation(now).Obj(),
+			want: true,
+		},
+		"lower priority last": {
+			a: utiltesting.MakeWorkload("a", "ns").Priority(1).Creation(now).Obj(),
+			b: utiltesting.MakeWorkload("b", "ns").Priority(2).Creation(now.Add(time.Second)).Obj(),
+		},
+		"priority tie broken by timestamp": {
+			a:    utiltesting.MakeWorkload("b", "ns").Priority(1).Creation(now).Obj(),
+			b:    utiltesting.MakeWorkload("a", "ns").Priority(1).Creation(now.Add(time.Second)).Obj(),
+			want: true,
+		},
+		"timestamp tie broken by name": {
+			a:    utiltesting.MakeWorkload("a", "ns").Priority(1).Creation(now).Obj(),
+			b:    utiltesting.MakeWorkload("b", "ns").Priority(1).Creation(now).Obj(),
+			want: true,
+		},
+		"timestamp tie broken by namespace": {
+			a: utiltesting.MakeWorkload("a", "ns2").Priority(1).Creation(now).Obj(),
+			b: utiltesting.MakeWorkload("b", "ns1").Priority(1).Creation(now).Obj(),
+		},
+		"same workload": {
+			a: utiltesting.MakeWorkload("a", "ns").Priority(1).Creation(now).Obj(),
+			b: utiltesting.MakeWorkload("a", "ns").Priority(1).Creation(now).Obj(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := Less(NewInfo(tc.a), NewInfo(tc.b)); got != tc.want {
+				t.Errorf("Unexpected Less, got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestReclaimablePodsAreEqual(t *testing.T) {
 	cases := map[string]struct {
 		a, b       []kueue.ReclaimablePod
@@ -527,6 +670,68 @@ func TestIsEvictedByDeactivation(t *testing.T) {
 	}
 }
 
+func TestFlavorForPodSetResource(t *testing.T) {
+	admission := utiltesting.MakeAdmission("cq").
+		PodSets(
+			kueue.PodSetAssignment{
+				Name: "main",
+				Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{
+					corev1.ResourceCPU: "default",
+				},
+			},
+			kueue.PodSetAssignment{
+				Name: "workers",
+				Flavors: map[corev1.ResourceName]kueue.ResourceFlavorReference{
+					corev1.ResourceCPU:    "spot",
+					corev1.ResourceMemory: "spot",
+				},
+			},
+		).
+		Obj()
+	cases := map[string]struct {
+		workload   *kueue.Workload
+		podSet     string
+		resource   corev1.ResourceName
+		wantFlavor string
+		wantFound  bool
+	}{
+		"nil workload": {
+			podSet:   "main",
+			resource: corev1.ResourceCPU,
+		},
+		"nil admission": {
+			workload: utiltesting.MakeWorkload("test", "test").Obj(),
+			podSet:   "main",
+			resource: corev1.ResourceCPU,
+		},
+		"missing podSet": {
+			workload: utiltesting.MakeWorkload("test", "test").ReserveQuota(admission).Obj(),
+			podSet:   "launcher",
+			resource: corev1.ResourceCPU,
+		},
+		"missing resource": {
+			workload: utiltesting.MakeWorkload("test", "test").ReserveQuota(admission).Obj(),
+			podSet:   "main",
+			resource: corev1.ResourceMemory,
+		},
+		"assigned resource": {
+			workload:   utiltesting.MakeWorkload("test", "test").ReserveQuota(admission).Obj(),
+			podSet:     "workers",
+			resource:   corev1.ResourceMemory,
+			wantFlavor: "spot",
+			wantFound:  true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gotFlavor, gotFound := FlavorForPodSetResource(tc.workload, tc.podSet, tc.resource)
+			if gotFlavor != tc.wantFlavor || gotFound != tc.wantFound {
+				t.Errorf("Unexpected result from FlavorForPodSetResource, got (%q, %v), want (%q, %v)", gotFlavor, gotFound, tc.wantFlavor, tc.wantFound)
+			}
+		})
+	}
+}
+
 func TestIsEvictedByPodsReadyTimeout(t *testing.T) {
 	cases := map[string]struct {
 		workload             *kueue.Workload
@@ -584,3 +789,71 @@ func TestIsEvictedByPodsReadyTimeout(t *testing.T) {
 		})
 	}
 }
+
+func TestFingerprint(t *testing.T) {
+	cases := map[string]struct {
+		a, b      *kueue.Workload
+		wantEqual bool
+	}{
+		"same shape with different names": {
+			a: utiltesting.MakeWorkload("a", "ns").
+				Queue("q").
+				Request(corev1.ResourceCPU, "1").
+				Request(corev1.ResourceMemory, "1Gi").
+				Obj(),
+			b: utiltesting.MakeWorkload("b", "other").
+				Queue("other").
+				Priority(10).
+				Request(corev1.ResourceMemory, "1Gi").
+				Request(corev1.ResourceCPU, "1000m").
+				Obj(),
+			wantEqual: true,
+		},
+		"different requests": {
+			a: utiltesting.MakeWorkload("a", "ns").Request(corev1.ResourceCPU, "1").Obj(),
+			b: utiltesting.MakeWorkload("b", "ns").Request(corev1.ResourceCPU, "2").Obj(),
+		},
+		"different resources": {
+			a: utiltesting.MakeWorkload("a", "ns").Request(corev1.ResourceCPU, "1").Obj(),
+			b: utiltesting.MakeWorkload("b", "ns").Request(corev1.ResourceMemory, "1").Obj(),
+		},
+		"different counts": {
+			a: utiltesting.MakeWorkload("a", "ns").
+				PodSets(*utiltesting.MakePodSet("main", 1).Request(corev1.ResourceCPU, "1").Obj()).
+				Obj(),
+			b: utiltesting.MakeWorkload("b", "ns").
+				PodSets(*utiltesting.MakePodSet("main", 2).Request(corev1.ResourceCPU, "1").Obj()).
+				Obj(),
+		},
+		"different minimum counts": {
+			a: utiltesting.MakeWorkload("a", "ns").
+				PodSets(*utiltesting.MakePodSet("main", 2).Request(corev1.ResourceCPU, "1").Obj()).
+				Obj(),
+			b: utiltesting.MakeWorkload("b", "ns").
+				PodSets(*utiltesting.MakePodSet("main", 2).SetMinimumCount(1).Request(corev1.ResourceCPU, "1").Obj()).
+				Obj(),
+		},
+		"same PodSets in a different order": {
+			a: utiltesting.MakeWorkload("a", "ns").
+				PodSets(
+					*utiltesting.MakePodSet("driver", 1).Request(corev1.ResourceCPU, "1").Obj(),
+					*utiltesting.MakePodSet("workers", 4).Request(corev1.ResourceCPU, "2").Obj(),
+				).
+				Obj(),
+			b: utiltesting.MakeWorkload("b", "ns").
+				PodSets(
+					*utiltesting.MakePodSet("workers", 4).Request(corev1.ResourceCPU, "2").Obj(),
+					*utiltesting.MakePodSet("driver", 1).Request(corev1.ResourceCPU, "1").Obj(),
+				).
+				Obj(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a, b := Fingerprint(tc.a), Fingerprint(tc.b)
+			if gotEqual := a == b; gotEqual != tc.wantEqual {
+				t.Errorf("Unexpected fingerprints %q and %q, want equal %t", a, b, tc.wantEqual)
+			}
+		})
+	}
+}